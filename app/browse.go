@@ -0,0 +1,338 @@
+package app
+
+import (
+	"fmt"
+	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/repo/models"
+	"gorm.io/gorm"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const browsePageSize = 20
+
+// browseSorts maps the sort options to their ordering. Every ordering is
+// followed by the dataset ID so that pages are stable.
+var browseSorts = map[string]string{
+	"newest":     "datasets.created_at desc",
+	"price_asc":  "datasets.price_atto asc",
+	"price_desc": "datasets.price_atto desc",
+	"size_asc":   "datasets.file_size asc",
+	"size_desc":  "datasets.file_size desc",
+	"purchases":  "datasets.purchases desc",
+}
+
+// The facets are the filters counts are returned for.
+const (
+	facetFileType = "fileType"
+	facetPrice    = "price"
+	facetSize     = "size"
+	facetSeller   = "seller"
+	facetCreated  = "created"
+)
+
+// priceBuckets and sizeBuckets are the lower bounds, in FIL and bytes, of
+// the ranges counted by the price and size facets.
+var (
+	priceBuckets = []interface{}{fil.Amount{}, fil.MustParseAmount("0.1"), fil.MustParseAmount("1"), fil.MustParseAmount("10"), fil.MustParseAmount("100")}
+	sizeBuckets  = []interface{}{int64(0), int64(1 << 20), int64(100 << 20), int64(1 << 30), int64(10 << 30)}
+)
+
+// createdPeriods are the periods counted by the created facet.
+var createdPeriods = []struct {
+	name     string
+	duration time.Duration
+}{
+	{"day", time.Hour * 24},
+	{"week", time.Hour * 24 * 7},
+	{"month", time.Hour * 24 * 30},
+	{"year", time.Hour * 24 * 365},
+}
+
+// browseFilter holds the filters of a browse request. Zero values are
+// not applied.
+type browseFilter struct {
+	FileTypes []string
+	Sellers   []string
+	MinPrice  *fil.Amount
+	MaxPrice  *fil.Amount
+	MinSize   *int64
+	MaxSize   *int64
+	After     time.Time
+	Before    time.Time
+}
+
+// parseBrowseFilter reads the filters from the query string. The file
+// type and seller filters match any of the values given, either as
+// repeated parameters or separated by commas.
+func parseBrowseFilter(query url.Values) (browseFilter, error) {
+	var (
+		f   browseFilter
+		err error
+	)
+	f.FileTypes = splitValues(query["fileType"])
+	f.Sellers = splitValues(query["seller"])
+
+	for param, dst := range map[string]**fil.Amount{"minPrice": &f.MinPrice, "maxPrice": &f.MaxPrice} {
+		if s := query.Get(param); s != "" {
+			v, err := fil.ParseAmount(s)
+			if err != nil || v.Sign() < 0 {
+				return f, ErrInvalidOption
+			}
+			*dst = &v
+		}
+	}
+	for param, dst := range map[string]**int64{"minSize": &f.MinSize, "maxSize": &f.MaxSize} {
+		if s := query.Get(param); s != "" {
+			v, err := strconv.ParseInt(s, 10, 64)
+			if err != nil || v < 0 {
+				return f, ErrInvalidOption
+			}
+			*dst = &v
+		}
+	}
+	if s := query.Get("after"); s != "" {
+		if f.After, err = time.Parse(time.RFC3339, s); err != nil {
+			return f, ErrInvalidOption
+		}
+	}
+	if s := query.Get("before"); s != "" {
+		if f.Before, err = time.Parse(time.RFC3339, s); err != nil {
+			return f, ErrInvalidOption
+		}
+	}
+	return f, nil
+}
+
+func splitValues(params []string) []string {
+	var values []string
+	for _, param := range params {
+		for _, v := range strings.Split(param, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+// apply adds the filters to the query of listed datasets, except for the
+// filter of the given facet. Leaving out a facet's own filter lets its
+// counts show how many datasets each of its other values would add.
+func (f browseFilter) apply(db *gorm.DB, except string) *gorm.DB {
+	tx := listedDatasets(db)
+	if len(f.FileTypes) > 0 && except != facetFileType {
+		tx = tx.Where("datasets.file_type IN ?", f.FileTypes)
+	}
+	if len(f.Sellers) > 0 && except != facetSeller {
+		tx = tx.Where("datasets.user_id IN ?", f.Sellers)
+	}
+	if except != facetPrice {
+		if f.MinPrice != nil {
+			tx = tx.Where("datasets.price_atto >= ?", *f.MinPrice)
+		}
+		if f.MaxPrice != nil {
+			tx = tx.Where("datasets.price_atto <= ?", *f.MaxPrice)
+		}
+	}
+	if except != facetSize {
+		if f.MinSize != nil {
+			tx = tx.Where("datasets.file_size >= ?", *f.MinSize)
+		}
+		if f.MaxSize != nil {
+			tx = tx.Where("datasets.file_size <= ?", *f.MaxSize)
+		}
+	}
+	if except != facetCreated {
+		if !f.After.IsZero() {
+			tx = tx.Where("datasets.created_at >= ?", f.After)
+		}
+		if !f.Before.IsZero() {
+			tx = tx.Where("datasets.created_at < ?", f.Before)
+		}
+	}
+	return tx
+}
+
+type valueFacet struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Count int64  `json:"count"`
+}
+
+// rangeFacet is the count for values from Min up to but not including
+// Max. The last range has no maximum. The bounds are sizes in bytes or
+// FIL amounts.
+type rangeFacet struct {
+	Min   interface{} `json:"min"`
+	Max   interface{} `json:"max,omitempty"`
+	Count int64       `json:"count"`
+}
+
+type periodFacet struct {
+	Period string    `json:"period"`
+	After  time.Time `json:"after"`
+	Count  int64     `json:"count"`
+}
+
+type browseFacets struct {
+	FileTypes []valueFacet  `json:"fileTypes"`
+	Sellers   []valueFacet  `json:"sellers"`
+	Price     []rangeFacet  `json:"price"`
+	Size      []rangeFacet  `json:"size"`
+	Created   []periodFacet `json:"created"`
+}
+
+// countFacets returns the number of datasets matching each facet value.
+func countFacets(db *gorm.DB, f browseFilter, now time.Time) (browseFacets, error) {
+	facets := browseFacets{
+		FileTypes: []valueFacet{},
+		Sellers:   []valueFacet{},
+	}
+
+	err := f.apply(db, facetFileType).
+		Select("datasets.file_type AS value, count(*) AS count").
+		Group("datasets.file_type").
+		Order("count desc, value").
+		Scan(&facets.FileTypes).Error
+	if err != nil {
+		return facets, err
+	}
+
+	err = f.apply(db, facetSeller).
+		Select("datasets.user_id AS value, datasets.username AS label, count(*) AS count").
+		Group("datasets.user_id, datasets.username").
+		Order("count desc, value").
+		Scan(&facets.Sellers).Error
+	if err != nil {
+		return facets, err
+	}
+
+	if facets.Price, err = countRanges(f.apply(db, facetPrice), "datasets.price_atto", priceBuckets); err != nil {
+		return facets, err
+	}
+	if facets.Size, err = countRanges(f.apply(db, facetSize), "datasets.file_size", sizeBuckets); err != nil {
+		return facets, err
+	}
+
+	for _, period := range createdPeriods {
+		facet := periodFacet{Period: period.name, After: now.Add(-period.duration)}
+		if err := f.apply(db, facetCreated).Where("datasets.created_at >= ?", facet.After).Count(&facet.Count).Error; err != nil {
+			return facets, err
+		}
+		facets.Created = append(facets.Created, facet)
+	}
+	return facets, nil
+}
+
+// countRanges counts the rows in each of the ranges starting at the
+// bounds with a single grouped query.
+func countRanges(tx *gorm.DB, column string, bounds []interface{}) ([]rangeFacet, error) {
+	var (
+		expr strings.Builder
+		args []interface{}
+	)
+	expr.WriteString("CASE")
+	for i, bound := range bounds[1:] {
+		fmt.Fprintf(&expr, " WHEN %s < ? THEN %d", column, i)
+		args = append(args, bound)
+	}
+	fmt.Fprintf(&expr, " ELSE %d END", len(bounds)-1)
+
+	var rows []struct {
+		Bucket int
+		Count  int64
+	}
+	if err := tx.Select(expr.String()+" AS bucket, count(*) AS count", args...).Group("bucket").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	ranges := make([]rangeFacet, len(bounds))
+	for i := range bounds {
+		ranges[i].Min = bounds[i]
+		if i < len(bounds)-1 {
+			ranges[i].Max = bounds[i+1]
+		}
+	}
+	for _, row := range rows {
+		if row.Bucket >= 0 && row.Bucket < len(ranges) {
+			ranges[row.Bucket].Count = row.Count
+		}
+	}
+	return ranges, nil
+}
+
+func (s *FileHiveServer) handleGETBrowse(w http.ResponseWriter, r *http.Request) {
+	var (
+		page int
+		err  error
+	)
+	pageStr := r.URL.Query().Get("page")
+	if pageStr != "" {
+		page, err = strconv.Atoi(pageStr)
+		if err != nil || page < 0 {
+			http.Error(w, wrapError(ErrInvalidOption), http.StatusBadRequest)
+			return
+		}
+	}
+
+	sort := r.URL.Query().Get("sort")
+	if sort == "" {
+		sort = "newest"
+	}
+	order, ok := browseSorts[sort]
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidOption), http.StatusBadRequest)
+		return
+	}
+
+	filter, err := parseBrowseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusBadRequest)
+		return
+	}
+
+	var (
+		datasets = []models.Dataset{}
+		count    int64
+		facets   browseFacets
+	)
+	err = s.db.View(func(db *gorm.DB) error {
+		if err := filter.apply(db, "").Count(&count).Error; err != nil {
+			return err
+		}
+		if err := filter.apply(db, "").Order(order).Order("datasets.id").Offset(page * browsePageSize).Limit(browsePageSize).Find(&datasets).Error; err != nil {
+			return err
+		}
+		if err := loadDatasetTags(db, datasets); err != nil {
+			return err
+		}
+		facets, err = countFacets(db, filter, time.Now())
+		return err
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	pages := int((count + browsePageSize - 1) / browsePageSize)
+	if pages == 0 {
+		pages = 1
+	}
+	sanitizedJSONResponse(w, struct {
+		Pages    int              `json:"pages"`
+		Page     int              `json:"page"`
+		Total    int64            `json:"total"`
+		Datasets []models.Dataset `json:"datasets"`
+		Facets   browseFacets     `json:"facets"`
+	}{
+		Pages:    pages,
+		Page:     page,
+		Total:    count,
+		Datasets: datasets,
+		Facets:   facets,
+	})
+}
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"encoding/json"
+	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/repo"
+	"github.com/OB1Company/filehive/repo/models"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_Browse(t *testing.T) {
+	db, err := repo.NewDatabase("", repo.Dialect("memory"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	err = db.Update(func(db *gorm.DB) error {
+		for _, user := range []models.User{
+			{ID: "alice", Email: "alice@example.com", Name: "Alice"},
+			{ID: "bob", Email: "bob@example.com", Name: "Bob"},
+			{ID: "carol", Email: "carol@example.com", Name: "Carol", Disabled: true},
+		} {
+			if err := db.Create(&user).Error; err != nil {
+				return err
+			}
+		}
+		for _, dataset := range []models.Dataset{
+			{ID: "a1", UserID: "alice", Username: "Alice", FileType: ".csv", Price: fil.MustParseAmount("0.5"), FileSize: 1 << 10, Purchases: 3, CreatedAt: now.Add(-time.Hour)},
+			{ID: "a2", UserID: "alice", Username: "Alice", FileType: ".csv", Price: fil.MustParseAmount("5"), FileSize: 200 << 20, Purchases: 1, CreatedAt: now.Add(-time.Hour * 24 * 10)},
+			{ID: "a3", UserID: "alice", Username: "Alice", FileType: ".json", Price: fil.MustParseAmount("50"), FileSize: 2 << 30, CreatedAt: now.Add(-time.Hour * 24 * 400)},
+			{ID: "b1", UserID: "bob", Username: "Bob", FileType: ".json", Price: fil.MustParseAmount("5"), FileSize: 5 << 20, Purchases: 7, CreatedAt: now.Add(-time.Hour * 2)},
+			{ID: "b2", UserID: "bob", Username: "Bob", FileType: ".csv", Price: fil.MustParseAmount("0.5"), FileSize: 1 << 10, Delisted: true, CreatedAt: now},
+			{ID: "c1", UserID: "carol", Username: "Carol", FileType: ".csv", Price: fil.MustParseAmount("1"), FileSize: 1 << 10, CreatedAt: now},
+		} {
+			if err := db.Create(&dataset).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &FileHiveServer{db: db}
+
+	type response struct {
+		Pages    int              `json:"pages"`
+		Total    int64            `json:"total"`
+		Datasets []models.Dataset `json:"datasets"`
+		Facets   browseFacets     `json:"facets"`
+	}
+	browse := func(query string, statusCode int) response {
+		w := httptest.NewRecorder()
+		server.handleGETBrowse(w, httptest.NewRequest(http.MethodGet, "/api/v1/datasets/browse"+query, nil))
+		if w.Code != statusCode {
+			t.Fatalf("%s: expected status code %d, got %d", query, statusCode, w.Code)
+		}
+		var resp response
+		if statusCode == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp
+	}
+	ids := func(resp response) []string {
+		ret := []string{}
+		for _, dataset := range resp.Datasets {
+			ret = append(ret, dataset.ID)
+		}
+		return ret
+	}
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{query: "", expected: []string{"a1", "b1", "a2", "a3"}},
+		{query: "?sort=price_asc", expected: []string{"a1", "a2", "b1", "a3"}},
+		{query: "?sort=price_desc", expected: []string{"a3", "a2", "b1", "a1"}},
+		{query: "?sort=size_desc", expected: []string{"a3", "a2", "b1", "a1"}},
+		{query: "?sort=purchases", expected: []string{"b1", "a1", "a2", "a3"}},
+		{query: "?fileType=.csv", expected: []string{"a1", "a2"}},
+		{query: "?fileType=.csv,.json&seller=bob", expected: []string{"b1"}},
+		{query: "?minPrice=1&maxPrice=10&sort=price_asc", expected: []string{"a2", "b1"}},
+		{query: "?minSize=1048576&maxSize=1073741824&sort=size_asc", expected: []string{"b1", "a2"}},
+		{query: "?after=" + now.Add(-time.Hour*24*30).UTC().Format(time.RFC3339) + "&before=" + now.Add(-time.Hour*24).UTC().Format(time.RFC3339), expected: []string{"a2"}},
+	}
+	for _, test := range tests {
+		if got := ids(browse(test.query, http.StatusOK)); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.query, test.expected, got)
+		}
+	}
+
+	for _, query := range []string{"?sort=random", "?minPrice=cheap", "?maxSize=-1", "?after=yesterday", "?page=-1"} {
+		browse(query, http.StatusBadRequest)
+	}
+
+	// A facet's counts ignore its own filter but apply the others.
+	resp := browse("?fileType=.csv&seller=alice", http.StatusOK)
+	if resp.Total != 2 || resp.Pages != 1 {
+		t.Errorf("Expected 2 datasets on 1 page, got %d on %d", resp.Total, resp.Pages)
+	}
+	expectedFileTypes := []valueFacet{{Value: ".csv", Count: 2}, {Value: ".json", Count: 1}}
+	if !reflect.DeepEqual(resp.Facets.FileTypes, expectedFileTypes) {
+		t.Errorf("Expected file type facets %v, got %v", expectedFileTypes, resp.Facets.FileTypes)
+	}
+	expectedSellers := []valueFacet{{Value: "alice", Label: "Alice", Count: 2}}
+	if !reflect.DeepEqual(resp.Facets.Sellers, expectedSellers) {
+		t.Errorf("Expected seller facets %v, got %v", expectedSellers, resp.Facets.Sellers)
+	}
+
+	resp = browse("", http.StatusOK)
+	priceCounts := []int64{}
+	for _, r := range resp.Facets.Price {
+		priceCounts = append(priceCounts, r.Count)
+	}
+	if !reflect.DeepEqual(priceCounts, []int64{0, 1, 2, 1, 0}) {
+		t.Errorf("Unexpected price facet counts %v", priceCounts)
+	}
+	if last := resp.Facets.Price[len(resp.Facets.Price)-1]; last.Min != "100" || last.Max != nil {
+		t.Errorf("Expected the last price range to be unbounded, got %v", last)
+	}
+	sizeCounts := []int64{}
+	for _, r := range resp.Facets.Size {
+		sizeCounts = append(sizeCounts, r.Count)
+	}
+	if !reflect.DeepEqual(sizeCounts, []int64{1, 1, 1, 1, 0}) {
+		t.Errorf("Unexpected size facet counts %v", sizeCounts)
+	}
+	createdCounts := []int64{}
+	for _, p := range resp.Facets.Created {
+		createdCounts = append(createdCounts, p.Count)
+	}
+	if !reflect.DeepEqual(createdCounts, []int64{2, 2, 3, 3}) {
+		t.Errorf("Unexpected created facet counts %v", createdCounts)
+	}
+}
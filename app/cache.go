@@ -0,0 +1,733 @@
+package app
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"github.com/OB1Company/filehive/repo/models"
+	"github.com/ipfs/go-cid"
+	chunker "github.com/ipfs/go-ipfs-chunker"
+	ipld "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-unixfs/importer/balanced"
+	"github.com/ipfs/go-unixfs/importer/helpers"
+	"github.com/multiformats/go-multihash"
+	"golang.org/x/crypto/blake2b"
+	"gorm.io/gorm"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultCacheSize = 10 << 30
+
+// cacheEntry is a file in the content cache named by its content ID.
+type cacheEntry struct {
+	CID      string    `json:"cid"`
+	Size     int64     `json:"size"`
+	Pinned   bool      `json:"pinned"`
+	LastUsed time.Time `json:"lastUsed"`
+
+	// verified is set once the file has been checked against the CID since
+	// the server started.
+	verified bool
+}
+
+// cacheStats counts the use of the content cache since the server started.
+type cacheStats struct {
+	Size      int64  `json:"size"`
+	MaxSize   int64  `json:"maxSize"`
+	Entries   int    `json:"entries"`
+	Pinned    int    `json:"pinned"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Corrupted uint64 `json:"corrupted"`
+}
+
+// contentCache is a size bounded cache of retrieved content on disk, so
+// popular datasets don't have to be retrieved from Filecoin for every
+// download. The least recently used entries are evicted first and pinned
+// entries are never evicted to make room. A maxSize of zero means no limit.
+type contentCache struct {
+	dir     string
+	maxSize int64
+
+	mtx     sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	pins    map[string]bool
+	stats   cacheStats
+}
+
+// newContentCache loads the content cache from the files left in dir.
+// Loaded files are verified again the first time they are used.
+func newContentCache(dir string, maxSize int64, pins []string) (*contentCache, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &contentCache{
+		dir:     dir,
+		maxSize: maxSize,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		pins:    make(map[string]bool),
+	}
+	for _, id := range pins {
+		c.pins[id] = true
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().Before(infos[j].ModTime())
+	})
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(info.Name(), ".part") {
+			// Left over from a retrieval that was interrupted.
+			os.Remove(path.Join(dir, info.Name()))
+			continue
+		}
+		c.entries[info.Name()] = c.lru.PushFront(&cacheEntry{
+			CID:      info.Name(),
+			Size:     info.Size(),
+			Pinned:   c.pins[info.Name()],
+			LastUsed: info.ModTime(),
+		})
+		c.stats.Size += info.Size()
+	}
+	c.evict()
+	return c, nil
+}
+
+// get returns the cached content for the CID. If it isn't cached, or the
+// cached file is the wrong size or fails verification, the content is
+// retrieved with fetch and verified before it is added to the cache.
+func (c *contentCache) get(id string, size int64, fetch func(w io.Writer) error) (*os.File, error) {
+	if f := c.open(id, size); f != nil {
+		return f, nil
+	}
+
+	tmp, err := ioutil.TempFile(c.dir, id+".*.part")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := fetch(tmp); err != nil {
+		return nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if err := verifyContent(id, tmp); err != nil {
+		c.mtx.Lock()
+		c.stats.Corrupted++
+		c.mtx.Unlock()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	return c.add(id, tmp.Name())
+}
+
+// open returns the cached file for the CID or nil if it isn't usable.
+func (c *contentCache) open(id string, size int64) *os.File {
+	c.mtx.Lock()
+	el, ok := c.entries[id]
+	if !ok {
+		c.stats.Misses++
+		c.mtx.Unlock()
+		return nil
+	}
+	entry := el.Value.(*cacheEntry)
+	if size > 0 && entry.Size != size {
+		log.Warningf("Discarding cached content %s with size %d, expected %d", id, entry.Size, size)
+		c.discard(el)
+		c.mtx.Unlock()
+		return nil
+	}
+	verified, entrySize := entry.verified, entry.Size
+	c.mtx.Unlock()
+
+	f, err := os.Open(c.path(id))
+	if err != nil {
+		c.mtx.Lock()
+		c.discard(el)
+		c.mtx.Unlock()
+		return nil
+	}
+	if info, err := f.Stat(); err != nil || info.Size() != entrySize {
+		// The file was changed outside of the cache.
+		f.Close()
+		c.mtx.Lock()
+		c.discard(el)
+		c.mtx.Unlock()
+		return nil
+	}
+	if !verified {
+		err := verifyContent(id, f)
+		if err == nil {
+			_, err = f.Seek(0, io.SeekStart)
+		}
+		if err != nil {
+			log.Warningf("Discarding cached content %s: %s", id, err)
+			f.Close()
+			c.mtx.Lock()
+			c.discard(el)
+			c.mtx.Unlock()
+			return nil
+		}
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	entry.verified = true
+	entry.LastUsed = time.Now()
+	if c.entries[id] == el {
+		c.lru.MoveToFront(el)
+	}
+	c.stats.Hits++
+	return f
+}
+
+// discard drops an entry that could not be used and counts it as a miss.
+func (c *contentCache) discard(el *list.Element) {
+	c.stats.Corrupted++
+	c.stats.Misses++
+	c.remove(el)
+}
+
+// add moves a retrieved file into the cache and opens it. Content that is
+// too large to ever fit in the cache is opened and deleted, so it can still
+// be served but isn't kept.
+func (c *contentCache) add(id, filePath string) (*os.File, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.maxSize > 0 && info.Size() > c.maxSize && !c.pins[id] {
+		f, err := os.Open(filePath)
+		if err != nil {
+			return nil, err
+		}
+		os.Remove(filePath)
+		return f, nil
+	}
+
+	if el, ok := c.entries[id]; ok {
+		c.remove(el)
+	}
+	if err := os.Rename(filePath, c.path(id)); err != nil {
+		return nil, err
+	}
+	c.entries[id] = c.lru.PushFront(&cacheEntry{
+		CID:      id,
+		Size:     info.Size(),
+		Pinned:   c.pins[id],
+		LastUsed: time.Now(),
+		verified: true,
+	})
+	c.stats.Size += info.Size()
+
+	f, err := os.Open(c.path(id))
+	if err != nil {
+		return nil, err
+	}
+	c.evict()
+	return f, nil
+}
+
+// evict removes the least recently used entries that aren't pinned until
+// the cache fits in its maximum size. Files that are being served stay
+// readable until they are closed.
+func (c *contentCache) evict() {
+	if c.maxSize <= 0 {
+		return
+	}
+	el := c.lru.Back()
+	for el != nil && c.stats.Size > c.maxSize {
+		prev := el.Prev()
+		if !el.Value.(*cacheEntry).Pinned {
+			c.remove(el)
+			c.stats.Evictions++
+		}
+		el = prev
+	}
+}
+
+// remove deletes an entry and its file.
+func (c *contentCache) remove(el *list.Element) {
+	entry := el.Value.(*cacheEntry)
+	if c.entries[entry.CID] != el {
+		return
+	}
+	delete(c.entries, entry.CID)
+	c.lru.Remove(el)
+	c.stats.Size -= entry.Size
+	if err := os.Remove(c.path(entry.CID)); err != nil && !os.IsNotExist(err) {
+		log.Errorf("Error removing cached content %s: %s", entry.CID, err)
+	}
+}
+
+// setPinned pins or unpins the CID. A CID can be pinned before it is cached.
+func (c *contentCache) setPinned(id string, pinned bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if pinned {
+		c.pins[id] = true
+	} else {
+		delete(c.pins, id)
+	}
+	if el, ok := c.entries[id]; ok {
+		el.Value.(*cacheEntry).Pinned = pinned
+	}
+	c.evict()
+}
+
+// evictEntry removes the CID from the cache and returns whether it was
+// cached. It also removes any pin on the CID.
+func (c *contentCache) evictEntry(id string) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	delete(c.pins, id)
+	el, ok := c.entries[id]
+	if !ok {
+		return false
+	}
+	c.remove(el)
+	c.stats.Evictions++
+	return true
+}
+
+// snapshot returns the cache statistics and entries, most recently used
+// first.
+func (c *contentCache) snapshot() (cacheStats, []cacheEntry) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	stats := c.stats
+	stats.MaxSize = c.maxSize
+	stats.Entries = c.lru.Len()
+	entries := make([]cacheEntry, 0, c.lru.Len())
+	for el := c.lru.Front(); el != nil; el = el.Next() {
+		entry := el.Value.(*cacheEntry)
+		if entry.Pinned {
+			stats.Pinned++
+		}
+		entries = append(entries, *entry)
+	}
+	return stats, entries
+}
+
+func (c *contentCache) path(id string) string {
+	return path.Join(c.dir, id)
+}
+
+// verifyContent checks that the content hashes to the CID. Raw CIDs are a
+// hash of the content itself. For UnixFS CIDs the DAG is rebuilt the same
+// way IPFS adds files by default.
+func verifyContent(id string, r io.Reader) error {
+	c, err := cid.Decode(id)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrContentMismatch, err)
+	}
+
+	var computed cid.Cid
+	switch c.Type() {
+	case cid.Raw:
+		// The content is streamed through the hash so large files aren't
+		// read into memory.
+		decoded, err := multihash.Decode(c.Hash())
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrContentMismatch, err)
+		}
+		h, err := contentHasher(decoded.Code)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(h, r); err != nil {
+			return err
+		}
+		digest := h.Sum(nil)
+		if decoded.Length > len(digest) || !bytes.Equal(digest[:decoded.Length], decoded.Digest) {
+			return ErrContentMismatch
+		}
+		return nil
+	case cid.DagProtobuf:
+		params := helpers.DagBuilderParams{
+			Maxlinks:   helpers.DefaultLinksPerBlock,
+			RawLeaves:  c.Version() == 1,
+			CidBuilder: c.Prefix(),
+			Dagserv:    discardDAGService{},
+		}
+		db, err := params.New(chunker.DefaultSplitter(r))
+		if err != nil {
+			return err
+		}
+		nd, err := balanced.Layout(db)
+		if err != nil {
+			return err
+		}
+		computed = nd.Cid()
+	default:
+		return fmt.Errorf("%w: unsupported codec %d", ErrContentMismatch, c.Type())
+	}
+
+	if !computed.Equals(c) {
+		return ErrContentMismatch
+	}
+	return nil
+}
+
+// contentHasher returns the hash function of a raw content ID's multihash.
+func contentHasher(code uint64) (hash.Hash, error) {
+	switch code {
+	case multihash.SHA2_256:
+		return sha256.New(), nil
+	case multihash.SHA2_512:
+		return sha512.New(), nil
+	case multihash.BLAKE2B_MIN + 31:
+		return blake2b.New256(nil)
+	case multihash.BLAKE2B_MAX:
+		return blake2b.New512(nil)
+	}
+	return nil, fmt.Errorf("%w: unsupported hash function %d", ErrContentMismatch, code)
+}
+
+// discardDAGService lets a DAG be built to find its CID without keeping
+// the blocks.
+type discardDAGService struct{}
+
+func (discardDAGService) Get(context.Context, cid.Cid) (ipld.Node, error) {
+	return nil, ipld.ErrNotFound
+}
+
+func (discardDAGService) GetMany(context.Context, []cid.Cid) <-chan *ipld.NodeOption {
+	ch := make(chan *ipld.NodeOption)
+	close(ch)
+	return ch
+}
+
+func (discardDAGService) Add(context.Context, ipld.Node) error        { return nil }
+func (discardDAGService) AddMany(context.Context, []ipld.Node) error  { return nil }
+func (discardDAGService) Remove(context.Context, cid.Cid) error       { return nil }
+func (discardDAGService) RemoveMany(context.Context, []cid.Cid) error { return nil }
+
+// contentCache returns the server's content cache, loading it the first
+// time it is used.
+func (s *FileHiveServer) contentCache() (*contentCache, error) {
+	s.cacheMtx.Lock()
+	defer s.cacheMtx.Unlock()
+
+	if s.cache != nil {
+		return s.cache, nil
+	}
+
+	var pins []models.CachePin
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Find(&pins).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(pins))
+	for _, pin := range pins {
+		ids = append(ids, pin.ID)
+	}
+
+	cache, err := newContentCache(path.Join(s.dataDir, "cache"), s.cacheSize, ids)
+	if err != nil {
+		return nil, err
+	}
+	s.cache = cache
+	return cache, nil
+}
+
+// cachedContent returns the content stored under the CID from the local
+// content cache, retrieving it with the token first if it isn't cached.
+// Cached files can be seeked, which lets downloads be served in ranges.
+func (s *FileHiveServer) cachedContent(contentID, token string, size int64) (*os.File, error) {
+	cache, err := s.contentCache()
+	if err != nil {
+		return nil, err
+	}
+	return cache.get(contentID, size, func(w io.Writer) error {
+		fileStream, err := s.filecoinBackend.Get(contentID, token)
+		if err != nil {
+			return err
+		}
+		if closer, ok := fileStream.(io.Closer); ok {
+			defer closer.Close()
+		}
+		_, err = io.Copy(w, fileStream)
+		return err
+	})
+}
+
+// handleGETAdminCache returns the content cache statistics and entries.
+func (s *FileHiveServer) handleGETAdminCache(w http.ResponseWriter, r *http.Request) {
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	if !user.Admin {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	cache, err := s.contentCache()
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	stats, entries := cache.snapshot()
+
+	sanitizedJSONResponse(w, struct {
+		Stats   cacheStats   `json:"stats"`
+		Entries []cacheEntry `json:"entries"`
+	}{
+		Stats:   stats,
+		Entries: entries,
+	})
+}
+
+// handlePUTAdminCachePin pins a dataset's content in the cache, retrieving
+// it if it isn't already cached.
+func (s *FileHiveServer) handlePUTAdminCachePin(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	id := sp[len(sp)-2]
+
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	if !user.Admin {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var (
+		version  models.DatasetVersion
+		uploader models.User
+	)
+	err = s.db.View(func(db *gorm.DB) error {
+		if err := db.Where("content_id = ?", id).First(&version).Error; err != nil {
+			return err
+		}
+		var dataset models.Dataset
+		if err := db.Where("id = ?", version.DatasetID).First(&dataset).Error; err != nil {
+			return err
+		}
+		return db.Where("id = ?", dataset.UserID).First(&uploader).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrDatasetNotFound), http.StatusNotFound)
+		return
+	}
+
+	cache, err := s.contentCache()
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	err = s.db.Update(func(db *gorm.DB) error {
+		return db.Save(&models.CachePin{ID: id}).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	cache.setPinned(id, true)
+
+	f, err := s.cachedContent(id, uploader.PowergateToken, version.FileSize)
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	f.Close()
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleDELETEAdminCachePin unpins content so it can be evicted again.
+func (s *FileHiveServer) handleDELETEAdminCachePin(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	id := sp[len(sp)-2]
+
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	if !user.Admin {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	cache, err := s.contentCache()
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	err = s.db.Update(func(db *gorm.DB) error {
+		return db.Unscoped().Where("id = ?", id).Delete(&models.CachePin{}).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	cache.setPinned(id, false)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleDELETEAdminCacheEntry evicts content from the cache and removes any
+// pin on it.
+func (s *FileHiveServer) handleDELETEAdminCacheEntry(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	id := sp[len(sp)-1]
+
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	if !user.Admin {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	cache, err := s.contentCache()
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	err = s.db.Update(func(db *gorm.DB) error {
+		return db.Unscoped().Where("id = ?", id).Delete(&models.CachePin{}).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	if !cache.evictEntry(id) {
+		http.Error(w, wrapError(ErrNotCached), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// deliveryWriter records how much of a dataset file was written so the
+// caller can tell whether the download reached the end of the file.
+type deliveryWriter struct {
+	http.ResponseWriter
+	status  int
+	written int64
+}
+
+func (w *deliveryWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *deliveryWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.written += int64(n)
+	return n, err
+}
+
+// delivered returns whether the response sent the file through to its
+// last byte, either whole or as the final range of a resumed download.
+func (w *deliveryWriter) delivered(size int64) bool {
+	switch w.status {
+	case http.StatusOK:
+		return w.written == size
+	case http.StatusPartialContent:
+		var start, end, total int64
+		if _, err := fmt.Sscanf(w.Header().Get("Content-Range"), "bytes %d-%d/%d", &start, &end, &total); err != nil {
+			return false
+		}
+		return end == total-1 && w.written == end-start+1
+	}
+	return false
+}
@@ -0,0 +1,279 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/repo"
+	"github.com/OB1Company/filehive/repo/models"
+	"github.com/filecoin-project/go-address"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+	"gorm.io/gorm"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_RangeDownloads(t *testing.T) {
+	db, err := repo.NewDatabase("", repo.Dialect("memory"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filesDir := path.Join(testStaticDir, "files")
+	defer os.RemoveAll(testStaticDir)
+
+	filBackend, err := fil.NewMockFilecoinBackend(filesDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wbe := fil.NewMockWalletBackend()
+	escrowAddr, err := wbe.NewAddress("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wbe.GenerateToAddress(escrowAddr, fil.MustParseAmount("10").AttoFIL())
+
+	server := &FileHiveServer{
+		db:              db,
+		filecoinBackend: filBackend,
+		walletBackend:   wbe,
+		staticFileDir:   testStaticDir,
+		dataDir:         testStaticDir,
+		escrowAddress:   escrowAddr,
+	}
+
+	content := "0123456789abcdef"
+	jobID, cid, _, err := filBackend.Store(strings.NewReader(content), address.Undef, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Update(func(db *gorm.DB) error {
+		for _, user := range []models.User{
+			{ID: "seller", Email: "seller@ob1.io"},
+			{ID: "buyer", Email: "buyer@ob1.io"},
+			{ID: "admin", Email: "admin@ob1.io", Admin: true},
+		} {
+			if err := db.Save(&user).Error; err != nil {
+				return err
+			}
+		}
+		if err := db.Save(&models.Dataset{ID: "dataset1", UserID: "seller", Price: fil.MustParseAmount("1"), Version: 1, ContentID: cid, JobID: jobID, FileSize: int64(len(content)), DatasetFilename: "hex.txt", FileType: "text/plain"}).Error; err != nil {
+			return err
+		}
+		if err := db.Save(&models.DatasetVersion{ID: "version1", DatasetID: "dataset1", Version: 1, ContentID: cid, JobID: jobID, FileSize: int64(len(content))}).Error; err != nil {
+			return err
+		}
+		return db.Save(&models.Purchase{ID: "purchase1", UserID: "buyer", SellerID: "seller", DatasetID: "dataset1", Price: fil.MustParseAmount("1"), FeeAmount: "0", Version: 1, State: purchaseStateNotified, Status: purchaseStatusEscrowed, ReleaseAfter: time.Now().Add(time.Hour)}).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	download := func(headers map[string]string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/download/dataset1", nil)
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		server.handleGETDatasetFile(w, r.WithContext(context.WithValue(r.Context(), "email", "buyer@ob1.io")))
+		return w
+	}
+	purchaseStatus := func() string {
+		var purchase models.Purchase
+		err := db.View(func(db *gorm.DB) error {
+			return db.Where("id = ?", "purchase1").First(&purchase).Error
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return purchase.Status
+	}
+
+	// The first part of a download is not a delivery.
+	w := download(map[string]string{"Range": "bytes=0-5"})
+	if w.Code != http.StatusPartialContent || w.Body.String() != "012345" {
+		t.Fatalf("Expected first range, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("ETag") != `"`+cid+`"` || w.Header().Get("Content-Range") != "bytes 0-5/16" {
+		t.Errorf("Unexpected headers %v", w.Header())
+	}
+	if status := purchaseStatus(); status != purchaseStatusEscrowed {
+		t.Errorf("Expected purchase to still be escrowed, got %s", status)
+	}
+	if _, err := os.Stat(path.Join(testStaticDir, "cache", cid)); err != nil {
+		t.Errorf("Expected content to be cached: %s", err)
+	}
+
+	// Resuming through to the end completes the delivery.
+	w = download(map[string]string{"Range": "bytes=6-", "If-Range": `"` + cid + `"`})
+	if w.Code != http.StatusPartialContent || w.Body.String() != "6789abcdef" {
+		t.Fatalf("Expected resumed range, got %d: %s", w.Code, w.Body.String())
+	}
+	if status := purchaseStatus(); status != purchaseStatusReleased {
+		t.Errorf("Expected purchase to be released, got %s", status)
+	}
+
+	w = download(map[string]string{"If-None-Match": `"` + cid + `"`})
+	if w.Code != http.StatusNotModified {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotModified, w.Code)
+	}
+
+	// A truncated cache entry is fetched again.
+	if err := ioutil.WriteFile(path.Join(testStaticDir, "cache", cid), []byte("0123"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	w = download(nil)
+	if w.Code != http.StatusOK || w.Body.String() != content || w.Header().Get("Content-Length") != strconv.Itoa(len(content)) {
+		t.Errorf("Expected full download, got %d: %s", w.Code, w.Body.String())
+	}
+
+	admin := func(handler http.HandlerFunc, method, target, email string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, target, nil)
+		handler(w, r.WithContext(context.WithValue(r.Context(), "email", email)))
+		return w
+	}
+	if w := admin(server.handlePUTAdminCachePin, http.MethodPut, "/api/v1/admin/cache/"+cid+"/pin", "buyer@ob1.io"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+	if w := admin(server.handleDELETEAdminCacheEntry, http.MethodDelete, "/api/v1/admin/cache/"+cid, "admin@ob1.io"); w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	if w := admin(server.handleDELETEAdminCacheEntry, http.MethodDelete, "/api/v1/admin/cache/"+cid, "admin@ob1.io"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	// Pinning retrieves the content into the cache again.
+	if w := admin(server.handlePUTAdminCachePin, http.MethodPut, "/api/v1/admin/cache/"+cid+"/pin", "admin@ob1.io"); w.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	w = admin(server.handleGETAdminCache, http.MethodGet, "/api/v1/admin/cache", "admin@ob1.io")
+	var resp struct {
+		Stats   cacheStats   `json:"stats"`
+		Entries []cacheEntry `json:"entries"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Entries) != 1 || !resp.Entries[0].Pinned || resp.Stats.Pinned != 1 || resp.Stats.Size != int64(len(content)) {
+		t.Errorf("Unexpected cache %+v", resp)
+	}
+	var pins []models.CachePin
+	err = db.View(func(db *gorm.DB) error {
+		return db.Find(&pins).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pins) != 1 || pins[0].ID != cid {
+		t.Errorf("Expected pin to be saved, got %v", pins)
+	}
+}
+
+func Test_ContentCache(t *testing.T) {
+	dir := path.Join(testStaticDir, "cache")
+	defer os.RemoveAll(testStaticDir)
+
+	rawCID := func(content string) string {
+		mh, err := multihash.Sum([]byte(content), multihash.SHA2_256, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cid.NewCidV1(cid.Raw, mh).String()
+	}
+	fetches := 0
+	get := func(c *contentCache, id, content string) error {
+		f, err := c.get(id, int64(len(content)), func(w io.Writer) error {
+			fetches++
+			_, err := io.WriteString(w, content)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		b, err := ioutil.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		if string(b) != content {
+			t.Errorf("Expected %q, got %q", content, string(b))
+		}
+		return nil
+	}
+	cached := func(c *contentCache) []string {
+		_, entries := c.snapshot()
+		var ids []string
+		for _, entry := range entries {
+			ids = append(ids, entry.CID)
+		}
+		return ids
+	}
+
+	// Files added with ipfs add are verified against their UnixFS CID.
+	if err := verifyContent("QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o", strings.NewReader("hello world\n")); err != nil {
+		t.Errorf("Expected UnixFS content to verify: %s", err)
+	}
+	if err := verifyContent("QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o", strings.NewReader("hello world")); !errors.Is(err, ErrContentMismatch) {
+		t.Errorf("Expected content mismatch, got %v", err)
+	}
+
+	cache, err := newContentCache(dir, 10, []string{rawCID("aaaa")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b, c := rawCID("aaaa"), rawCID("bbbb"), rawCID("cccc")
+	for _, test := range []struct{ id, content string }{{a, "aaaa"}, {b, "bbbb"}, {b, "bbbb"}, {c, "cccc"}} {
+		if err := get(cache, test.id, test.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The pinned entry stays and the least recently used one is evicted.
+	if ids := cached(cache); len(ids) != 2 || ids[0] != c || ids[1] != a {
+		t.Errorf("Unexpected cache entries %v", ids)
+	}
+	if fetches != 3 {
+		t.Errorf("Expected 3 fetches, got %d", fetches)
+	}
+
+	// Content that doesn't match its CID is never cached.
+	if err := get(cache, b, "bbbx"); !errors.Is(err, ErrContentMismatch) {
+		t.Errorf("Expected content mismatch, got %v", err)
+	}
+
+	// Files left on disk are verified again after a restart.
+	if err := ioutil.WriteFile(path.Join(dir, c), []byte("xxxx"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	cache, err = newContentCache(dir, 10, []string{a})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := get(cache, c, "cccc"); err != nil {
+		t.Fatal(err)
+	}
+	if err := get(cache, a, "aaaa"); err != nil {
+		t.Fatal(err)
+	}
+	stats, _ := cache.snapshot()
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Corrupted != 1 || stats.Size != 8 || stats.Pinned != 1 || fetches != 5 {
+		t.Errorf("Unexpected cache stats %+v after %d fetches", stats, fetches)
+	}
+
+	cache.setPinned(a, false)
+	if !cache.evictEntry(a) || cache.evictEntry(a) {
+		t.Error("Expected entry to be evicted once")
+	}
+}
@@ -0,0 +1,465 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/OB1Company/filehive/repo/models"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	maxDatasetTags   = 10
+	maxTagLength     = 32
+	defaultTagsLimit = 50
+)
+
+// findCategory returns the category with the given ID or slug.
+func findCategory(db *gorm.DB, idOrSlug string) (models.Category, error) {
+	var category models.Category
+	err := db.Where("id = ? OR slug = ?", idOrSlug, idOrSlug).First(&category).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return category, ErrCategoryNotFound
+	}
+	return category, err
+}
+
+// categoryScope returns the IDs of the category with the given ID or slug
+// and of all the categories below it.
+func categoryScope(db *gorm.DB, idOrSlug string) ([]string, error) {
+	root, err := findCategory(db, idOrSlug)
+	if err != nil {
+		return nil, err
+	}
+
+	var categories []models.Category
+	if err := db.Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	children := make(map[string][]string)
+	for _, c := range categories {
+		children[c.ParentID] = append(children[c.ParentID], c.ID)
+	}
+
+	scope := []string{root.ID}
+	for i := 0; i < len(scope); i++ {
+		scope = append(scope, children[scope[i]]...)
+	}
+	return scope, nil
+}
+
+// requestCategoryScope returns the categories selected by the category
+// query parameter, or nil if the request is not scoped to a category.
+func (s *FileHiveServer) requestCategoryScope(r *http.Request) ([]string, error) {
+	category := r.URL.Query().Get("category")
+	if category == "" {
+		return nil, nil
+	}
+	var scope []string
+	err := s.db.View(func(db *gorm.DB) error {
+		var err error
+		scope, err = categoryScope(db, category)
+		return err
+	})
+	return scope, err
+}
+
+// slugify returns a lower case, hyphen separated form of the name for use
+// in URLs.
+func slugify(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
+}
+
+// normalizeTags lower cases the tags, collapses their whitespace and drops
+// empty and duplicate tags. A nil slice is returned as nil so that callers
+// can tell an absent list from an empty one.
+func normalizeTags(tags []string) ([]string, error) {
+	if tags == nil {
+		return nil, nil
+	}
+	var (
+		normalized = []string{}
+		seen       = make(map[string]bool)
+	)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > maxTagLength {
+			return nil, ErrInvalidTags
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxDatasetTags {
+		return nil, ErrInvalidTags
+	}
+	return normalized, nil
+}
+
+// saveDatasetTags replaces the tags of the dataset.
+func saveDatasetTags(db *gorm.DB, datasetID string, tags []string) error {
+	if err := db.Where("dataset_id = ?", datasetID).Delete(&models.DatasetTag{}).Error; err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if err := db.Create(&models.DatasetTag{DatasetID: datasetID, Tag: tag}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadDatasetTags fills in the tags of the datasets.
+func loadDatasetTags(db *gorm.DB, datasets []models.Dataset) error {
+	if len(datasets) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(datasets))
+	for _, dataset := range datasets {
+		ids = append(ids, dataset.ID)
+	}
+	var tags []models.DatasetTag
+	if err := db.Where("dataset_id IN ?", ids).Order("tag").Find(&tags).Error; err != nil {
+		return err
+	}
+	byDataset := make(map[string][]string)
+	for _, tag := range tags {
+		byDataset[tag.DatasetID] = append(byDataset[tag.DatasetID], tag.Tag)
+	}
+	for i := range datasets {
+		datasets[i].Tags = byDataset[datasets[i].ID]
+		if datasets[i].Tags == nil {
+			datasets[i].Tags = []string{}
+		}
+	}
+	return nil
+}
+
+// categoryNode is a category in the category tree. The dataset count
+// includes the datasets listed in its subcategories.
+type categoryNode struct {
+	models.Category
+	Datasets int64           `json:"datasets"`
+	Children []*categoryNode `json:"children"`
+}
+
+func (s *FileHiveServer) handleGETCategories(w http.ResponseWriter, r *http.Request) {
+	var (
+		categories []models.Category
+		counts     []struct {
+			CategoryID string
+			Count      int64
+		}
+	)
+	err := s.db.View(func(db *gorm.DB) error {
+		if err := db.Order("name").Find(&categories).Error; err != nil {
+			return err
+		}
+		return listedDatasets(db).Select("datasets.category_id AS category_id, count(*) AS count").Group("datasets.category_id").Scan(&counts).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	nodes := make(map[string]*categoryNode)
+	for _, c := range categories {
+		nodes[c.ID] = &categoryNode{Category: c, Children: []*categoryNode{}}
+	}
+	roots := []*categoryNode{}
+	for _, c := range categories {
+		if parent, ok := nodes[c.ParentID]; ok {
+			parent.Children = append(parent.Children, nodes[c.ID])
+		} else {
+			roots = append(roots, nodes[c.ID])
+		}
+	}
+	for _, count := range counts {
+		// Add the count to the category and everything above it.
+		node := nodes[count.CategoryID]
+		for node != nil {
+			node.Datasets += count.Count
+			node = nodes[node.ParentID]
+		}
+	}
+
+	sanitizedJSONResponse(w, roots)
+}
+
+func (s *FileHiveServer) handleGETTags(w http.ResponseWriter, r *http.Request) {
+	limit := defaultTagsLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			http.Error(w, wrapError(ErrInvalidOption), http.StatusBadRequest)
+			return
+		}
+	}
+
+	scope, err := s.requestCategoryScope(r)
+	if errors.Is(err, ErrCategoryNotFound) {
+		http.Error(w, wrapError(err), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	type tagCount struct {
+		Tag   string `json:"tag"`
+		Count int64  `json:"count"`
+	}
+	tags := []tagCount{}
+	err = s.db.View(func(db *gorm.DB) error {
+		tx := listedDatasets(db).Joins("join dataset_tags on dataset_tags.dataset_id = datasets.id")
+		if scope != nil {
+			tx = tx.Where("datasets.category_id IN ?", scope)
+		}
+		return tx.Select("dataset_tags.tag AS tag, count(*) AS count").Group("dataset_tags.tag").Order("count desc, tag").Limit(limit).Scan(&tags).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	sanitizedJSONResponse(w, tags)
+}
+
+func (s *FileHiveServer) handlePOSTAdminCategory(w http.ResponseWriter, r *http.Request) {
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	if !user.Admin {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var category models.Category
+	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
+		http.Error(w, wrapError(ErrInvalidJSON), http.StatusBadRequest)
+		return
+	}
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Slug == "" {
+		category.Slug = category.Name
+	}
+	category.Slug = slugify(category.Slug)
+	if category.Name == "" || category.Slug == "" {
+		http.Error(w, wrapError(ErrInvalidCategory), http.StatusBadRequest)
+		return
+	}
+
+	category.ID, err = makeID()
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	err = s.db.Update(func(db *gorm.DB) error {
+		if category.ParentID != "" {
+			parent, err := findCategory(db, category.ParentID)
+			if err != nil {
+				return err
+			}
+			category.ParentID = parent.ID
+		}
+		if err := db.Where("slug = ?", category.Slug).First(&models.Category{}).Error; err == nil {
+			return ErrCategoryExists
+		}
+		return db.Create(&category).Error
+	})
+	if errors.Is(err, ErrCategoryNotFound) {
+		http.Error(w, wrapError(err), http.StatusNotFound)
+		return
+	} else if errors.Is(err, ErrCategoryExists) {
+		http.Error(w, wrapError(err), http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	log.Infof("Created category %s (%s)", category.Slug, category.ID)
+	sanitizedJSONResponse(w, category)
+}
+
+func (s *FileHiveServer) handlePATCHAdminCategory(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	id := sp[len(sp)-1]
+
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	if !user.Admin {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	// A null or missing parent leaves the category where it is, an empty
+	// one moves it to the top of the tree.
+	type data struct {
+		Name     string  `json:"name"`
+		Slug     string  `json:"slug"`
+		ParentID *string `json:"parentID"`
+	}
+	var d data
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+		http.Error(w, wrapError(ErrInvalidJSON), http.StatusBadRequest)
+		return
+	}
+
+	var category models.Category
+	err = s.db.Update(func(db *gorm.DB) error {
+		if err := db.Where("id = ?", id).First(&category).Error; err != nil {
+			return ErrCategoryNotFound
+		}
+		if name := strings.TrimSpace(d.Name); name != "" {
+			category.Name = name
+		}
+		if d.Slug != "" {
+			category.Slug = slugify(d.Slug)
+			if category.Slug == "" {
+				return ErrInvalidCategory
+			}
+			if err := db.Where("slug = ? AND id != ?", category.Slug, category.ID).First(&models.Category{}).Error; err == nil {
+				return ErrCategoryExists
+			}
+		}
+		if d.ParentID != nil {
+			category.ParentID = ""
+			if *d.ParentID != "" {
+				parent, err := findCategory(db, *d.ParentID)
+				if err != nil {
+					return err
+				}
+				category.ParentID = parent.ID
+			}
+
+			// Walk up from the new parent to make sure the category
+			// does not end up below itself.
+			for ancestor := category.ParentID; ancestor != ""; {
+				if ancestor == category.ID {
+					return ErrInvalidCategory
+				}
+				var c models.Category
+				if err := db.Where("id = ?", ancestor).First(&c).Error; err != nil {
+					return err
+				}
+				ancestor = c.ParentID
+			}
+		}
+		return db.Save(&category).Error
+	})
+	if errors.Is(err, ErrCategoryNotFound) {
+		http.Error(w, wrapError(err), http.StatusNotFound)
+		return
+	} else if errors.Is(err, ErrCategoryExists) {
+		http.Error(w, wrapError(err), http.StatusConflict)
+		return
+	} else if errors.Is(err, ErrInvalidCategory) {
+		http.Error(w, wrapError(err), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	sanitizedJSONResponse(w, category)
+}
+
+// handleDELETEAdminCategory deletes a category. Its subcategories and
+// datasets are moved up to its parent.
+func (s *FileHiveServer) handleDELETEAdminCategory(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	id := sp[len(sp)-1]
+
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	if !user.Admin {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var datasetIDs []string
+	err = s.db.Update(func(db *gorm.DB) error {
+		var category models.Category
+		if err := db.Where("id = ?", id).First(&category).Error; err != nil {
+			return ErrCategoryNotFound
+		}
+		if err := db.Model(&models.Dataset{}).Where("category_id = ?", id).Pluck("id", &datasetIDs).Error; err != nil {
+			return err
+		}
+		if err := db.Model(&models.Dataset{}).Where("category_id = ?", id).Update("category_id", category.ParentID).Error; err != nil {
+			return err
+		}
+		if err := db.Model(&models.Category{}).Where("parent_id = ?", id).Update("parent_id", category.ParentID).Error; err != nil {
+			return err
+		}
+		return db.Unscoped().Delete(&category).Error
+	})
+	if errors.Is(err, ErrCategoryNotFound) {
+		http.Error(w, wrapError(err), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	s.updateSearchIndex(datasetIDs...)
+
+	w.WriteHeader(http.StatusOK)
+}
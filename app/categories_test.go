@@ -0,0 +1,166 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/OB1Company/filehive/repo"
+	"github.com/OB1Company/filehive/repo/models"
+	"github.com/OB1Company/filehive/repo/search"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_NormalizeTags(t *testing.T) {
+	tags, err := normalizeTags([]string{" Climate  Data", "climate data", "", "Weather"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tags, []string{"climate data", "weather"}) {
+		t.Errorf("Unexpected tags %v", tags)
+	}
+
+	if tags, err := normalizeTags(nil); err != nil || tags != nil {
+		t.Errorf("Expected nil tags, got %v", tags)
+	}
+	if tags, err := normalizeTags([]string{" "}); err != nil || tags == nil || len(tags) != 0 {
+		t.Errorf("Expected empty tags, got %v", tags)
+	}
+	if _, err := normalizeTags([]string{strings.Repeat("a", maxTagLength+1)}); err != ErrInvalidTags {
+		t.Errorf("Expected ErrInvalidTags for a long tag, got %v", err)
+	}
+	if _, err := normalizeTags(strings.Split("a b c d e f g h i j k", " ")); err != ErrInvalidTags {
+		t.Errorf("Expected ErrInvalidTags for too many tags, got %v", err)
+	}
+}
+
+func Test_Categories(t *testing.T) {
+	db, err := repo.NewDatabase("", repo.Dialect("memory"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Update(func(db *gorm.DB) error {
+		for _, user := range []models.User{
+			{ID: "alice", Email: "alice@example.com", Name: "Alice", Admin: true},
+		} {
+			if err := db.Create(&user).Error; err != nil {
+				return err
+			}
+		}
+		for _, category := range []models.Category{
+			{ID: "science", Name: "Science", Slug: "science"},
+			{ID: "climate", ParentID: "science", Name: "Climate", Slug: "climate"},
+			{ID: "politics", Name: "Politics", Slug: "politics"},
+		} {
+			if err := db.Create(&category).Error; err != nil {
+				return err
+			}
+		}
+		for _, dataset := range []models.Dataset{
+			{ID: "d1", UserID: "alice", CategoryID: "science"},
+			{ID: "d2", UserID: "alice", CategoryID: "climate"},
+			{ID: "d3", UserID: "alice", CategoryID: "politics"},
+			{ID: "d4", UserID: "alice", CategoryID: "climate", Delisted: true},
+		} {
+			if err := db.Create(&dataset).Error; err != nil {
+				return err
+			}
+		}
+		for id, tags := range map[string][]string{
+			"d1": {"open data"},
+			"d2": {"open data", "temperature"},
+			"d3": {"elections"},
+			"d4": {"temperature"},
+		} {
+			if err := saveDatasetTags(db, id, tags); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &FileHiveServer{db: db, searchIndex: search.NewMemoryIndex()}
+
+	get := func(handler http.HandlerFunc, target string, statusCode int, resp interface{}) {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != statusCode {
+			t.Fatalf("%s: expected status code %d, got %d", target, statusCode, w.Code)
+		}
+		if statusCode == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	type node struct {
+		ID       string `json:"id"`
+		Datasets int64  `json:"datasets"`
+		Children []node `json:"children"`
+	}
+	var tree []node
+	get(server.handleGETCategories, "/api/v1/categories", http.StatusOK, &tree)
+	expected := []node{
+		{ID: "politics", Datasets: 1, Children: []node{}},
+		{ID: "science", Datasets: 2, Children: []node{{ID: "climate", Datasets: 1, Children: []node{}}}},
+	}
+	if !reflect.DeepEqual(tree, expected) {
+		t.Errorf("Expected category tree %v, got %v", expected, tree)
+	}
+
+	type tagCount struct {
+		Tag   string `json:"tag"`
+		Count int64  `json:"count"`
+	}
+	var tags []tagCount
+	get(server.handleGETTags, "/api/v1/tags", http.StatusOK, &tags)
+	if !reflect.DeepEqual(tags, []tagCount{{"open data", 2}, {"elections", 1}, {"temperature", 1}}) {
+		t.Errorf("Unexpected tag cloud %v", tags)
+	}
+	get(server.handleGETTags, "/api/v1/tags?category=climate", http.StatusOK, &tags)
+	if !reflect.DeepEqual(tags, []tagCount{{"open data", 1}, {"temperature", 1}}) {
+		t.Errorf("Unexpected scoped tag cloud %v", tags)
+	}
+	get(server.handleGETTags, "/api/v1/tags?limit=0", http.StatusBadRequest, nil)
+
+	type listing struct {
+		Datasets []models.Dataset `json:"datasets"`
+	}
+	ids := func(l listing) []string {
+		ret := []string{}
+		for _, dataset := range l.Datasets {
+			ret = append(ret, dataset.ID)
+		}
+		return ret
+	}
+	var recent listing
+	get(server.handleGETRecent, "/api/v1/latest?category=science", http.StatusOK, &recent)
+	if got := ids(recent); !reflect.DeepEqual(got, []string{"d2", "d1"}) && !reflect.DeepEqual(got, []string{"d1", "d2"}) {
+		t.Errorf("Expected the science datasets, got %v", got)
+	}
+	get(server.handleGETRecent, "/api/v1/latest?category=missing", http.StatusNotFound, nil)
+
+	// Deleting a category moves its datasets and subcategories to its parent.
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodDelete, "/api/v1/admin/categories/science", nil)
+	server.handleDELETEAdminCategory(w, r.WithContext(context.WithValue(r.Context(), "email", "alice@example.com")))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	get(server.handleGETCategories, "/api/v1/categories", http.StatusOK, &tree)
+	expected = []node{
+		{ID: "climate", Datasets: 1, Children: []node{}},
+		{ID: "politics", Datasets: 1, Children: []node{}},
+	}
+	if !reflect.DeepEqual(tree, expected) {
+		t.Errorf("Expected category tree %v, got %v", expected, tree)
+	}
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"github.com/OB1Company/filehive/repo/models"
+	"gorm.io/gorm"
+)
+
+// The reasons a user may be entitled to a dataset's content.
+const (
+	entitlementOwner        = "owner"
+	entitlementAdmin        = "admin"
+	entitlementFree         = "free"
+	entitlementSubscription = "subscription"
+	entitlementPurchase     = "purchase"
+)
+
+// entitlement is a user's right to a dataset's content. Version is the
+// latest version of the dataset the user may download.
+type entitlement struct {
+	Reason  string
+	Version int
+}
+
+// datasetEntitlement returns the user's entitlement to the dataset's
+// content. Sellers, admins and live subscribers may access every version,
+// as may anyone if the dataset is free. Buyers may access the versions that
+// had been published when they made their latest purchase, unless it was
+// refunded. ErrNotEntitled is returned if none of these apply.
+func datasetEntitlement(db *gorm.DB, user models.User, dataset models.Dataset) (entitlement, error) {
+	if user.ID == dataset.UserID {
+		return entitlement{Reason: entitlementOwner, Version: dataset.Version}, nil
+	}
+	if user.Admin {
+		return entitlement{Reason: entitlementAdmin, Version: dataset.Version}, nil
+	}
+	if datasetPricingMode(dataset) == pricingModeFree {
+		return entitlement{Reason: entitlementFree, Version: dataset.Version}, nil
+	}
+
+	subscribed, err := hasLiveSubscription(db, user.ID, dataset.ID)
+	if err != nil {
+		return entitlement{}, err
+	}
+	if subscribed {
+		return entitlement{Reason: entitlementSubscription, Version: dataset.Version}, nil
+	}
+
+	var version int
+	err = db.Model(&models.Purchase{}).
+		Where("user_id = ? AND dataset_id = ? AND status <> ? AND state NOT IN ?", user.ID, dataset.ID, purchaseStatusRefunded, unpaidPurchaseStates).
+		Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	if err != nil {
+		return entitlement{}, err
+	}
+	if version == 0 {
+		return entitlement{}, ErrNotEntitled
+	}
+	return entitlement{Reason: entitlementPurchase, Version: version}, nil
+}
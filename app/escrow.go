@@ -0,0 +1,535 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/OB1Company/filehive/repo/models"
+	"gorm.io/gorm"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultDisputeWindow = time.Hour * 72
+	escrowCheckInterval  = time.Minute
+
+	// settlementTimeout is how long a purchase can be settling before it
+	// is taken to have been abandoned part way through.
+	settlementTimeout = time.Minute * 10
+)
+
+// Purchase statuses. Purchases made without an escrow address pay the
+// seller straight away and are complete. Escrowed purchases end up either
+// released to the seller or refunded to the buyer. Settling marks a
+// purchase whose funds are being moved so that only one request can
+// release or refund it. A purchase released to the seller whose fee could
+// not be sent is left fee unpaid until the fee is sent.
+const (
+	purchaseStatusComplete  = "complete"
+	purchaseStatusEscrowed  = "escrowed"
+	purchaseStatusDisputed  = "disputed"
+	purchaseStatusSettling  = "settling"
+	purchaseStatusReleased  = "released"
+	purchaseStatusFeeUnpaid = "fee_unpaid"
+	purchaseStatusRefunded  = "refunded"
+)
+
+// claimPurchase moves a purchase from its status to settling, noting the
+// status it came from. It returns false if the purchase has changed status
+// since it was loaded, meaning someone else is settling it.
+func (s *FileHiveServer) claimPurchase(purchase models.Purchase) (bool, error) {
+	var claimed bool
+	err := s.db.Update(func(db *gorm.DB) error {
+		tx := db.Model(&models.Purchase{}).Where("id = ? AND status = ?", purchase.ID, purchase.Status).Updates(map[string]interface{}{
+			"status":        purchaseStatusSettling,
+			"settling_from": purchase.Status,
+		})
+		claimed = tx.RowsAffected > 0
+		return tx.Error
+	})
+	return claimed, err
+}
+
+// unclaimPurchase puts a purchase back in the status it was in before
+// a failed attempt to settle it.
+func (s *FileHiveServer) unclaimPurchase(purchase models.Purchase) {
+	err := s.db.Update(func(db *gorm.DB) error {
+		return db.Model(&models.Purchase{}).Where("id = ?", purchase.ID).Update("status", purchase.Status).Error
+	})
+	if err != nil {
+		log.Errorf("Error restoring status of purchase %s: %s", purchase.ID, err)
+	}
+}
+
+// sendEscrowPayment sends amt from the escrow address for the purchase,
+// unless the payment is found to have been sent already.
+func (s *FileHiveServer) sendEscrowPayment(purchase models.Purchase, to string, amt *big.Int, txType string) (string, error) {
+	txid, sent, err := s.findPayment(purchase.ID, purchase.CreatedAt, s.escrowAddress, to, amt, txType)
+	if err != nil || sent {
+		return txid, err
+	}
+	return s.sendFIL(s.escrowAddress, to, amt, s.escrowToken, txType, purchase.ID)
+}
+
+// releaseEscrow pays the seller the escrowed price, less the marketplace
+// fee, and sends the fee to the payout address.
+func (s *FileHiveServer) releaseEscrow(purchase models.Purchase) (models.Purchase, error) {
+	if purchase.Status != purchaseStatusEscrowed && purchase.Status != purchaseStatusDisputed {
+		return purchase, ErrNotEscrowed
+	}
+
+	var seller models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("id = ?", purchase.SellerID).First(&seller).Error
+	})
+	if err != nil {
+		return purchase, err
+	}
+
+	feeAmount, ok := new(big.Int).SetString(purchase.FeeAmount, 10)
+	if !ok {
+		return purchase, fmt.Errorf("purchase %s has an invalid fee amount", purchase.ID)
+	}
+
+	claimed, err := s.claimPurchase(purchase)
+	if err != nil {
+		return purchase, err
+	}
+	if !claimed {
+		return purchase, ErrNotEscrowed
+	}
+
+	amt := purchase.Price.AttoFIL()
+	amt.Sub(amt, feeAmount)
+
+	txid, err := s.sendEscrowPayment(purchase, seller.FilecoinAddress, amt, transactionTypeRelease)
+	if err != nil {
+		s.unclaimPurchase(purchase)
+		return purchase, err
+	}
+
+	purchase.Status = purchaseStatusReleased
+	purchase.ReleaseTxid = txid
+	if feeAmount.Sign() > 0 && s.filecoinAddress != s.escrowAddress {
+		purchase.FeeTxid, err = s.sendEscrowPayment(purchase, s.filecoinAddress, feeAmount, transactionTypeFee)
+		if err != nil {
+			log.Errorf("Error sending fee for purchase %s: %s", purchase.ID, err)
+			purchase.Status = purchaseStatusFeeUnpaid
+		}
+	}
+
+	err = s.db.Update(func(db *gorm.DB) error {
+		return db.Model(&purchase).Updates(map[string]interface{}{
+			"status":       purchase.Status,
+			"release_txid": purchase.ReleaseTxid,
+			"fee_txid":     purchase.FeeTxid,
+		}).Error
+	})
+	if err != nil {
+		return purchase, err
+	}
+	log.Infof("Released escrow for purchase %s to seller %s", purchase.ID, seller.ID)
+	return purchase, nil
+}
+
+// payEscrowFee sends the marketplace fee for a purchase that was released
+// to the seller without its fee, unless the fee is found to have been sent
+// already.
+func (s *FileHiveServer) payEscrowFee(purchase models.Purchase) (models.Purchase, error) {
+	if purchase.Status != purchaseStatusFeeUnpaid {
+		return purchase, ErrNotEscrowed
+	}
+
+	feeAmount, ok := new(big.Int).SetString(purchase.FeeAmount, 10)
+	if !ok {
+		return purchase, fmt.Errorf("purchase %s has an invalid fee amount", purchase.ID)
+	}
+
+	claimed, err := s.claimPurchase(purchase)
+	if err != nil {
+		return purchase, err
+	}
+	if !claimed {
+		return purchase, ErrNotEscrowed
+	}
+
+	txid, err := s.sendEscrowPayment(purchase, s.filecoinAddress, feeAmount, transactionTypeFee)
+	if err != nil {
+		s.unclaimPurchase(purchase)
+		return purchase, err
+	}
+
+	purchase.Status = purchaseStatusReleased
+	purchase.FeeTxid = txid
+	err = s.db.Update(func(db *gorm.DB) error {
+		return db.Model(&purchase).Updates(map[string]interface{}{
+			"status":   purchase.Status,
+			"fee_txid": purchase.FeeTxid,
+		}).Error
+	})
+	if err != nil {
+		return purchase, err
+	}
+	log.Infof("Sent unpaid fee for purchase %s", purchase.ID)
+	return purchase, nil
+}
+
+// refundEscrow sends the escrowed price back to the buyer.
+func (s *FileHiveServer) refundEscrow(purchase models.Purchase) (models.Purchase, error) {
+	if purchase.Status != purchaseStatusEscrowed && purchase.Status != purchaseStatusDisputed {
+		return purchase, ErrNotEscrowed
+	}
+
+	var buyer models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("id = ?", purchase.UserID).First(&buyer).Error
+	})
+	if err != nil {
+		return purchase, err
+	}
+
+	claimed, err := s.claimPurchase(purchase)
+	if err != nil {
+		return purchase, err
+	}
+	if !claimed {
+		return purchase, ErrNotEscrowed
+	}
+
+	txid, err := s.sendEscrowPayment(purchase, buyer.FilecoinAddress, purchase.Price.AttoFIL(), transactionTypeRefund)
+	if err != nil {
+		s.unclaimPurchase(purchase)
+		return purchase, err
+	}
+
+	purchase.Status = purchaseStatusRefunded
+	purchase.RefundTxid = txid
+	err = s.db.Update(func(db *gorm.DB) error {
+		return db.Model(&purchase).Updates(map[string]interface{}{
+			"status":      purchase.Status,
+			"refund_txid": purchase.RefundTxid,
+		}).Error
+	})
+	if err != nil {
+		return purchase, err
+	}
+	log.Infof("Refunded escrow for purchase %s to buyer %s", purchase.ID, buyer.ID)
+	return purchase, nil
+}
+
+// recoverSettlement finishes a settlement that was abandoned part way
+// through, such as by a crash after the funds were sent. A release or
+// refund found in the ledger or wallet is recorded, otherwise the purchase
+// is put back in the status it was settled from. A payment that may have
+// been sent but can't be found is left settling to be checked by hand.
+func (s *FileHiveServer) recoverSettlement(purchase models.Purchase) error {
+	var seller, buyer models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		if err := db.Where("id = ?", purchase.SellerID).First(&seller).Error; err != nil {
+			return err
+		}
+		return db.Where("id = ?", purchase.UserID).First(&buyer).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	feeAmount, ok := new(big.Int).SetString(purchase.FeeAmount, 10)
+	if !ok {
+		return fmt.Errorf("purchase %s has an invalid fee amount", purchase.ID)
+	}
+	amt := purchase.Price.AttoFIL()
+	amt.Sub(amt, feeAmount)
+
+	updates := map[string]interface{}{}
+	releaseTxid, released, err := s.findPayment(purchase.ID, purchase.CreatedAt, s.escrowAddress, seller.FilecoinAddress, amt, transactionTypeRelease)
+	if err != nil {
+		return err
+	}
+	if released {
+		updates["status"] = purchaseStatusReleased
+		updates["release_txid"] = releaseTxid
+		if feeAmount.Sign() > 0 && s.filecoinAddress != s.escrowAddress {
+			feeTxid, paid, err := s.findPayment(purchase.ID, purchase.CreatedAt, s.escrowAddress, s.filecoinAddress, feeAmount, transactionTypeFee)
+			if err != nil {
+				return err
+			}
+			updates["fee_txid"] = feeTxid
+			if !paid {
+				updates["status"] = purchaseStatusFeeUnpaid
+			}
+		}
+	} else {
+		refundTxid, refunded, err := s.findPayment(purchase.ID, purchase.CreatedAt, s.escrowAddress, buyer.FilecoinAddress, purchase.Price.AttoFIL(), transactionTypeRefund)
+		if err != nil {
+			return err
+		}
+		if refunded {
+			updates["status"] = purchaseStatusRefunded
+			updates["refund_txid"] = refundTxid
+		} else if purchase.SettlingFrom != "" {
+			updates["status"] = purchase.SettlingFrom
+		} else {
+			updates["status"] = purchaseStatusEscrowed
+		}
+	}
+
+	err = s.db.Update(func(db *gorm.DB) error {
+		return db.Model(&models.Purchase{}).Where("id = ? AND status = ?", purchase.ID, purchaseStatusSettling).Updates(updates).Error
+	})
+	if err != nil {
+		return err
+	}
+	log.Infof("Recovered settlement of purchase %s as %s", purchase.ID, updates["status"])
+	return nil
+}
+
+// releaseDeliveredPurchases releases the escrow on a buyer's purchases of
+// a dataset after it has been successfully downloaded.
+func (s *FileHiveServer) releaseDeliveredPurchases(userID, datasetID string) {
+	var purchases []models.Purchase
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("user_id = ? AND dataset_id = ? AND status = ? AND state NOT IN ?", userID, datasetID, purchaseStatusEscrowed, unpaidPurchaseStates).Find(&purchases).Error
+	})
+	if err != nil {
+		log.Errorf("Error loading purchases of dataset %s: %s", datasetID, err)
+		return
+	}
+	for _, purchase := range purchases {
+		if _, err := s.releaseEscrow(purchase); err != nil {
+			log.Errorf("Error releasing escrow for purchase %s: %s", purchase.ID, err)
+		}
+	}
+}
+
+// storageFailed returns whether the dataset's current storage job failed
+// for good, meaning the dataset can't be delivered.
+func storageFailed(db *gorm.DB, datasetID string) (bool, error) {
+	var dataset models.Dataset
+	if err := db.Where("id = ?", datasetID).First(&dataset).Error; err != nil {
+		return false, err
+	}
+	var job models.StorageJob
+	err := db.Where("id = ?", dataset.JobID).First(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return job.Flagged || (job.Status == jobStatusCanceled && job.RetryJobID == ""), nil
+}
+
+// settleEscrowedPurchases periodically settles escrowed purchases until
+// the server is shut down.
+func (s *FileHiveServer) settleEscrowedPurchases() {
+	ticker := time.NewTicker(escrowCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.settleEscrow(); err != nil {
+			log.Errorf("Error settling escrowed purchases: %s", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-s.shutdown:
+			return
+		}
+	}
+}
+
+// settleEscrow refunds escrowed purchases of datasets that could not be
+// stored and releases those whose dispute window has passed without
+// a dispute. Settlements abandoned part way through are recovered first,
+// and fees that could not be sent when a purchase was released are sent
+// again.
+func (s *FileHiveServer) settleEscrow() error {
+	var abandoned []models.Purchase
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("status = ? AND updated_at < ?", purchaseStatusSettling, time.Now().Add(-settlementTimeout)).Find(&abandoned).Error
+	})
+	if err != nil {
+		return err
+	}
+	for _, purchase := range abandoned {
+		if err := s.recoverSettlement(purchase); err != nil {
+			log.Errorf("Error recovering settlement of purchase %s: %s", purchase.ID, err)
+		}
+	}
+
+	var purchases, unpaid []models.Purchase
+	err = s.db.View(func(db *gorm.DB) error {
+		if err := db.Where("status = ?", purchaseStatusFeeUnpaid).Find(&unpaid).Error; err != nil {
+			return err
+		}
+		return db.Where("status = ? AND state NOT IN ?", purchaseStatusEscrowed, unpaidPurchaseStates).Find(&purchases).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, purchase := range unpaid {
+		if _, err := s.payEscrowFee(purchase); err != nil {
+			log.Errorf("Error sending fee for purchase %s: %s", purchase.ID, err)
+		}
+	}
+
+	for _, purchase := range purchases {
+		var failed bool
+		err := s.db.View(func(db *gorm.DB) error {
+			var err error
+			failed, err = storageFailed(db, purchase.DatasetID)
+			return err
+		})
+		if err != nil {
+			log.Errorf("Error checking storage of purchase %s: %s", purchase.ID, err)
+			continue
+		}
+
+		if failed {
+			_, err = s.refundEscrow(purchase)
+		} else if time.Now().After(purchase.ReleaseAfter) {
+			_, err = s.releaseEscrow(purchase)
+		}
+		if err != nil {
+			log.Errorf("Error settling purchase %s: %s", purchase.ID, err)
+		}
+	}
+	return nil
+}
+
+// handlePOSTPurchaseDispute lets a buyer hold an escrowed payment until an
+// admin has looked at the purchase.
+func (s *FileHiveServer) handlePOSTPurchaseDispute(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	id := sp[len(sp)-2]
+
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	type data struct {
+		Reason string `json:"reason"`
+	}
+	var d data
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+			http.Error(w, wrapError(ErrInvalidJSON), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var purchase models.Purchase
+	err = s.db.View(func(db *gorm.DB) error {
+		return db.Where("id = ? AND user_id = ? AND state NOT IN ?", id, user.ID, unpaidPurchaseStates).First(&purchase).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrPurchaseNotFound), http.StatusNotFound)
+		return
+	}
+
+	if purchase.Status != purchaseStatusEscrowed {
+		http.Error(w, wrapError(ErrNotEscrowed), http.StatusBadRequest)
+		return
+	}
+	if time.Now().After(purchase.ReleaseAfter) {
+		http.Error(w, wrapError(ErrDisputeWindow), http.StatusBadRequest)
+		return
+	}
+
+	var disputed bool
+	err = s.db.Update(func(db *gorm.DB) error {
+		tx := db.Model(&models.Purchase{}).Where("id = ? AND status = ?", purchase.ID, purchaseStatusEscrowed).Updates(map[string]interface{}{
+			"status":         purchaseStatusDisputed,
+			"dispute_reason": d.Reason,
+		})
+		disputed = tx.RowsAffected > 0
+		return tx.Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	if !disputed {
+		http.Error(w, wrapError(ErrNotEscrowed), http.StatusBadRequest)
+		return
+	}
+	purchase.Status = purchaseStatusDisputed
+	purchase.DisputeReason = d.Reason
+
+	sanitizedJSONResponse(w, purchase)
+}
+
+func (s *FileHiveServer) handlePOSTAdminPurchaseRelease(w http.ResponseWriter, r *http.Request) {
+	s.settleAdminPurchase(w, r, s.releaseEscrow)
+}
+
+func (s *FileHiveServer) handlePOSTAdminPurchaseRefund(w http.ResponseWriter, r *http.Request) {
+	s.settleAdminPurchase(w, r, s.refundEscrow)
+}
+
+// settleAdminPurchase resolves an escrowed or disputed purchase on behalf
+// of an admin using the given settle function.
+func (s *FileHiveServer) settleAdminPurchase(w http.ResponseWriter, r *http.Request, settle func(models.Purchase) (models.Purchase, error)) {
+	sp := strings.Split(r.URL.Path, "/")
+	id := sp[len(sp)-2]
+
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	if !user.Admin {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var purchase models.Purchase
+	err = s.db.View(func(db *gorm.DB) error {
+		return db.Where("id = ? AND state NOT IN ?", id, unpaidPurchaseStates).First(&purchase).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrPurchaseNotFound), http.StatusNotFound)
+		return
+	}
+
+	purchase, err = settle(purchase)
+	if errors.Is(err, ErrNotEscrowed) {
+		http.Error(w, wrapError(err), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	sanitizedJSONResponse(w, purchase)
+}
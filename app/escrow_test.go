@@ -0,0 +1,194 @@
+package app
+
+import (
+	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/repo"
+	"github.com/OB1Company/filehive/repo/models"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func Test_Escrow(t *testing.T) {
+	db, err := repo.NewDatabase("", repo.Dialect("memory"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wbe := fil.NewMockWalletBackend()
+	addresses := make([]string, 4)
+	for i := range addresses {
+		addresses[i], err = wbe.NewAddress("")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	escrowAddr, feeAddr, buyerAddr, sellerAddr := addresses[0], addresses[1], addresses[2], addresses[3]
+	wbe.GenerateToAddress(escrowAddr, fil.MustParseAmount("10").AttoFIL())
+
+	server := &FileHiveServer{
+		db:              db,
+		walletBackend:   wbe,
+		filecoinAddress: feeAddr,
+		feePercent:      defaultFeePercent,
+		escrowAddress:   escrowAddr,
+		disputeWindow:   time.Hour,
+	}
+
+	err = db.Update(func(db *gorm.DB) error {
+		if err := db.Save(&models.User{ID: "buyer", Email: "buyer@ob1.io", FilecoinAddress: buyerAddr}).Error; err != nil {
+			return err
+		}
+		if err := db.Save(&models.User{ID: "seller", Email: "seller@ob1.io", FilecoinAddress: sellerAddr}).Error; err != nil {
+			return err
+		}
+		if err := db.Save(&models.Dataset{ID: "stored", UserID: "seller", JobID: "job1"}).Error; err != nil {
+			return err
+		}
+		if err := db.Save(&models.Dataset{ID: "unopened", UserID: "seller", JobID: "job1"}).Error; err != nil {
+			return err
+		}
+		if err := db.Save(&models.Dataset{ID: "lost", UserID: "seller", JobID: "job2"}).Error; err != nil {
+			return err
+		}
+		if err := db.Save(&models.StorageJob{ID: "job1", DatasetID: "stored", Status: jobStatusSuccess}).Error; err != nil {
+			return err
+		}
+		if err := db.Save(&models.StorageJob{ID: "job2", DatasetID: "lost", Status: jobStatusFailed, Flagged: true}).Error; err != nil {
+			return err
+		}
+		purchases := []models.Purchase{
+			{ID: "downloaded", UserID: "buyer", SellerID: "seller", DatasetID: "stored", Price: fil.MustParseAmount("1"), FeeAmount: fil.MustParseAmount("0.05").AttoFIL().String(), Status: purchaseStatusEscrowed, ReleaseAfter: time.Now().Add(time.Hour)},
+			{ID: "expired", UserID: "buyer", SellerID: "seller", DatasetID: "unopened", Price: fil.MustParseAmount("2"), FeeAmount: fil.MustParseAmount("0.1").AttoFIL().String(), Status: purchaseStatusEscrowed, ReleaseAfter: time.Now().Add(-time.Minute)},
+			{ID: "failed", UserID: "buyer", SellerID: "seller", DatasetID: "lost", Price: fil.MustParseAmount("3"), FeeAmount: fil.MustParseAmount("0.15").AttoFIL().String(), Status: purchaseStatusEscrowed, ReleaseAfter: time.Now().Add(time.Hour)},
+		}
+		for _, purchase := range purchases {
+			if err := db.Save(&purchase).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loadPurchase := func(id string) models.Purchase {
+		var purchase models.Purchase
+		err := db.View(func(db *gorm.DB) error {
+			return db.Where("id = ?", id).First(&purchase).Error
+		})
+		if err != nil {
+			t.Fatalf("purchase %s: %s", id, err)
+		}
+		return purchase
+	}
+	checkBalance := func(name, addr string, expected string) {
+		balance, err := wbe.Balance(addr, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fil.NewAmount(balance).Cmp(fil.MustParseAmount(expected)) != 0 {
+			t.Errorf("Expected %s balance of %s, got %s", name, expected, fil.NewAmount(balance))
+		}
+	}
+
+	// The first purchase is released once it has been downloaded, but only
+	// the first time.
+	server.releaseDeliveredPurchases("buyer", "stored")
+	server.releaseDeliveredPurchases("buyer", "stored")
+	if purchase := loadPurchase("downloaded"); purchase.Status != purchaseStatusReleased || purchase.ReleaseTxid == "" {
+		t.Errorf("Expected downloaded purchase to be released, got %s", purchase.Status)
+	}
+	if purchase := loadPurchase("expired"); purchase.Status != purchaseStatusEscrowed {
+		t.Errorf("Expected expired purchase to still be escrowed, got %s", purchase.Status)
+	}
+	checkBalance("seller", sellerAddr, "0.95")
+	checkBalance("fee", feeAddr, "0.05")
+
+	// The second is released after its dispute window and the third is
+	// refunded as the dataset could not be stored.
+	if err := server.settleEscrow(); err != nil {
+		t.Fatal(err)
+	}
+	if purchase := loadPurchase("expired"); purchase.Status != purchaseStatusReleased {
+		t.Errorf("Expected expired purchase to be released, got %s", purchase.Status)
+	}
+	if purchase := loadPurchase("failed"); purchase.Status != purchaseStatusRefunded || purchase.RefundTxid == "" {
+		t.Errorf("Expected failed purchase to be refunded, got %s", purchase.Status)
+	}
+	checkBalance("seller", sellerAddr, "2.85")
+	checkBalance("fee", feeAddr, "0.15")
+	checkBalance("buyer", buyerAddr, "3")
+
+	if _, err := server.refundEscrow(loadPurchase("expired")); err != ErrNotEscrowed {
+		t.Errorf("Expected released purchase not to be refunded, got %v", err)
+	}
+
+	// A fee that can't be sent when the seller is paid is sent when the
+	// escrow is next settled.
+	err = db.Update(func(db *gorm.DB) error {
+		return db.Save(&models.Purchase{ID: "short", UserID: "buyer", SellerID: "seller", DatasetID: "unopened", Price: fil.MustParseAmount("5"), FeeAmount: fil.MustParseAmount("0.25").AttoFIL().String(), Status: purchaseStatusEscrowed, ReleaseAfter: time.Now().Add(-time.Minute)}).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wbe.GenerateToAddress(escrowAddr, fil.MustParseAmount("0.8").AttoFIL())
+	if err := server.settleEscrow(); err != nil {
+		t.Fatal(err)
+	}
+	if purchase := loadPurchase("short"); purchase.Status != purchaseStatusFeeUnpaid || purchase.ReleaseTxid == "" || purchase.FeeTxid != "" {
+		t.Errorf("Expected short purchase to be released with its fee unpaid, got %s", purchase.Status)
+	}
+	checkBalance("seller", sellerAddr, "7.6")
+	checkBalance("fee", feeAddr, "0.15")
+
+	wbe.GenerateToAddress(escrowAddr, fil.MustParseAmount("0.2").AttoFIL())
+	if err := server.settleEscrow(); err != nil {
+		t.Fatal(err)
+	}
+	if purchase := loadPurchase("short"); purchase.Status != purchaseStatusReleased || purchase.FeeTxid == "" {
+		t.Errorf("Expected short purchase fee to be sent, got %s", purchase.Status)
+	}
+	checkBalance("seller", sellerAddr, "7.6")
+	checkBalance("fee", feeAddr, "0.4")
+
+	// A release that crashed after paying the seller is finished without
+	// paying again, and a settlement that never moved any money is put
+	// back as it was.
+	wbe.GenerateToAddress(escrowAddr, fil.MustParseAmount("3").AttoFIL())
+	err = db.Update(func(db *gorm.DB) error {
+		for _, purchase := range []models.Purchase{
+			{ID: "crashed", UserID: "buyer", SellerID: "seller", DatasetID: "unopened", Price: fil.MustParseAmount("2"), FeeAmount: fil.MustParseAmount("0.1").AttoFIL().String(), Status: purchaseStatusSettling, SettlingFrom: purchaseStatusEscrowed, ReleaseAfter: time.Now().Add(-time.Minute)},
+			{ID: "abandoned", UserID: "buyer", SellerID: "seller", DatasetID: "unopened", Price: fil.MustParseAmount("1"), FeeAmount: fil.MustParseAmount("0.05").AttoFIL().String(), Status: purchaseStatusSettling, SettlingFrom: purchaseStatusDisputed, ReleaseAfter: time.Now().Add(-time.Minute)},
+			{ID: "settling", UserID: "buyer", SellerID: "seller", DatasetID: "unopened", Price: fil.MustParseAmount("1"), FeeAmount: fil.MustParseAmount("0.05").AttoFIL().String(), Status: purchaseStatusSettling, SettlingFrom: purchaseStatusEscrowed, ReleaseAfter: time.Now().Add(-time.Minute)},
+		} {
+			if err := db.Save(&purchase).Error; err != nil {
+				return err
+			}
+		}
+		return db.Model(&models.Purchase{}).Where("id IN ?", []string{"crashed", "abandoned"}).UpdateColumn("updated_at", time.Now().Add(-time.Hour)).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	releaseTxid, err := server.sendFIL(escrowAddr, sellerAddr, fil.MustParseAmount("1.9").AttoFIL(), "", transactionTypeRelease, "crashed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.settleEscrow(); err != nil {
+		t.Fatal(err)
+	}
+	if purchase := loadPurchase("crashed"); purchase.Status != purchaseStatusReleased || purchase.ReleaseTxid != releaseTxid || purchase.FeeTxid == "" {
+		t.Errorf("Expected crashed purchase to be released with release %s, got %s with release %s", releaseTxid, purchase.Status, purchase.ReleaseTxid)
+	}
+	if purchase := loadPurchase("abandoned"); purchase.Status != purchaseStatusDisputed {
+		t.Errorf("Expected abandoned purchase to be disputed again, got %s", purchase.Status)
+	}
+	if purchase := loadPurchase("settling"); purchase.Status != purchaseStatusSettling {
+		t.Errorf("Expected a settlement in progress to be left alone, got %s", purchase.Status)
+	}
+	checkBalance("seller", sellerAddr, "9.5")
+	checkBalance("fee", feeAddr, "0.5")
+	checkBalance("escrow", escrowAddr, "1")
+}
@@ -0,0 +1,363 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/repo/models"
+	"gorm.io/gorm"
+	"math"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultFeePercent = 5
+
+// The fee policies record which rule set the fee on a purchase.
+const (
+	feePolicyNone      = "none"
+	feePolicyDefault   = "default"
+	feePolicySeller    = "seller"
+	feePolicyPromotion = "promotion"
+)
+
+// feeQuote is the marketplace fee for a sale along with the rule that
+// produced it.
+type feeQuote struct {
+	Amount  *big.Int
+	Percent float64
+	Minimum fil.Amount
+	Policy  string
+}
+
+// quoteFee returns the marketplace fee on a sale of amt by the seller at
+// the given time. A running promotion waives the fee, otherwise the
+// seller's override is used if an admin has set one. No fee is taken
+// unless a Filecoin address is configured for payouts.
+func (s *FileHiveServer) quoteFee(sellerID string, amt *big.Int, at time.Time) (feeQuote, error) {
+	if s.filecoinAddress == "" {
+		return feeQuote{Amount: new(big.Int), Policy: feePolicyNone}, nil
+	}
+
+	quote := feeQuote{
+		Percent: s.feePercent,
+		Minimum: s.minimumFee,
+		Policy:  feePolicyDefault,
+	}
+	err := s.db.View(func(db *gorm.DB) error {
+		var promotions int64
+		if err := db.Model(&models.FeePromotion{}).Where("starts_at <= ? AND ends_at > ? AND (user_id = '' OR user_id = ?)", at, at, sellerID).Count(&promotions).Error; err != nil {
+			return err
+		}
+		if promotions > 0 {
+			quote = feeQuote{Policy: feePolicyPromotion}
+			return nil
+		}
+
+		var override models.FeeOverride
+		err := db.Where("id = ?", sellerID).First(&override).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		quote = feeQuote{
+			Percent: override.FeePercent,
+			Minimum: override.MinimumFee,
+			Policy:  feePolicySeller,
+		}
+		return nil
+	})
+	if err != nil {
+		return feeQuote{}, err
+	}
+
+	quote.Amount = computeFee(amt, quote.Percent, quote.Minimum)
+	return quote, nil
+}
+
+// computeFee returns percent of amt, to the nearest hundredth of a
+// percent, but not less than minimum. The fee never exceeds amt.
+func computeFee(amt *big.Int, percent float64, minimum fil.Amount) *big.Int {
+	basisPoints := big.NewInt(int64(math.Round(percent * 100)))
+	fee := new(big.Int).Mul(amt, basisPoints)
+	fee.Div(fee, big.NewInt(10000))
+
+	if min := minimum.AttoFIL(); fee.Cmp(min) < 0 {
+		fee = min
+	}
+	if fee.Cmp(amt) > 0 {
+		fee.Set(amt)
+	}
+	return fee
+}
+
+func validFee(percent float64, minimum fil.Amount) bool {
+	return percent >= 0 && percent <= 100 && minimum.Sign() >= 0
+}
+
+func (s *FileHiveServer) handleGETAdminFees(w http.ResponseWriter, r *http.Request) {
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	if !user.Admin {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var (
+		overrides  []models.FeeOverride
+		promotions []models.FeePromotion
+	)
+	err = s.db.View(func(db *gorm.DB) error {
+		if err := db.Order("created_at DESC").Find(&overrides).Error; err != nil {
+			return err
+		}
+		return db.Order("starts_at DESC").Find(&promotions).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	sanitizedJSONResponse(w, struct {
+		FeePercent float64               `json:"feePercent"`
+		MinimumFee fil.Amount            `json:"minimumFee"`
+		Overrides  []models.FeeOverride  `json:"overrides"`
+		Promotions []models.FeePromotion `json:"promotions"`
+	}{
+		FeePercent: s.feePercent,
+		MinimumFee: s.minimumFee,
+		Overrides:  overrides,
+		Promotions: promotions,
+	})
+}
+
+func (s *FileHiveServer) handlePUTAdminSellerFee(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	sellerID := sp[len(sp)-1]
+
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	if !user.Admin {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	type fee struct {
+		FeePercent float64    `json:"feePercent"`
+		MinimumFee fil.Amount `json:"minimumFee"`
+	}
+	var f fee
+	if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+		http.Error(w, wrapError(ErrInvalidJSON), http.StatusBadRequest)
+		return
+	}
+	if !validFee(f.FeePercent, f.MinimumFee) {
+		http.Error(w, wrapError(ErrInvalidFee), http.StatusBadRequest)
+		return
+	}
+
+	var override models.FeeOverride
+	err = s.db.Update(func(db *gorm.DB) error {
+		if err := db.Where("id = ?", sellerID).First(&models.User{}).Error; err != nil {
+			return ErrUserNotFound
+		}
+		if err := db.Where("id = ?", sellerID).First(&override).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		override.ID = sellerID
+		override.FeePercent = f.FeePercent
+		override.MinimumFee = f.MinimumFee
+		return db.Save(&override).Error
+	})
+	if errors.Is(err, ErrUserNotFound) {
+		http.Error(w, wrapError(err), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	log.Infof("Set fee for seller %s to %.2f%% (minimum %s FIL)", sellerID, f.FeePercent, f.MinimumFee)
+	sanitizedJSONResponse(w, override)
+}
+
+func (s *FileHiveServer) handleDELETEAdminSellerFee(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	sellerID := sp[len(sp)-1]
+
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	if !user.Admin {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	err = s.db.Update(func(db *gorm.DB) error {
+		return db.Unscoped().Where("id = ?", sellerID).Delete(&models.FeeOverride{}).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *FileHiveServer) handlePOSTAdminFeePromotion(w http.ResponseWriter, r *http.Request) {
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	if !user.Admin {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var promotion models.FeePromotion
+	if err := json.NewDecoder(r.Body).Decode(&promotion); err != nil {
+		http.Error(w, wrapError(ErrInvalidJSON), http.StatusBadRequest)
+		return
+	}
+	if promotion.StartsAt.IsZero() {
+		promotion.StartsAt = time.Now()
+	}
+	if !promotion.EndsAt.After(promotion.StartsAt) {
+		http.Error(w, wrapError(ErrInvalidOption), http.StatusBadRequest)
+		return
+	}
+
+	promotion.ID, err = makeID()
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	err = s.db.Update(func(db *gorm.DB) error {
+		if promotion.UserID != "" {
+			if err := db.Where("id = ?", promotion.UserID).First(&models.User{}).Error; err != nil {
+				return ErrUserNotFound
+			}
+		}
+		return db.Create(&promotion).Error
+	})
+	if errors.Is(err, ErrUserNotFound) {
+		http.Error(w, wrapError(err), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	log.Infof("Created fee promotion %s from %s to %s", promotion.ID, promotion.StartsAt, promotion.EndsAt)
+	sanitizedJSONResponse(w, promotion)
+}
+
+func (s *FileHiveServer) handleDELETEAdminFeePromotion(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	id := sp[len(sp)-1]
+
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	if !user.Admin {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var deleted int64
+	err = s.db.Update(func(db *gorm.DB) error {
+		tx := db.Where("id = ?", id).Delete(&models.FeePromotion{})
+		deleted = tx.RowsAffected
+		return tx.Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		http.Error(w, wrapError(ErrPromotionNotFound), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
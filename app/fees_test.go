@@ -0,0 +1,130 @@
+package app
+
+import (
+	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/repo"
+	"github.com/OB1Company/filehive/repo/models"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func Test_QuoteFee(t *testing.T) {
+	db, err := repo.NewDatabase("", repo.Dialect("memory"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	err = db.Update(func(db *gorm.DB) error {
+		if err := db.Save(&models.FeeOverride{ID: "discounted", FeePercent: 2.5}).Error; err != nil {
+			return err
+		}
+		if err := db.Save(&models.FeeOverride{ID: "promoted", FeePercent: 10}).Error; err != nil {
+			return err
+		}
+		if err := db.Save(&models.FeePromotion{ID: "current", UserID: "promoted", StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)}).Error; err != nil {
+			return err
+		}
+		return db.Save(&models.FeePromotion{ID: "expired", StartsAt: now.Add(-time.Hour * 2), EndsAt: now.Add(-time.Hour)}).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &FileHiveServer{
+		db:              db,
+		filecoinAddress: "f1fee",
+		feePercent:      defaultFeePercent,
+		minimumFee:      fil.MustParseAmount("0.01"),
+	}
+
+	tests := []struct {
+		name     string
+		sellerID string
+		price    string
+		at       time.Time
+		policy   string
+		fee      string
+	}{
+		{
+			name:     "Default fee",
+			sellerID: "seller",
+			price:    "10",
+			at:       now,
+			policy:   feePolicyDefault,
+			fee:      "0.5",
+		},
+		{
+			name:     "Minimum fee",
+			sellerID: "seller",
+			price:    "0.1",
+			at:       now,
+			policy:   feePolicyDefault,
+			fee:      "0.01",
+		},
+		{
+			name:     "Fee capped at price",
+			sellerID: "seller",
+			price:    "0.005",
+			at:       now,
+			policy:   feePolicyDefault,
+			fee:      "0.005",
+		},
+		{
+			name:     "Seller override",
+			sellerID: "discounted",
+			price:    "10",
+			at:       now,
+			policy:   feePolicySeller,
+			fee:      "0.25",
+		},
+		{
+			name:     "Seller promotion",
+			sellerID: "promoted",
+			price:    "10",
+			at:       now,
+			policy:   feePolicyPromotion,
+			fee:      "0",
+		},
+		{
+			name:     "Override after promotion ends",
+			sellerID: "promoted",
+			price:    "10",
+			at:       now.Add(time.Hour * 2),
+			policy:   feePolicySeller,
+			fee:      "1",
+		},
+		{
+			name:     "Promotion at time of sale",
+			sellerID: "seller",
+			price:    "10",
+			at:       now.Add(-time.Minute * 90),
+			policy:   feePolicyPromotion,
+			fee:      "0",
+		},
+	}
+
+	for _, test := range tests {
+		quote, err := server.quoteFee(test.sellerID, fil.MustParseAmount(test.price).AttoFIL(), test.at)
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		if quote.Policy != test.policy {
+			t.Errorf("%s: expected policy %s, got %s", test.name, test.policy, quote.Policy)
+		}
+		if fee := fil.NewAmount(quote.Amount); fee.Cmp(fil.MustParseAmount(test.fee)) != 0 {
+			t.Errorf("%s: expected fee %s, got %s", test.name, test.fee, fee)
+		}
+	}
+
+	server.filecoinAddress = ""
+	quote, err := server.quoteFee("seller", fil.MustParseAmount("10").AttoFIL(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quote.Policy != feePolicyNone || quote.Amount.Sign() != 0 {
+		t.Errorf("Expected no fee without a payout address, got %s %s", quote.Policy, quote.Amount)
+	}
+}
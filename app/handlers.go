@@ -2,28 +2,21 @@ package app
 
 import (
 	"bytes"
-	"context"
-	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/OB1Company/filehive/emails"
 	"github.com/OB1Company/filehive/fil"
 	"github.com/OB1Company/filehive/repo/models"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/filecoin-project/go-address"
 	"github.com/gorilla/mux"
-	"github.com/mailgun/mailgun-go/v4"
-	"github.com/nfnt/resize"
 	"gorm.io/gorm"
-	"image/jpeg"
 	"io"
-	"io/ioutil"
 	"math/big"
 	"net/http"
-	"net/url"
 	"os"
 	"path"
-	"path/filepath"
 	"regexp"
 	"sort"
 	"strconv"
@@ -49,6 +42,56 @@ var (
 	ErrInvalidOption      = errors.New("invalid option")
 	ErrMissingForm        = errors.New("missing form")
 	ErrInsuffientFunds    = errors.New("insufficient funds")
+	ErrFileTooLarge       = errors.New("file exceeds maximum upload size")
+	ErrUploadNotFound     = errors.New("upload not found")
+	ErrUploadOffset       = errors.New("upload offset does not match")
+	ErrUploadInProgress   = errors.New("upload is already in progress")
+	ErrUploadIncomplete   = errors.New("upload is incomplete")
+	ErrChecksumMismatch   = errors.New("checksum does not match")
+	ErrJobNotFound        = errors.New("storage job not found")
+	ErrJobNotRetryable    = errors.New("storage job cannot be retried")
+	ErrEmailNotFound      = errors.New("email not found")
+	ErrEmailNotFailed     = errors.New("only failed emails can be resent")
+	ErrPurchaseNotFound   = errors.New("purchase not found")
+	ErrNotEscrowed        = errors.New("purchase is not held in escrow")
+	ErrDisputeWindow      = errors.New("dispute window has closed")
+	ErrIdempotencyKey     = errors.New("idempotency key was used for another purchase")
+	ErrPurchaseInProgress = errors.New("purchase is already in progress")
+	ErrInvalidFee         = errors.New("invalid fee")
+	ErrPromotionNotFound  = errors.New("fee promotion not found")
+	ErrTxNotFound         = errors.New("transaction not found")
+	ErrCategoryNotFound   = errors.New("category not found")
+	ErrCategoryExists     = errors.New("category slug already exists")
+	ErrInvalidCategory    = errors.New("invalid category")
+	ErrInvalidTags        = errors.New("invalid tags")
+	ErrVersionNotFound    = errors.New("dataset version not found")
+	ErrVersionNotCovered  = errors.New("purchase does not cover dataset version")
+	ErrInvalidPricing     = errors.New("invalid pricing mode or price")
+	ErrBelowMinimumPrice  = errors.New("offer is below the minimum price")
+	ErrNotForSale         = errors.New("dataset is only sold by subscription")
+	ErrInvalidSubTerms    = errors.New("invalid subscription price or period")
+	ErrNotSubscribable    = errors.New("dataset does not offer subscriptions")
+	ErrSubscribed         = errors.New("already subscribed to dataset")
+	ErrSubNotFound        = errors.New("subscription not found")
+	ErrNotEntitled        = errors.New("dataset has not been purchased")
+	ErrInvalidLink        = errors.New("download link is invalid")
+	ErrLinkExpired        = errors.New("download link has expired or been used up")
+	ErrContentMismatch    = errors.New("content does not match its content ID")
+	ErrNotCached          = errors.New("content is not cached")
+	ErrUnknownCurrency    = errors.New("no exchange rate for currency")
+	ErrRatesUnavailable   = errors.New("exchange rates are unavailable")
+	ErrInvalidLanguage    = errors.New("emails are not available in that language")
+
+	ErrNotificationNotFound = errors.New("notification not found")
+	ErrInvalidNotification  = errors.New("invalid notification type")
+
+	ErrWebhookNotFound     = errors.New("webhook not found")
+	ErrInvalidWebhookURL   = errors.New("webhook url must be an absolute http or https url")
+	ErrInvalidWebhookEvent = errors.New("invalid webhook event")
+
+	ErrWebhookAddressNotAllowed = errors.New("webhooks can't be sent to private addresses")
+
+	ErrPaymentUnknown = errors.New("payment may have been sent and needs to be checked by hand")
 
 	emailRegex = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 )
@@ -75,6 +118,16 @@ func isEmailValid(e string) bool {
 	return emailRegex.MatchString(e)
 }
 
+// validLanguage checks that emails have been translated into the language.
+func validLanguage(language string) bool {
+	for _, locale := range emails.Locales() {
+		if language == locale {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *FileHiveServer) loginUser(w http.ResponseWriter, email string) {
 	expirationTime := time.Now().Add(jwtExpirationHours * time.Hour)
 
@@ -267,41 +320,16 @@ func (s *FileHiveServer) handlePOSTUser(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Send email notification
-	mg := mailgun.NewMailgun(s.mailDomain, s.mailgunKey)
-
-	sender := "administrator@" + s.mailDomain
-	subject := "Welcome to Filehive! 🐝"
-	body := ""
-	recipient := d.Email
-
-	// The message object allows you to add attachments and Bcc recipients
-	message := mg.NewMessage(sender, subject, body, recipient)
-
-	// If you want to use a pre-made template in Mailgun set it here
-	//message.SetTemplate("welcome-email")
-
-	pwd, _ := os.Getwd()
-	template, err := ioutil.ReadFile(filepath.Join(pwd, "email_templates/welcome-email.tpl"))
-	if err != nil {
-		log.Debug(err)
-	}
-
-	templateString := strings.ReplaceAll(string(template), "%recipient_name%", d.Name)
-	templateString = strings.ReplaceAll(templateString, "%domain_name%", s.mailDomain)
-	templateString = strings.ReplaceAll(templateString, "%code%", otp)
-	templateString = strings.ReplaceAll(templateString, "%email%", url.QueryEscape(d.Email))
-
-	message.SetHtml(templateString)
-
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
-	defer cancel()
-
-	// Send the message with a 10 second timeout
-	resp, id, err := mg.Send(ctx, message)
-	log.Debugf("Mailgun Response: %v, %v", resp, id)
-
+	welcome, err := emails.Welcome(emails.Locale(user.Language, user.Country), emails.WelcomeData{
+		Site:  emails.Site{Domain: s.mailDomain},
+		Name:  user.Name,
+		Email: user.Email,
+		Code:  otp,
+	})
 	if err != nil {
 		log.Error(err)
+	} else if err := s.queueEmail(user.Email, welcome); err != nil {
+		log.Error(err)
 	}
 
 	s.loginUser(w, user.Email)
@@ -421,11 +449,12 @@ func (s *FileHiveServer) handlePATCHUser(w http.ResponseWriter, r *http.Request)
 	}
 
 	type data struct {
-		Email    string `json:"email"`
-		Name     string `json:"name"`
-		Password string `json:"password"`
-		Country  string `json:"country"`
-		Avatar   string `json:"avatar"`
+		Email    string  `json:"email"`
+		Name     string  `json:"name"`
+		Password string  `json:"password"`
+		Country  string  `json:"country"`
+		Language *string `json:"language"`
+		Avatar   string  `json:"avatar"`
 	}
 	var d data
 	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
@@ -462,6 +491,12 @@ func (s *FileHiveServer) handlePATCHUser(w http.ResponseWriter, r *http.Request)
 		if d.Country != "" {
 			user.Country = d.Country
 		}
+		if d.Language != nil {
+			if *d.Language != "" && !validLanguage(*d.Language) {
+				return ErrInvalidLanguage
+			}
+			user.Language = *d.Language
+		}
 		if newPW != nil {
 			user.HashedPassword = newPW
 		}
@@ -480,7 +515,7 @@ func (s *FileHiveServer) handlePATCHUser(w http.ResponseWriter, r *http.Request)
 		return nil
 	})
 	if err != nil {
-		if errors.Is(err, ErrInvalidEmail) || errors.Is(err, ErrInvalidImage) {
+		if errors.Is(err, ErrInvalidEmail) || errors.Is(err, ErrInvalidImage) || errors.Is(err, ErrInvalidLanguage) {
 			http.Error(w, wrapError(err), http.StatusBadRequest)
 			return
 		} else if errors.Is(err, ErrUserExists) {
@@ -553,6 +588,10 @@ func (s *FileHiveServer) handleGETWalletBalance(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	if err := s.scanWalletAddress(user.FilecoinAddress, user.PowergateToken); err != nil {
+		log.Errorf("Error scanning wallet %s: %s", user.FilecoinAddress, err)
+	}
+
 	balance, err := s.walletBackend.Balance(user.FilecoinAddress, user.PowergateToken)
 	if err != nil {
 		http.Error(w, wrapError(err), http.StatusInternalServerError)
@@ -560,9 +599,11 @@ func (s *FileHiveServer) handleGETWalletBalance(w http.ResponseWriter, r *http.R
 	}
 
 	sanitizedJSONResponse(w, struct {
-		Balance float64
+		Balance     fil.Amount
+		BalanceFiat *fiatValue `json:",omitempty"`
 	}{
-		Balance: fil.AttoFILToFIL(balance),
+		Balance:     fil.NewAmount(balance),
+		BalanceFiat: s.fiatValue(fil.NewAmount(balance), requestedCurrency(r)),
 	})
 }
 
@@ -586,16 +627,20 @@ func (s *FileHiveServer) handlePOSTWalletSend(w http.ResponseWriter, r *http.Req
 	}
 
 	type data struct {
-		Address string  `json:"address"`
-		Amount  float64 `json:"amount"`
+		Address string     `json:"address"`
+		Amount  fil.Amount `json:"amount"`
 	}
 	var d data
 	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
 		http.Error(w, wrapError(ErrInvalidJSON), http.StatusBadRequest)
 		return
 	}
+	if d.Amount.Sign() <= 0 {
+		http.Error(w, wrapError(fil.ErrInvalidAmount), http.StatusBadRequest)
+		return
+	}
 
-	txid, err := s.walletBackend.Send(user.FilecoinAddress, d.Address, fil.FILtoAttoFIL(d.Amount), user.PowergateToken)
+	txid, err := s.sendFIL(user.FilecoinAddress, d.Address, d.Amount.AttoFIL(), user.PowergateToken, transactionTypeWithdrawal, "")
 	if err != nil {
 		if errors.Is(err, fil.ErrInsuffientFunds) {
 			http.Error(w, wrapError(fil.ErrInsuffientFunds), http.StatusBadRequest)
@@ -612,59 +657,10 @@ func (s *FileHiveServer) handlePOSTWalletSend(w http.ResponseWriter, r *http.Req
 	})
 }
 
-func (s *FileHiveServer) handleGETWalletTransactions(w http.ResponseWriter, r *http.Request) {
-	emailIface := r.Context().Value("email")
-
-	email, ok := emailIface.(string)
-	if !ok {
-		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
-		return
-	}
-
-	var user models.User
-	err := s.db.View(func(db *gorm.DB) error {
-		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
-
-	})
-	if err != nil {
-		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
-		return
-	}
-
-	var limit, offset int
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
-
-	if limitStr != "" {
-		limit, err = strconv.Atoi(limitStr)
-		if err != nil {
-			http.Error(w, wrapError(ErrInvalidOption), http.StatusBadRequest)
-			return
-		}
-	} else {
-		limit = -1
-	}
-	if offsetStr != "" {
-		offset, err = strconv.Atoi(offsetStr)
-		if err != nil {
-			http.Error(w, wrapError(ErrInvalidOption), http.StatusBadRequest)
-			return
-		}
-	}
-
-	txs, err := s.walletBackend.Transactions(user.FilecoinAddress, limit, offset)
-	if err != nil {
-		http.Error(w, wrapError(err), http.StatusInternalServerError)
-		return
-	}
-
-	sanitizedJSONResponse(w, txs)
-}
-
 func (s *FileHiveServer) handlePOSTGenerateCoins(w http.ResponseWriter, r *http.Request) {
 	type data struct {
-		Address string  `json:"address"`
-		Amount  float64 `json:"amount"`
+		Address string     `json:"address"`
+		Amount  fil.Amount `json:"amount"`
 	}
 	var d data
 	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
@@ -672,7 +668,7 @@ func (s *FileHiveServer) handlePOSTGenerateCoins(w http.ResponseWriter, r *http.
 		return
 	}
 
-	s.walletBackend.(*fil.MockWalletBackend).GenerateToAddress(d.Address, fil.FILtoAttoFIL(d.Amount))
+	s.walletBackend.(*fil.MockWalletBackend).GenerateToAddress(d.Address, d.Amount.AttoFIL())
 }
 
 func (s *FileHiveServer) handleGETDelist(w http.ResponseWriter, r *http.Request) {
@@ -697,8 +693,16 @@ func (s *FileHiveServer) handleGETDelist(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	var notifyDataset *models.Dataset
 	if user.Admin {
 		err = s.db.Update(func(db *gorm.DB) error {
+			var dataset models.Dataset
+			if err := db.Where("id = ?", id).First(&dataset).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			if dataset.ID != "" && !dataset.Delisted && dataset.UserID != user.ID {
+				notifyDataset = &dataset
+			}
 			if err := db.Model(&models.Dataset{}).Where("id = ?", id).Update("delisted", true).Error; err != nil {
 				return err
 			}
@@ -706,13 +710,24 @@ func (s *FileHiveServer) handleGETDelist(w http.ResponseWriter, r *http.Request)
 		})
 	} else {
 		err = s.db.Update(func(db *gorm.DB) error {
-			if err := db.Model(&models.Dataset{}).Where("id = ? and email = ?", id, email).Update("delisted", true).Error; err != nil {
+			if err := db.Model(&models.Dataset{}).Where("id = ? and user_id = ?", id, user.ID).Update("delisted", true).Error; err != nil {
 				return err
 			}
 			return nil
 		})
 	}
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	s.updateSearchIndex(id)
 
+	// Let the seller know when an admin takes their dataset down.
+	if notifyDataset != nil {
+		if err := s.notifyDatasetDelisted(*notifyDataset); err != nil {
+			log.Errorf("Error notifying delisting of dataset %s: %s", id, err)
+		}
+	}
 }
 
 func (s *FileHiveServer) handleGETRelist(w http.ResponseWriter, r *http.Request) {
@@ -746,12 +761,107 @@ func (s *FileHiveServer) handleGETRelist(w http.ResponseWriter, r *http.Request)
 		})
 	} else {
 		err = s.db.Update(func(db *gorm.DB) error {
-			if err := db.Model(&models.Dataset{}).Where("id = ? and email = ?", id, email).Update("delisted", false).Error; err != nil {
+			if err := db.Model(&models.Dataset{}).Where("id = ? and user_id = ?", id, user.ID).Update("delisted", false).Error; err != nil {
 				return err
 			}
 			return nil
 		})
 	}
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	s.updateSearchIndex(id)
+}
+
+// datasetMetadata is the listing information a seller supplies alongside
+// the dataset file. When a resumable upload is finalized with a DatasetID
+// the file is added to that dataset as a new version instead, and only the
+// changelog, filename and file type are used.
+type datasetMetadata struct {
+	Title            string     `json:"title"`
+	ShortDescription string     `json:"shortDescription"`
+	FullDescription  string     `json:"fullDescription"`
+	Image            string     `json:"image"`
+	FileType         string     `json:"fileType"`
+	Price            fil.Amount `json:"price"`
+	Filename         string     `json:"filename"`
+	Category         string     `json:"category"`
+	Tags             []string   `json:"tags"`
+	Changelog        string     `json:"changelog"`
+	DatasetID        string     `json:"datasetID"`
+
+	PricingMode  string     `json:"pricingMode"`
+	MinimumPrice fil.Amount `json:"minimumPrice"`
+
+	SubscriptionPrice  fil.Amount `json:"subscriptionPrice"`
+	SubscriptionPeriod int        `json:"subscriptionPeriod"`
+
+	FiatCurrency string `json:"fiatCurrency"`
+	FiatPrice    string `json:"fiatPrice"`
+}
+
+// newDataset saves the listing image and builds the dataset record for the
+// provided metadata. The content fields are left for the caller to fill in
+// once the file has been stored.
+func (s *FileHiveServer) newDataset(user models.User, id string, d datasetMetadata) (models.Dataset, error) {
+	tags, err := normalizeTags(d.Tags)
+	if err != nil {
+		return models.Dataset{}, err
+	}
+
+	if !validSubscriptionTerms(d.SubscriptionPrice, d.SubscriptionPeriod) {
+		return models.Dataset{}, ErrInvalidSubTerms
+	}
+
+	var category models.Category
+	if d.Category != "" {
+		err := s.db.View(func(db *gorm.DB) error {
+			var err error
+			category, err = findCategory(db, d.Category)
+			return err
+		})
+		if err != nil {
+			return models.Dataset{}, err
+		}
+	}
+
+	filename := fmt.Sprintf("%s.jpg", id)
+	if err := saveDatasetImage(path.Join(s.staticFileDir, "images", filename), d.Image); err != nil {
+		return models.Dataset{}, ErrInvalidImage
+	}
+
+	dataset := models.Dataset{
+		Title:            d.Title,
+		ShortDescription: d.ShortDescription,
+		FullDescription:  d.FullDescription,
+		FileType:         d.FileType,
+		Price:            d.Price,
+		PricingMode:      d.PricingMode,
+		MinimumPrice:     d.MinimumPrice,
+		UserID:           user.ID,
+		ID:               id,
+		Username:         user.Name,
+		ImageFilename:    filename,
+		DatasetFilename:  d.Filename,
+		CategoryID:       category.ID,
+		Tags:             tags,
+		Version:          1,
+
+		SubscriptionPrice:  d.SubscriptionPrice,
+		SubscriptionPeriod: d.SubscriptionPeriod,
+
+		FiatCurrency: strings.ToUpper(d.FiatCurrency),
+		FiatPrice:    d.FiatPrice,
+	}
+	if err := s.pegPrice(&dataset); err != nil {
+		return models.Dataset{}, err
+	}
+	dataset.PricingMode = datasetPricingMode(dataset)
+	if !validPricing(dataset) {
+		return models.Dataset{}, ErrInvalidPricing
+	}
+	return dataset, nil
 }
 
 func (s *FileHiveServer) handlePOSTDataset(w http.ResponseWriter, r *http.Request) {
@@ -797,6 +907,8 @@ func (s *FileHiveServer) handlePOSTDataset(w http.ResponseWriter, r *http.Reques
 		jobID                          string
 		size                           int64
 		cid                            string
+		checksum                       string
+		changelog                      string
 	)
 	for {
 		part, err := mr.NextPart()
@@ -809,62 +921,51 @@ func (s *FileHiveServer) handlePOSTDataset(w http.ResponseWriter, r *http.Reques
 		}
 
 		if part.FormName() == "file" {
-			fileBytes, err := ioutil.ReadAll(part)
-			if err != nil {
-				http.Error(w, "failed to read content of the part", http.StatusInternalServerError)
+			// Spool the part to disk, hashing it on the way, so nothing
+			// reaches the backend unless the whole file is within the size
+			// limit. The file is never held in memory.
+			f, upload, err := s.spoolUpload(part)
+			if errors.Is(err, ErrFileTooLarge) {
+				http.Error(w, wrapError(ErrFileTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			} else if err != nil {
+				http.Error(w, wrapError(err), http.StatusInternalServerError)
 				return
 			}
 
-			jobID, cid, _, err = s.filecoinBackend.Store(bytes.NewReader(fileBytes), addr, user.PowergateToken)
+			jobID, cid, _, err = s.filecoinBackend.Store(f, addr, user.PowergateToken)
+			f.Close()
+			os.Remove(f.Name())
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
 
-			size = int64(len(fileBytes))
+			size = upload.Size()
+			checksum = upload.Checksum()
 
 			containsFile = true
 		}
 
 		if part.FormName() == "metadata" {
-			type data struct {
-				Title            string  `json:"title"`
-				ShortDescription string  `json:"shortDescription"`
-				FullDescription  string  `json:"fullDescription"`
-				Image            string  `json:"image"`
-				FileType         string  `json:"fileType"`
-				Price            float64 `json:"price"`
-				Filename         string  `json:"filename"`
-			}
-			var d data
+			var d datasetMetadata
 			if err := json.NewDecoder(part).Decode(&d); err != nil {
 				http.Error(w, wrapError(ErrInvalidJSON), http.StatusBadRequest)
 				return
 			}
 
-			filename := fmt.Sprintf("%s.jpg", id)
-			if err := saveDatasetImage(path.Join(s.staticFileDir, "images", filename), d.Image); err != nil {
-				http.Error(w, wrapError(ErrInvalidImage), http.StatusBadRequest)
+			dataset, err = s.newDataset(user, id, d)
+			if err != nil {
+				http.Error(w, wrapError(err), http.StatusBadRequest)
 				return
 			}
-
-			dataset = models.Dataset{
-				Title:            d.Title,
-				ShortDescription: d.ShortDescription,
-				FullDescription:  d.FullDescription,
-				FileType:         d.FileType,
-				Price:            d.Price,
-				UserID:           user.ID,
-				ID:               id,
-				Username:         user.Name,
-				ImageFilename:    filename,
-				DatasetFilename:  d.Filename,
-			}
+			changelog = d.Changelog
 			containsMetadata = true
 		}
 	}
 	dataset.FileSize = size
 	dataset.ContentID = cid
+	dataset.SHA256 = checksum
 
 	if !containsFile || !containsMetadata {
 		http.Error(w, wrapError(ErrMissingForm), http.StatusInternalServerError)
@@ -872,12 +973,27 @@ func (s *FileHiveServer) handlePOSTDataset(w http.ResponseWriter, r *http.Reques
 	}
 	dataset.JobID = jobID
 	err = s.db.Update(func(db *gorm.DB) error {
-		return db.Save(&dataset).Error
+		if err := db.Save(&dataset).Error; err != nil {
+			return err
+		}
+		if err := saveDatasetTags(db, dataset.ID, dataset.Tags); err != nil {
+			return err
+		}
+		if err := saveDatasetVersion(db, dataset, changelog); err != nil {
+			return err
+		}
+		if err := saveStorageJob(db, newStorageJob(dataset)); err != nil {
+			return err
+		}
+		return queueWebhookEvent(db, user.ID, webhookEventDatasetCreated, dataset)
 	})
 	if err != nil {
 		http.Error(w, wrapError(err), http.StatusInternalServerError)
 		return
 	}
+	s.updateSearchIndex(dataset.ID)
+	s.wakeWebhooks()
+
 	sanitizedJSONResponse(w, struct {
 		DatasetID string `json:"datasetID"`
 	}{
@@ -905,13 +1021,24 @@ func (s *FileHiveServer) handlePATCHDataset(w http.ResponseWriter, r *http.Reque
 	}
 
 	type data struct {
-		ID               string  `json:"id"`
-		Title            string  `json:"title"`
-		ShortDescription string  `json:"shortDescription"`
-		FullDescription  string  `json:"fullDescription"`
-		Image            string  `json:"image"`
-		FileType         string  `json:"fileType"`
-		Price            float64 `json:"price"`
+		ID               string      `json:"id"`
+		Title            string      `json:"title"`
+		ShortDescription string      `json:"shortDescription"`
+		FullDescription  string      `json:"fullDescription"`
+		Image            string      `json:"image"`
+		FileType         string      `json:"fileType"`
+		Price            *fil.Amount `json:"price"`
+		Category         string      `json:"category"`
+		Tags             []string    `json:"tags"`
+
+		PricingMode  *string     `json:"pricingMode"`
+		MinimumPrice *fil.Amount `json:"minimumPrice"`
+
+		SubscriptionPrice  *fil.Amount `json:"subscriptionPrice"`
+		SubscriptionPeriod *int        `json:"subscriptionPeriod"`
+
+		FiatCurrency *string `json:"fiatCurrency"`
+		FiatPrice    *string `json:"fiatPrice"`
 	}
 	var d data
 	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
@@ -951,21 +1078,92 @@ func (s *FileHiveServer) handlePATCHDataset(w http.ResponseWriter, r *http.Reque
 	if d.FullDescription != "" {
 		dataset.FullDescription = d.FullDescription
 	}
-	if d.Price != dataset.Price {
-		dataset.Price = d.Price
+	if d.Price != nil {
+		dataset.Price = *d.Price
 	}
 	if d.FileType != "" {
 		dataset.FileType = d.FileType
 	}
+	if d.Category != "" {
+		var category models.Category
+		err := s.db.View(func(db *gorm.DB) error {
+			var err error
+			category, err = findCategory(db, d.Category)
+			return err
+		})
+		if errors.Is(err, ErrCategoryNotFound) {
+			http.Error(w, wrapError(err), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			http.Error(w, wrapError(err), http.StatusInternalServerError)
+			return
+		}
+		dataset.CategoryID = category.ID
+	}
+	if d.SubscriptionPrice != nil {
+		dataset.SubscriptionPrice = *d.SubscriptionPrice
+	}
+	if d.SubscriptionPeriod != nil {
+		dataset.SubscriptionPeriod = *d.SubscriptionPeriod
+	}
+	if !validSubscriptionTerms(dataset.SubscriptionPrice, dataset.SubscriptionPeriod) {
+		http.Error(w, wrapError(ErrInvalidSubTerms), http.StatusBadRequest)
+		return
+	}
+	if d.PricingMode != nil {
+		dataset.PricingMode = *d.PricingMode
+	}
+	if d.MinimumPrice != nil {
+		dataset.MinimumPrice = *d.MinimumPrice
+	}
+	// An empty fiat currency unpegs the price, leaving it at the last
+	// converted amount unless a new price was given.
+	if d.FiatCurrency != nil {
+		dataset.FiatCurrency = strings.ToUpper(*d.FiatCurrency)
+	}
+	if d.FiatPrice != nil {
+		dataset.FiatPrice = *d.FiatPrice
+	}
+	if dataset.FiatCurrency == "" {
+		dataset.FiatPrice = ""
+	}
+	if err := s.pegPrice(&dataset); errors.Is(err, ErrRatesUnavailable) {
+		http.Error(w, wrapError(err), http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		http.Error(w, wrapError(err), http.StatusBadRequest)
+		return
+	}
+	dataset.PricingMode = datasetPricingMode(dataset)
+	if !validPricing(dataset) {
+		http.Error(w, wrapError(ErrInvalidPricing), http.StatusBadRequest)
+		return
+	}
+	// A missing tag list leaves the tags as they are, an empty one clears
+	// them.
+	tags, err := normalizeTags(d.Tags)
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusBadRequest)
+		return
+	}
 
 	err = s.db.Update(func(db *gorm.DB) error {
-		return db.Save(&dataset).Error
-
+		if err := db.Save(&dataset).Error; err != nil {
+			return err
+		}
+		if err := updateVersionListing(db, dataset); err != nil {
+			return err
+		}
+		if tags != nil {
+			return saveDatasetTags(db, dataset.ID, tags)
+		}
+		return nil
 	})
 	if err != nil {
 		http.Error(w, wrapError(err), http.StatusInternalServerError)
 		return
 	}
+	s.updateSearchIndex(dataset.ID)
 }
 
 func (s *FileHiveServer) handleGETDatasetFile(w http.ResponseWriter, r *http.Request) {
@@ -1000,26 +1198,75 @@ func (s *FileHiveServer) handleGETDatasetFile(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	var requested int
+	if versionStr := r.URL.Query().Get("version"); versionStr != "" {
+		requested, err = strconv.Atoi(versionStr)
+		if err != nil || requested <= 0 {
+			http.Error(w, wrapError(ErrInvalidOption), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var version models.DatasetVersion
+	err = s.db.View(func(db *gorm.DB) error {
+		var err error
+		version, err = downloadVersion(db, user, dataset, requested)
+		return err
+	})
+	if errors.Is(err, ErrVersionNotFound) {
+		http.Error(w, wrapError(err), http.StatusNotFound)
+		return
+	} else if errors.Is(err, ErrVersionNotCovered) || errors.Is(err, ErrNotEntitled) {
+		http.Error(w, wrapError(err), http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	if !s.serveDatasetVersion(w, r, dataset, version) {
+		return
+	}
+
+	// The buyer has their data so any escrowed payment can go to the seller.
+	s.releaseDeliveredPurchases(user.ID, dataset.ID)
+}
+
+// serveDatasetVersion sends the content of a dataset version, retrieving
+// it with the uploader's Powergate token if it isn't in the local cache.
+// Range requests are supported so interrupted downloads can be resumed.
+// It returns whether the file was sent through to its end.
+func (s *FileHiveServer) serveDatasetVersion(w http.ResponseWriter, r *http.Request, dataset models.Dataset, version models.DatasetVersion) bool {
 	// Get datset uploader account token
 	var uploader models.User
-	err = s.db.View(func(db *gorm.DB) error {
+	err := s.db.View(func(db *gorm.DB) error {
 		return db.Where("id = ?", dataset.UserID).First(&uploader).Error
 	})
 	if err != nil {
 		http.Error(w, wrapError(ErrUserNotFound), http.StatusUnauthorized)
-		return
+		return false
 	}
 
-	fileStream, err := s.filecoinBackend.Get(dataset.ContentID, uploader.PowergateToken)
+	f, err := s.cachedContent(version.ContentID, uploader.PowergateToken, version.FileSize)
 	if err != nil {
 		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusNotFound)
-		return
+		return false
 	}
+	defer f.Close()
 
-	w.Header().Set("Content-Disposition", "attachment; filename="+dataset.DatasetFilename)
-	w.Header().Set("Content-Type", dataset.FileType)
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return false
+	}
 
-	io.Copy(w, fileStream)
+	w.Header().Set("Content-Disposition", "attachment; filename="+version.DatasetFilename)
+	w.Header().Set("Content-Type", version.FileType)
+	w.Header().Set("ETag", `"`+version.ContentID+`"`)
+
+	dw := &deliveryWriter{ResponseWriter: w}
+	http.ServeContent(dw, r, version.DatasetFilename, time.Time{}, f)
+	return dw.delivered(info.Size())
 }
 
 func (s *FileHiveServer) handleGETPurchased(w http.ResponseWriter, r *http.Request) {
@@ -1049,7 +1296,7 @@ func (s *FileHiveServer) handleGETPurchased(w http.ResponseWriter, r *http.Reque
 	// Retrieve matching purchase if it exists
 	var purchase models.Purchase
 	err = s.db.View(func(db *gorm.DB) error {
-		return db.Where("user_id = ? and dataset_id = ?", user.ID, id).First(&purchase).Error
+		return db.Where("user_id = ? and dataset_id = ? and status <> ? and state NOT IN ?", user.ID, id, purchaseStatusRefunded, unpaidPurchaseStates).First(&purchase).Error
 
 	})
 	if err != nil {
@@ -1096,7 +1343,29 @@ func (s *FileHiveServer) handleGETDatasetDeal(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	jobStatus, err := s.filecoinBackend.JobStatus(dataset.JobID, user.PowergateToken)
+	err = s.db.View(func(db *gorm.DB) error {
+		_, err := datasetEntitlement(db, user, dataset)
+		return err
+	})
+	if errors.Is(err, ErrNotEntitled) {
+		http.Error(w, wrapError(err), http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	// The job belongs to the uploader so it is looked up with their token.
+	var uploader models.User
+	err = s.db.View(func(db *gorm.DB) error {
+		return db.Where("id = ?", dataset.UserID).First(&uploader).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrUserNotFound), http.StatusNotFound)
+		return
+	}
+
+	jobStatus, err := s.filecoinBackend.JobStatus(dataset.JobID, uploader.PowergateToken)
 	if err != nil {
 		http.Error(w, wrapError(ErrDatasetNotFound), http.StatusBadRequest)
 		return
@@ -1119,8 +1388,16 @@ func (s *FileHiveServer) handleGETDataset(w http.ResponseWriter, r *http.Request
 		}
 
 		dataset.Views++
-		return db.Save(&dataset).Error
+		if err := db.Save(&dataset).Error; err != nil {
+			return err
+		}
 
+		datasets := []models.Dataset{dataset}
+		if err := loadDatasetTags(db, datasets); err != nil {
+			return err
+		}
+		dataset.Tags = datasets[0].Tags
+		return nil
 	})
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -1131,7 +1408,7 @@ func (s *FileHiveServer) handleGETDataset(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	sanitizedJSONResponse(w, dataset)
+	sanitizedJSONResponse(w, s.newDatasetResponse(dataset, requestedCurrency(r)))
 }
 
 func (s *FileHiveServer) handleGETDatasets(w http.ResponseWriter, r *http.Request) {
@@ -1171,8 +1448,10 @@ func (s *FileHiveServer) handleGETDatasets(w http.ResponseWriter, r *http.Reques
 		if err := db.Model(&models.Dataset{}).Where("user_id = ?", user.ID).Count(&count).Error; err != nil {
 			return err
 		}
-		return db.Where("user_id = ?", user.ID).Offset(page * 10).Limit(10).Find(&datasets).Error
-
+		if err := db.Where("user_id = ?", user.ID).Offset(page * 10).Limit(10).Find(&datasets).Error; err != nil {
+			return err
+		}
+		return loadDatasetTags(db, datasets)
 	})
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -1253,6 +1532,12 @@ func (s *FileHiveServer) handlePOSTDisableUsers(w http.ResponseWriter, r *http.R
 			}
 			return nil
 		})
+
+		datasetIDs := make([]string, 0, len(datasets))
+		for _, ds := range datasets {
+			datasetIDs = append(datasetIDs, ds.ID)
+		}
+		s.updateSearchIndex(datasetIDs...)
 	}
 
 	log.Debug(disabledUsers)
@@ -1409,6 +1694,12 @@ func (s *FileHiveServer) handlePOSTEnableUsers(w http.ResponseWriter, r *http.Re
 			}
 			return nil
 		})
+
+		datasetIDs := make([]string, 0, len(datasets))
+		for _, ds := range datasets {
+			datasetIDs = append(datasetIDs, ds.ID)
+		}
+		s.updateSearchIndex(datasetIDs...)
 	}
 
 	log.Debug(enabledUsers)
@@ -1458,143 +1749,119 @@ func (s *FileHiveServer) handlePOSTPurchase(w http.ResponseWriter, r *http.Reque
 		}
 	}
 
-	purchaseID, err := makeID()
-	if err != nil {
-		http.Error(w, wrapError(err), http.StatusInternalServerError)
-		return
+	// Pay what you want datasets take the buyer's offer in the body.
+	type data struct {
+		Amount fil.Amount `json:"amount"`
 	}
-
-	balance, err := s.walletBackend.Balance(user.FilecoinAddress, "")
-	if err != nil {
+	var d data
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+			http.Error(w, wrapError(ErrInvalidJSON), http.StatusBadRequest)
+			return
+		}
+	}
+	// Pegged datasets are charged what their fiat price is worth now.
+	if err := s.pegPrice(&dataset); errors.Is(err, ErrRatesUnavailable) {
+		http.Error(w, wrapError(err), http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
 		http.Error(w, wrapError(err), http.StatusInternalServerError)
 		return
 	}
-
-	amt := fil.FILtoAttoFIL(dataset.Price)
-	if balance.Cmp(amt) < 0 {
-		http.Error(w, wrapError(ErrInsuffientFunds), http.StatusBadRequest)
+	price, err := purchasePrice(dataset, d.Amount)
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusBadRequest)
 		return
 	}
 
-	feeAmount := new(big.Int).Set(amt)
-
-	if s.filecoinAddress != "" {
-		// Send fee amount to Filehive if address is specified
-		feeAmount.Div(amt, new(big.Int).SetInt64(20))
-		_, err := s.walletBackend.Send(user.FilecoinAddress, s.filecoinAddress, feeAmount, user.PowergateToken)
+	// A request retried with the same idempotency key picks the purchase
+	// up where it left off rather than charging the buyer again. Claiming
+	// a dataset for nothing again returns the earlier claim.
+	var (
+		purchase models.Purchase
+		exists   bool
+	)
+	key := r.Header.Get("Idempotency-Key")
+	if price.IsZero() {
+		err = s.db.View(func(db *gorm.DB) error {
+			tx := db.Where("user_id = ? AND dataset_id = ? AND price_atto = ? AND status <> ? AND state NOT IN ?", user.ID, dataset.ID, fil.Amount{}, purchaseStatusRefunded, unpaidPurchaseStates).Limit(1).Find(&purchase)
+			exists = tx.RowsAffected > 0
+			return tx.Error
+		})
 		if err != nil {
 			http.Error(w, wrapError(err), http.StatusInternalServerError)
 			return
 		}
-
-		amt.Sub(amt, feeAmount)
-	}
-
-	txid, err := s.walletBackend.Send(user.FilecoinAddress, datasetUser.FilecoinAddress, amt, user.PowergateToken)
-	if err != nil {
-		http.Error(w, wrapError(err), http.StatusInternalServerError)
-		return
 	}
-
-	purchase := models.Purchase{
-		UserID:           user.ID,
-		SellerID:         datasetUser.ID,
-		Username:         datasetUser.Name,
-		ImageFilename:    dataset.ImageFilename,
-		Price:            dataset.Price,
-		ShortDescription: dataset.ShortDescription,
-		FileType:         dataset.FileType,
-		DatasetID:        dataset.ID,
-		Title:            dataset.Title,
-		Timestamp:        time.Now(),
-		ID:               purchaseID,
-	}
-
-	err = s.db.Update(func(db *gorm.DB) error {
-		dataset.Purchases++
-		if err := db.Save(&dataset).Error; err != nil {
-			return err
+	if key != "" && !exists {
+		purchase, exists, err = s.loadIdempotentPurchase(user.ID, key)
+		if err != nil {
+			http.Error(w, wrapError(err), http.StatusInternalServerError)
+			return
+		}
+		if exists && purchase.DatasetID != dataset.ID {
+			http.Error(w, wrapError(ErrIdempotencyKey), http.StatusBadRequest)
+			return
 		}
-		return db.Save(&purchase).Error
-	})
-	if err != nil {
-		http.Error(w, wrapError(err), http.StatusInternalServerError)
-		return
 	}
 
-	var seller models.User
+	if (!exists || purchase.State == purchaseStateFailed) && price.Sign() > 0 {
+		balance, err := s.walletBackend.Balance(user.FilecoinAddress, "")
+		if err != nil {
+			http.Error(w, wrapError(err), http.StatusInternalServerError)
+			return
+		}
 
-	err = s.db.View(func(db *gorm.DB) error {
-		return db.Where("id = ?", purchase.SellerID).First(&seller).Error
-	})
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			http.Error(w, wrapError(ErrUserNotFound), http.StatusNotFound)
+		if balance.Cmp(price.AttoFIL()) < 0 {
+			http.Error(w, wrapError(ErrInsuffientFunds), http.StatusBadRequest)
 			return
 		}
 	}
 
-	// Get image thumb for dataset
-	f, err := os.Open(path.Join(s.staticFileDir, "images", purchase.ImageFilename))
-	if err != nil {
-		http.Error(w, wrapError(ErrImageNotFound), http.StatusNotFound)
-		return
+	if !exists {
+		purchase, err = s.newPurchase(user, datasetUser, dataset, price, key)
+		if err != nil {
+			// A concurrent request with the same key may have won the race.
+			purchase, exists, _ = s.loadIdempotentPurchase(user.ID, key)
+			if key == "" || !exists {
+				http.Error(w, wrapError(err), http.StatusInternalServerError)
+				return
+			}
+		}
 	}
 
-	image, err := jpeg.Decode(f)
-	thumb := resize.Thumbnail(48, 48, image, resize.NearestNeighbor)
-	buf := new(bytes.Buffer)
-	err = jpeg.Encode(buf, thumb, &jpeg.Options{100})
-	imageBit := buf.Bytes()
-	thumbBase64 := base64.StdEncoding.EncodeToString([]byte(imageBit))
-
-	// Send email to seller
-	mg := mailgun.NewMailgun(s.mailDomain, s.mailgunKey)
-
-	sender := "administrator@" + s.mailDomain
-	subject := "You've made a sale on Filehive! 🤑"
-	body := ""
-	recipient := seller.Email
-
-	message := mg.NewMessage(sender, subject, body, recipient)
-
-	pwd, _ := os.Getwd()
-	template, err := ioutil.ReadFile(filepath.Join(pwd, "email_templates/sale.tpl"))
-	if err != nil {
-		http.Error(w, wrapError(err), http.StatusBadRequest)
+	if !s.purchaseLocks.acquire(purchase.ID) {
+		http.Error(w, wrapError(ErrPurchaseInProgress), http.StatusConflict)
 		return
 	}
+	defer s.purchaseLocks.release(purchase.ID)
 
-	templateString := strings.ReplaceAll(string(template), "%recipient_name%", seller.Name)
-	templateString = strings.ReplaceAll(templateString, "%domain_name%", s.mailDomain)
-	templateString = strings.ReplaceAll(templateString, "%api_domain%", "api."+s.mailDomain)
-	templateString = strings.ReplaceAll(templateString, "%customer%", user.Name)
-	templateString = strings.ReplaceAll(templateString, "%image%", "data:image/png;base64, "+thumbBase64)
-	templateString = strings.ReplaceAll(templateString, "%dataset_name%", purchase.Title)
-	templateString = strings.ReplaceAll(templateString, "%dataset_shortdescription%", purchase.ShortDescription)
-	templateString = strings.ReplaceAll(templateString, "%dataset_price%", fmt.Sprintf("%f FIL", purchase.Price))
-	templateString = strings.ReplaceAll(templateString, "%order_id%", purchase.ID)
-	templateString = strings.ReplaceAll(templateString, "%timestamp%", purchase.Timestamp.Format("2006-01-02 15:04:05"))
-	templateString = strings.ReplaceAll(templateString, "%email%", url.QueryEscape(user.Email))
-
-	message.SetHtml(templateString)
-
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
-	defer cancel()
-
-	// Send the message with a 10 second timeout
-	resp, id, err := mg.Send(ctx, message)
-	log.Debugf("Mailgun Response: %v, %v", resp, id)
+	if purchase.State == purchaseStateFailed {
+		if err := s.setPurchaseState(&purchase, purchaseStatePending, nil); err != nil {
+			http.Error(w, wrapError(err), http.StatusInternalServerError)
+			return
+		}
+	}
 
+	purchase, err = s.processPurchase(purchase)
 	if err != nil {
-		http.Error(w, wrapError(err), http.StatusBadRequest)
-		return
+		if errors.Is(err, fil.ErrInsuffientFunds) {
+			http.Error(w, wrapError(ErrInsuffientFunds), http.StatusBadRequest)
+			return
+		}
+		if purchase.State != purchaseStateRecorded {
+			http.Error(w, wrapError(err), http.StatusInternalServerError)
+			return
+		}
+		// The purchase went through but the seller could not be told.
+		// Recovery tries the email again when the server next starts.
+		log.Errorf("Error notifying seller of purchase %s: %s", purchase.ID, err)
 	}
 
 	sanitizedJSONResponse(w, struct {
 		Txid string `json:"txid"`
 	}{
-		Txid: txid,
+		Txid: purchase.Txid,
 	})
 }
 
@@ -1627,10 +1894,10 @@ func (s *FileHiveServer) handleGETAdminSales(w http.ResponseWriter, r *http.Requ
 		count int64
 	)
 	err = s.db.View(func(db *gorm.DB) error {
-		if err := db.Model(&models.Purchase{}).Count(&count).Error; err != nil {
+		if err := db.Model(&models.Purchase{}).Where("state NOT IN ?", unpaidPurchaseStates).Count(&count).Error; err != nil {
 			return err
 		}
-		return db.Order("created_at DESC").Find(&sales).Error
+		return db.Where("state NOT IN ?", unpaidPurchaseStates).Order("created_at DESC").Find(&sales).Error
 
 	})
 	if err != nil {
@@ -1638,10 +1905,31 @@ func (s *FileHiveServer) handleGETAdminSales(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	// Totals are summed in attoFIL so they reconcile exactly with the
+	// amounts recorded on each purchase.
+	var (
+		volume = new(big.Int)
+		fees   = new(big.Int)
+	)
+	for _, sale := range sales {
+		volume.Add(volume, saleAmount(sale))
+		if feeAmount, ok := new(big.Int).SetString(sale.FeeAmount, 10); ok {
+			fees.Add(fees, feeAmount)
+		}
+	}
+
 	sanitizedJSONResponse(w, struct {
-		Users []models.Purchase `json:"sales"`
+		Users        []models.Purchase            `json:"sales"`
+		Count        int64                        `json:"count"`
+		Volume       fil.Amount                   `json:"volume"`
+		Fees         fil.Amount                   `json:"fees"`
+		PricingModes map[string]pricingModeTotals `json:"pricingModes"`
 	}{
-		Users: sales,
+		Users:        sales,
+		Count:        count,
+		Volume:       fil.NewAmount(volume),
+		Fees:         fil.NewAmount(fees),
+		PricingModes: salesByPricingMode(sales),
 	})
 }
 
@@ -1677,14 +1965,18 @@ func (s *FileHiveServer) handleGETSales(w http.ResponseWriter, r *http.Request)
 	}
 
 	var (
-		sales []models.Purchase
-		count int64
+		sales    []models.Purchase
+		allSales []models.Purchase
+		count    int64
 	)
 	err = s.db.View(func(db *gorm.DB) error {
-		if err := db.Model(&models.Purchase{}).Where("seller_id = ?", seller.ID).Count(&count).Error; err != nil {
+		if err := db.Model(&models.Purchase{}).Where("seller_id = ? AND state NOT IN ?", seller.ID, unpaidPurchaseStates).Count(&count).Error; err != nil {
 			return err
 		}
-		return db.Where("seller_id = ?", seller.ID).Offset(page * pagesize).Limit(pagesize).Find(&sales).Error
+		if err := db.Select("pricing_mode", "amount", "price_atto").Where("seller_id = ? AND state NOT IN ?", seller.ID, unpaidPurchaseStates).Find(&allSales).Error; err != nil {
+			return err
+		}
+		return db.Where("seller_id = ? AND state NOT IN ?", seller.ID, unpaidPurchaseStates).Offset(page * pagesize).Limit(pagesize).Find(&sales).Error
 
 	})
 	if err != nil {
@@ -1693,13 +1985,15 @@ func (s *FileHiveServer) handleGETSales(w http.ResponseWriter, r *http.Request)
 	}
 
 	sanitizedJSONResponse(w, struct {
-		Pages int               `json:"pages"`
-		Page  int               `json:"page"`
-		Sales []models.Purchase `json:"sales"`
+		Pages        int                          `json:"pages"`
+		Page         int                          `json:"page"`
+		Sales        []models.Purchase            `json:"sales"`
+		PricingModes map[string]pricingModeTotals `json:"pricingModes"`
 	}{
-		Pages: (int(count) / pagesize) + 1,
-		Page:  page,
-		Sales: sales,
+		Pages:        (int(count) / pagesize) + 1,
+		Page:         page,
+		Sales:        sales,
+		PricingModes: salesByPricingMode(allSales),
 	})
 }
 
@@ -1737,10 +2031,10 @@ func (s *FileHiveServer) handleGETPurchases(w http.ResponseWriter, r *http.Reque
 		count     int64
 	)
 	err = s.db.View(func(db *gorm.DB) error {
-		if err := db.Model(&models.Purchase{}).Where("user_id = ?", user.ID).Count(&count).Error; err != nil {
+		if err := db.Model(&models.Purchase{}).Where("user_id = ? AND state NOT IN ?", user.ID, unpaidPurchaseStates).Count(&count).Error; err != nil {
 			return err
 		}
-		return db.Where("user_id = ?", user.ID).Order("timestamp DESC").Offset(page * 1000).Limit(1000).Find(&purchases).Error
+		return db.Where("user_id = ? AND state NOT IN ?", user.ID, unpaidPurchaseStates).Order("timestamp DESC").Offset(page * 1000).Limit(1000).Find(&purchases).Error
 
 	})
 	if err != nil {
@@ -1748,14 +2042,23 @@ func (s *FileHiveServer) handleGETPurchases(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	currency := requestedCurrency(r)
+	resp := make([]purchaseResponse, 0, len(purchases))
+	for _, purchase := range purchases {
+		resp = append(resp, purchaseResponse{
+			Purchase:  purchase,
+			PriceFiat: s.fiatValue(purchase.Price, currency),
+		})
+	}
+
 	sanitizedJSONResponse(w, struct {
-		Pages     int               `json:"pages"`
-		Page      int               `json:"page"`
-		Purchases []models.Purchase `json:"purchases"`
+		Pages     int                `json:"pages"`
+		Page      int                `json:"page"`
+		Purchases []purchaseResponse `json:"purchases"`
 	}{
 		Pages:     (int(count) / 1000) + 1,
 		Page:      page,
-		Purchases: purchases,
+		Purchases: resp,
 	})
 }
 
@@ -1774,16 +2077,34 @@ func (s *FileHiveServer) handleGETRecent(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
+	scope, err := s.requestCategoryScope(r)
+	if errors.Is(err, ErrCategoryNotFound) {
+		http.Error(w, wrapError(err), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
 	var (
 		recent []models.Dataset
 		count  int64
 	)
 
 	err = s.db.View(func(db *gorm.DB) error {
-		if err := db.Model(&models.Dataset{}).Count(&count).Error; err != nil {
+		countTx := db.Model(&models.Dataset{})
+		tx := db.Order("created_at desc").Where("delisted = false")
+		if scope != nil {
+			countTx = countTx.Where("category_id IN ?", scope)
+			tx = tx.Where("category_id IN ?", scope)
+		}
+		if err := countTx.Count(&count).Error; err != nil {
+			return err
+		}
+		if err := tx.Offset(page * pageSize).Limit(pageSize).Find(&recent).Error; err != nil {
 			return err
 		}
-		return db.Order("created_at desc").Where("delisted = false").Offset(page * pageSize).Limit(pageSize).Find(&recent).Error
+		return loadDatasetTags(db, recent)
 	})
 	if err != nil {
 		http.Error(w, wrapError(err), http.StatusInternalServerError)
@@ -1817,6 +2138,21 @@ func (s *FileHiveServer) handleGETTrending(w http.ResponseWriter, r *http.Reques
 		}
 	}
 
+	scope, err := s.requestCategoryScope(r)
+	if errors.Is(err, ErrCategoryNotFound) {
+		http.Error(w, wrapError(err), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	inScope := func(tx *gorm.DB) *gorm.DB {
+		if scope != nil {
+			return tx.Where("category_id IN ?", scope)
+		}
+		return tx
+	}
+
 	var (
 		trending []models.Dataset
 		recent   []models.Dataset
@@ -1857,7 +2193,7 @@ func (s *FileHiveServer) handleGETTrending(w http.ResponseWriter, r *http.Reques
 
 		for _, res := range results[page*pageSize:] {
 			var ds models.Dataset
-			if err := db.Where("id = ? and delisted = 0", res.DatasetID).First(&ds).Error; err != nil {
+			if err := inScope(db.Where("id = ? and delisted = 0", res.DatasetID)).First(&ds).Error; err != nil {
 				log.Debug("found a delisted dataset")
 			} else {
 				trending = append(trending, ds)
@@ -1869,7 +2205,7 @@ func (s *FileHiveServer) handleGETTrending(w http.ResponseWriter, r *http.Reques
 		}
 
 		var recentCount int64
-		if err := db.Model(&models.Dataset{}).Count(&recentCount).Error; err != nil {
+		if err := inScope(db.Model(&models.Dataset{})).Count(&recentCount).Error; err != nil {
 			return err
 		}
 
@@ -1880,7 +2216,7 @@ func (s *FileHiveServer) handleGETTrending(w http.ResponseWriter, r *http.Reques
 				recentPage = page - trendingPages
 			}
 
-			tx := db.Order("created_at desc").Limit((recentPage * pageSize) + (pageSize - len(trending)))
+			tx := inScope(db.Order("created_at desc").Limit((recentPage * pageSize) + (pageSize - len(trending))))
 
 			for _, r := range results {
 				tx.Where("id != ?", r.DatasetID)
@@ -1896,6 +2232,11 @@ func (s *FileHiveServer) handleGETTrending(w http.ResponseWriter, r *http.Reques
 
 		return nil
 	})
+	if err == nil {
+		err = s.db.View(func(db *gorm.DB) error {
+			return loadDatasetTags(db, trending)
+		})
+	}
 	if err != nil {
 		http.Error(w, wrapError(err), http.StatusInternalServerError)
 		return
@@ -2025,38 +2366,18 @@ func (s *FileHiveServer) handleGETPasswordReset(w http.ResponseWriter, r *http.R
 	}
 
 	// Send email notification
-	mg := mailgun.NewMailgun(s.mailDomain, s.mailgunKey)
-
-	sender := "administrator@" + s.mailDomain
-	subject := "Password Reset Instructions for Filehive Account"
-	body := ""
-	recipient := user.Email
-
-	message := mg.NewMessage(sender, subject, body, recipient)
-
-	pwd, _ := os.Getwd()
-	template, err := ioutil.ReadFile(filepath.Join(pwd, "email_templates/password-reset.tpl"))
+	reset, err := emails.PasswordReset(emails.Locale(user.Language, user.Country), emails.PasswordResetData{
+		Site:  emails.Site{Domain: s.mailDomain},
+		Name:  user.Name,
+		Email: user.Email,
+		Code:  otp,
+	})
 	if err != nil {
-		http.Error(w, wrapError(err), http.StatusBadRequest)
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
 		return
 	}
-
-	templateString := strings.ReplaceAll(string(template), "%recipient_name%", user.Name)
-	templateString = strings.ReplaceAll(templateString, "%domain_name%", s.mailDomain)
-	templateString = strings.ReplaceAll(templateString, "%code%", otp)
-	templateString = strings.ReplaceAll(templateString, "%email%", url.QueryEscape(user.Email))
-
-	message.SetHtml(templateString)
-
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
-	defer cancel()
-
-	// Send the message with a 10 second timeout
-	resp, id, err := mg.Send(ctx, message)
-	log.Debugf("Mailgun Response: %v, %v", resp, id)
-
-	if err != nil {
-		http.Error(w, wrapError(err), http.StatusBadRequest)
+	if err := s.queueEmail(user.Email, reset); err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
 		return
 	}
 }
@@ -2081,56 +2402,3 @@ func (s *FileHiveServer) handleGETConfirm(w http.ResponseWriter, r *http.Request
 	}
 
 }
-
-func (s *FileHiveServer) handleGETSearch(w http.ResponseWriter, r *http.Request) {
-	var (
-		page int
-		err  error
-	)
-	pageStr := r.URL.Query().Get("page")
-	if pageStr != "" {
-		page, err = strconv.Atoi(pageStr)
-		if err != nil {
-			http.Error(w, wrapError(ErrInvalidOption), http.StatusBadRequest)
-			return
-		}
-	}
-
-	searchTerm := r.URL.Query().Get("query")
-
-	var (
-		results []models.Dataset
-		count   int64
-	)
-
-	err = s.db.View(func(db *gorm.DB) error {
-
-		searchTerm = fmt.Sprintf("%%%s%%", searchTerm)
-
-		if err := db.Model(&models.Dataset{}).Where("(datasets.delisted = 0 and datasets.title LIKE ? OR datasets.short_description LIKE ? OR datasets.full_description LIKE ?) and users.Disabled = false", searchTerm, searchTerm, searchTerm).Joins("left join users on datasets.user_id=users.id").Count(&count).Error; err != nil {
-			return err
-		}
-		if err := db.Model(&models.Dataset{}).Where("(datasets.delisted = 0 and datasets.title LIKE ? OR datasets.short_description LIKE ? OR datasets.full_description LIKE ?) and users.Disabled = false", searchTerm, searchTerm, searchTerm).Joins("left join users on datasets.user_id=users.id").Scan(&results).Offset(page * 10).Limit(10).Error; err != nil {
-			return err
-		}
-		return nil
-	})
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		http.Error(w, wrapError(ErrDatasetNotFound), http.StatusInternalServerError)
-		return
-	}
-	if err != nil {
-		http.Error(w, wrapError(err), http.StatusInternalServerError)
-		return
-	}
-
-	sanitizedJSONResponse(w, struct {
-		Pages    int              `json:"pages"`
-		Page     int              `json:"page"`
-		Datasets []models.Dataset `json:"datasets"`
-	}{
-		Pages:    (int(count) / 10) + 1,
-		Page:     page,
-		Datasets: results[page*10:],
-	})
-}
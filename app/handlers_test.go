@@ -1,15 +1,23 @@
 package app
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/OB1Company/filehive/fil"
 	"github.com/OB1Company/filehive/repo"
 	"github.com/OB1Company/filehive/repo/models"
 	"github.com/filecoin-project/go-address"
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+	userPb "github.com/textileio/powergate/api/gen/powergate/user/v1"
 	"gorm.io/gorm"
 	"io"
+	"io/ioutil"
 	"math/big"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path"
 	"testing"
@@ -130,6 +138,14 @@ func Test_Handlers(t *testing.T) {
 				body:             []byte(`{"email": "brian2@ob1.io"}`),
 				expectedResponse: nil,
 			},
+			{
+				name:             "Patch user invalid language",
+				path:             "/api/v1/user",
+				method:           http.MethodPatch,
+				statusCode:       http.StatusBadRequest,
+				body:             []byte(`{"language": "klingon"}`),
+				expectedResponse: errorReturn(ErrInvalidLanguage),
+			},
 			{
 				name:       "Check user patched correctly",
 				path:       "/api/v1/user/brian2@ob1.io",
@@ -182,6 +198,7 @@ func Test_Handlers(t *testing.T) {
 				expectedResponse: mustMarshalAndSanitizeJSON(&models.Dataset{
 					ID:        "abc",
 					Username:  "Brian2",
+					Tags:      []string{},
 					UserID:    "1234",
 					Views:     1,
 					CreatedAt: time.Unix(0, 0),
@@ -383,9 +400,9 @@ func Test_Handlers(t *testing.T) {
 					return nil
 				},
 				expectedResponse: mustMarshalAndSanitizeJSON(struct {
-					Balance float64
+					Balance string
 				}{
-					Balance: 15.5,
+					Balance: "15.5",
 				}),
 			},
 			{
@@ -393,7 +410,7 @@ func Test_Handlers(t *testing.T) {
 				path:       "/api/v1/wallet/send",
 				method:     http.MethodPost,
 				statusCode: http.StatusOK,
-				body:       []byte(`{"address": "f1gyvikksfdmokwhg5jhcrkvfqkyd2sjdy46klgbq", "amount": 1}`),
+				body:       []byte(`{"address": "f1gyvikksfdmokwhg5jhcrkvfqkyd2sjdy46klgbq", "amount": "1"}`),
 				setup: func(db *repo.Database, wbe fil.WalletBackend) error {
 					wbe.(*fil.MockWalletBackend).SetNextTxid("bafkreif2mzhq6663465bcb2s3xgqefysbmr3a2bxloobw7s4vrxooj6kva")
 					return nil
@@ -418,9 +435,9 @@ func Test_Handlers(t *testing.T) {
 				method:     http.MethodGet,
 				statusCode: http.StatusOK,
 				expectedResponse: mustMarshalAndSanitizeJSON(struct {
-					Balance float64
+					Balance string
 				}{
-					Balance: 14.5,
+					Balance: "14.5",
 				}),
 			},
 			{
@@ -429,25 +446,37 @@ func Test_Handlers(t *testing.T) {
 				method:     http.MethodGet,
 				statusCode: http.StatusOK,
 				expectedResponse: mustMarshalAndSanitizeJSON([]struct {
-					To            string  `json:"to"`
-					From          string  `json:"from"`
-					TransactionID string  `json:"transactionID"`
-					Amount        float64 `json:"amount"`
-					Timestamp     string  `json:"timestamp"`
+					To            string `json:"to"`
+					From          string `json:"from"`
+					TransactionID string `json:"transactionID"`
+					Amount        string `json:"amount"`
+					Timestamp     string `json:"timestamp"`
+					Direction     string `json:"direction"`
+					Type          string `json:"type"`
+					PurchaseID    string `json:"purchaseID"`
+					Status        string `json:"status"`
+					Height        int64  `json:"height"`
+					ExitCode      int64  `json:"exitCode"`
 				}{
 					{
 						Timestamp:     "0001-01-01T00:00:00Z",
-						Amount:        15.5,
+						Amount:        "15.5",
 						To:            "f1cu3c2dqsbyt7nq63x2yubyy6ofuini2nfvnnahi",
 						From:          "",
 						TransactionID: "bafkreiewgqfti56ls5zt2kko2utajoliipl3te7cl5lvtiowgny6qb2pde",
+						Direction:     "in",
+						Type:          "deposit",
+						Status:        "pending",
 					},
 					{
 						Timestamp:     "0001-01-01T00:00:00Z",
-						Amount:        1,
+						Amount:        "1",
 						To:            "f1gyvikksfdmokwhg5jhcrkvfqkyd2sjdy46klgbq",
 						From:          "f1cu3c2dqsbyt7nq63x2yubyy6ofuini2nfvnnahi",
 						TransactionID: "bafkreif2mzhq6663465bcb2s3xgqefysbmr3a2bxloobw7s4vrxooj6kva",
+						Direction:     "out",
+						Type:          "withdrawal",
+						Status:        "pending",
 					},
 				}),
 			},
@@ -457,18 +486,27 @@ func Test_Handlers(t *testing.T) {
 				method:     http.MethodGet,
 				statusCode: http.StatusOK,
 				expectedResponse: mustMarshalAndSanitizeJSON([]struct {
-					To            string  `json:"to"`
-					From          string  `json:"from"`
-					TransactionID string  `json:"transactionID"`
-					Amount        float64 `json:"amount"`
-					Timestamp     string  `json:"timestamp"`
+					To            string `json:"to"`
+					From          string `json:"from"`
+					TransactionID string `json:"transactionID"`
+					Amount        string `json:"amount"`
+					Timestamp     string `json:"timestamp"`
+					Direction     string `json:"direction"`
+					Type          string `json:"type"`
+					PurchaseID    string `json:"purchaseID"`
+					Status        string `json:"status"`
+					Height        int64  `json:"height"`
+					ExitCode      int64  `json:"exitCode"`
 				}{
 					{
 						Timestamp:     "0001-01-01T00:00:00Z",
-						Amount:        15.5,
+						Amount:        "15.5",
 						To:            "f1cu3c2dqsbyt7nq63x2yubyy6ofuini2nfvnnahi",
 						From:          "",
 						TransactionID: "bafkreiewgqfti56ls5zt2kko2utajoliipl3te7cl5lvtiowgny6qb2pde",
+						Direction:     "in",
+						Type:          "deposit",
+						Status:        "pending",
 					},
 				}),
 			},
@@ -478,21 +516,121 @@ func Test_Handlers(t *testing.T) {
 				method:     http.MethodGet,
 				statusCode: http.StatusOK,
 				expectedResponse: mustMarshalAndSanitizeJSON([]struct {
-					To            string  `json:"to"`
-					From          string  `json:"from"`
-					TransactionID string  `json:"transactionID"`
-					Amount        float64 `json:"amount"`
-					Timestamp     string  `json:"timestamp"`
+					To            string `json:"to"`
+					From          string `json:"from"`
+					TransactionID string `json:"transactionID"`
+					Amount        string `json:"amount"`
+					Timestamp     string `json:"timestamp"`
+					Direction     string `json:"direction"`
+					Type          string `json:"type"`
+					PurchaseID    string `json:"purchaseID"`
+					Status        string `json:"status"`
+					Height        int64  `json:"height"`
+					ExitCode      int64  `json:"exitCode"`
 				}{
 					{
 						Timestamp:     "0001-01-01T00:00:00Z",
-						Amount:        1,
+						Amount:        "1",
 						To:            "f1gyvikksfdmokwhg5jhcrkvfqkyd2sjdy46klgbq",
 						From:          "f1cu3c2dqsbyt7nq63x2yubyy6ofuini2nfvnnahi",
 						TransactionID: "bafkreif2mzhq6663465bcb2s3xgqefysbmr3a2bxloobw7s4vrxooj6kva",
+						Direction:     "out",
+						Type:          "withdrawal",
+						Status:        "pending",
 					},
 				}),
 			},
+			{
+				name:       "Get wallet transactions by direction",
+				path:       "/api/v1/wallet/transactions?direction=out",
+				method:     http.MethodGet,
+				statusCode: http.StatusOK,
+				expectedResponse: mustMarshalAndSanitizeJSON([]struct {
+					To            string `json:"to"`
+					From          string `json:"from"`
+					TransactionID string `json:"transactionID"`
+					Amount        string `json:"amount"`
+					Timestamp     string `json:"timestamp"`
+					Direction     string `json:"direction"`
+					Type          string `json:"type"`
+					PurchaseID    string `json:"purchaseID"`
+					Status        string `json:"status"`
+					Height        int64  `json:"height"`
+					ExitCode      int64  `json:"exitCode"`
+				}{
+					{
+						Timestamp:     "0001-01-01T00:00:00Z",
+						Amount:        "1",
+						To:            "f1gyvikksfdmokwhg5jhcrkvfqkyd2sjdy46klgbq",
+						From:          "f1cu3c2dqsbyt7nq63x2yubyy6ofuini2nfvnnahi",
+						TransactionID: "bafkreif2mzhq6663465bcb2s3xgqefysbmr3a2bxloobw7s4vrxooj6kva",
+						Direction:     "out",
+						Type:          "withdrawal",
+						Status:        "pending",
+					},
+				}),
+			},
+			{
+				name:             "Get wallet transactions by date",
+				path:             "/api/v1/wallet/transactions?after=2020-01-01T00:00:00Z",
+				method:           http.MethodGet,
+				statusCode:       http.StatusOK,
+				expectedResponse: []byte("[]"),
+			},
+			{
+				name:             "Get wallet transactions invalid direction",
+				path:             "/api/v1/wallet/transactions?direction=sideways",
+				method:           http.MethodGet,
+				statusCode:       http.StatusBadRequest,
+				expectedResponse: errorReturn(ErrInvalidOption),
+			},
+			{
+				name:             "Get wallet transactions invalid date",
+				path:             "/api/v1/wallet/transactions?before=yesterday",
+				method:           http.MethodGet,
+				statusCode:       http.StatusBadRequest,
+				expectedResponse: errorReturn(ErrInvalidOption),
+			},
+			{
+				name:       "Get wallet transaction confirmed",
+				path:       "/api/v1/wallet/transactions/bafkreif2mzhq6663465bcb2s3xgqefysbmr3a2bxloobw7s4vrxooj6kva",
+				method:     http.MethodGet,
+				statusCode: http.StatusOK,
+				setup: func(db *repo.Database, wbe fil.WalletBackend) error {
+					wbe.(*fil.MockWalletBackend).GenerateBlock()
+					return nil
+				},
+				expectedResponse: mustMarshalAndSanitizeJSON(struct {
+					To            string `json:"to"`
+					From          string `json:"from"`
+					TransactionID string `json:"transactionID"`
+					Amount        string `json:"amount"`
+					Timestamp     string `json:"timestamp"`
+					Direction     string `json:"direction"`
+					Type          string `json:"type"`
+					PurchaseID    string `json:"purchaseID"`
+					Status        string `json:"status"`
+					Height        int64  `json:"height"`
+					ExitCode      int64  `json:"exitCode"`
+				}{
+					Timestamp:     "0001-01-01T00:00:00Z",
+					Amount:        "1",
+					To:            "f1gyvikksfdmokwhg5jhcrkvfqkyd2sjdy46klgbq",
+					From:          "f1cu3c2dqsbyt7nq63x2yubyy6ofuini2nfvnnahi",
+					TransactionID: "bafkreif2mzhq6663465bcb2s3xgqefysbmr3a2bxloobw7s4vrxooj6kva",
+					Direction:     "out",
+					Type:          "withdrawal",
+					Status:        "confirmed",
+					Height:        1,
+				}),
+			},
+			{
+				name:             "Get wallet transaction not found",
+				path:             "/api/v1/wallet/transactions/bafkreiewgqfti56ls5zt2kko2utajoliipl3te7cl5lvtiowgny6qb2pdx",
+				method:           http.MethodGet,
+				statusCode:       http.StatusNotFound,
+				expectedResponse: errorReturn(ErrTxNotFound),
+			},
 			{
 				name:             "Get wallet transactions invalid limit",
 				path:             "/api/v1/wallet/transactions?limit=zzz",
@@ -609,7 +747,7 @@ Snowden Files
 				},
 				expectedResponse: mustMarshalAndSanitizeJSON(models.Dataset{
 					JobID:            "bafkreibsth7fjp4n45bvrrcn7edtx6jz7b6ghasce4stxg3u4olhqsfb7y",
-					Price:            0,
+					Price:            fil.MustParseAmount("0"),
 					UserID:           "ABCD",
 					FileType:         ".txt",
 					Title:            "Changed title",
@@ -618,6 +756,8 @@ Snowden Files
 					ImageFilename:    "1AYAVn7Jq2UXcpMnHFqE4YMoLY1S2oUjyrkbPGHU88ndZg.jpg",
 					ID:               "1234",
 					Username:         "Brian",
+					Tags:             []string{},
+					Version:          1,
 					Views:            1,
 					FileSize:         14,
 					CreatedAt:        time.Unix(0, 0),
@@ -664,11 +804,13 @@ Snowden Files
 							ID:               "1234",
 							ImageFilename:    "1AYAVn7Jq2UXcpMnHFqE4YMoLY1S2oUjyrkbPGHU88ndZg.jpg",
 							JobID:            "bafkreibsth7fjp4n45bvrrcn7edtx6jz7b6ghasce4stxg3u4olhqsfb7y",
-							Price:            0,
+							Price:            fil.MustParseAmount("0"),
 							ShortDescription: "This is a short description",
 							Title:            "Changed title",
 							UserID:           "ABCD",
 							Username:         "Brian",
+							Tags:             []string{},
+							Version:          1,
 							Views:            1,
 							FileSize:         14,
 							CreatedAt:        time.Unix(0, 0),
@@ -794,9 +936,9 @@ Snowden Files
 				method:     http.MethodGet,
 				statusCode: http.StatusOK,
 				expectedResponse: mustMarshalAndSanitizeJSON(struct {
-					Balance float64
+					Balance string
 				}{
-					Balance: 1.234,
+					Balance: "1.234",
 				}),
 			},
 		})
@@ -927,11 +1069,13 @@ Snowden Files 2
 							ID:               "5678",
 							ImageFilename:    "1AYAVn7Jq2UXcpMnHFqE4YMoLY1S2oUjyrkbPGHU88ndZg.jpg",
 							JobID:            "bafkreibsth7fjp4n45bvrrcn7edtx6jz7b6ghasce4stxg3u4olhqsfb7y",
-							Price:            1.234,
+							Price:            fil.MustParseAmount("1.234"),
 							ShortDescription: "This is a short description",
 							Title:            "Snowden Leaks 2",
 							UserID:           "ABCD",
 							Username:         "Brian",
+							Tags:             []string{},
+							Version:          1,
 							Purchases:        0,
 							Views:            2,
 							CreatedAt:        time.Unix(0, 0),
@@ -943,11 +1087,13 @@ Snowden Files 2
 							ID:               "1234",
 							ImageFilename:    "1AYAVn7Jq2UXcpMnHFqE4YMoLY1S2oUjyrkbPGHU88ndZg.jpg",
 							JobID:            "bafkreibsth7fjp4n45bvrrcn7edtx6jz7b6ghasce4stxg3u4olhqsfb7y",
-							Price:            1.234,
+							Price:            fil.MustParseAmount("1.234"),
 							ShortDescription: "This is a short description",
 							Title:            "Snowden Leaks",
 							UserID:           "ABCD",
 							Username:         "Brian",
+							Tags:             []string{},
+							Version:          1,
 							Views:            1,
 							Purchases:        0,
 							CreatedAt:        time.Unix(0, 0),
@@ -1028,11 +1174,13 @@ Snowden Files 2
 							ID:               "5678",
 							ImageFilename:    "1AYAVn7Jq2UXcpMnHFqE4YMoLY1S2oUjyrkbPGHU88ndZg.jpg",
 							JobID:            "bafkreibsth7fjp4n45bvrrcn7edtx6jz7b6ghasce4stxg3u4olhqsfb7y",
-							Price:            1.234,
+							Price:            fil.MustParseAmount("1.234"),
 							ShortDescription: "This is a short description",
 							Title:            "Snowden Leaks 2",
 							UserID:           "ABCD",
 							Username:         "Brian",
+							Tags:             []string{},
+							Version:          1,
 							Purchases:        0,
 							Views:            2,
 						},
@@ -1042,11 +1190,13 @@ Snowden Files 2
 							ID:               "1234",
 							ImageFilename:    "1AYAVn7Jq2UXcpMnHFqE4YMoLY1S2oUjyrkbPGHU88ndZg.jpg",
 							JobID:            "bafkreibsth7fjp4n45bvrrcn7edtx6jz7b6ghasce4stxg3u4olhqsfb7y",
-							Price:            1.234,
+							Price:            fil.MustParseAmount("1.234"),
 							ShortDescription: "This is a short description",
 							Title:            "Snowden Leaks",
 							UserID:           "ABCD",
 							Username:         "Brian",
+							Tags:             []string{},
+							Version:          1,
 							Views:            1,
 							Purchases:        0,
 						},
@@ -1055,4 +1205,488 @@ Snowden Files 2
 			},
 		})
 	})
+
+	t.Run("Resumable Upload Tests", func(t *testing.T) {
+		runAPITests(t, apiTests{
+			{
+				name:             "Post user success",
+				path:             "/api/v1/user",
+				method:           http.MethodPost,
+				statusCode:       http.StatusOK,
+				body:             []byte(`{"email": "brian@ob1.io", "password":"letMeIn99", "name": "Brian", "country": "United_States"}`),
+				expectedResponse: nil,
+			},
+			{
+				name:             "Post upload invalid size",
+				path:             "/api/v1/upload",
+				method:           http.MethodPost,
+				statusCode:       http.StatusBadRequest,
+				body:             []byte(`{"filename": "snowden.txt", "size": 0}`),
+				expectedResponse: errorReturn(ErrInvalidOption),
+			},
+			{
+				name:             "Post upload success",
+				path:             "/api/v1/upload",
+				method:           http.MethodPost,
+				statusCode:       http.StatusOK,
+				body:             []byte(`{"filename": "snowden.txt", "size": 14, "sha256": "f5b3b7bfdf1bb2e5e6a9eb8ad13ecc31e3ed5b1dbd2d2ed0c0c2e56bd1d1bfd4"}`),
+				expectedResponse: nil,
+			},
+			{
+				name:       "Patch upload first chunk",
+				path:       "/api/v1/upload/abcd",
+				method:     http.MethodPatch,
+				statusCode: http.StatusOK,
+				headers:    map[string]string{"Upload-Offset": "0"},
+				setup: func(db *repo.Database, wbe fil.WalletBackend) error {
+					return db.Update(func(db *gorm.DB) error {
+						var upload models.Upload
+						if err := db.Where("filename = ?", "snowden.txt").First(&upload).Error; err != nil {
+							return err
+						}
+						if err := os.Rename(path.Join(testStaticDir, "uploads", upload.ID), path.Join(testStaticDir, "uploads", "abcd")); err != nil {
+							return err
+						}
+						return db.Model(&models.Upload{}).Where("id = ?", upload.ID).Update("id", "abcd").Error
+					})
+				},
+				body:             []byte("Snowden"),
+				expectedResponse: nil,
+			},
+			{
+				name:             "Patch upload wrong offset",
+				path:             "/api/v1/upload/abcd",
+				method:           http.MethodPatch,
+				statusCode:       http.StatusConflict,
+				headers:          map[string]string{"Upload-Offset": "0"},
+				body:             []byte("Snowden"),
+				expectedResponse: errorReturn(ErrUploadOffset),
+			},
+			{
+				name:             "Head upload",
+				path:             "/api/v1/upload/abcd",
+				method:           http.MethodHead,
+				statusCode:       http.StatusOK,
+				expectedResponse: nil,
+			},
+			{
+				name:             "Finalize incomplete upload",
+				path:             "/api/v1/upload/abcd/finalize",
+				method:           http.MethodPost,
+				statusCode:       http.StatusConflict,
+				body:             []byte(fmt.Sprintf(`{"title": "Snowden Leaks", "fileType": ".txt", "price": 1.234, "image": "%s"}`, jpgTestImage)),
+				expectedResponse: errorReturn(ErrUploadIncomplete),
+			},
+			{
+				name:             "Patch upload too large",
+				path:             "/api/v1/upload/abcd",
+				method:           http.MethodPatch,
+				statusCode:       http.StatusRequestEntityTooLarge,
+				headers:          map[string]string{"Upload-Offset": "7"},
+				body:             []byte(" Files\nand more"),
+				expectedResponse: errorReturn(ErrFileTooLarge),
+			},
+			{
+				name:             "Finalize upload checksum mismatch",
+				path:             "/api/v1/upload/abcd/finalize",
+				method:           http.MethodPost,
+				statusCode:       http.StatusBadRequest,
+				body:             []byte(fmt.Sprintf(`{"title": "Snowden Leaks", "fileType": ".txt", "price": 1.234, "image": "%s"}`, jpgTestImage)),
+				expectedResponse: errorReturn(ErrChecksumMismatch),
+			},
+			{
+				name:       "Finalize upload success",
+				path:       "/api/v1/upload/abcd/finalize",
+				method:     http.MethodPost,
+				statusCode: http.StatusOK,
+				setup: func(db *repo.Database, wbe fil.WalletBackend) error {
+					return db.Update(func(db *gorm.DB) error {
+						return db.Model(&models.Upload{}).Where("id = ?", "abcd").Update("sha256", "").Error
+					})
+				},
+				body:             []byte(fmt.Sprintf(`{"title": "Snowden Leaks", "fileType": ".txt", "price": 1.234, "image": "%s"}`, jpgTestImage)),
+				expectedResponse: nil,
+			},
+			{
+				name:             "Head finalized upload",
+				path:             "/api/v1/upload/abcd",
+				method:           http.MethodHead,
+				statusCode:       http.StatusNotFound,
+				expectedResponse: nil,
+			},
+			{
+				name:       "Get datasets",
+				path:       "/api/v1/datasets",
+				method:     http.MethodGet,
+				statusCode: http.StatusOK,
+				setup: func(db *repo.Database, wbe fil.WalletBackend) error {
+					var dataset models.Dataset
+					err := db.View(func(db *gorm.DB) error {
+						return db.Where("title = ?", "Snowden Leaks").First(&dataset).Error
+					})
+					if err != nil {
+						return err
+					}
+					if dataset.FileSize != 14 || dataset.DatasetFilename != "snowden.txt" {
+						return fmt.Errorf("unexpected dataset %d %s", dataset.FileSize, dataset.DatasetFilename)
+					}
+					return nil
+				},
+				expectedResponse: nil,
+			},
+		})
+	})
+
+	t.Run("Storage Job Tests", func(t *testing.T) {
+		runAPITests(t, apiTests{
+			{
+				name:             "Post user success",
+				path:             "/api/v1/user",
+				method:           http.MethodPost,
+				statusCode:       http.StatusOK,
+				body:             []byte(`{"email": "brian@ob1.io", "password":"letMeIn99", "name": "Brian", "country": "United_States"}`),
+				expectedResponse: nil,
+			},
+			{
+				name:        "Post dataset success",
+				path:        "/api/v1/dataset",
+				method:      http.MethodPost,
+				statusCode:  http.StatusOK,
+				contentType: "multipart/form-data; boundary=cc0ce5746707c1948657e8d0a2ca5570c2ddfd90ae6b7d5b49eac967c527",
+				body: []byte(`--cc0ce5746707c1948657e8d0a2ca5570c2ddfd90ae6b7d5b49eac967c527
+Content-Disposition: form-data; name="metadata"
+Content-Type: application/json
+
+{"title":"Snowden Leaks", "shortDescription": "This is a short description", "fullDescription": "This is a long description", "fileType": ".txt", "price": 1.234, "image": "/9j/4AAQSkZJRgABAQAAAQABAAD//gA7Q1JFQVRPUjogZ2QtanBlZyB2MS4wICh1c2luZyBJSkcgSlBFRyB2NjIpLCBxdWFsaXR5ID0gNjUK/9sAQwALCAgKCAcLCgkKDQwLDREcEhEPDxEiGRoUHCkkKyooJCcnLTJANy0wPTAnJzhMOT1DRUhJSCs2T1VORlRAR0hF/9sAQwEMDQ0RDxEhEhIhRS4nLkVFRUVFRUVFRUVFRUVFRUVFRUVFRUVFRUVFRUVFRUVFRUVFRUVFRUVFRUVFRUVFRUVF/8AAEQgAMgAyAwEiAAIRAQMRAf/EAB8AAAEFAQEBAQEBAAAAAAAAAAABAgMEBQYHCAkKC//EALUQAAIBAwMCBAMFBQQEAAABfQECAwAEEQUSITFBBhNRYQcicRQygZGhCCNCscEVUtHwJDNicoIJChYXGBkaJSYnKCkqNDU2Nzg5OkNERUZHSElKU1RVVldYWVpjZGVmZ2hpanN0dXZ3eHl6g4SFhoeIiYqSk5SVlpeYmZqio6Slpqeoqaqys7S1tre4ubrCw8TFxsfIycrS09TV1tfY2drh4uPk5ebn6Onq8fLz9PX29/j5+v/EAB8BAAMBAQEBAQEBAQEAAAAAAAABAgMEBQYHCAkKC//EALURAAIBAgQEAwQHBQQEAAECdwABAgMRBAUhMQYSQVEHYXETIjKBCBRCkaGxwQkjM1LwFWJy0QoWJDThJfEXGBkaJicoKSo1Njc4OTpDREVGR0hJSlNUVVZXWFlaY2RlZmdoaWpzdHV2d3h5eoKDhIWGh4iJipKTlJWWl5iZmqKjpKWmp6ipqrKztLW2t7i5usLDxMXGx8jJytLT1NXW19jZ2uLj5OXm5+jp6vLz9PX29/j5+v/aAAwDAQACEQMRAD8A840awhv5zFKWDYyMHrVvWtE/szynj3GJ+MnsaoWFw1ndxTr1Rskeor0+70uPXNBYQ4JkQSRH36iiXw3CO9meWxxNJIqICWY4AHeu5g8C232aMztL5pUFtpGM/lUXgPw+13qD3lwhEdscAEdX/wDrVseNddl0l4bSxcLcN8zHAOB6c1UnyJLqxRTlJ9kY83guzQcNN/30P8KwNY0W206AvufceFBPWvRtMtrw6RHLqUm+dxvOVA2j04rzjxJqAv8AUXEZ/cxHavv71M20+UcbNc3Q5/bRUu2igCVRXpfw51MXFtJp0rfPD88ee6nr+R/nXmq13fw40xpL6TUXyEiGxfcnrVwV7kSdrHo7C10ixnn2rFEu6R8cZPU15r4espfFviua/uQTbxvvbPT/AGVrX+IetMyQ6PakmSUhpAv6Cuh0DT4PC/hsGbCsE82ZvfHSs4O160umiLmtFTW73/rzMjx7rC6Zp32WFgJ7gY4/hXua8nbmtTXtWk1rVZruQnDHCL/dXtWW1TBPd7suVl7q6EeKKKKsgkt42nlSNBlmIAr1rTZINA0MAkBYU3MfU15joEsEN5588irs+6GPetfX9cW8iis7eVSjHLsDxRJ+7yx3YRV5XeyNjwlbPrviCbWL0bkjbcoPQt2H4Vf+IOsTyxpplrHIyt80rKpwfQU3R9W0vS9PitkvIBtHzHeOT3q+3ibTiP8Aj9g/77FE+R2itkEXK7m92eXm2uB1gk/74NRPDKoOY3H1U16RceI7FgcXkJ/4GKwdT1q2lgkVJ0YlSOGoco20CzONzRUe6igBq1KtFFAD6KKKAGmo26UUUAR0UUUAf//Z"}
+--cc0ce5746707c1948657e8d0a2ca5570c2ddfd90ae6b7d5b49eac967c527
+Content-Disposition: form-data; name="file"; filename="snowden.txt"
+Content-Type: application/octet-stream
+
+Snowden Files
+
+--cc0ce5746707c1948657e8d0a2ca5570c2ddfd90ae6b7d5b49eac967c527--`),
+				expectedResponse: nil,
+			},
+			{
+				name:       "Get jobs success",
+				path:       "/api/v1/jobs",
+				method:     http.MethodGet,
+				statusCode: http.StatusOK,
+				setup: func(db *repo.Database, wbe fil.WalletBackend) error {
+					return db.Update(func(db *gorm.DB) error {
+						var dataset models.Dataset
+						if err := db.Where("title = ?", "Snowden Leaks").First(&dataset).Error; err != nil {
+							return err
+						}
+						if err := db.Model(&models.StorageJob{}).Where("dataset_id = ?", dataset.ID).Updates(map[string]interface{}{"id": "job1", "dataset_id": "1234"}).Error; err != nil {
+							return err
+						}
+						if err := db.Model(&models.StorageJobEvent{}).Where("job_id = ?", dataset.JobID).Update("job_id", "job1").Error; err != nil {
+							return err
+						}
+						return db.Model(&models.Dataset{}).Where("id = ?", dataset.ID).Updates(map[string]interface{}{"id": "1234", "job_id": "job1"}).Error
+					})
+				},
+				expectedResponse: nil,
+			},
+			{
+				name:             "Get jobs invalid page",
+				path:             "/api/v1/jobs?page=abc",
+				method:           http.MethodGet,
+				statusCode:       http.StatusBadRequest,
+				expectedResponse: errorReturn(ErrInvalidOption),
+			},
+			{
+				name:             "Get dataset jobs success",
+				path:             "/api/v1/dataset/1234/jobs",
+				method:           http.MethodGet,
+				statusCode:       http.StatusOK,
+				expectedResponse: nil,
+			},
+			{
+				name:             "Get dataset jobs not found",
+				path:             "/api/v1/dataset/5678/jobs",
+				method:           http.MethodGet,
+				statusCode:       http.StatusNotFound,
+				expectedResponse: errorReturn(ErrDatasetNotFound),
+			},
+			{
+				name:             "Get admin jobs not admin",
+				path:             "/api/v1/admin/jobs",
+				method:           http.MethodGet,
+				statusCode:       http.StatusUnauthorized,
+				expectedResponse: errorReturn(ErrInvalidCredentials),
+			},
+			{
+				name:       "Get admin jobs success",
+				path:       "/api/v1/admin/jobs?flagged=true",
+				method:     http.MethodGet,
+				statusCode: http.StatusOK,
+				setup: func(db *repo.Database, wbe fil.WalletBackend) error {
+					return db.Update(func(db *gorm.DB) error {
+						return db.Model(&models.User{}).Where("email = ?", "brian@ob1.io").Update("admin", true).Error
+					})
+				},
+				expectedResponse: nil,
+			},
+			{
+				name:             "Post admin job retry not retryable",
+				path:             "/api/v1/admin/jobs/job1/retry",
+				method:           http.MethodPost,
+				statusCode:       http.StatusBadRequest,
+				expectedResponse: errorReturn(ErrJobNotRetryable),
+			},
+			{
+				name:             "Post admin job retry not found",
+				path:             "/api/v1/admin/jobs/job2/retry",
+				method:           http.MethodPost,
+				statusCode:       http.StatusNotFound,
+				expectedResponse: errorReturn(ErrJobNotFound),
+			},
+			{
+				name:       "Post admin job retry success",
+				path:       "/api/v1/admin/jobs/job1/retry",
+				method:     http.MethodPost,
+				statusCode: http.StatusOK,
+				setup: func(db *repo.Database, wbe fil.WalletBackend) error {
+					return db.Update(func(db *gorm.DB) error {
+						return db.Model(&models.StorageJob{}).Where("id = ?", "job1").Updates(map[string]interface{}{"status": jobStatusFailed, "flagged": true}).Error
+					})
+				},
+				expectedResponse: nil,
+			},
+			{
+				name:             "Post admin job retry already retried",
+				path:             "/api/v1/admin/jobs/job1/retry",
+				method:           http.MethodPost,
+				statusCode:       http.StatusBadRequest,
+				expectedResponse: errorReturn(ErrJobNotRetryable),
+			},
+		})
+	})
+	t.Run("Entitlement Tests", func(t *testing.T) {
+		// Downloads are verified against the content ID, so test files are
+		// stored under their raw CID like the mock backend does.
+		rawCID := func(content []byte) string {
+			mh, err := multihash.Sum(content, multihash.SHA2_256, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return cid.NewCidV1(cid.Raw, mh).String()
+		}
+		snowdenCID := rawCID([]byte("Snowden Files\n"))
+		freeContent := []byte("Free Files")
+		freeCID := rawCID(freeContent)
+
+		// Moves the dataset posted by Brian to a known ID so the download
+		// path can be built, and adds a free dataset.
+		setupDatasets := func(db *repo.Database, wbe fil.WalletBackend) error {
+			return db.Update(func(db *gorm.DB) error {
+				var dataset models.Dataset
+				if err := db.Where("title = ?", "Snowden Leaks").First(&dataset).Error; err != nil {
+					return err
+				}
+				if err := ioutil.WriteFile(path.Join(testStaticDir, "files", freeCID), freeContent, os.ModePerm); err != nil {
+					return err
+				}
+				if err := db.Model(&models.DatasetVersion{}).Where("dataset_id = ?", dataset.ID).Update("dataset_id", "1234").Error; err != nil {
+					return err
+				}
+				if err := db.Model(&models.Dataset{}).Where("id = ?", dataset.ID).Update("id", "1234").Error; err != nil {
+					return err
+				}
+				return db.Create(&models.Dataset{ID: "free1", UserID: dataset.UserID, ContentID: freeCID, JobID: dataset.JobID, Version: 1}).Error
+			})
+		}
+		setPurchase := func(status string) func(db *repo.Database, wbe fil.WalletBackend) error {
+			return func(db *repo.Database, wbe fil.WalletBackend) error {
+				return db.Update(func(db *gorm.DB) error {
+					var user models.User
+					if err := db.Where("email = ?", "alice@ob1.io").First(&user).Error; err != nil {
+						return err
+					}
+					return db.Save(&models.Purchase{ID: "purchase1", UserID: user.ID, DatasetID: "1234", Version: 1, State: purchaseStateNotified, Status: status}).Error
+				})
+			}
+		}
+
+		runAPITests(t, apiTests{
+			{
+				name:             "Post user success",
+				path:             "/api/v1/user",
+				method:           http.MethodPost,
+				statusCode:       http.StatusOK,
+				body:             []byte(`{"email": "brian@ob1.io", "password":"letMeIn99", "name": "Brian", "country": "United_States"}`),
+				expectedResponse: nil,
+			},
+			{
+				name:        "Post dataset success",
+				path:        "/api/v1/dataset",
+				method:      http.MethodPost,
+				statusCode:  http.StatusOK,
+				contentType: "multipart/form-data; boundary=cc0ce5746707c1948657e8d0a2ca5570c2ddfd90ae6b7d5b49eac967c527",
+				body: []byte(`--cc0ce5746707c1948657e8d0a2ca5570c2ddfd90ae6b7d5b49eac967c527
+Content-Disposition: form-data; name="metadata"
+Content-Type: application/json
+
+{"title":"Snowden Leaks", "shortDescription": "This is a short description", "fullDescription": "This is a long description", "fileType": ".txt", "price": 1.234, "image": "/9j/4AAQSkZJRgABAQAAAQABAAD//gA7Q1JFQVRPUjogZ2QtanBlZyB2MS4wICh1c2luZyBJSkcgSlBFRyB2NjIpLCBxdWFsaXR5ID0gNjUK/9sAQwALCAgKCAcLCgkKDQwLDREcEhEPDxEiGRoUHCkkKyooJCcnLTJANy0wPTAnJzhMOT1DRUhJSCs2T1VORlRAR0hF/9sAQwEMDQ0RDxEhEhIhRS4nLkVFRUVFRUVFRUVFRUVFRUVFRUVFRUVFRUVFRUVFRUVFRUVFRUVFRUVFRUVFRUVFRUVF/8AAEQgAMgAyAwEiAAIRAQMRAf/EAB8AAAEFAQEBAQEBAAAAAAAAAAABAgMEBQYHCAkKC//EALUQAAIBAwMCBAMFBQQEAAABfQECAwAEEQUSITFBBhNRYQcicRQygZGhCCNCscEVUtHwJDNicoIJChYXGBkaJSYnKCkqNDU2Nzg5OkNERUZHSElKU1RVVldYWVpjZGVmZ2hpanN0dXZ3eHl6g4SFhoeIiYqSk5SVlpeYmZqio6Slpqeoqaqys7S1tre4ubrCw8TFxsfIycrS09TV1tfY2drh4uPk5ebn6Onq8fLz9PX29/j5+v/EAB8BAAMBAQEBAQEBAQEAAAAAAAABAgMEBQYHCAkKC//EALURAAIBAgQEAwQHBQQEAAECdwABAgMRBAUhMQYSQVEHYXETIjKBCBRCkaGxwQkjM1LwFWJy0QoWJDThJfEXGBkaJicoKSo1Njc4OTpDREVGR0hJSlNUVVZXWFlaY2RlZmdoaWpzdHV2d3h5eoKDhIWGh4iJipKTlJWWl5iZmqKjpKWmp6ipqrKztLW2t7i5usLDxMXGx8jJytLT1NXW19jZ2uLj5OXm5+jp6vLz9PX29/j5+v/aAAwDAQACEQMRAD8A840awhv5zFKWDYyMHrVvWtE/szynj3GJ+MnsaoWFw1ndxTr1Rskeor0+70uPXNBYQ4JkQSRH36iiXw3CO9meWxxNJIqICWY4AHeu5g8C232aMztL5pUFtpGM/lUXgPw+13qD3lwhEdscAEdX/wDrVseNddl0l4bSxcLcN8zHAOB6c1UnyJLqxRTlJ9kY83guzQcNN/30P8KwNY0W206AvufceFBPWvRtMtrw6RHLqUm+dxvOVA2j04rzjxJqAv8AUXEZ/cxHavv71M20+UcbNc3Q5/bRUu2igCVRXpfw51MXFtJp0rfPD88ee6nr+R/nXmq13fw40xpL6TUXyEiGxfcnrVwV7kSdrHo7C10ixnn2rFEu6R8cZPU15r4espfFviua/uQTbxvvbPT/AGVrX+IetMyQ6PakmSUhpAv6Cuh0DT4PC/hsGbCsE82ZvfHSs4O160umiLmtFTW73/rzMjx7rC6Zp32WFgJ7gY4/hXua8nbmtTXtWk1rVZruQnDHCL/dXtWW1TBPd7suVl7q6EeKKKKsgkt42nlSNBlmIAr1rTZINA0MAkBYU3MfU15joEsEN5588irs+6GPetfX9cW8iis7eVSjHLsDxRJ+7yx3YRV5XeyNjwlbPrviCbWL0bkjbcoPQt2H4Vf+IOsTyxpplrHIyt80rKpwfQU3R9W0vS9PitkvIBtHzHeOT3q+3ibTiP8Aj9g/77FE+R2itkEXK7m92eXm2uB1gk/74NRPDKoOY3H1U16RceI7FgcXkJ/4GKwdT1q2lgkVJ0YlSOGoco20CzONzRUe6igBq1KtFFAD6KKKAGmo26UUUAR0UUUAf//Z"}
+--cc0ce5746707c1948657e8d0a2ca5570c2ddfd90ae6b7d5b49eac967c527
+Content-Disposition: form-data; name="file"; filename="snowden.txt"
+Content-Type: application/octet-stream
+
+Snowden Files
+
+--cc0ce5746707c1948657e8d0a2ca5570c2ddfd90ae6b7d5b49eac967c527--`),
+				expectedResponse: nil,
+			},
+			{
+				name:             "Download own dataset",
+				path:             "/api/v1/download/1234",
+				method:           http.MethodGet,
+				statusCode:       http.StatusOK,
+				setup:            setupDatasets,
+				expectedResponse: []byte("Snowden Files\n"),
+			},
+			{
+				name:             "Get deal status of own dataset",
+				path:             "/api/v1/datasetdeal/" + snowdenCID,
+				method:           http.MethodGet,
+				statusCode:       http.StatusOK,
+				expectedResponse: nil,
+			},
+			{
+				name:             "Post second user success",
+				path:             "/api/v1/user",
+				method:           http.MethodPost,
+				statusCode:       http.StatusOK,
+				body:             []byte(`{"email": "alice@ob1.io", "password":"letMeIn99", "name": "Alice", "country": "United_States"}`),
+				expectedResponse: nil,
+			},
+			{
+				name:             "Download dataset not purchased",
+				path:             "/api/v1/download/1234",
+				method:           http.MethodGet,
+				statusCode:       http.StatusUnauthorized,
+				expectedResponse: errorReturn(ErrNotEntitled),
+			},
+			{
+				name:             "Get deal status not purchased",
+				path:             "/api/v1/datasetdeal/" + snowdenCID,
+				method:           http.MethodGet,
+				statusCode:       http.StatusUnauthorized,
+				expectedResponse: errorReturn(ErrNotEntitled),
+			},
+			{
+				name:             "Download free dataset",
+				path:             "/api/v1/download/free1",
+				method:           http.MethodGet,
+				statusCode:       http.StatusOK,
+				expectedResponse: []byte("Free Files"),
+			},
+			{
+				name:             "Download purchased dataset",
+				path:             "/api/v1/download/1234",
+				method:           http.MethodGet,
+				statusCode:       http.StatusOK,
+				setup:            setPurchase(purchaseStatusComplete),
+				expectedResponse: []byte("Snowden Files\n"),
+			},
+			{
+				name:             "Get deal status purchased",
+				path:             "/api/v1/datasetdeal/" + snowdenCID,
+				method:           http.MethodGet,
+				statusCode:       http.StatusOK,
+				expectedResponse: nil,
+			},
+			{
+				name:             "Download refunded dataset",
+				path:             "/api/v1/download/1234",
+				method:           http.MethodGet,
+				statusCode:       http.StatusUnauthorized,
+				setup:            setPurchase(purchaseStatusRefunded),
+				expectedResponse: errorReturn(ErrNotEntitled),
+			},
+			{
+				name:       "Download dataset as admin",
+				path:       "/api/v1/download/1234",
+				method:     http.MethodGet,
+				statusCode: http.StatusOK,
+				setup: func(db *repo.Database, wbe fil.WalletBackend) error {
+					return db.Update(func(db *gorm.DB) error {
+						return db.Model(&models.User{}).Where("email = ?", "alice@ob1.io").Update("admin", true).Error
+					})
+				},
+				expectedResponse: []byte("Snowden Files\n"),
+			},
+		})
+	})
+}
+
+// tokenFilecoinBackend is a Filecoin backend that only reports a job to
+// the user it was stored for, like Powergate.
+type tokenFilecoinBackend struct {
+	*fil.MockFilecoinBackend
+	token string
+}
+
+func (b tokenFilecoinBackend) JobStatus(jobID string, userToken string) (*userPb.StorageJob, error) {
+	if userToken != b.token {
+		return nil, errors.New("job not found")
+	}
+	return b.MockFilecoinBackend.JobStatus(jobID, userToken)
+}
+
+func Test_DatasetDealStatus(t *testing.T) {
+	db, err := repo.NewDatabase("", repo.Dialect("memory"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filesDir := path.Join(testStaticDir, "files")
+	defer os.RemoveAll(testStaticDir)
+
+	mock, err := fil.NewMockFilecoinBackend(filesDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.SetJobStatus("job1", userPb.JobStatus_JOB_STATUS_SUCCESS)
+
+	server := &FileHiveServer{
+		db:              db,
+		filecoinBackend: tokenFilecoinBackend{mock, "sellertoken"},
+		walletBackend:   fil.NewMockWalletBackend(),
+		staticFileDir:   testStaticDir,
+	}
+
+	err = db.Update(func(db *gorm.DB) error {
+		if err := db.Save(&models.User{ID: "seller", Email: "seller@ob1.io", PowergateToken: "sellertoken"}).Error; err != nil {
+			return err
+		}
+		if err := db.Save(&models.User{ID: "buyer", Email: "buyer@ob1.io", PowergateToken: "buyertoken"}).Error; err != nil {
+			return err
+		}
+		if err := db.Save(&models.Dataset{ID: "dataset1", UserID: "seller", Version: 1, ContentID: "cid1", JobID: "job1", Price: fil.MustParseAmount("1")}).Error; err != nil {
+			return err
+		}
+		return db.Save(&models.Purchase{ID: "purchase1", UserID: "buyer", DatasetID: "dataset1", Version: 1, State: purchaseStateNotified, Status: purchaseStatusComplete}).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A buyer sees the status of the seller's storage job.
+	for _, email := range []string{"seller@ob1.io", "buyer@ob1.io"} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/datasetdeal/cid1", nil)
+		server.handleGETDatasetDeal(w, r.WithContext(context.WithValue(r.Context(), "email", email)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status code %d, got %d: %s", email, http.StatusOK, w.Code, w.Body.String())
+		}
+		var job struct {
+			ID string `json:"id"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&job); err != nil || job.ID != "job1" {
+			t.Errorf("%s: expected the status of job1, got %s, %v", email, job.ID, err)
+		}
+	}
 }
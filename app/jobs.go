@@ -0,0 +1,535 @@
+package app
+
+import (
+	"errors"
+	"github.com/OB1Company/filehive/repo/models"
+	userPb "github.com/textileio/powergate/api/gen/powergate/user/v1"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultJobPollInterval = time.Minute
+	defaultMaxJobRetries   = 3
+)
+
+// Storage job statuses as persisted in the StorageJob and StorageJobEvent
+// models. They mirror the Powergate job statuses.
+const (
+	jobStatusUnspecified = "unspecified"
+	jobStatusQueued      = "queued"
+	jobStatusExecuting   = "executing"
+	jobStatusFailed      = "failed"
+	jobStatusCanceled    = "canceled"
+	jobStatusSuccess     = "success"
+)
+
+var storageJobStatuses = map[userPb.JobStatus]string{
+	userPb.JobStatus_JOB_STATUS_UNSPECIFIED: jobStatusUnspecified,
+	userPb.JobStatus_JOB_STATUS_QUEUED:      jobStatusQueued,
+	userPb.JobStatus_JOB_STATUS_EXECUTING:   jobStatusExecuting,
+	userPb.JobStatus_JOB_STATUS_FAILED:      jobStatusFailed,
+	userPb.JobStatus_JOB_STATUS_CANCELED:    jobStatusCanceled,
+	userPb.JobStatus_JOB_STATUS_SUCCESS:     jobStatusSuccess,
+}
+
+// storageJobDetails is a storage job along with its status history and
+// the deals it has made.
+type storageJobDetails struct {
+	models.StorageJob
+	Events []models.StorageJobEvent `json:"events"`
+	Deals  []models.StorageDeal     `json:"deals"`
+}
+
+// newStorageJob returns the initial tracking record for the storage job
+// of a newly stored dataset.
+func newStorageJob(dataset models.Dataset) models.StorageJob {
+	return models.StorageJob{
+		ID:        dataset.JobID,
+		DatasetID: dataset.ID,
+		UserID:    dataset.UserID,
+		ContentID: dataset.ContentID,
+		Status:    jobStatusQueued,
+	}
+}
+
+// saveStorageJob persists a new storage job along with its initial event.
+func saveStorageJob(db *gorm.DB, job models.StorageJob) error {
+	if err := db.Save(&job).Error; err != nil {
+		return err
+	}
+	return db.Save(&models.StorageJobEvent{
+		JobID:     job.ID,
+		Status:    job.Status,
+		Timestamp: time.Now(),
+	}).Error
+}
+
+// trackStorageJobs polls the Filecoin backend for the status of every
+// unfinished storage job until the server is shut down.
+func (s *FileHiveServer) trackStorageJobs() {
+	ticker := time.NewTicker(s.jobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.pollStorageJobs(); err != nil {
+			log.Errorf("Error polling storage jobs: %s", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-s.shutdown:
+			return
+		}
+	}
+}
+
+// pollStorageJobs makes a single pass over the unfinished storage jobs. Jobs
+// that are still queued or executing are checked against the backend and
+// failed jobs are retried until they run out of attempts, at which point
+// they are flagged for an admin to look at.
+func (s *FileHiveServer) pollStorageJobs() error {
+	if err := s.backfillStorageJobs(); err != nil {
+		return err
+	}
+
+	var jobs []models.StorageJob
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("status IN ? OR (status = ? AND flagged = ? AND retry_job_id = ?)",
+			[]string{jobStatusUnspecified, jobStatusQueued, jobStatusExecuting}, jobStatusFailed, false, "").
+			Order("created_at").Find(&jobs).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		if err := s.checkStorageJob(job); err != nil {
+			log.Errorf("Error checking storage job %s: %s", job.ID, err)
+		}
+	}
+	return nil
+}
+
+// backfillStorageJobs creates tracking records for datasets that were
+// stored before jobs were tracked.
+func (s *FileHiveServer) backfillStorageJobs() error {
+	return s.db.Update(func(db *gorm.DB) error {
+		var datasets []models.Dataset
+		err := db.Where("job_id <> ? AND job_id NOT IN (?)", "", db.Model(&models.StorageJob{}).Select("id")).
+			Find(&datasets).Error
+		if err != nil {
+			return err
+		}
+		for _, dataset := range datasets {
+			if err := saveStorageJob(db, newStorageJob(dataset)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *FileHiveServer) checkStorageJob(job models.StorageJob) error {
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("id = ?", job.UserID).First(&user).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if job.Status != jobStatusFailed {
+		status, err := s.filecoinBackend.JobStatus(job.ID, user.PowergateToken)
+		if err != nil {
+			return err
+		}
+		if status == nil {
+			return nil
+		}
+		job, err = s.updateStorageJob(job, status)
+		if err != nil {
+			return err
+		}
+		if job.Status != jobStatusFailed {
+			return nil
+		}
+	}
+
+	if job.Attempt >= s.maxJobRetries {
+		log.Warningf("Storage job %s for dataset %s failed after %d attempts: %s", job.ID, job.DatasetID, job.Attempt+1, job.ErrorCause)
+		return s.db.Update(func(db *gorm.DB) error {
+			return db.Model(&job).Update("flagged", true).Error
+		})
+	}
+
+	_, err = s.retryStorageJob(job, user)
+	return err
+}
+
+// updateStorageJob records the status and deals reported by the backend,
+// adding an event if the status has changed.
+func (s *FileHiveServer) updateStorageJob(job models.StorageJob, status *userPb.StorageJob) (models.StorageJob, error) {
+	newStatus, ok := storageJobStatuses[status.Status]
+	if !ok {
+		newStatus = jobStatusUnspecified
+	}
+	completed := newStatus == jobStatusSuccess && job.Status != jobStatusSuccess
+
+	err := s.db.Update(func(db *gorm.DB) error {
+		now := time.Now()
+		changed := newStatus != job.Status
+		if newStatus != job.Status || status.ErrorCause != job.ErrorCause {
+			err := db.Save(&models.StorageJobEvent{
+				JobID:      job.ID,
+				Status:     newStatus,
+				ErrorCause: status.ErrorCause,
+				Timestamp:  now,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, info := range status.DealInfo {
+			if info.ProposalCid == "" {
+				continue
+			}
+			var deal models.StorageDeal
+			if err := db.Where("id = ?", info.ProposalCid).First(&deal).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			if deal.ID == "" || deal.StateName != info.StateName || deal.DealID != info.DealId {
+				changed = true
+			}
+			deal.ID = info.ProposalCid
+			deal.JobID = job.ID
+			deal.Miner = info.Miner
+			deal.StateName = info.StateName
+			deal.PieceCid = info.PieceCid
+			deal.Size = info.Size
+			deal.PricePerEpoch = info.PricePerEpoch
+			deal.StartEpoch = info.StartEpoch
+			deal.Duration = info.Duration
+			deal.DealID = info.DealId
+			deal.ActivationEpoch = info.ActivationEpoch
+			deal.Message = info.Message
+			if err := db.Save(&deal).Error; err != nil {
+				return err
+			}
+		}
+
+		job.Status = newStatus
+		job.ErrorCause = status.ErrorCause
+		job.CheckedAt = now
+		if err := db.Save(&job).Error; err != nil {
+			return err
+		}
+
+		if !changed {
+			return nil
+		}
+		details, err := loadStorageJobDetails(db, []models.StorageJob{job})
+		if err != nil {
+			return err
+		}
+		return queueWebhookEvent(db, job.UserID, webhookEventDatasetDealUpdated, details[0])
+	})
+	if err != nil {
+		return job, err
+	}
+	s.wakeWebhooks()
+
+	if completed {
+		if err := s.notifyJobCompleted(job); err != nil {
+			log.Errorf("Error notifying completion of storage job %s: %s", job.ID, err)
+		}
+	}
+	return job, nil
+}
+
+// retryStorageJob starts a new storage job for the content of a failed job.
+// The new job replaces the failed one as the job of its dataset version,
+// and as the dataset's job if the version is still the latest.
+func (s *FileHiveServer) retryStorageJob(job models.StorageJob, user models.User) (models.StorageJob, error) {
+	jobID, err := s.filecoinBackend.Retry(job.ContentID, user.PowergateToken)
+	if err != nil {
+		return models.StorageJob{}, err
+	}
+
+	newJob := models.StorageJob{
+		ID:        jobID,
+		DatasetID: job.DatasetID,
+		UserID:    job.UserID,
+		ContentID: job.ContentID,
+		Status:    jobStatusQueued,
+		Attempt:   job.Attempt + 1,
+	}
+	err = s.db.Update(func(db *gorm.DB) error {
+		if err := saveStorageJob(db, newJob); err != nil {
+			return err
+		}
+		if err := db.Model(&job).Update("retry_job_id", jobID).Error; err != nil {
+			return err
+		}
+		if err := db.Model(&models.DatasetVersion{}).Where("dataset_id = ? AND job_id = ?", job.DatasetID, job.ID).Update("job_id", jobID).Error; err != nil {
+			return err
+		}
+		return db.Model(&models.Dataset{}).Where("id = ? AND job_id = ?", job.DatasetID, job.ID).Update("job_id", jobID).Error
+	})
+	if err != nil {
+		return models.StorageJob{}, err
+	}
+	log.Infof("Retrying storage job %s for dataset %s as job %s", job.ID, job.DatasetID, jobID)
+	return newJob, nil
+}
+
+// loadStorageJobDetails attaches the events and deals to each job.
+func loadStorageJobDetails(db *gorm.DB, jobs []models.StorageJob) ([]storageJobDetails, error) {
+	details := make([]storageJobDetails, 0, len(jobs))
+	for _, job := range jobs {
+		d := storageJobDetails{
+			StorageJob: job,
+			Events:     []models.StorageJobEvent{},
+			Deals:      []models.StorageDeal{},
+		}
+		if err := db.Where("job_id = ?", job.ID).Order("timestamp").Find(&d.Events).Error; err != nil {
+			return nil, err
+		}
+		if err := db.Where("job_id = ?", job.ID).Find(&d.Deals).Error; err != nil {
+			return nil, err
+		}
+		details = append(details, d)
+	}
+	return details, nil
+}
+
+func (s *FileHiveServer) handleGETJobs(w http.ResponseWriter, r *http.Request) {
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var page int
+	pageStr := r.URL.Query().Get("page")
+	if pageStr != "" {
+		page, err = strconv.Atoi(pageStr)
+		if err != nil {
+			http.Error(w, wrapError(ErrInvalidOption), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var (
+		jobs  []storageJobDetails
+		count int64
+	)
+	err = s.db.View(func(db *gorm.DB) error {
+		query := db.Model(&models.StorageJob{}).Where("user_id = ?", user.ID)
+		if status := r.URL.Query().Get("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+		if err := query.Count(&count).Error; err != nil {
+			return err
+		}
+		var results []models.StorageJob
+		if err := query.Order("created_at DESC").Offset(page * 10).Limit(10).Find(&results).Error; err != nil {
+			return err
+		}
+		jobs, err = loadStorageJobDetails(db, results)
+		return err
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	sanitizedJSONResponse(w, struct {
+		Pages int                 `json:"pages"`
+		Page  int                 `json:"page"`
+		Jobs  []storageJobDetails `json:"jobs"`
+	}{
+		Pages: (int(count) / 10) + 1,
+		Page:  page,
+		Jobs:  jobs,
+	})
+}
+
+// handleGETDatasetJobs returns every storage job, including retries, that
+// has been run for a dataset. Only the seller or an admin may see them.
+func (s *FileHiveServer) handleGETDatasetJobs(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	id := sp[len(sp)-2]
+
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var dataset models.Dataset
+	err = s.db.View(func(db *gorm.DB) error {
+		return db.Where("id = ?", id).First(&dataset).Error
+	})
+	if err != nil || (dataset.UserID != user.ID && !user.Admin) {
+		http.Error(w, wrapError(ErrDatasetNotFound), http.StatusNotFound)
+		return
+	}
+
+	var jobs []storageJobDetails
+	err = s.db.View(func(db *gorm.DB) error {
+		var results []models.StorageJob
+		if err := db.Where("dataset_id = ?", dataset.ID).Order("attempt DESC").Find(&results).Error; err != nil {
+			return err
+		}
+		jobs, err = loadStorageJobDetails(db, results)
+		return err
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	sanitizedJSONResponse(w, struct {
+		Jobs []storageJobDetails `json:"jobs"`
+	}{
+		Jobs: jobs,
+	})
+}
+
+func (s *FileHiveServer) handleGETAdminJobs(w http.ResponseWriter, r *http.Request) {
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	if !user.Admin {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var jobs []storageJobDetails
+	err = s.db.View(func(db *gorm.DB) error {
+		query := db.Model(&models.StorageJob{})
+		if r.URL.Query().Get("flagged") == "true" {
+			query = query.Where("flagged = ? AND retry_job_id = ?", true, "")
+		}
+		if status := r.URL.Query().Get("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+		var results []models.StorageJob
+		if err := query.Order("created_at DESC").Find(&results).Error; err != nil {
+			return err
+		}
+		jobs, err = loadStorageJobDetails(db, results)
+		return err
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	sanitizedJSONResponse(w, struct {
+		Jobs []storageJobDetails `json:"jobs"`
+	}{
+		Jobs: jobs,
+	})
+}
+
+// handlePOSTAdminJobRetry lets an admin retry a failed or canceled job,
+// including one that was flagged after running out of automatic retries.
+func (s *FileHiveServer) handlePOSTAdminJobRetry(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	id := sp[len(sp)-2]
+
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	if !user.Admin {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var (
+		job    models.StorageJob
+		seller models.User
+	)
+	err = s.db.View(func(db *gorm.DB) error {
+		if err := db.Where("id = ?", id).First(&job).Error; err != nil {
+			return err
+		}
+		return db.Where("id = ?", job.UserID).First(&seller).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrJobNotFound), http.StatusNotFound)
+		return
+	}
+
+	if (job.Status != jobStatusFailed && job.Status != jobStatusCanceled) || job.RetryJobID != "" {
+		http.Error(w, wrapError(ErrJobNotRetryable), http.StatusBadRequest)
+		return
+	}
+
+	newJob, err := s.retryStorageJob(job, seller)
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	sanitizedJSONResponse(w, newJob)
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/repo"
+	"github.com/OB1Company/filehive/repo/models"
+	userPb "github.com/textileio/powergate/api/gen/powergate/user/v1"
+	"gorm.io/gorm"
+	"os"
+	"path"
+	"testing"
+)
+
+func Test_StorageJobTracker(t *testing.T) {
+	db, err := repo.NewDatabase("", repo.Dialect("memory"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filesDir := path.Join(testStaticDir, "files")
+	defer os.RemoveAll(testStaticDir)
+
+	filBackend, err := fil.NewMockFilecoinBackend(filesDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &FileHiveServer{
+		db:              db,
+		filecoinBackend: filBackend,
+		walletBackend:   fil.NewMockWalletBackend(),
+		staticFileDir:   testStaticDir,
+		dataDir:         testStaticDir,
+		maxJobRetries:   1,
+	}
+
+	err = db.Update(func(db *gorm.DB) error {
+		if err := db.Save(&models.User{ID: "seller", Email: "seller@ob1.io"}).Error; err != nil {
+			return err
+		}
+		if err := db.Save(&models.Dataset{ID: "dataset1", UserID: "seller", JobID: "job1", ContentID: "content1"}).Error; err != nil {
+			return err
+		}
+		return db.Save(&models.Dataset{ID: "dataset2", UserID: "seller", JobID: "job2", ContentID: "content2"}).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filBackend.SetJobStatus("job1", userPb.JobStatus_JOB_STATUS_SUCCESS)
+	filBackend.SetJobStatus("job2", userPb.JobStatus_JOB_STATUS_FAILED)
+
+	loadJob := func(id string) models.StorageJob {
+		var job models.StorageJob
+		err := db.View(func(db *gorm.DB) error {
+			return db.Where("id = ?", id).First(&job).Error
+		})
+		if err != nil {
+			t.Fatalf("job %s: %s", id, err)
+		}
+		return job
+	}
+
+	if err := server.pollStorageJobs(); err != nil {
+		t.Fatal(err)
+	}
+
+	if job := loadJob("job1"); job.Status != jobStatusSuccess || job.DatasetID != "dataset1" {
+		t.Errorf("Expected job1 to be successful, got %s", job.Status)
+	}
+
+	job2 := loadJob("job2")
+	if job2.Status != jobStatusFailed || job2.RetryJobID == "" {
+		t.Fatalf("Expected job2 to be retried, got status %s", job2.Status)
+	}
+
+	var dataset models.Dataset
+	err = db.View(func(db *gorm.DB) error {
+		return db.Where("id = ?", "dataset2").First(&dataset).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dataset.JobID != job2.RetryJobID {
+		t.Errorf("Expected dataset job to be %s, got %s", job2.RetryJobID, dataset.JobID)
+	}
+
+	var events []models.StorageJobEvent
+	err = db.View(func(db *gorm.DB) error {
+		return db.Where("job_id = ?", "job2").Order("timestamp").Find(&events).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Status != jobStatusQueued || events[1].Status != jobStatusFailed {
+		t.Errorf("Expected queued and failed events for job2, got %v", events)
+	}
+
+	// The retry fails too and, having used up its retries, is flagged.
+	filBackend.SetJobStatus(job2.RetryJobID, userPb.JobStatus_JOB_STATUS_FAILED)
+	if err := server.pollStorageJobs(); err != nil {
+		t.Fatal(err)
+	}
+
+	retry := loadJob(job2.RetryJobID)
+	if retry.Attempt != 1 || retry.Status != jobStatusFailed || !retry.Flagged || retry.RetryJobID != "" {
+		t.Errorf("Expected retry to be flagged, got %+v", retry)
+	}
+
+	if err := server.pollStorageJobs(); err != nil {
+		t.Fatal(err)
+	}
+	if retry := loadJob(job2.RetryJobID); retry.RetryJobID != "" {
+		t.Error("Expected flagged job not to be retried")
+	}
+}
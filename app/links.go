@@ -0,0 +1,230 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/OB1Company/filehive/repo/models"
+	"gorm.io/gorm"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLinkExpiry = time.Hour
+	maxLinkExpiry     = time.Hour * 24 * 7
+	maxLinkUses       = 100
+)
+
+// signDownloadLink returns the signature for a download link that expires
+// at the given unix time. Links are signed with the JWT key so they stop
+// working if the key is changed.
+func (s *FileHiveServer) signDownloadLink(id string, expires int64) string {
+	mac := hmac.New(sha256.New, s.jwtKey)
+	fmt.Fprintf(mac, "download:%s:%d", id, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// downloadLinkURL returns the signed URL for a download link on the host
+// the request was made to. The expiry and signature are kept in the path
+// so the URL survives response sanitizing and naive shell quoting.
+func (s *FileHiveServer) downloadLinkURL(r *http.Request, link models.DownloadLink) string {
+	scheme := "http"
+	if s.useSSL {
+		scheme = "https"
+	}
+	expires := link.ExpiresAt.Unix()
+	u := url.URL{
+		Scheme: scheme,
+		Host:   r.Host,
+		Path:   fmt.Sprintf("/api/v1/download/link/%s/%d/%s", link.ID, expires, s.signDownloadLink(link.ID, expires)),
+	}
+	return u.String()
+}
+
+// handlePOSTDownloadLink mints a signed download link for one of the user's
+// purchases. The link can be used from machines without a session, such as
+// scripts and data pipelines.
+func (s *FileHiveServer) handlePOSTDownloadLink(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	id := sp[len(sp)-2]
+
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	type data struct {
+		ExpiresIn int `json:"expiresIn"`
+		MaxUses   int `json:"maxUses"`
+	}
+	var d data
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+			http.Error(w, wrapError(ErrInvalidJSON), http.StatusBadRequest)
+			return
+		}
+	}
+
+	expiry := defaultLinkExpiry
+	if d.ExpiresIn != 0 {
+		expiry = time.Duration(d.ExpiresIn) * time.Second
+	}
+	if d.MaxUses == 0 {
+		d.MaxUses = 1
+	}
+	if expiry <= 0 || expiry > maxLinkExpiry || d.MaxUses < 0 || d.MaxUses > maxLinkUses {
+		http.Error(w, wrapError(ErrInvalidOption), http.StatusBadRequest)
+		return
+	}
+
+	var purchase models.Purchase
+	err = s.db.View(func(db *gorm.DB) error {
+		return db.Where("id = ? AND user_id = ? AND status <> ? AND state NOT IN ?", id, user.ID, purchaseStatusRefunded, unpaidPurchaseStates).First(&purchase).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrPurchaseNotFound), http.StatusNotFound)
+		return
+	}
+
+	linkID, err := makeID()
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	link := models.DownloadLink{
+		ID:         linkID,
+		UserID:     user.ID,
+		PurchaseID: purchase.ID,
+		DatasetID:  purchase.DatasetID,
+		MaxUses:    d.MaxUses,
+		ExpiresAt:  time.Now().Add(expiry),
+	}
+	err = s.db.Update(func(db *gorm.DB) error {
+		return db.Create(&link).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	sanitizedJSONResponse(w, struct {
+		URL       string    `json:"url"`
+		ExpiresAt time.Time `json:"expiresAt"`
+		MaxUses   int       `json:"maxUses"`
+	}{
+		URL:       s.downloadLinkURL(r, link),
+		ExpiresAt: link.ExpiresAt,
+		MaxUses:   link.MaxUses,
+	})
+}
+
+// handleGETSignedDownload sends the dataset for a signed download link.
+// Each download uses up one of the link's uses, even if it is not
+// completed. A range request resuming a download part way through doesn't
+// use up another, so a one-use link can still be resumed, but it is only
+// accepted once the link has been used.
+func (s *FileHiveServer) handleGETSignedDownload(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	id, sig := sp[len(sp)-3], sp[len(sp)-1]
+
+	expires, err := strconv.ParseInt(sp[len(sp)-2], 10, 64)
+	if err != nil || !hmac.Equal([]byte(sig), []byte(s.signDownloadLink(id, expires))) {
+		http.Error(w, wrapError(ErrInvalidLink), http.StatusForbidden)
+		return
+	}
+	if time.Now().Unix() >= expires {
+		http.Error(w, wrapError(ErrLinkExpired), http.StatusGone)
+		return
+	}
+
+	var (
+		link     models.DownloadLink
+		purchase models.Purchase
+		buyer    models.User
+		dataset  models.Dataset
+		version  models.DatasetVersion
+		used     bool
+	)
+	err = s.db.Update(func(db *gorm.DB) error {
+		if resumesDownload(r) {
+			var n int64
+			err := db.Model(&models.DownloadLink{}).Where("id = ? AND uses > 0 AND expires_at > ?", id, time.Now()).Count(&n).Error
+			used = n > 0
+			return err
+		}
+		tx := db.Model(&models.DownloadLink{}).Where("id = ? AND uses < max_uses AND expires_at > ?", id, time.Now()).Update("uses", gorm.Expr("uses + ?", 1))
+		if tx.Error != nil {
+			return tx.Error
+		}
+		used = tx.RowsAffected > 0
+		return nil
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	if !used {
+		http.Error(w, wrapError(ErrLinkExpired), http.StatusGone)
+		return
+	}
+
+	err = s.db.View(func(db *gorm.DB) error {
+		if err := db.Where("id = ?", id).First(&link).Error; err != nil {
+			return err
+		}
+		if err := db.Where("id = ? AND status <> ?", link.PurchaseID, purchaseStatusRefunded).First(&purchase).Error; err != nil {
+			return ErrPurchaseNotFound
+		}
+		if err := db.Where("id = ?", link.UserID).First(&buyer).Error; err != nil {
+			return err
+		}
+		if err := db.Where("id = ?", link.DatasetID).First(&dataset).Error; err != nil {
+			return ErrDatasetNotFound
+		}
+		var err error
+		version, err = downloadVersion(db, buyer, dataset, 0)
+		return err
+	})
+	if errors.Is(err, ErrPurchaseNotFound) || errors.Is(err, ErrDatasetNotFound) {
+		http.Error(w, wrapError(err), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	if !s.serveDatasetVersion(w, r, dataset, version) {
+		return
+	}
+
+	s.releaseDeliveredPurchases(buyer.ID, dataset.ID)
+}
+
+// resumesDownload returns whether the request is for a range starting
+// part way through the file, as sent to resume an interrupted download.
+func resumesDownload(r *http.Request) bool {
+	var start int64
+	if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &start); err != nil {
+		return false
+	}
+	return start > 0
+}
@@ -0,0 +1,172 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/repo"
+	"github.com/OB1Company/filehive/repo/models"
+	"github.com/filecoin-project/go-address"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_DownloadLinks(t *testing.T) {
+	db, err := repo.NewDatabase("", repo.Dialect("memory"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filesDir := path.Join(testStaticDir, "files")
+	defer os.RemoveAll(testStaticDir)
+
+	filBackend, err := fil.NewMockFilecoinBackend(filesDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &FileHiveServer{
+		db:              db,
+		filecoinBackend: filBackend,
+		walletBackend:   fil.NewMockWalletBackend(),
+		staticFileDir:   testStaticDir,
+		dataDir:         testStaticDir,
+		jwtKey:          []byte("secret"),
+	}
+
+	jobID, cid, _, err := filBackend.Store(strings.NewReader("rainfall"), address.Undef, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Update(func(db *gorm.DB) error {
+		for _, user := range []models.User{
+			{ID: "seller", Email: "seller@ob1.io"},
+			{ID: "buyer", Email: "buyer@ob1.io"},
+			{ID: "other", Email: "other@ob1.io"},
+		} {
+			if err := db.Save(&user).Error; err != nil {
+				return err
+			}
+		}
+		if err := db.Save(&models.Dataset{ID: "dataset1", UserID: "seller", Version: 1, ContentID: cid, JobID: jobID, DatasetFilename: "rainfall.csv"}).Error; err != nil {
+			return err
+		}
+		for _, purchase := range []models.Purchase{
+			{ID: "paid", UserID: "buyer", DatasetID: "dataset1", Version: 1, State: purchaseStateNotified, Status: purchaseStatusComplete},
+			{ID: "refunded", UserID: "buyer", DatasetID: "dataset1", Version: 1, State: purchaseStateNotified, Status: purchaseStatusRefunded},
+		} {
+			if err := db.Save(&purchase).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mint := func(email, purchaseID, body string) (*httptest.ResponseRecorder, string) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/purchase/"+purchaseID+"/link", bytes.NewBufferString(body))
+		server.handlePOSTDownloadLink(w, r.WithContext(context.WithValue(r.Context(), "email", email)))
+		if w.Code != http.StatusOK {
+			return w, ""
+		}
+		var resp struct {
+			URL string `json:"url"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		return w, resp.URL
+	}
+	download := func(link string, header ...string) *httptest.ResponseRecorder {
+		u, err := url.Parse(link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, u.Path, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			r.Header.Set(header[i], header[i+1])
+		}
+		server.handleGETSignedDownload(w, r)
+		return w
+	}
+
+	for _, test := range []struct {
+		email      string
+		purchaseID string
+		body       string
+		statusCode int
+	}{
+		{email: "other@ob1.io", purchaseID: "paid", statusCode: http.StatusNotFound},
+		{email: "buyer@ob1.io", purchaseID: "refunded", statusCode: http.StatusNotFound},
+		{email: "buyer@ob1.io", purchaseID: "paid", body: `{"expiresIn": 31536000}`, statusCode: http.StatusBadRequest},
+		{email: "buyer@ob1.io", purchaseID: "paid", body: `{"maxUses": 1000}`, statusCode: http.StatusBadRequest},
+	} {
+		if w, _ := mint(test.email, test.purchaseID, test.body); w.Code != test.statusCode {
+			t.Errorf("%s %s: expected status code %d, got %d", test.email, test.purchaseID, test.statusCode, w.Code)
+		}
+	}
+
+	_, link := mint("buyer@ob1.io", "paid", `{"maxUses": 2}`)
+	for i := 0; i < 2; i++ {
+		w := download(link)
+		if w.Code != http.StatusOK || w.Body.String() != "rainfall" {
+			t.Fatalf("Expected download %d to succeed, got %d: %s", i, w.Code, w.Body.String())
+		}
+	}
+	if w := download(link); w.Code != http.StatusGone {
+		t.Errorf("Expected used up link to be gone, got %d", w.Code)
+	}
+
+	// An interrupted download on a one-use link can be resumed, but a
+	// link that hasn't been used can't be started part way through.
+	_, link = mint("buyer@ob1.io", "paid", `{"maxUses": 1}`)
+	if w := download(link, "Range", "bytes=4-"); w.Code != http.StatusGone {
+		t.Errorf("Expected unused link to refuse a resumed download, got %d", w.Code)
+	}
+	if w := download(link, "Range", "bytes=0-3"); w.Code != http.StatusPartialContent || w.Body.String() != "rain" {
+		t.Fatalf("Expected first range to be sent, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := download(link, "Range", "bytes=4-"); w.Code != http.StatusPartialContent || w.Body.String() != "fall" {
+		t.Errorf("Expected resumed download to be sent, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := download(link); w.Code != http.StatusGone {
+		t.Errorf("Expected used up link to be gone, got %d", w.Code)
+	}
+	err = db.Update(func(db *gorm.DB) error {
+		return db.Where("max_uses = ?", 1).Delete(&models.DownloadLink{}).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, link = mint("buyer@ob1.io", "paid", "")
+	sp := strings.Split(link, "/")
+	sp[len(sp)-2] += "0"
+	if w := download(strings.Join(sp, "/")); w.Code != http.StatusForbidden {
+		t.Errorf("Expected tampered link to be forbidden, got %d", w.Code)
+	}
+
+	// An expired link is refused even though it has uses left.
+	err = db.Update(func(db *gorm.DB) error {
+		return db.Model(&models.DownloadLink{}).Where("max_uses = ?", 1).Update("expires_at", time.Now().Add(-time.Second)).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := download(link); w.Code != http.StatusGone {
+		t.Errorf("Expected expired link to be gone, got %d", w.Code)
+	}
+}
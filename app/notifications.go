@@ -0,0 +1,444 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/OB1Company/filehive/emails"
+	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/repo/models"
+	"gorm.io/gorm"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Notification types as persisted in the Notification model.
+const (
+	notificationSale            = "sale"
+	notificationJobCompleted    = "job_completed"
+	notificationLowBalance      = "low_balance"
+	notificationDatasetDelisted = "dataset_delisted"
+)
+
+// notificationEmailDefaults says whether each type of notification is also
+// emailed to users who haven't set a preference for it.
+var notificationEmailDefaults = map[string]bool{
+	notificationSale:            true,
+	notificationJobCompleted:    false,
+	notificationLowBalance:      true,
+	notificationDatasetDelisted: true,
+}
+
+const notificationPageSize = 50
+
+// notify adds the notification to the user's feed. If the user wants that
+// type of notification by email the email made by render is queued too.
+func (s *FileHiveServer) notify(user models.User, notification models.Notification, render func(locale string) (emails.Email, error)) error {
+	id, err := makeID()
+	if err != nil {
+		return err
+	}
+	notification.ID = id
+	notification.UserID = user.ID
+	notification.Timestamp = time.Now()
+	err = s.db.Update(func(db *gorm.DB) error {
+		return db.Create(&notification).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	var prefs map[string]bool
+	err = s.db.View(func(db *gorm.DB) error {
+		prefs, err = notificationPreferences(db, user.ID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if !prefs[notification.Type] {
+		return nil
+	}
+
+	email, err := render(emails.Locale(user.Language, user.Country))
+	if err != nil {
+		return err
+	}
+	return s.queueEmail(user.Email, email)
+}
+
+// notificationPreferences returns whether the user wants each type of
+// notification emailed to them.
+func notificationPreferences(db *gorm.DB, userID string) (map[string]bool, error) {
+	var saved []models.NotificationPreference
+	if err := db.Where("user_id = ?", userID).Find(&saved).Error; err != nil {
+		return nil, err
+	}
+	prefs := make(map[string]bool)
+	for typ, email := range notificationEmailDefaults {
+		prefs[typ] = email
+	}
+	for _, pref := range saved {
+		if _, ok := prefs[pref.Type]; ok {
+			prefs[pref.Type] = pref.Email
+		}
+	}
+	return prefs, nil
+}
+
+// notifySale lets the seller know about the purchase. It does nothing if
+// the seller has already been notified, so it can be called again when a
+// purchase is recovered.
+func (s *FileHiveServer) notifySale(purchase models.Purchase, buyer, seller models.User) error {
+	var count int64
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Model(&models.Notification{}).Where("type = ? AND purchase_id = ?", notificationSale, purchase.ID).Count(&count).Error
+	})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	return s.notify(seller, models.Notification{
+		Type:       notificationSale,
+		Message:    fmt.Sprintf("%s bought %s for %s FIL", buyer.Name, purchase.Title, purchase.Price),
+		DatasetID:  purchase.DatasetID,
+		PurchaseID: purchase.ID,
+	}, func(locale string) (emails.Email, error) {
+		return s.saleEmail(locale, purchase, buyer, seller)
+	})
+}
+
+// notifyJobCompleted lets the owner of a dataset know it has been stored.
+func (s *FileHiveServer) notifyJobCompleted(job models.StorageJob) error {
+	var (
+		user    models.User
+		dataset models.Dataset
+	)
+	err := s.db.View(func(db *gorm.DB) error {
+		if err := db.Where("id = ?", job.UserID).First(&user).Error; err != nil {
+			return err
+		}
+		return db.Where("id = ?", job.DatasetID).First(&dataset).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.notify(user, models.Notification{
+		Type:      notificationJobCompleted,
+		Message:   fmt.Sprintf("%s has been stored on Filecoin", dataset.Title),
+		DatasetID: dataset.ID,
+		JobID:     job.ID,
+	}, func(locale string) (emails.Email, error) {
+		return emails.JobCompleted(locale, emails.JobCompletedData{
+			Site:         emails.Site{Domain: s.mailDomain},
+			Name:         user.Name,
+			DatasetID:    dataset.ID,
+			DatasetTitle: dataset.Title,
+		})
+	})
+}
+
+// notifyLowBalance warns the owner of the address that their balance has
+// fallen below the low balance threshold.
+func (s *FileHiveServer) notifyLowBalance(addr string, balance *big.Int) error {
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("filecoin_address = ?", addr).First(&user).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// The escrow and fee addresses don't belong to a user.
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	amount := fil.NewAmount(balance)
+	return s.notify(user, models.Notification{
+		Type:    notificationLowBalance,
+		Message: fmt.Sprintf("Your wallet balance is down to %s FIL", amount),
+	}, func(locale string) (emails.Email, error) {
+		return emails.LowBalance(locale, emails.LowBalanceData{
+			Site:      emails.Site{Domain: s.mailDomain},
+			Name:      user.Name,
+			Address:   addr,
+			Balance:   amount.String() + " FIL",
+			Threshold: s.lowBalance.String() + " FIL",
+		})
+	})
+}
+
+// notifyDatasetDelisted lets the owner of a dataset know an admin has
+// delisted it.
+func (s *FileHiveServer) notifyDatasetDelisted(dataset models.Dataset) error {
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("id = ?", dataset.UserID).First(&user).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.notify(user, models.Notification{
+		Type:      notificationDatasetDelisted,
+		Message:   fmt.Sprintf("%s was delisted by an admin", dataset.Title),
+		DatasetID: dataset.ID,
+	}, func(locale string) (emails.Email, error) {
+		return emails.DatasetDelisted(locale, emails.DatasetDelistedData{
+			Site:         emails.Site{Domain: s.mailDomain},
+			Name:         user.Name,
+			DatasetID:    dataset.ID,
+			DatasetTitle: dataset.Title,
+		})
+	})
+}
+
+// handleGETNotifications returns the user's notification feed, newest
+// first, along with how many notifications are unread. Pass unread=true to
+// only list unread notifications.
+func (s *FileHiveServer) handleGETNotifications(w http.ResponseWriter, r *http.Request) {
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var page int
+	pageStr := r.URL.Query().Get("page")
+	if pageStr != "" {
+		page, err = strconv.Atoi(pageStr)
+		if err != nil || page < 0 {
+			http.Error(w, wrapError(ErrInvalidOption), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var unreadOnly bool
+	if unreadStr := r.URL.Query().Get("unread"); unreadStr != "" {
+		unreadOnly, err = strconv.ParseBool(unreadStr)
+		if err != nil {
+			http.Error(w, wrapError(ErrInvalidOption), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var (
+		notifications []models.Notification
+		count, unread int64
+	)
+	err = s.db.View(func(db *gorm.DB) error {
+		if err := db.Model(&models.Notification{}).Where("user_id = ? AND read = ?", user.ID, false).Count(&unread).Error; err != nil {
+			return err
+		}
+		query := db.Model(&models.Notification{}).Where("user_id = ?", user.ID)
+		if unreadOnly {
+			query = query.Where("read = ?", false)
+		}
+		if err := query.Count(&count).Error; err != nil {
+			return err
+		}
+		return query.Order("timestamp DESC").Offset(page * notificationPageSize).Limit(notificationPageSize).Find(&notifications).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	sanitizedJSONResponse(w, struct {
+		Pages         int                   `json:"pages"`
+		Page          int                   `json:"page"`
+		Unread        int64                 `json:"unread"`
+		Notifications []models.Notification `json:"notifications"`
+	}{
+		Pages:         (int(count) / notificationPageSize) + 1,
+		Page:          page,
+		Unread:        unread,
+		Notifications: notifications,
+	})
+}
+
+// handlePOSTNotificationRead marks one of the user's notifications read.
+func (s *FileHiveServer) handlePOSTNotificationRead(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	id := sp[len(sp)-2]
+
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var notification models.Notification
+	err = s.db.Update(func(db *gorm.DB) error {
+		if err := db.Where("id = ? AND user_id = ?", id, user.ID).First(&notification).Error; err != nil {
+			return err
+		}
+		notification.Read = true
+		return db.Model(&notification).Update("read", true).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, wrapError(ErrNotificationNotFound), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	sanitizedJSONResponse(w, notification)
+}
+
+// handlePOSTNotificationsRead marks all of the user's notifications read.
+func (s *FileHiveServer) handlePOSTNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	err = s.db.Update(func(db *gorm.DB) error {
+		return db.Model(&models.Notification{}).Where("user_id = ? AND read = ?", user.ID, false).Update("read", true).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// notificationPreferencesResponse lists whether each type of notification
+// is emailed to the user.
+type notificationPreferencesResponse struct {
+	Email map[string]bool `json:"email"`
+}
+
+// handleGETNotificationPreferences returns which types of notification the
+// user has emailed to them.
+func (s *FileHiveServer) handleGETNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var (
+		user  models.User
+		prefs map[string]bool
+	)
+	err := s.db.View(func(db *gorm.DB) error {
+		if err := db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error; err != nil {
+			return ErrInvalidCredentials
+		}
+		var err error
+		prefs, err = notificationPreferences(db, user.ID)
+		return err
+	})
+	if errors.Is(err, ErrInvalidCredentials) {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	sanitizedJSONResponse(w, notificationPreferencesResponse{Email: prefs})
+}
+
+// handlePUTNotificationPreferences sets which types of notification the user
+// has emailed to them. Types left out of the request are unchanged.
+func (s *FileHiveServer) handlePUTNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var d notificationPreferencesResponse
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+		http.Error(w, wrapError(ErrInvalidJSON), http.StatusBadRequest)
+		return
+	}
+	for typ := range d.Email {
+		if _, ok := notificationEmailDefaults[typ]; !ok {
+			http.Error(w, wrapError(ErrInvalidNotification), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var prefs map[string]bool
+	err = s.db.Update(func(db *gorm.DB) error {
+		for typ, send := range d.Email {
+			err := db.Save(&models.NotificationPreference{
+				UserID: user.ID,
+				Type:   typ,
+				Email:  send,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		var err error
+		prefs, err = notificationPreferences(db, user.ID)
+		return err
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	sanitizedJSONResponse(w, notificationPreferencesResponse{Email: prefs})
+}
@@ -0,0 +1,174 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/mail"
+	"github.com/OB1Company/filehive/repo"
+	"github.com/OB1Company/filehive/repo/models"
+	"github.com/OB1Company/filehive/repo/search"
+	userPb "github.com/textileio/powergate/api/gen/powergate/user/v1"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_Notifications(t *testing.T) {
+	db, err := repo.NewDatabase("", repo.Dialect("memory"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wallet := fil.NewMockWalletBackend()
+	server := &FileHiveServer{
+		db:            db,
+		walletBackend: wallet,
+		mailDomain:    "filehive.io",
+		mailer:        mail.NewMemoryMailer(),
+		outboxWake:    make(chan struct{}, 1),
+		lowBalance:    fil.MustParseAmount("1"),
+		searchIndex:   search.NewMemoryIndex(),
+	}
+
+	addr, err := wallet.NewAddress("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(db *gorm.DB) error {
+		if err := db.Save(&models.User{ID: "admin", Email: "admin@ob1.io", Admin: true}).Error; err != nil {
+			return err
+		}
+		if err := db.Save(&models.User{ID: "brian", Email: "brian@ob1.io", Name: "Brian", FilecoinAddress: addr}).Error; err != nil {
+			return err
+		}
+		if err := db.Save(&models.User{ID: "amanda", Email: "amanda@ob1.io", Name: "Amanda"}).Error; err != nil {
+			return err
+		}
+		if err := db.Save(&models.Dataset{ID: "weather", UserID: "brian", Title: "Weather", JobID: "job1"}).Error; err != nil {
+			return err
+		}
+		return db.Save(&models.StorageJob{ID: "job1", DatasetID: "weather", UserID: "brian", Status: jobStatusExecuting}).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(method, target, email string, body []byte, handler http.HandlerFunc) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, target, bytes.NewReader(body))
+		handler(w, r.WithContext(context.WithValue(r.Context(), "email", email)))
+		return w
+	}
+	type feed struct {
+		Unread        int64                 `json:"unread"`
+		Notifications []models.Notification `json:"notifications"`
+	}
+	loadFeed := func(query string) feed {
+		w := request(http.MethodGet, "/api/v1/notifications"+query, "brian@ob1.io", nil, server.handleGETNotifications)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected the feed, got %d: %s", w.Code, w.Body.String())
+		}
+		var f feed
+		if err := json.NewDecoder(w.Body).Decode(&f); err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	queued := func() []models.OutboxEmail {
+		var emails []models.OutboxEmail
+		err := db.View(func(db *gorm.DB) error {
+			return db.Order("queued_at").Find(&emails).Error
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return emails
+	}
+
+	// A completed storage job is only emailed if the user asks for it.
+	if _, err := server.updateStorageJob(models.StorageJob{ID: "job1", DatasetID: "weather", UserID: "brian", Status: jobStatusExecuting}, &userPb.StorageJob{Status: userPb.JobStatus_JOB_STATUS_SUCCESS}); err != nil {
+		t.Fatal(err)
+	}
+	if f := loadFeed(""); f.Unread != 1 || len(f.Notifications) != 1 || f.Notifications[0].Type != notificationJobCompleted || f.Notifications[0].DatasetID != "weather" {
+		t.Fatalf("Expected a job completed notification, got %+v", f)
+	}
+	if emails := queued(); len(emails) != 0 {
+		t.Errorf("Expected job completion not to be emailed by default, got %d emails", len(emails))
+	}
+
+	// An admin delisting the dataset is emailed by default.
+	w := request(http.MethodGet, "/api/v1/delist/weather", "admin@ob1.io", nil, server.handleGETDelist)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected delisting to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	if emails := queued(); len(emails) != 1 || emails[0].Recipient != "brian@ob1.io" || emails[0].Subject != "Your dataset has been delisted" {
+		t.Errorf("Expected the delisting to be emailed, got %v", emails)
+	}
+
+	// Falling below the low balance threshold is notified once.
+	wallet.GenerateToAddress(addr, fil.MustParseAmount("2").AttoFIL())
+	for i := 0; i < 2; i++ {
+		if err := server.scanWalletAddress(addr, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := wallet.Send(addr, "f1om6safovokyxkddpbbvg5avhsjsmsn2pletqvqa", fil.MustParseAmount("1.5").AttoFIL(), ""); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := server.scanWalletAddress(addr, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f := loadFeed("")
+	if f.Unread != 3 || len(f.Notifications) != 3 || f.Notifications[0].Type != notificationLowBalance {
+		t.Fatalf("Expected one low balance notification, got %+v", f)
+	}
+
+	// Preferences decide what is emailed.
+	w = request(http.MethodPut, "/api/v1/notifications/preferences", "brian@ob1.io", []byte(`{"email": {"dataset_delisted": false, "job_completed": true}}`), server.handlePUTNotificationPreferences)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected preferences to be saved, got %d: %s", w.Code, w.Body.String())
+	}
+	w = request(http.MethodPut, "/api/v1/notifications/preferences", "brian@ob1.io", []byte(`{"email": {"dataset_delisted": true}}`), server.handlePUTNotificationPreferences)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected preferences to be updated, got %d: %s", w.Code, w.Body.String())
+	}
+	w = request(http.MethodGet, "/api/v1/notifications/preferences", "brian@ob1.io", nil, server.handleGETNotificationPreferences)
+	var prefs notificationPreferencesResponse
+	if err := json.NewDecoder(w.Body).Decode(&prefs); err != nil {
+		t.Fatal(err)
+	}
+	if !prefs.Email[notificationJobCompleted] || !prefs.Email[notificationDatasetDelisted] || !prefs.Email[notificationSale] {
+		t.Errorf("Unexpected preferences %v", prefs.Email)
+	}
+	w = request(http.MethodPut, "/api/v1/notifications/preferences", "brian@ob1.io", []byte(`{"email": {"spam": true}}`), server.handlePUTNotificationPreferences)
+	if w.Code != http.StatusBadRequest || w.Body.String() != string(errorReturn(ErrInvalidNotification)) {
+		t.Errorf("Expected an unknown type to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Notifications can be read one at a time or all at once, but only by
+	// their owner.
+	id := f.Notifications[0].ID
+	w = request(http.MethodPost, "/api/v1/notifications/"+id+"/read", "amanda@ob1.io", nil, server.handlePOSTNotificationRead)
+	if w.Code != http.StatusNotFound || w.Body.String() != string(errorReturn(ErrNotificationNotFound)) {
+		t.Errorf("Expected another user's notification not to be found, got %d: %s", w.Code, w.Body.String())
+	}
+	w = request(http.MethodPost, "/api/v1/notifications/"+id+"/read", "brian@ob1.io", nil, server.handlePOSTNotificationRead)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the notification to be read, got %d: %s", w.Code, w.Body.String())
+	}
+	if f := loadFeed("?unread=true"); f.Unread != 2 || len(f.Notifications) != 2 {
+		t.Errorf("Expected two unread notifications, got %+v", f)
+	}
+	w = request(http.MethodPost, "/api/v1/notifications/read", "brian@ob1.io", nil, server.handlePOSTNotificationsRead)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the notifications to be read, got %d: %s", w.Code, w.Body.String())
+	}
+	if f := loadFeed(""); f.Unread != 0 || len(f.Notifications) != 3 {
+		t.Errorf("Expected every notification to be read, got %+v", f)
+	}
+}
@@ -0,0 +1,377 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/OB1Company/filehive/emails"
+	"github.com/OB1Company/filehive/mail"
+	"github.com/OB1Company/filehive/repo/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Outbox email statuses as persisted in the OutboxEmail model.
+const (
+	emailStatusPending = "pending"
+	emailStatusSent    = "sent"
+	emailStatusFailed  = "failed"
+)
+
+const (
+	defaultOutboxPollInterval = time.Second * 30
+
+	// maxEmailAttempts is how many times an email is tried before it is
+	// marked failed for an admin to resend.
+	maxEmailAttempts = 8
+
+	// emailRetryDelay is how long to wait after the first failed attempt.
+	// The delay doubles after each attempt up to maxEmailRetryDelay.
+	emailRetryDelay    = time.Minute
+	maxEmailRetryDelay = time.Hour * 4
+
+	// emailBatchSize is the most emails sent in one pass over the outbox.
+	emailBatchSize = 100
+)
+
+// queueEmail adds a rendered email from the administrator address to the
+// outbox and wakes the outbox worker to send it.
+func (s *FileHiveServer) queueEmail(recipient string, email emails.Email) error {
+	id, err := makeID()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	err = s.db.Update(func(db *gorm.DB) error {
+		return db.Create(&models.OutboxEmail{
+			ID:          id,
+			Sender:      "administrator@" + s.mailDomain,
+			Recipient:   recipient,
+			Subject:     email.Subject,
+			HTML:        email.HTML,
+			Text:        email.Text,
+			Status:      emailStatusPending,
+			QueuedAt:    now,
+			NextAttempt: now,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	s.wakeOutbox()
+	return nil
+}
+
+// wakeOutbox has the outbox worker look for emails to send without waiting
+// for its next poll.
+func (s *FileHiveServer) wakeOutbox() {
+	select {
+	case s.outboxWake <- struct{}{}:
+	default:
+	}
+}
+
+// runOutbox sends the queued emails until the server is shut down. It
+// wakes whenever an email is queued and otherwise polls for emails that
+// are due to be retried.
+func (s *FileHiveServer) runOutbox() {
+	ticker := time.NewTicker(defaultOutboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.deliverOutbox(); err != nil {
+			log.Errorf("Error delivering outbox: %s", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-s.outboxWake:
+		case <-s.shutdown:
+			return
+		}
+	}
+}
+
+// deliverOutbox makes a single pass over the emails that are due to be
+// sent.
+func (s *FileHiveServer) deliverOutbox() error {
+	var emails []models.OutboxEmail
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("status = ? AND next_attempt <= ?", emailStatusPending, time.Now()).
+			Order("queued_at").Limit(emailBatchSize).Find(&emails).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, email := range emails {
+		if err := s.deliverEmail(email); err != nil {
+			log.Errorf("Error updating email %s: %s", email.ID, err)
+		}
+	}
+	return nil
+}
+
+// deliverEmail tries to send the email and records the outcome. A failed
+// send is retried with exponential backoff until the email runs out of
+// attempts.
+func (s *FileHiveServer) deliverEmail(email models.OutboxEmail) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	sendErr := s.mailer.Send(ctx, mail.Message{
+		From:    email.Sender,
+		To:      email.Recipient,
+		Subject: email.Subject,
+		HTML:    email.HTML,
+		Text:    email.Text,
+	})
+
+	now := time.Now()
+	updates := map[string]interface{}{"attempts": email.Attempts + 1}
+	switch {
+	case sendErr == nil:
+		updates["status"] = emailStatusSent
+		updates["sent_at"] = now
+		updates["html"] = ""
+		updates["text"] = ""
+		updates["last_error"] = ""
+	case email.Attempts+1 >= maxEmailAttempts:
+		log.Warningf("Email %s to %s failed after %d attempts: %s", email.ID, email.Recipient, email.Attempts+1, sendErr)
+		updates["status"] = emailStatusFailed
+		updates["last_error"] = sendErr.Error()
+	default:
+		log.Debugf("Error sending email %s to %s: %s", email.ID, email.Recipient, sendErr)
+		updates["last_error"] = sendErr.Error()
+		updates["next_attempt"] = now.Add(emailBackoff(email.Attempts + 1))
+	}
+
+	return s.db.Update(func(db *gorm.DB) error {
+		return db.Model(&models.OutboxEmail{}).Where("id = ?", email.ID).Updates(updates).Error
+	})
+}
+
+// emailBackoff returns how long to wait before trying an email again after
+// the given number of failed attempts.
+func emailBackoff(attempts int) time.Duration {
+	return retryBackoff(attempts, emailRetryDelay, maxEmailRetryDelay)
+}
+
+// retryBackoff returns the delay after the given number of failed
+// attempts, doubling from delay after each attempt up to max.
+func retryBackoff(attempts int, delay, max time.Duration) time.Duration {
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// handleGETAdminEmails lists the emails in the outbox, newest first,
+// optionally filtered by status so failed emails can be found.
+func (s *FileHiveServer) handleGETAdminEmails(w http.ResponseWriter, r *http.Request) {
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	if !user.Admin {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var page int
+	pageStr := r.URL.Query().Get("page")
+	if pageStr != "" {
+		page, err = strconv.Atoi(pageStr)
+		if err != nil {
+			http.Error(w, wrapError(ErrInvalidOption), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var (
+		emails []models.OutboxEmail
+		count  int64
+	)
+	err = s.db.View(func(db *gorm.DB) error {
+		query := db.Model(&models.OutboxEmail{})
+		if status := r.URL.Query().Get("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+		if err := query.Count(&count).Error; err != nil {
+			return err
+		}
+		return query.Order("queued_at DESC").Offset(page * 100).Limit(100).Find(&emails).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	sanitizedJSONResponse(w, struct {
+		Pages  int                  `json:"pages"`
+		Page   int                  `json:"page"`
+		Emails []models.OutboxEmail `json:"emails"`
+	}{
+		Pages:  (int(count) / 100) + 1,
+		Page:   page,
+		Emails: emails,
+	})
+}
+
+// handlePOSTAdminEmailResend queues a failed email to be sent again with a
+// fresh set of attempts.
+func (s *FileHiveServer) handlePOSTAdminEmailResend(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	id := sp[len(sp)-2]
+
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	if !user.Admin {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var queued models.OutboxEmail
+	err = s.db.View(func(db *gorm.DB) error {
+		return db.Where("id = ?", id).First(&queued).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrEmailNotFound), http.StatusNotFound)
+		return
+	}
+
+	if queued.Status != emailStatusFailed {
+		http.Error(w, wrapError(ErrEmailNotFailed), http.StatusBadRequest)
+		return
+	}
+
+	queued.Status = emailStatusPending
+	queued.Attempts = 0
+	queued.NextAttempt = time.Now()
+	err = s.db.Update(func(db *gorm.DB) error {
+		return db.Model(&models.OutboxEmail{}).Where("id = ? AND status = ?", queued.ID, emailStatusFailed).Updates(map[string]interface{}{
+			"status":       queued.Status,
+			"attempts":     queued.Attempts,
+			"next_attempt": queued.NextAttempt,
+		}).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	s.wakeOutbox()
+
+	sanitizedJSONResponse(w, queued)
+}
+
+// handleGETAdminEmailPreview renders an email with sample data so the
+// templates can be checked. The locale query parameter picks the
+// translation and format=html or format=text returns just that body instead
+// of the JSON subject, html and text.
+func (s *FileHiveServer) handleGETAdminEmailPreview(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	if !user.Admin {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	locale := r.URL.Query().Get("locale")
+	if locale == "" {
+		locale = emails.DefaultLocale
+	} else if !validLanguage(locale) {
+		http.Error(w, wrapError(ErrInvalidLanguage), http.StatusBadRequest)
+		return
+	}
+
+	preview, err := emails.Preview(name, locale, s.mailDomain)
+	if errors.Is(err, emails.ErrUnknownEmail) {
+		http.Error(w, wrapError(err), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, preview.HTML)
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, preview.Text)
+	case "":
+		// The preview isn't run through the sanitizer as it would strip
+		// the layout from the HTML. It only holds sample data.
+		out, err := json.MarshalIndent(struct {
+			Subject string `json:"subject"`
+			HTML    string `json:"html"`
+			Text    string `json:"text"`
+		}{preview.Subject, preview.HTML, preview.Text}, "", "    ")
+		if err != nil {
+			http.Error(w, wrapError(err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprint(w, string(out))
+	default:
+		http.Error(w, wrapError(ErrInvalidOption), http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,315 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/OB1Company/filehive/emails"
+	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/mail"
+	"github.com/OB1Company/filehive/repo"
+	"github.com/OB1Company/filehive/repo/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_EmailOutbox(t *testing.T) {
+	db, err := repo.NewDatabase("", repo.Dialect("memory"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filesDir := path.Join(testStaticDir, "files")
+	if err := os.MkdirAll(filesDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(testStaticDir)
+
+	filBackend, err := fil.NewMockFilecoinBackend(filesDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mailer := mail.NewMemoryMailer()
+	server := &FileHiveServer{
+		db:              db,
+		filecoinBackend: filBackend,
+		walletBackend:   fil.NewMockWalletBackend(),
+		staticFileDir:   testStaticDir,
+		jwtKey:          []byte("key"),
+		mailDomain:      "filehive.io",
+		mailer:          mailer,
+		outboxWake:      make(chan struct{}, 1),
+	}
+
+	err = db.Update(func(db *gorm.DB) error {
+		return db.Save(&models.User{ID: "admin", Email: "admin@ob1.io", Admin: true}).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loadEmail := func() models.OutboxEmail {
+		var email models.OutboxEmail
+		err := db.View(func(db *gorm.DB) error {
+			return db.Where("recipient = ?", "brian@ob1.io").First(&email).Error
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return email
+	}
+
+	// Signing up queues the welcome email rather than sending it, so it
+	// succeeds while the mail provider is down.
+	mailer.SetError(errors.New("mail is down"))
+	w := httptest.NewRecorder()
+	body := `{"email": "brian@ob1.io", "name": "Brian", "password": "asdfadsf1234567!"}`
+	server.handlePOSTUser(w, httptest.NewRequest(http.MethodPost, "/api/v1/user", bytes.NewBufferString(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected signup to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	select {
+	case <-server.outboxWake:
+	default:
+		t.Error("Expected queueing an email to wake the outbox")
+	}
+	if email := loadEmail(); email.Status != emailStatusPending || email.Sender != "administrator@filehive.io" {
+		t.Fatalf("Expected a pending email from administrator@filehive.io, got %s from %s", email.Status, email.Sender)
+	}
+
+	// A failed send is retried later rather than straight away.
+	if err := server.deliverOutbox(); err != nil {
+		t.Fatal(err)
+	}
+	email := loadEmail()
+	if email.Attempts != 1 || email.LastError != "mail is down" || !email.NextAttempt.After(time.Now()) {
+		t.Errorf("Expected a retry to be scheduled, got %d attempts, error %q, next attempt %s", email.Attempts, email.LastError, email.NextAttempt)
+	}
+	if err := server.deliverOutbox(); err != nil {
+		t.Fatal(err)
+	}
+	if email := loadEmail(); email.Attempts != 1 {
+		t.Errorf("Expected no attempt before the backoff, got %d attempts", email.Attempts)
+	}
+
+	// It's marked failed once it runs out of attempts.
+	for i := 1; i < maxEmailAttempts; i++ {
+		err := db.Update(func(db *gorm.DB) error {
+			return db.Model(&models.OutboxEmail{}).Where("id = ?", email.ID).Update("next_attempt", time.Now()).Error
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := server.deliverOutbox(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if email := loadEmail(); email.Status != emailStatusFailed || email.Attempts != maxEmailAttempts {
+		t.Fatalf("Expected email to fail after %d attempts, got %s after %d", maxEmailAttempts, email.Status, email.Attempts)
+	}
+
+	admin := func(method, target string, handler http.HandlerFunc) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, target, nil)
+		handler(w, r.WithContext(context.WithValue(r.Context(), "email", "admin@ob1.io")))
+		return w
+	}
+
+	w = admin(http.MethodGet, "/api/v1/admin/emails?status=failed", server.handleGETAdminEmails)
+	if w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(email.ID)) {
+		t.Errorf("Expected the failed email to be listed, got %d: %s", w.Code, w.Body.String())
+	}
+	if bytes.Contains(w.Body.Bytes(), []byte("html")) {
+		t.Errorf("Expected the email body not to be listed, got %s", w.Body.String())
+	}
+
+	// An admin can resend it once the provider is back.
+	mailer.SetError(nil)
+	w = admin(http.MethodPost, "/api/v1/admin/emails/"+email.ID+"/resend", server.handlePOSTAdminEmailResend)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected resend to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	if err := server.deliverOutbox(); err != nil {
+		t.Fatal(err)
+	}
+	email = loadEmail()
+	if email.Status != emailStatusSent || email.SentAt == nil || email.HTML != "" || email.Text != "" {
+		t.Errorf("Expected email to be sent and its body cleared, got %s", email.Status)
+	}
+	messages := mailer.Messages()
+	if len(messages) != 1 || messages[0].To != "brian@ob1.io" || messages[0].Subject != "Welcome to Filehive! 🐝" {
+		t.Errorf("Expected the welcome email to be sent once, got %v", messages)
+	}
+	if len(messages) == 1 && !strings.Contains(messages[0].Text, "email=brian%40ob1.io") {
+		t.Errorf("Expected a plain-text confirmation link, got %q", messages[0].Text)
+	}
+
+	w = admin(http.MethodPost, "/api/v1/admin/emails/"+email.ID+"/resend", server.handlePOSTAdminEmailResend)
+	if w.Body.String() != string(errorReturn(ErrEmailNotFailed)) {
+		t.Errorf("Expected a sent email not to be resent, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func Test_Emails(t *testing.T) {
+	db, err := repo.NewDatabase("", repo.Dialect("memory"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filesDir := path.Join(testStaticDir, "files")
+	if err := os.MkdirAll(filesDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(testStaticDir)
+
+	filBackend, err := fil.NewMockFilecoinBackend(filesDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mailer := mail.NewMemoryMailer()
+	server := &FileHiveServer{
+		db:              db,
+		filecoinBackend: filBackend,
+		walletBackend:   fil.NewMockWalletBackend(),
+		staticFileDir:   testStaticDir,
+		jwtKey:          []byte("key"),
+		mailDomain:      "filehive.io",
+		mailer:          mailer,
+		outboxWake:      make(chan struct{}, 1),
+	}
+
+	signup := func(email string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		body := `{"email": "` + email + `", "name": "Brian", "password": "asdfadsf1234567!"}`
+		server.handlePOSTUser(w, httptest.NewRequest(http.MethodPost, "/api/v1/user", bytes.NewBufferString(body)))
+		if err := server.deliverOutbox(); err != nil {
+			t.Fatal(err)
+		}
+		return w
+	}
+
+	// Email is sent through the configured mailer from the site's
+	// administrator address.
+	if w := signup("brian@ob1.io"); w.Code != http.StatusOK {
+		t.Fatalf("Expected signup to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	messages := mailer.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("Expected one email, got %d", len(messages))
+	}
+	if messages[0].To != "brian@ob1.io" || messages[0].From != "administrator@filehive.io" {
+		t.Errorf("Expected welcome email from administrator@filehive.io to brian@ob1.io, got %s to %s", messages[0].From, messages[0].To)
+	}
+	if messages[0].HTML == "" || messages[0].Text == "" {
+		t.Error("Expected the email to have HTML and text parts")
+	}
+
+	// Failing to send the welcome email doesn't stop the signup.
+	mailer.SetError(errors.New("mail is down"))
+	if w := signup("amanda@ob1.io"); w.Code != http.StatusOK {
+		t.Errorf("Expected signup to succeed without email, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(mailer.Messages()) != 1 {
+		t.Errorf("Expected no more emails, got %d", len(mailer.Messages()))
+	}
+}
+
+func Test_EmailPreview(t *testing.T) {
+	db, err := repo.NewDatabase("", repo.Dialect("memory"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &FileHiveServer{
+		db:         db,
+		mailDomain: "filehive.io",
+	}
+
+	err = db.Update(func(db *gorm.DB) error {
+		if err := db.Save(&models.User{ID: "admin", Email: "admin@ob1.io", Admin: true}).Error; err != nil {
+			return err
+		}
+		return db.Save(&models.User{ID: "brian", Email: "brian@ob1.io"}).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	preview := func(email, name, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/admin/emails/preview/"+name+query, nil)
+		r = mux.SetURLVars(r, map[string]string{"name": name})
+		server.handleGETAdminEmailPreview(w, r.WithContext(context.WithValue(r.Context(), "email", email)))
+		return w
+	}
+
+	w := preview("admin@ob1.io", "sale", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected a preview, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Subject string `json:"subject"`
+		HTML    string `json:"html"`
+		Text    string `json:"text"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Subject != "You've made a sale on Filehive! 🤑" || !strings.Contains(resp.HTML, "https://filehive.io/dashboard/sales") || resp.Text == "" {
+		t.Errorf("Unexpected preview %+v", resp)
+	}
+
+	w = preview("admin@ob1.io", "welcome", "?locale=es&format=text")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Body.String(), "Hola") || w.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Errorf("Expected the Spanish plain-text preview, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = preview("admin@ob1.io", "password-reset", "?format=html")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Body.String(), "<!DOCTYPE html>") {
+		t.Errorf("Expected the HTML preview, got %d: %s", w.Code, w.Body.String())
+	}
+
+	for _, test := range []struct {
+		email string
+		name  string
+		query string
+		code  int
+		err   error
+	}{
+		{"brian@ob1.io", "sale", "", http.StatusUnauthorized, ErrInvalidCredentials},
+		{"admin@ob1.io", "invoice", "", http.StatusNotFound, emails.ErrUnknownEmail},
+		{"admin@ob1.io", "sale", "?locale=klingon", http.StatusBadRequest, ErrInvalidLanguage},
+		{"admin@ob1.io", "sale", "?format=pdf", http.StatusBadRequest, ErrInvalidOption},
+	} {
+		w := preview(test.email, test.name, test.query)
+		if w.Code != test.code || w.Body.String() != string(errorReturn(test.err)) {
+			t.Errorf("%s%s: expected %d %s, got %d: %s", test.name, test.query, test.code, test.err, w.Code, w.Body.String())
+		}
+	}
+}
+
+func Test_EmailBackoff(t *testing.T) {
+	for _, test := range []struct {
+		attempts int
+		delay    time.Duration
+	}{
+		{1, time.Minute},
+		{2, time.Minute * 2},
+		{3, time.Minute * 4},
+		{20, maxEmailRetryDelay},
+	} {
+		if delay := emailBackoff(test.attempts); delay != test.delay {
+			t.Errorf("After %d attempts expected %s, got %s", test.attempts, test.delay, delay)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/repo/models"
+	"gorm.io/gorm"
+	"math/big"
+)
+
+// Pricing modes of a dataset. Free datasets are claimed without any wallet
+// activity. Fixed price datasets cost their price, which is zero only for
+// datasets that are sold by subscription alone. Pay what you want datasets
+// cost whatever the buyer offers as long as it is at least the minimum
+// price, and the price is what is suggested to buyers.
+const (
+	pricingModeFree  = "free"
+	pricingModeFixed = "fixed"
+	pricingModePWYW  = "pay_what_you_want"
+)
+
+// datasetPricingMode returns the dataset's pricing mode. Datasets listed
+// before pricing modes existed are free if they have no price of any kind
+// and fixed price otherwise.
+func datasetPricingMode(dataset models.Dataset) string {
+	if dataset.PricingMode != "" {
+		return dataset.PricingMode
+	}
+	if dataset.Price.IsZero() && dataset.SubscriptionPeriod == 0 {
+		return pricingModeFree
+	}
+	return pricingModeFixed
+}
+
+// validPricing returns whether the dataset's prices make sense for its
+// pricing mode.
+func validPricing(dataset models.Dataset) bool {
+	if dataset.Price.Sign() < 0 || dataset.MinimumPrice.Sign() < 0 {
+		return false
+	}
+	// Only fixed prices can be pegged to a fiat price.
+	if dataset.FiatCurrency != "" || dataset.FiatPrice != "" {
+		if dataset.PricingMode != pricingModeFixed || !fil.ValidCurrency(dataset.FiatCurrency) {
+			return false
+		}
+	}
+	switch dataset.PricingMode {
+	case pricingModeFree:
+		return dataset.Price.IsZero() && dataset.MinimumPrice.IsZero()
+	case pricingModeFixed:
+		return dataset.MinimumPrice.IsZero() && (dataset.Price.Sign() > 0 || dataset.SubscriptionPeriod > 0)
+	case pricingModePWYW:
+		return dataset.Price.Cmp(dataset.MinimumPrice) >= 0
+	}
+	return false
+}
+
+// purchasePrice returns what the buyer pays for the dataset. The offer is
+// only used for pay what you want datasets, where no offer means paying the
+// suggested price.
+func purchasePrice(dataset models.Dataset, offer fil.Amount) (fil.Amount, error) {
+	switch datasetPricingMode(dataset) {
+	case pricingModeFree:
+		return fil.Amount{}, nil
+	case pricingModePWYW:
+		if offer.IsZero() {
+			return dataset.Price, nil
+		}
+		if offer.Cmp(dataset.MinimumPrice) < 0 {
+			return fil.Amount{}, ErrBelowMinimumPrice
+		}
+		return offer, nil
+	}
+	if dataset.Price.Sign() <= 0 {
+		return fil.Amount{}, ErrNotForSale
+	}
+	return dataset.Price, nil
+}
+
+// pricingModeTotals are the number of sales made in a pricing mode and
+// the amount they were paid.
+type pricingModeTotals struct {
+	Count  int        `json:"count"`
+	Volume fil.Amount `json:"volume"`
+}
+
+// saleAmount returns the amount paid for a sale in attoFIL. Sales made
+// before amounts were recorded fall back to their price.
+func saleAmount(sale models.Purchase) *big.Int {
+	if amt, ok := new(big.Int).SetString(sale.Amount, 10); ok {
+		return amt
+	}
+	return sale.Price.AttoFIL()
+}
+
+// salesByPricingMode totals the sales in each pricing mode. Sales made
+// before pricing modes existed count as fixed price.
+func salesByPricingMode(sales []models.Purchase) map[string]pricingModeTotals {
+	volumes := make(map[string]*big.Int)
+	counts := make(map[string]int)
+	for _, mode := range []string{pricingModeFree, pricingModeFixed, pricingModePWYW} {
+		volumes[mode] = new(big.Int)
+	}
+	for _, sale := range sales {
+		mode := sale.PricingMode
+		if volumes[mode] == nil {
+			mode = pricingModeFixed
+		}
+		volumes[mode].Add(volumes[mode], saleAmount(sale))
+		counts[mode]++
+	}
+
+	totals := make(map[string]pricingModeTotals, len(volumes))
+	for mode, volume := range volumes {
+		totals[mode] = pricingModeTotals{Count: counts[mode], Volume: fil.NewAmount(volume)}
+	}
+	return totals
+}
+
+// backfillPricingModes sets the pricing mode of datasets and purchases made
+// before pricing modes existed.
+func (s *FileHiveServer) backfillPricingModes() error {
+	return s.db.Update(func(db *gorm.DB) error {
+		err := db.Model(&models.Dataset{}).Where("pricing_mode = ? AND price_atto = ? AND subscription_period = ?", "", fil.Amount{}, 0).
+			Update("pricing_mode", pricingModeFree).Error
+		if err != nil {
+			return err
+		}
+		if err := db.Model(&models.Dataset{}).Where("pricing_mode = ?", "").Update("pricing_mode", pricingModeFixed).Error; err != nil {
+			return err
+		}
+		if err := db.Model(&models.Purchase{}).Where("pricing_mode = ? AND price_atto = ?", "", fil.Amount{}).Update("pricing_mode", pricingModeFree).Error; err != nil {
+			return err
+		}
+		return db.Model(&models.Purchase{}).Where("pricing_mode = ?", "").Update("pricing_mode", pricingModeFixed).Error
+	})
+}
@@ -0,0 +1,184 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/repo"
+	"github.com/OB1Company/filehive/repo/models"
+	"github.com/OB1Company/filehive/repo/search"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_PricingModes(t *testing.T) {
+	for _, test := range []struct {
+		dataset models.Dataset
+		valid   bool
+	}{
+		{models.Dataset{PricingMode: pricingModeFree}, true},
+		{models.Dataset{PricingMode: pricingModeFree, Price: fil.MustParseAmount("1")}, false},
+		{models.Dataset{PricingMode: pricingModeFixed, Price: fil.MustParseAmount("1")}, true},
+		{models.Dataset{PricingMode: pricingModeFixed}, false},
+		{models.Dataset{PricingMode: pricingModeFixed, SubscriptionPeriod: 30}, true},
+		{models.Dataset{PricingMode: pricingModeFixed, Price: fil.MustParseAmount("1"), MinimumPrice: fil.MustParseAmount("1")}, false},
+		{models.Dataset{PricingMode: pricingModePWYW, Price: fil.MustParseAmount("2"), MinimumPrice: fil.MustParseAmount("1")}, true},
+		{models.Dataset{PricingMode: pricingModePWYW, Price: fil.MustParseAmount("1"), MinimumPrice: fil.MustParseAmount("2")}, false},
+		{models.Dataset{PricingMode: pricingModePWYW, MinimumPrice: fil.MustParseAmount("-1")}, false},
+		{models.Dataset{PricingMode: "auction", Price: fil.MustParseAmount("1")}, false},
+	} {
+		if valid := validPricing(test.dataset); valid != test.valid {
+			t.Errorf("%s %s/%s: expected valid %t", test.dataset.PricingMode, test.dataset.Price, test.dataset.MinimumPrice, test.valid)
+		}
+	}
+
+	db, err := repo.NewDatabase("", repo.Dialect("memory"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wbe := fil.NewMockWalletBackend()
+	addresses := make([]string, 4)
+	for i := range addresses {
+		addresses[i], err = wbe.NewAddress("")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	feeAddr, buyerAddr, sellerAddr, poorAddr := addresses[0], addresses[1], addresses[2], addresses[3]
+	wbe.GenerateToAddress(buyerAddr, fil.MustParseAmount("10").AttoFIL())
+
+	server := &FileHiveServer{
+		db:              db,
+		walletBackend:   wbe,
+		filecoinAddress: feeAddr,
+		feePercent:      defaultFeePercent,
+		staticFileDir:   testStaticDir,
+		searchIndex:     search.NewMemoryIndex(),
+	}
+
+	err = db.Update(func(db *gorm.DB) error {
+		for _, user := range []models.User{
+			{ID: "buyer", Email: "buyer@ob1.io", FilecoinAddress: buyerAddr},
+			{ID: "seller", Email: "seller@ob1.io", FilecoinAddress: sellerAddr},
+			{ID: "poor", Email: "poor@ob1.io", FilecoinAddress: poorAddr},
+			{ID: "admin", Email: "admin@ob1.io", Admin: true},
+		} {
+			if err := db.Save(&user).Error; err != nil {
+				return err
+			}
+		}
+		for _, dataset := range []models.Dataset{
+			{ID: "free", UserID: "seller", PricingMode: pricingModeFree},
+			{ID: "fixed", UserID: "seller", PricingMode: pricingModeFixed, Price: fil.MustParseAmount("2")},
+			{ID: "pwyw", UserID: "seller", PricingMode: pricingModePWYW, Price: fil.MustParseAmount("3"), MinimumPrice: fil.MustParseAmount("1")},
+			{ID: "feed", UserID: "seller", PricingMode: pricingModeFixed, SubscriptionPrice: fil.MustParseAmount("1"), SubscriptionPeriod: 30},
+		} {
+			if err := db.Save(&dataset).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	purchase := func(email, datasetID, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/purchase/"+datasetID, bytes.NewBufferString(body))
+		server.handlePOSTPurchase(w, r.WithContext(context.WithValue(r.Context(), "email", email)))
+		return w
+	}
+
+	// The seller can't be emailed in tests but a purchase that went through
+	// still succeeds once it has been recorded.
+	for _, test := range []struct {
+		email     string
+		datasetID string
+		body      string
+		err       error
+	}{
+		{email: "poor@ob1.io", datasetID: "free"},
+		{email: "poor@ob1.io", datasetID: "free"},
+		{email: "poor@ob1.io", datasetID: "fixed", err: ErrInsuffientFunds},
+		{email: "buyer@ob1.io", datasetID: "fixed", body: `{"amount": 0.5}`},
+		{email: "buyer@ob1.io", datasetID: "pwyw", body: `{"amount": 0.5}`, err: ErrBelowMinimumPrice},
+		{email: "buyer@ob1.io", datasetID: "pwyw", body: `{"amount": "1.5"}`},
+		{email: "buyer@ob1.io", datasetID: "pwyw"},
+		{email: "buyer@ob1.io", datasetID: "feed", err: ErrNotForSale},
+	} {
+		w := purchase(test.email, test.datasetID, test.body)
+		if test.err == nil {
+			if w.Code != http.StatusOK {
+				t.Errorf("%s %s %s: expected success, got %d: %s", test.email, test.datasetID, test.body, w.Code, w.Body.String())
+			}
+			continue
+		}
+		if w.Body.String() != string(errorReturn(test.err)) {
+			t.Errorf("%s %s %s: expected %s, got %d: %s", test.email, test.datasetID, test.body, test.err, w.Code, w.Body.String())
+		}
+	}
+
+	// Claiming the free dataset moved no money and was only recorded once.
+	txs, err := wbe.Transactions(poorAddr, -1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 0 {
+		t.Errorf("Expected no transactions for the free claim, got %d", len(txs))
+	}
+	balance, err := wbe.Balance(buyerAddr, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fil.NewAmount(balance).Cmp(fil.MustParseAmount("3.5")) != 0 {
+		t.Errorf("Expected buyer to have paid 6.5, balance is %s", fil.NewAmount(balance))
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/admin/sales", nil)
+	server.handleGETAdminSales(w, r.WithContext(context.WithValue(r.Context(), "email", "admin@ob1.io")))
+	var report struct {
+		Sales        []models.Purchase            `json:"sales"`
+		PricingModes map[string]pricingModeTotals `json:"pricingModes"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]pricingModeTotals{
+		pricingModeFree:  {Count: 1, Volume: fil.MustParseAmount("0")},
+		pricingModeFixed: {Count: 1, Volume: fil.MustParseAmount("2")},
+		pricingModePWYW:  {Count: 2, Volume: fil.MustParseAmount("4.5")},
+	}
+	for mode, totals := range expected {
+		if got := report.PricingModes[mode]; got.Count != totals.Count || got.Volume.Cmp(totals.Volume) != 0 {
+			t.Errorf("%s: expected totals %+v, got %+v", mode, totals, report.PricingModes[mode])
+		}
+	}
+	for _, sale := range report.Sales {
+		if sale.DatasetID == "free" && (sale.PricingMode != pricingModeFree || !sale.Price.IsZero() || sale.Txid != "" || sale.Status != purchaseStatusComplete) {
+			t.Errorf("Unexpected free claim %+v", sale)
+		}
+	}
+	// Editing a listing without giving a price leaves the price as it was.
+	for _, id := range []string{"fixed", "pwyw"} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPatch, "/api/v1/dataset", bytes.NewBufferString(`{"id": "`+id+`", "title": "Renamed"}`))
+		server.handlePATCHDataset(w, r.WithContext(context.WithValue(r.Context(), "email", "seller@ob1.io")))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected the dataset to be updated, got %d: %s", id, w.Code, w.Body.String())
+			continue
+		}
+		var dataset models.Dataset
+		if err := db.View(func(db *gorm.DB) error { return db.Where("id = ?", id).First(&dataset).Error }); err != nil {
+			t.Fatal(err)
+		}
+		if dataset.Title != "Renamed" || dataset.Price.IsZero() {
+			t.Errorf("%s: expected the title to change and the price to be kept, got %s at %s", id, dataset.Title, dataset.Price)
+		}
+	}
+}
@@ -0,0 +1,444 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/OB1Company/filehive/emails"
+	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/repo/models"
+	"github.com/nfnt/resize"
+	"gorm.io/gorm"
+	"image/jpeg"
+	"math/big"
+	"os"
+	"path"
+	"time"
+)
+
+// Purchase states. A purchase is saved as pending before any money moves
+// and each step records its state once it is done, so a purchase can be
+// picked up again after a crash or a retried request without paying twice.
+const (
+	purchaseStatePending  = "pending"
+	purchaseStateFeeSent  = "fee_sent"
+	purchaseStatePaid     = "paid"
+	purchaseStateRecorded = "recorded"
+	purchaseStateNotified = "notified"
+	purchaseStateFailed   = "failed"
+)
+
+// unpaidPurchaseStates are the states of purchases the buyer has not paid
+// for. These are left out of purchase and sales listings.
+var unpaidPurchaseStates = []string{purchaseStatePending, purchaseStateFeeSent, purchaseStateFailed}
+
+// newPurchase saves a pending purchase of the dataset at the given price.
+// The amounts and addresses are fixed here so that resuming the purchase
+// pays the same parties the same amounts.
+func (s *FileHiveServer) newPurchase(buyer, seller models.User, dataset models.Dataset, price fil.Amount, idempotencyKey string) (models.Purchase, error) {
+	purchaseID, err := makeID()
+	if err != nil {
+		return models.Purchase{}, err
+	}
+
+	amt := price.AttoFIL()
+	purchase := models.Purchase{
+		UserID:           buyer.ID,
+		SellerID:         seller.ID,
+		Username:         seller.Name,
+		ImageFilename:    dataset.ImageFilename,
+		Price:            price,
+		PricingMode:      datasetPricingMode(dataset),
+		FiatCurrency:     dataset.FiatCurrency,
+		FiatPrice:        dataset.FiatPrice,
+		ShortDescription: dataset.ShortDescription,
+		FileType:         dataset.FileType,
+		DatasetID:        dataset.ID,
+		Version:          dataset.Version,
+		Title:            dataset.Title,
+		Timestamp:        time.Now(),
+		ID:               purchaseID,
+		State:            purchaseStatePending,
+		Amount:           amt.String(),
+	}
+	fee, err := s.quoteFee(seller.ID, amt, purchase.Timestamp)
+	if err != nil {
+		return models.Purchase{}, err
+	}
+	purchase.FeeAmount = fee.Amount.String()
+	purchase.FeePercent = fee.Percent
+	purchase.FeeMinimum = fee.Minimum
+	purchase.FeePolicy = fee.Policy
+	if idempotencyKey != "" {
+		purchase.IdempotencyKey = &idempotencyKey
+	}
+
+	if amt.Sign() == 0 {
+		// Nothing is paid for a claim so there is nothing to hold.
+		purchase.Status = purchaseStatusComplete
+	} else if s.escrowAddress != "" {
+		// Hold the full price in escrow. The seller is paid once the
+		// dataset has been delivered.
+		purchase.Status = purchaseStatusEscrowed
+		purchase.ReleaseAfter = time.Now().Add(s.disputeWindow)
+		purchase.PaymentAddress = s.escrowAddress
+	} else {
+		purchase.Status = purchaseStatusComplete
+		purchase.PaymentAddress = seller.FilecoinAddress
+		purchase.FeeAddress = s.filecoinAddress
+	}
+
+	err = s.db.Update(func(db *gorm.DB) error {
+		return db.Create(&purchase).Error
+	})
+	return purchase, err
+}
+
+// processPurchase moves a purchase through each of its remaining states.
+// The returned purchase is in the last state that was reached.
+func (s *FileHiveServer) processPurchase(purchase models.Purchase) (models.Purchase, error) {
+	var buyer, seller models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		if err := db.Where("id = ?", purchase.UserID).First(&buyer).Error; err != nil {
+			return err
+		}
+		return db.Where("id = ?", purchase.SellerID).First(&seller).Error
+	})
+	if err != nil {
+		return purchase, err
+	}
+
+	amt, feeAmount, err := purchaseAmounts(purchase)
+	if err != nil {
+		return purchase, err
+	}
+
+	if purchase.State == purchaseStatePending {
+		if feeAmount.Sign() > 0 {
+			purchase.FeeTxid, err = s.sendPurchasePayment(purchase, buyer, purchase.FeeAddress, feeAmount, transactionTypeFee)
+			if err != nil {
+				return purchase, s.failPurchase(purchase, err)
+			}
+		}
+		if err := s.setPurchaseState(&purchase, purchaseStateFeeSent, map[string]interface{}{"fee_txid": purchase.FeeTxid}); err != nil {
+			return purchase, err
+		}
+	}
+
+	if purchase.State == purchaseStateFeeSent {
+		if amt.Sign() > 0 {
+			purchase.Txid, err = s.sendPurchasePayment(purchase, buyer, purchase.PaymentAddress, new(big.Int).Sub(amt, feeAmount), transactionTypePurchase)
+			if err != nil {
+				if feeAmount.Sign() == 0 {
+					return purchase, s.failPurchase(purchase, err)
+				}
+				return purchase, err
+			}
+		}
+		if err := s.setPurchaseState(&purchase, purchaseStatePaid, map[string]interface{}{"txid": purchase.Txid}); err != nil {
+			return purchase, err
+		}
+	}
+
+	if purchase.State == purchaseStatePaid {
+		err := s.db.Update(func(db *gorm.DB) error {
+			if err := db.Model(&models.Dataset{}).Where("id = ?", purchase.DatasetID).Update("purchases", gorm.Expr("purchases + ?", 1)).Error; err != nil {
+				return err
+			}
+			if err := db.Model(&purchase).Update("state", purchaseStateRecorded).Error; err != nil {
+				return err
+			}
+			recorded := purchase
+			recorded.State = purchaseStateRecorded
+			return queueWebhookEvent(db, purchase.SellerID, webhookEventPurchaseCreated, recorded)
+		})
+		if err != nil {
+			return purchase, err
+		}
+		purchase.State = purchaseStateRecorded
+		s.wakeWebhooks()
+	}
+
+	if purchase.State == purchaseStateRecorded {
+		if err := s.notifySale(purchase, buyer, seller); err != nil {
+			return purchase, err
+		}
+		if err := s.setPurchaseState(&purchase, purchaseStateNotified, nil); err != nil {
+			return purchase, err
+		}
+	}
+
+	return purchase, nil
+}
+
+// purchaseAmounts returns the price of a purchase and the part of it the
+// buyer sends to the fee address. Escrowed purchases are paid into escrow
+// in full and the fee is taken when the escrow is released.
+func purchaseAmounts(purchase models.Purchase) (*big.Int, *big.Int, error) {
+	amt, ok := new(big.Int).SetString(purchase.Amount, 10)
+	if !ok {
+		return nil, nil, fmt.Errorf("purchase %s has an invalid amount", purchase.ID)
+	}
+	if purchase.FeeAddress == "" {
+		return amt, new(big.Int), nil
+	}
+	feeAmount, ok := new(big.Int).SetString(purchase.FeeAmount, 10)
+	if !ok {
+		return nil, nil, fmt.Errorf("purchase %s has an invalid fee amount", purchase.ID)
+	}
+	return amt, feeAmount, nil
+}
+
+func (s *FileHiveServer) setPurchaseState(purchase *models.Purchase, state string, fields map[string]interface{}) error {
+	if fields == nil {
+		fields = make(map[string]interface{})
+	}
+	fields["state"] = state
+	err := s.db.Update(func(db *gorm.DB) error {
+		return db.Model(purchase).Updates(fields).Error
+	})
+	if err != nil {
+		return err
+	}
+	purchase.State = state
+	return nil
+}
+
+// failPurchase marks a purchase that failed before the buyer paid anything
+// as failed and returns the cause.
+func (s *FileHiveServer) failPurchase(purchase models.Purchase, cause error) error {
+	if err := s.setPurchaseState(&purchase, purchaseStateFailed, nil); err != nil {
+		log.Errorf("Error marking purchase %s failed: %s", purchase.ID, err)
+	}
+	return cause
+}
+
+// sendPurchasePayment sends amt from the buyer to the given address unless
+// a matching transaction was already made for the purchase, in which case
+// its ID is returned. That covers a crash between the send and recording
+// its state.
+func (s *FileHiveServer) sendPurchasePayment(purchase models.Purchase, buyer models.User, to string, amt *big.Int, txType string) (string, error) {
+	return s.sendPayment(purchase.ID, purchase.CreatedAt, buyer, to, amt, txType)
+}
+
+// sendPayment sends amt from the buyer to the given address for the
+// purchase or subscription charge with the given ID, unless the payment is
+// found to have been sent already.
+func (s *FileHiveServer) sendPayment(paymentID string, since time.Time, buyer models.User, to string, amt *big.Int, txType string) (string, error) {
+	txid, sent, err := s.findPayment(paymentID, since, buyer.FilecoinAddress, to, amt, txType)
+	if err != nil {
+		return "", err
+	}
+	if sent {
+		return txid, nil
+	}
+	return s.sendFIL(buyer.FilecoinAddress, to, amt, buyer.PowergateToken, txType, paymentID)
+}
+
+// findPayment reports whether the payment of the given type for the
+// purchase or subscription charge was already sent, and its transaction ID
+// if it is known. The ledger is checked first as it survives restarts,
+// then the wallet for a matching transaction made since the payment was
+// created, which is recorded in the ledger if found. A send that was
+// interrupted part way through and can't be found in the wallet returns
+// ErrPaymentUnknown rather than risk paying twice.
+func (s *FileHiveServer) findPayment(paymentID string, since time.Time, from, to string, amt *big.Int, txType string) (string, bool, error) {
+	var (
+		recorded models.Transaction
+		found    bool
+	)
+	err := s.db.View(func(db *gorm.DB) error {
+		err := db.Where("purchase_id = ? AND type = ?", paymentID, txType).Order("created_at DESC").First(&recorded).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		found = err == nil
+		return err
+	})
+	if err != nil {
+		return "", false, err
+	}
+	if found && recorded.Status != transactionStatusSending {
+		return recorded.Txid, true, nil
+	}
+
+	txid, err := s.findPaymentTransaction(since, from, to, amt)
+	if err != nil {
+		return "", false, err
+	}
+	if txid == "" {
+		if found {
+			log.Errorf("Payment %s may have been sent as transaction %s but it can't be found in the wallet", paymentID, recorded.ID)
+			return "", false, ErrPaymentUnknown
+		}
+		return "", false, nil
+	}
+
+	log.Infof("Found existing transaction %s for payment %s", txid, paymentID)
+	tx := models.Transaction{
+		ID:          recorded.ID,
+		Txid:        txid,
+		FromAddress: from,
+		ToAddress:   to,
+		Amount:      amt.String(),
+		Type:        txType,
+		PurchaseID:  paymentID,
+		Timestamp:   s.transactionTime(from, txid),
+	}
+	if found {
+		err = s.completeTransaction(tx)
+	} else {
+		err = s.recordTransaction(tx)
+	}
+	if err != nil {
+		log.Errorf("Error recording transaction %s for payment %s: %s", txid, paymentID, err)
+	}
+	return txid, true, nil
+}
+
+// findPaymentTransaction looks for a transaction in the wallet made since
+// the given time that matches the payment and is not recorded against
+// another purchase or subscription charge.
+func (s *FileHiveServer) findPaymentTransaction(since time.Time, from, to string, amt *big.Int) (string, error) {
+	txs, err := s.walletBackend.Transactions(from, -1, 0)
+	if err != nil {
+		return "", err
+	}
+	for _, tx := range txs {
+		if tx.ID == "" || tx.From != from || tx.To != to || tx.Amount.Cmp(amt) != 0 || tx.Timestamp.Before(since) {
+			continue
+		}
+		var purchases, charges int64
+		err := s.db.View(func(db *gorm.DB) error {
+			if err := db.Model(&models.Purchase{}).Where("txid = ? OR fee_txid = ? OR release_txid = ? OR refund_txid = ?", tx.ID, tx.ID, tx.ID, tx.ID).Count(&purchases).Error; err != nil {
+				return err
+			}
+			return db.Model(&models.SubscriptionCharge{}).Where("txid = ? OR fee_txid = ?", tx.ID, tx.ID).Count(&charges).Error
+		})
+		if err != nil {
+			return "", err
+		}
+		if purchases == 0 && charges == 0 {
+			return tx.ID, nil
+		}
+	}
+	return "", nil
+}
+
+// recoverPurchases finishes the purchases that were left part way through
+// when the server stopped. Purchases that had not taken any money yet are
+// failed rather than charging a buyer who is no longer waiting on them.
+func (s *FileHiveServer) recoverPurchases() error {
+	var purchases []models.Purchase
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("state IN ?", []string{purchaseStatePending, purchaseStateFeeSent, purchaseStatePaid, purchaseStateRecorded}).Find(&purchases).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, purchase := range purchases {
+		if !s.purchaseLocks.acquire(purchase.ID) {
+			continue
+		}
+		if err := s.recoverPurchase(purchase); err != nil {
+			log.Errorf("Error recovering purchase %s: %s", purchase.ID, err)
+		}
+		s.purchaseLocks.release(purchase.ID)
+	}
+	return nil
+}
+
+func (s *FileHiveServer) recoverPurchase(purchase models.Purchase) error {
+	if purchase.State == purchaseStatePending {
+		var buyer models.User
+		err := s.db.View(func(db *gorm.DB) error {
+			return db.Where("id = ?", purchase.UserID).First(&buyer).Error
+		})
+		if err != nil {
+			return err
+		}
+
+		amt, feeAmount, err := purchaseAmounts(purchase)
+		if err != nil {
+			return err
+		}
+
+		to, sent, txType := purchase.PaymentAddress, new(big.Int).Sub(amt, feeAmount), transactionTypePurchase
+		if feeAmount.Sign() > 0 {
+			to, sent, txType = purchase.FeeAddress, feeAmount, transactionTypeFee
+		}
+		_, paid, err := s.findPayment(purchase.ID, purchase.CreatedAt, buyer.FilecoinAddress, to, sent, txType)
+		if err != nil {
+			return err
+		}
+		if !paid {
+			log.Warningf("Failing purchase %s which was never paid", purchase.ID)
+			return s.setPurchaseState(&purchase, purchaseStateFailed, nil)
+		}
+	}
+
+	purchase, err := s.processPurchase(purchase)
+	if err != nil {
+		return err
+	}
+	log.Infof("Recovered purchase %s in state %s", purchase.ID, purchase.State)
+	return nil
+}
+
+// saleEmail renders the email letting the seller know about the purchase.
+// The dataset's thumbnail is left out if its image can't be loaded.
+func (s *FileHiveServer) saleEmail(locale string, purchase models.Purchase, buyer, seller models.User) (emails.Email, error) {
+	thumb, err := s.datasetThumbnail(purchase.ImageFilename)
+	if err != nil {
+		log.Warningf("Error loading thumbnail for sale email for purchase %s: %s", purchase.ID, err)
+	}
+
+	return emails.Sale(locale, emails.SaleData{
+		Site:               emails.Site{Domain: s.mailDomain},
+		SellerName:         seller.Name,
+		BuyerName:          buyer.Name,
+		BuyerEmail:         buyer.Email,
+		DatasetTitle:       purchase.Title,
+		DatasetDescription: purchase.ShortDescription,
+		Price:              purchase.Price.String() + " FIL",
+		OrderID:            purchase.ID,
+		Timestamp:          purchase.Timestamp,
+		Image:              thumb,
+	})
+}
+
+// datasetThumbnail returns a small JPEG of a dataset image for emails.
+func (s *FileHiveServer) datasetThumbnail(filename string) ([]byte, error) {
+	f, err := os.Open(path.Join(s.staticFileDir, "images", filename))
+	if err != nil {
+		return nil, ErrImageNotFound
+	}
+	defer f.Close()
+
+	image, err := jpeg.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	thumb := resize.Thumbnail(48, 48, image, resize.NearestNeighbor)
+	buf := new(bytes.Buffer)
+	if err := jpeg.Encode(buf, thumb, &jpeg.Options{Quality: 100}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// loadIdempotentPurchase returns the buyer's purchase made with the given
+// idempotency key, if there is one.
+func (s *FileHiveServer) loadIdempotentPurchase(userID, key string) (models.Purchase, bool, error) {
+	var purchase models.Purchase
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("user_id = ? AND idempotency_key = ?", userID, key).First(&purchase).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return purchase, false, nil
+	} else if err != nil {
+		return purchase, false, err
+	}
+	return purchase, true, nil
+}
@@ -0,0 +1,220 @@
+package app
+
+import (
+	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/repo"
+	"github.com/OB1Company/filehive/repo/models"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func Test_PurchaseStateMachine(t *testing.T) {
+	db, err := repo.NewDatabase("", repo.Dialect("memory"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wbe := fil.NewMockWalletBackend()
+	addresses := make([]string, 3)
+	for i := range addresses {
+		addresses[i], err = wbe.NewAddress("")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	feeAddr, buyerAddr, sellerAddr := addresses[0], addresses[1], addresses[2]
+	wbe.GenerateToAddress(buyerAddr, fil.MustParseAmount("10").AttoFIL())
+
+	server := &FileHiveServer{
+		db:              db,
+		walletBackend:   wbe,
+		filecoinAddress: feeAddr,
+		feePercent:      defaultFeePercent,
+		staticFileDir:   testStaticDir,
+	}
+
+	buyer := models.User{ID: "buyer", Email: "buyer@ob1.io", FilecoinAddress: buyerAddr}
+	seller := models.User{ID: "seller", Email: "seller@ob1.io", FilecoinAddress: sellerAddr}
+	dataset := models.Dataset{ID: "dataset", UserID: "seller", Price: fil.MustParseAmount("2")}
+	err = db.Update(func(db *gorm.DB) error {
+		if err := db.Save(&buyer).Error; err != nil {
+			return err
+		}
+		if err := db.Save(&seller).Error; err != nil {
+			return err
+		}
+		return db.Save(&dataset).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkBalance := func(name, addr string, expected string) {
+		balance, err := wbe.Balance(addr, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fil.NewAmount(balance).Cmp(fil.MustParseAmount(expected)) != 0 {
+			t.Errorf("Expected %s balance of %s, got %s", name, expected, fil.NewAmount(balance))
+		}
+	}
+	loadDataset := func() models.Dataset {
+		var d models.Dataset
+		if err := db.View(func(db *gorm.DB) error { return db.Where("id = ?", dataset.ID).First(&d).Error }); err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	// Processing a purchase again must not charge the buyer twice, and a
+	// crash before it is marked notified must not notify the seller twice.
+	purchase, err := server.newPurchase(buyer, seller, dataset, dataset.Price, "key1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		purchase, err = server.processPurchase(purchase)
+		if err != nil || purchase.State != purchaseStateNotified {
+			t.Fatalf("Expected purchase to be notified, got %s, %v", purchase.State, err)
+		}
+		purchase.State = purchaseStateRecorded
+	}
+	if purchase.Txid == "" || purchase.FeeTxid == "" {
+		t.Error("Expected transaction IDs to be recorded")
+	}
+	var notifications int64
+	err = db.View(func(db *gorm.DB) error {
+		return db.Model(&models.Notification{}).Where("user_id = ? AND purchase_id = ?", seller.ID, purchase.ID).Count(&notifications).Error
+	})
+	if err != nil || notifications != 1 {
+		t.Errorf("Expected the seller to be notified once, got %d, %v", notifications, err)
+	}
+	checkBalance("buyer", buyerAddr, "8")
+	checkBalance("seller", sellerAddr, "1.9")
+	checkBalance("fee", feeAddr, "0.1")
+	if loadDataset().Purchases != 1 {
+		t.Error("Expected dataset purchase count to be incremented once")
+	}
+
+	if _, err := server.newPurchase(buyer, seller, dataset, dataset.Price, "key1"); err == nil {
+		t.Error("Expected idempotency key to be unique per buyer")
+	}
+	if existing, ok, err := server.loadIdempotentPurchase(buyer.ID, "key1"); err != nil || !ok || existing.ID != purchase.ID {
+		t.Errorf("Expected to load purchase by idempotency key, got %v, %v", ok, err)
+	}
+
+	// A purchase whose fee was sent before a crash adopts the fee
+	// transaction instead of sending it again.
+	crashed, err := server.newPurchase(buyer, seller, dataset, dataset.Price, "key2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feeTxid, err := wbe.Send(buyerAddr, feeAddr, fil.MustParseAmount("0.1").AttoFIL(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.recoverPurchases(); err != nil {
+		t.Fatal(err)
+	}
+	var recovered models.Purchase
+	if err := db.View(func(db *gorm.DB) error { return db.Where("id = ?", crashed.ID).First(&recovered).Error }); err != nil {
+		t.Fatal(err)
+	}
+	if recovered.State != purchaseStateNotified || recovered.FeeTxid != feeTxid {
+		t.Errorf("Expected recovered purchase to be notified with fee %s, got %s with fee %s", feeTxid, recovered.State, recovered.FeeTxid)
+	}
+	checkBalance("buyer", buyerAddr, "6")
+	checkBalance("seller", sellerAddr, "3.8")
+	checkBalance("fee", feeAddr, "0.2")
+
+	// A purchase that never took any money is failed on recovery.
+	abandoned, err := server.newPurchase(buyer, seller, dataset, dataset.Price, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.recoverPurchases(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.View(func(db *gorm.DB) error { return db.Where("id = ?", abandoned.ID).First(&abandoned).Error }); err != nil {
+		t.Fatal(err)
+	}
+	if abandoned.State != purchaseStateFailed {
+		t.Errorf("Expected abandoned purchase to fail, got %s", abandoned.State)
+	}
+	checkBalance("buyer", buyerAddr, "6")
+
+	// A buyer without the funds is not charged.
+	expensive := dataset
+	expensive.Price = fil.MustParseAmount("200")
+	purchase, err = server.newPurchase(buyer, seller, expensive, expensive.Price, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.processPurchase(purchase); err != fil.ErrInsuffientFunds {
+		t.Errorf("Expected insufficient funds, got %v", err)
+	}
+	if err := db.View(func(db *gorm.DB) error { return db.Where("id = ?", purchase.ID).First(&purchase).Error }); err != nil {
+		t.Fatal(err)
+	}
+	if purchase.State != purchaseStateFailed {
+		t.Errorf("Expected purchase to fail, got %s", purchase.State)
+	}
+	if loadDataset().Purchases != 2 {
+		t.Error("Expected failed purchases not to be counted")
+	}
+
+	// Payments recorded in the ledger are not sent again even when the
+	// wallet has no record of them, as with Powergate after a restart.
+	recorded, err := server.newPurchase(buyer, seller, dataset, dataset.Price, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(db *gorm.DB) error {
+		if err := db.Create(&models.Transaction{ID: "fee1", FromAddress: buyerAddr, ToAddress: feeAddr, Amount: fil.MustParseAmount("0.1").AttoFIL().String(), Type: transactionTypeFee, PurchaseID: recorded.ID, Status: transactionStatusUnknown}).Error; err != nil {
+			return err
+		}
+		return db.Create(&models.Transaction{ID: "purchase1", FromAddress: buyerAddr, ToAddress: sellerAddr, Amount: fil.MustParseAmount("1.9").AttoFIL().String(), Type: transactionTypePurchase, PurchaseID: recorded.ID, Status: transactionStatusUnknown}).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.recoverPurchases(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.View(func(db *gorm.DB) error { return db.Where("id = ?", recorded.ID).First(&recorded).Error }); err != nil {
+		t.Fatal(err)
+	}
+	if recorded.State != purchaseStateNotified {
+		t.Errorf("Expected recorded purchase to be notified, got %s", recorded.State)
+	}
+	checkBalance("buyer", buyerAddr, "6")
+	checkBalance("seller", sellerAddr, "3.8")
+	checkBalance("fee", feeAddr, "0.2")
+
+	// A send interrupted before its outcome was recorded is neither sent
+	// again nor failed when the wallet has no record of it.
+	interrupted, err := server.newPurchase(buyer, seller, dataset, dataset.Price, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(db *gorm.DB) error {
+		return db.Create(&models.Transaction{ID: "fee2", FromAddress: buyerAddr, ToAddress: feeAddr, Amount: fil.MustParseAmount("0.1").AttoFIL().String(), Type: transactionTypeFee, PurchaseID: interrupted.ID, Status: transactionStatusSending}).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.recoverPurchases(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.View(func(db *gorm.DB) error { return db.Where("id = ?", interrupted.ID).First(&interrupted).Error }); err != nil {
+		t.Fatal(err)
+	}
+	if interrupted.State != purchaseStatePending {
+		t.Errorf("Expected interrupted purchase to stay pending, got %s", interrupted.State)
+	}
+	if _, err := server.sendPurchasePayment(interrupted, buyer, interrupted.FeeAddress, fil.MustParseAmount("0.1").AttoFIL(), transactionTypeFee); err != ErrPaymentUnknown {
+		t.Errorf("Expected the payment to be refused, got %v", err)
+	}
+	checkBalance("buyer", buyerAddr, "6")
+	checkBalance("fee", feeAddr, "0.2")
+}
@@ -0,0 +1,241 @@
+package app
+
+import (
+	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/repo/models"
+	"gorm.io/gorm"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultRateRefreshInterval = time.Minute * 10
+
+// rateStaleIntervals is how many refresh intervals exchange rates are used
+// for after they were last fetched. Older rates are not used at all.
+const rateStaleIntervals = 3
+
+// fiatDecimals is the number of decimal places fiat amounts are given with.
+const fiatDecimals = 2
+
+// exchangeRates holds the price of one FIL in each currency as of the last
+// refresh.
+type exchangeRates struct {
+	mtx       sync.RWMutex
+	rates     map[string]*big.Rat
+	updatedAt time.Time
+}
+
+// fiatValue is an amount of FIL converted to a fiat currency at the rate
+// fetched at UpdatedAt.
+type fiatValue struct {
+	Currency  string    `json:"currency"`
+	Amount    string    `json:"amount"`
+	Rate      string    `json:"rate"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// parseFiatPrice parses a positive fiat price with at most two decimal
+// places, such as "9.99".
+func parseFiatPrice(s string) (*big.Rat, error) {
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	if whole == "" || len(frac) > fiatDecimals || strings.Trim(whole+frac, "0123456789") != "" {
+		return nil, ErrInvalidPricing
+	}
+	price, ok := new(big.Rat).SetString(s)
+	if !ok || price.Sign() <= 0 {
+		return nil, ErrInvalidPricing
+	}
+	return price, nil
+}
+
+// trackExchangeRates refreshes the exchange rates until the server is
+// shut down.
+func (s *FileHiveServer) trackExchangeRates() {
+	ticker := time.NewTicker(s.rateInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.refreshRates(); err != nil {
+			log.Errorf("Error refreshing exchange rates: %s", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-s.shutdown:
+			return
+		}
+	}
+}
+
+// refreshRates fetches the exchange rates from the provider and reprices
+// the datasets pegged to a fiat price.
+func (s *FileHiveServer) refreshRates() error {
+	rates, err := s.rateProvider.Rates()
+	if err != nil {
+		return err
+	}
+
+	s.rates.mtx.Lock()
+	s.rates.rates = rates
+	s.rates.updatedAt = time.Now()
+	s.rates.mtx.Unlock()
+
+	return s.repricePeggedDatasets()
+}
+
+// currentRates returns the exchange rates and when they were fetched. The
+// map is replaced rather than modified on refresh so it is safe to read.
+func (s *FileHiveServer) currentRates() (map[string]*big.Rat, time.Time, error) {
+	interval := s.rateInterval
+	if interval == 0 {
+		interval = defaultRateRefreshInterval
+	}
+
+	s.rates.mtx.RLock()
+	defer s.rates.mtx.RUnlock()
+
+	if s.rates.rates == nil || time.Since(s.rates.updatedAt) > interval*rateStaleIntervals {
+		return nil, time.Time{}, ErrRatesUnavailable
+	}
+	return s.rates.rates, s.rates.updatedAt, nil
+}
+
+// exchangeRate returns the price of one FIL in the currency and when it was
+// fetched.
+func (s *FileHiveServer) exchangeRate(currency string) (*big.Rat, time.Time, error) {
+	rates, updatedAt, err := s.currentRates()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	rate, ok := rates[currency]
+	if !ok {
+		return nil, time.Time{}, ErrUnknownCurrency
+	}
+	return new(big.Rat).Set(rate), updatedAt, nil
+}
+
+// fiatValue converts amt to the currency. It returns nil if no currency was
+// asked for or there is no current rate for it.
+func (s *FileHiveServer) fiatValue(amt fil.Amount, currency string) *fiatValue {
+	if currency == "" {
+		return nil
+	}
+	rate, updatedAt, err := s.exchangeRate(currency)
+	if err != nil {
+		return nil
+	}
+	return &fiatValue{
+		Currency:  currency,
+		Amount:    amt.Fiat(rate).FloatString(fiatDecimals),
+		Rate:      rate.FloatString(8),
+		UpdatedAt: updatedAt,
+	}
+}
+
+// requestedCurrency returns the currency the client asked for prices to be
+// converted to with the currency query parameter.
+func requestedCurrency(r *http.Request) string {
+	return strings.ToUpper(r.URL.Query().Get("currency"))
+}
+
+// pegPrice sets the price of a dataset pegged to a fiat price to the amount
+// of FIL it is currently worth. Datasets that are not pegged are left as
+// they are.
+func (s *FileHiveServer) pegPrice(dataset *models.Dataset) error {
+	if dataset.FiatCurrency == "" {
+		return nil
+	}
+	fiat, err := parseFiatPrice(dataset.FiatPrice)
+	if err != nil {
+		return err
+	}
+	rate, _, err := s.exchangeRate(dataset.FiatCurrency)
+	if err != nil {
+		return err
+	}
+	dataset.Price = fil.AmountFromFiat(fiat, rate)
+	return nil
+}
+
+// repricePeggedDatasets converts the prices of the pegged datasets at the
+// current rates so that listings, sorting and price filters follow the
+// exchange rate.
+func (s *FileHiveServer) repricePeggedDatasets() error {
+	var datasets []models.Dataset
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("fiat_currency <> ''").Find(&datasets).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, dataset := range datasets {
+		price := dataset.Price
+		if err := s.pegPrice(&dataset); err != nil {
+			log.Errorf("Error repricing dataset %s: %s", dataset.ID, err)
+			continue
+		}
+		if dataset.Price.Cmp(price) == 0 {
+			continue
+		}
+		err := s.db.Update(func(db *gorm.DB) error {
+			return db.Model(&models.Dataset{}).Where("id = ?", dataset.ID).Update("price_atto", dataset.Price).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// datasetResponse is a dataset along with its prices in the currency the
+// client asked for.
+type datasetResponse struct {
+	models.Dataset
+	PriceFiat             *fiatValue `json:"priceFiat,omitempty"`
+	SubscriptionPriceFiat *fiatValue `json:"subscriptionPriceFiat,omitempty"`
+}
+
+func (s *FileHiveServer) newDatasetResponse(dataset models.Dataset, currency string) datasetResponse {
+	resp := datasetResponse{
+		Dataset:   dataset,
+		PriceFiat: s.fiatValue(dataset.Price, currency),
+	}
+	if dataset.SubscriptionPeriod > 0 {
+		resp.SubscriptionPriceFiat = s.fiatValue(dataset.SubscriptionPrice, currency)
+	}
+	return resp
+}
+
+// purchaseResponse is a purchase along with its price in the currency the
+// client asked for at the current rate.
+type purchaseResponse struct {
+	models.Purchase
+	PriceFiat *fiatValue `json:"priceFiat,omitempty"`
+}
+
+func (s *FileHiveServer) handleGETRates(w http.ResponseWriter, r *http.Request) {
+	rates, updatedAt, err := s.currentRates()
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusServiceUnavailable)
+		return
+	}
+
+	prices := make(map[string]string, len(rates))
+	for currency, rate := range rates {
+		prices[currency] = rate.FloatString(8)
+	}
+
+	sanitizedJSONResponse(w, struct {
+		Rates     map[string]string `json:"rates"`
+		UpdatedAt time.Time         `json:"updatedAt"`
+	}{
+		Rates:     prices,
+		UpdatedAt: updatedAt,
+	})
+}
@@ -0,0 +1,172 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/repo"
+	"github.com/OB1Company/filehive/repo/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_FiatPrices(t *testing.T) {
+	for _, test := range []struct {
+		dataset models.Dataset
+		valid   bool
+	}{
+		{models.Dataset{PricingMode: pricingModeFixed, Price: fil.MustParseAmount("1"), FiatCurrency: "USD", FiatPrice: "5"}, true},
+		{models.Dataset{PricingMode: pricingModeFixed, Price: fil.MustParseAmount("1"), FiatPrice: "5"}, false},
+		{models.Dataset{PricingMode: pricingModeFixed, Price: fil.MustParseAmount("1"), FiatCurrency: "dollars", FiatPrice: "5"}, false},
+		{models.Dataset{PricingMode: pricingModePWYW, Price: fil.MustParseAmount("1"), FiatCurrency: "USD", FiatPrice: "5"}, false},
+	} {
+		if valid := validPricing(test.dataset); valid != test.valid {
+			t.Errorf("%s %s %s: expected valid %t", test.dataset.PricingMode, test.dataset.FiatCurrency, test.dataset.FiatPrice, test.valid)
+		}
+	}
+
+	db, err := repo.NewDatabase("", repo.Dialect("memory"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wbe := fil.NewMockWalletBackend()
+	feeAddr, err := wbe.NewAddress("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buyerAddr, err := wbe.NewAddress("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wbe.GenerateToAddress(buyerAddr, fil.MustParseAmount("10").AttoFIL())
+
+	provider, err := fil.NewStaticRateProvider(map[string]string{"USD": "5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &FileHiveServer{
+		db:              db,
+		walletBackend:   wbe,
+		filecoinAddress: feeAddr,
+		feePercent:      defaultFeePercent,
+		staticFileDir:   testStaticDir,
+		rateProvider:    provider,
+		rateInterval:    time.Minute,
+	}
+
+	err = db.Update(func(db *gorm.DB) error {
+		for _, user := range []models.User{
+			{ID: "buyer", Email: "buyer@ob1.io", FilecoinAddress: buyerAddr},
+			{ID: "seller", Email: "seller@ob1.io"},
+		} {
+			if err := db.Save(&user).Error; err != nil {
+				return err
+			}
+		}
+		for _, dataset := range []models.Dataset{
+			{ID: "pegged", UserID: "seller", PricingMode: pricingModeFixed, Price: fil.MustParseAmount("1"), FiatCurrency: "USD", FiatPrice: "10"},
+			{ID: "yen", UserID: "seller", PricingMode: pricingModeFixed, Price: fil.MustParseAmount("1"), FiatCurrency: "JPY", FiatPrice: "500"},
+			{ID: "fil", UserID: "seller", PricingMode: pricingModeFixed, Price: fil.MustParseAmount("3")},
+		} {
+			if err := db.Save(&dataset).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Prices aren't converted before the rates have been fetched.
+	w := httptest.NewRecorder()
+	server.handleGETRates(w, httptest.NewRequest(http.MethodGet, "/api/v1/rates", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected rates to be unavailable, got %d", w.Code)
+	}
+
+	if err := server.refreshRates(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Refreshing reprices the pegged dataset and leaves the others alone.
+	prices := map[string]string{"pegged": "2", "yen": "1", "fil": "3"}
+	for id, price := range prices {
+		var dataset models.Dataset
+		err := db.View(func(db *gorm.DB) error {
+			return db.Where("id = ?", id).First(&dataset).Error
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dataset.Price.String() != price {
+			t.Errorf("%s: expected price %s, got %s", id, price, dataset.Price)
+		}
+	}
+
+	getDataset := func(id, currency string) datasetResponse {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/dataset/"+id+"?currency="+currency, nil)
+		server.handleGETDataset(w, mux.SetURLVars(r, map[string]string{"id": id}))
+		var resp datasetResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	resp := getDataset("fil", "usd")
+	if resp.PriceFiat == nil || resp.PriceFiat.Amount != "15.00" || resp.PriceFiat.Currency != "USD" {
+		t.Errorf("Expected a price of 15.00 USD, got %+v", resp.PriceFiat)
+	}
+	if resp := getDataset("fil", ""); resp.PriceFiat != nil {
+		t.Errorf("Expected no fiat price without a currency, got %+v", resp.PriceFiat)
+	}
+	if resp := getDataset("fil", "JPY"); resp.PriceFiat != nil {
+		t.Errorf("Expected no fiat price for an unknown currency, got %+v", resp.PriceFiat)
+	}
+
+	purchase := func(datasetID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/purchase/"+datasetID, nil)
+		server.handlePOSTPurchase(w, r.WithContext(context.WithValue(r.Context(), "email", "buyer@ob1.io")))
+		return w
+	}
+
+	// The pegged price is converted at the rate when it is bought.
+	provider, err = fil.NewStaticRateProvider(map[string]string{"USD": "4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.rates.rates, _ = provider.Rates()
+	if w := purchase("pegged"); w.Code != http.StatusOK {
+		t.Fatalf("Expected purchase to go through, got %d: %s", w.Code, w.Body.String())
+	}
+	var bought models.Purchase
+	err = db.View(func(db *gorm.DB) error {
+		return db.Where("dataset_id = ?", "pegged").First(&bought).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bought.Price.String() != "2.5" || bought.FiatCurrency != "USD" || bought.FiatPrice != "10" {
+		t.Errorf("Expected 2.5 FIL for 10 USD, got %s FIL for %s %s", bought.Price, bought.FiatPrice, bought.FiatCurrency)
+	}
+
+	if w := purchase("yen"); w.Body.String() != string(errorReturn(ErrUnknownCurrency)) {
+		t.Errorf("Expected unknown currency, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Stale rates aren't used to price a sale.
+	server.rates.updatedAt = time.Now().Add(-time.Hour)
+	if w := purchase("pegged"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected rates to be unavailable, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp := getDataset("fil", "USD"); resp.PriceFiat != nil {
+		t.Errorf("Expected no fiat price with stale rates, got %+v", resp.PriceFiat)
+	}
+}
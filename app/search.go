@@ -0,0 +1,171 @@
+package app
+
+import (
+	"errors"
+	"github.com/OB1Company/filehive/repo/models"
+	"github.com/OB1Company/filehive/repo/search"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+)
+
+const searchPageSize = 10
+
+// listedDatasets selects the datasets that are listed by sellers whose
+// accounts are enabled.
+func listedDatasets(db *gorm.DB) *gorm.DB {
+	return db.Model(&models.Dataset{}).
+		Joins("left join users on datasets.user_id = users.id").
+		Where("datasets.delisted = ? AND users.disabled = ?", false, false)
+}
+
+// rebuildSearchIndex replaces the contents of the search index with the
+// listed datasets.
+func (s *FileHiveServer) rebuildSearchIndex() error {
+	var datasets []models.Dataset
+	err := s.db.View(func(db *gorm.DB) error {
+		return listedDatasets(db).Find(&datasets).Error
+	})
+	if err != nil {
+		return err
+	}
+	return s.searchIndex.Rebuild(datasets)
+}
+
+// updateSearchIndex indexes the datasets if they are listed and removes
+// them from the index otherwise. It should be called whenever a dataset
+// is created, edited, delisted or relisted.
+func (s *FileHiveServer) updateSearchIndex(datasetIDs ...string) {
+	if len(datasetIDs) == 0 {
+		return
+	}
+
+	var datasets []models.Dataset
+	err := s.db.View(func(db *gorm.DB) error {
+		return listedDatasets(db).Where("datasets.id IN ?", datasetIDs).Find(&datasets).Error
+	})
+	if err != nil {
+		log.Errorf("Error loading datasets to index: %s", err)
+		return
+	}
+
+	listed := make(map[string]bool)
+	for _, dataset := range datasets {
+		listed[dataset.ID] = true
+		if err := s.searchIndex.Index(dataset); err != nil {
+			log.Errorf("Error indexing dataset %s: %s", dataset.ID, err)
+		}
+	}
+	for _, id := range datasetIDs {
+		if listed[id] {
+			continue
+		}
+		if err := s.searchIndex.Remove(id); err != nil {
+			log.Errorf("Error removing dataset %s from search index: %s", id, err)
+		}
+	}
+}
+
+// searchResult is a dataset that matched a search along with its
+// relevance and an excerpt with the matching words highlighted.
+type searchResult struct {
+	models.Dataset
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+}
+
+func (s *FileHiveServer) handleGETSearch(w http.ResponseWriter, r *http.Request) {
+	var (
+		page int
+		err  error
+	)
+	pageStr := r.URL.Query().Get("page")
+	if pageStr != "" {
+		page, err = strconv.Atoi(pageStr)
+		if err != nil || page < 0 {
+			http.Error(w, wrapError(ErrInvalidOption), http.StatusBadRequest)
+			return
+		}
+	}
+
+	scope, err := s.requestCategoryScope(r)
+	if errors.Is(err, ErrCategoryNotFound) {
+		http.Error(w, wrapError(err), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	inScope := func(db *gorm.DB) *gorm.DB {
+		tx := listedDatasets(db)
+		if scope != nil {
+			tx = tx.Where("datasets.category_id IN ?", scope)
+		}
+		return tx
+	}
+
+	results := []searchResult{}
+	hits, count, err := s.searchIndex.Search(r.URL.Query().Get("query"), search.Filter{Categories: scope}, searchPageSize, page*searchPageSize)
+	if errors.Is(err, search.ErrEmptyQuery) {
+		// Without any search terms every listed dataset matches, newest
+		// first.
+		var datasets []models.Dataset
+		err = s.db.View(func(db *gorm.DB) error {
+			if err := inScope(db).Count(&count).Error; err != nil {
+				return err
+			}
+			if err := inScope(db).Order("datasets.created_at desc").Offset(page * searchPageSize).Limit(searchPageSize).Find(&datasets).Error; err != nil {
+				return err
+			}
+			return loadDatasetTags(db, datasets)
+		})
+		for _, dataset := range datasets {
+			results = append(results, searchResult{Dataset: dataset})
+		}
+	} else if err == nil && len(hits) > 0 {
+		ids := make([]string, 0, len(hits))
+		for _, hit := range hits {
+			ids = append(ids, hit.DatasetID)
+		}
+		var datasets []models.Dataset
+		err = s.db.View(func(db *gorm.DB) error {
+			if err := inScope(db).Where("datasets.id IN ?", ids).Find(&datasets).Error; err != nil {
+				return err
+			}
+			return loadDatasetTags(db, datasets)
+		})
+		byID := make(map[string]models.Dataset)
+		for _, dataset := range datasets {
+			byID[dataset.ID] = dataset
+		}
+		for _, hit := range hits {
+			dataset, ok := byID[hit.DatasetID]
+			if !ok {
+				continue
+			}
+			results = append(results, searchResult{
+				Dataset: dataset,
+				Score:   hit.Score,
+				Snippet: hit.Snippet,
+			})
+		}
+	}
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	pages := int((count + searchPageSize - 1) / searchPageSize)
+	if pages == 0 {
+		pages = 1
+	}
+	sanitizedJSONResponse(w, struct {
+		Pages    int            `json:"pages"`
+		Page     int            `json:"page"`
+		Datasets []searchResult `json:"datasets"`
+	}{
+		Pages:    pages,
+		Page:     page,
+		Datasets: results,
+	})
+}
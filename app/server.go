@@ -6,7 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/mail"
 	"github.com/OB1Company/filehive/repo"
+	"github.com/OB1Company/filehive/repo/search"
+	"github.com/filecoin-project/go-address"
 	"github.com/gorilla/csrf"
 	"github.com/gorilla/mux"
 	"github.com/multiformats/go-multihash"
@@ -17,6 +20,8 @@ import (
 	"os"
 	"path"
 	"regexp"
+	"sync"
+	"time"
 )
 
 var log = logging.MustGetLogger("APP")
@@ -28,12 +33,35 @@ type FileHiveServer struct {
 	filecoinBackend fil.FilecoinBackend
 	filecoinAddress string
 	staticFileDir   string
+	dataDir         string
 	listener        net.Listener
 	handler         http.Handler
 	jwtKey          []byte
 	domain          string
-	mailgunKey      string
+	mailer          mail.Mailer
+	outboxWake      chan struct{}
+	webhookWake     chan struct{}
 	mailDomain      string
+	maxUploadSize   int64
+	uploadLocks     idLocks
+	purchaseLocks   idLocks
+	walletMtx       sync.RWMutex
+	jobPollInterval time.Duration
+	maxJobRetries   int
+	escrowAddress   string
+	escrowToken     string
+	disputeWindow   time.Duration
+	gracePeriod     time.Duration
+	cacheSize       int64
+	cache           *contentCache
+	cacheMtx        sync.Mutex
+	feePercent      float64
+	minimumFee      fil.Amount
+	lowBalance      fil.Amount
+	searchIndex     search.Index
+	rateProvider    fil.RateProvider
+	rateInterval    time.Duration
+	rates           exchangeRates
 	shutdown        chan struct{}
 
 	testMode bool
@@ -44,7 +72,7 @@ type FileHiveServer struct {
 
 // NewServer instantiates a new FileHiveServer with the provided options.
 func NewServer(listener net.Listener, db *repo.Database, staticFileDir string, walletBackend fil.WalletBackend, filecoinBackend fil.FilecoinBackend, opts ...Option) (*FileHiveServer, error) {
-	var options Options
+	options := Options{FeePercent: defaultFeePercent, CacheSize: defaultCacheSize}
 	if err := options.Apply(opts...); err != nil {
 		return nil, err
 	}
@@ -67,6 +95,40 @@ func NewServer(listener net.Listener, db *repo.Database, staticFileDir string, w
 		}
 	}
 
+	if options.DataDir == "" {
+		options.DataDir = staticFileDir
+	}
+
+	// Without a mailer email goes out through Mailgun if it is configured
+	// and is otherwise written to a maildir in the data directory.
+	if options.Mailer == nil {
+		if options.MailgunKey != "" {
+			options.Mailer = mail.NewMailgunMailer(options.MailDomain, options.MailgunKey)
+		} else {
+			mailer, err := mail.NewFileMailer(path.Join(options.DataDir, "mail"))
+			if err != nil {
+				return nil, err
+			}
+			options.Mailer = mailer
+		}
+	}
+
+	if options.JobPollInterval == 0 {
+		options.JobPollInterval = defaultJobPollInterval
+	}
+
+	if options.DisputeWindow == 0 {
+		options.DisputeWindow = defaultDisputeWindow
+	}
+
+	if options.GracePeriod == 0 {
+		options.GracePeriod = defaultGracePeriod
+	}
+
+	if options.RateRefreshInterval == 0 {
+		options.RateRefreshInterval = defaultRateRefreshInterval
+	}
+
 	if options.JWTKey == nil {
 		jwtKey := make([]byte, 32)
 		rand.Read(jwtKey)
@@ -85,18 +147,48 @@ func NewServer(listener net.Listener, db *repo.Database, staticFileDir string, w
 			filecoinAddress: options.FilecoinAddress,
 			listener:        listener,
 			staticFileDir:   staticFileDir,
+			dataDir:         options.DataDir,
 			useSSL:          options.UseSSL,
 			sslCert:         options.SSLCert,
 			sslKey:          options.SSLKey,
 			jwtKey:          options.JWTKey,
 			domain:          options.Domain,
-			mailgunKey:      options.MailgunKey,
+			mailer:          options.Mailer,
+			outboxWake:      make(chan struct{}, 1),
+			webhookWake:     make(chan struct{}, 1),
 			mailDomain:      options.MailDomain,
+			maxUploadSize:   options.MaxUploadSize,
+			jobPollInterval: options.JobPollInterval,
+			maxJobRetries:   options.MaxJobRetries,
+			escrowAddress:   options.EscrowAddress,
+			escrowToken:     options.EscrowToken,
+			disputeWindow:   options.DisputeWindow,
+			gracePeriod:     options.GracePeriod,
+			cacheSize:       options.CacheSize,
+			feePercent:      options.FeePercent,
+			minimumFee:      options.MinimumFee,
+			lowBalance:      options.LowBalance,
+			searchIndex:     search.NewIndex(db),
+			rateProvider:    options.RateProvider,
+			rateInterval:    options.RateRefreshInterval,
 			shutdown:        make(chan struct{}),
 		}
 		topMux = http.NewServeMux()
 	)
 
+	if err := s.backfillDatasetVersions(); err != nil {
+		return nil, err
+	}
+	if err := s.backfillPricingModes(); err != nil {
+		return nil, err
+	}
+	if err := s.rebuildSearchIndex(); err != nil {
+		return nil, err
+	}
+	if _, err := s.contentCache(); err != nil {
+		return nil, err
+	}
+
 	r := s.newV1Router()
 
 	csrfKey := make([]byte, 32)
@@ -140,6 +232,24 @@ func (s *FileHiveServer) Close() error {
 
 // Serve begins listening on the configured address.
 func (s *FileHiveServer) Serve() error {
+	go func() {
+		if err := s.recoverPurchases(); err != nil {
+			log.Errorf("Error recovering purchases: %s", err)
+		}
+	}()
+	go s.trackStorageJobs()
+	go s.scanWallets()
+	go s.watchTransactions()
+	go s.renewSubscriptions()
+	go s.runOutbox()
+	go s.runWebhooks()
+	if s.escrowAddress != "" {
+		go s.settleEscrowedPurchases()
+	}
+	if s.rateProvider != nil {
+		go s.trackExchangeRates()
+	}
+
 	var err error
 	if s.useSSL {
 		err = http.ServeTLS(s.listener, s.handler, s.sslCert, s.sslKey)
@@ -162,9 +272,16 @@ func (s *FileHiveServer) newV1Router() *mux.Router {
 	r.HandleFunc("/api/v1/login", s.handlePOSTLogin).Methods("POST")
 	r.HandleFunc("/api/v1/image/{filename}", s.handleGETImage).Methods("GET")
 	r.HandleFunc("/api/v1/dataset/{id}", s.handleGETDataset).Methods("GET")
+	r.HandleFunc("/api/v1/dataset/{id}/versions", s.handleGETDatasetVersions).Methods("GET")
+	r.HandleFunc("/api/v1/dataset/{id}/versions/diff", s.handleGETDatasetVersionDiff).Methods("GET")
 	r.HandleFunc("/api/v1/latest", s.handleGETRecent).Methods("GET")
 	r.HandleFunc("/api/v1/trending", s.handleGETTrending).Methods("GET")
 	r.HandleFunc("/api/v1/search", s.handleGETSearch).Methods("GET")
+	r.HandleFunc("/api/v1/datasets/browse", s.handleGETBrowse).Methods("GET")
+	r.HandleFunc("/api/v1/categories", s.handleGETCategories).Methods("GET")
+	r.HandleFunc("/api/v1/tags", s.handleGETTags).Methods("GET")
+	r.HandleFunc("/api/v1/rates", s.handleGETRates).Methods("GET")
+	r.HandleFunc("/api/v1/download/link/{id}/{expires}/{sig}", s.handleGETSignedDownload).Methods("GET")
 	r.HandleFunc("/api/v1/confirm", s.handleGETConfirm).Methods("GET")
 	r.HandleFunc("/api/v1/passwordreset", s.handleGETPasswordReset).Methods("GET")
 	r.HandleFunc("/api/v1/passwordreset", s.handlePOSTPasswordReset).Methods("POST")
@@ -186,17 +303,60 @@ func (s *FileHiveServer) newV1Router() *mux.Router {
 	subRouter.HandleFunc("/wallet/balance", s.handleGETWalletBalance).Methods("GET")
 	subRouter.HandleFunc("/wallet/send", s.handlePOSTWalletSend).Methods("POST")
 	subRouter.HandleFunc("/wallet/transactions", s.handleGETWalletTransactions).Methods("GET")
+	subRouter.HandleFunc("/wallet/transactions/{txid}", s.handleGETWalletTransaction).Methods("GET")
 	subRouter.HandleFunc("/dataset", s.handlePOSTDataset).Methods("POST")
+	subRouter.HandleFunc("/upload", s.handlePOSTUpload).Methods("POST")
+	subRouter.HandleFunc("/upload/{id}", s.handleHEADUpload).Methods("HEAD")
+	subRouter.HandleFunc("/upload/{id}", s.handlePATCHUpload).Methods("PATCH")
+	subRouter.HandleFunc("/upload/{id}", s.handleDELETEUpload).Methods("DELETE")
+	subRouter.HandleFunc("/upload/{id}/finalize", s.handlePOSTUploadFinalize).Methods("POST")
 	subRouter.HandleFunc("/delist/{id}", s.handleGETDelist).Methods("GET")
 	subRouter.HandleFunc("/relist/{id}", s.handleGETRelist).Methods("GET")
 	subRouter.HandleFunc("/dataset", s.handlePATCHDataset).Methods("PATCH")
 	subRouter.HandleFunc("/datasets", s.handleGETDatasets).Methods("GET")
 	subRouter.HandleFunc("/datasetdeal/{id}", s.handleGETDatasetDeal).Methods("GET")
+	subRouter.HandleFunc("/dataset/{id}/versions", s.handlePOSTDatasetVersion).Methods("POST")
+	subRouter.HandleFunc("/dataset/{id}/jobs", s.handleGETDatasetJobs).Methods("GET")
+	subRouter.HandleFunc("/jobs", s.handleGETJobs).Methods("GET")
 	subRouter.HandleFunc("/purchase/{id}", s.handlePOSTPurchase).Methods("POST")
+	subRouter.HandleFunc("/purchase/{id}/dispute", s.handlePOSTPurchaseDispute).Methods("POST")
+	subRouter.HandleFunc("/purchase/{id}/link", s.handlePOSTDownloadLink).Methods("POST")
 	subRouter.HandleFunc("/purchases", s.handleGETPurchases).Methods("GET")
 	subRouter.HandleFunc("/purchased/{id}", s.handleGETPurchased).Methods("GET")
+	subRouter.HandleFunc("/subscribe/{id}", s.handlePOSTSubscribe).Methods("POST")
+	subRouter.HandleFunc("/subscriptions", s.handleGETSubscriptions).Methods("GET")
+	subRouter.HandleFunc("/subscriptions/{id}/cancel", s.handlePOSTSubscriptionCancel).Methods("POST")
 	subRouter.HandleFunc("/sales", s.handleGETSales).Methods("GET")
+	subRouter.HandleFunc("/notifications", s.handleGETNotifications).Methods("GET")
+	subRouter.HandleFunc("/notifications/read", s.handlePOSTNotificationsRead).Methods("POST")
+	subRouter.HandleFunc("/notifications/{id}/read", s.handlePOSTNotificationRead).Methods("POST")
+	subRouter.HandleFunc("/notifications/preferences", s.handleGETNotificationPreferences).Methods("GET")
+	subRouter.HandleFunc("/notifications/preferences", s.handlePUTNotificationPreferences).Methods("PUT")
+	subRouter.HandleFunc("/webhooks", s.handleGETWebhooks).Methods("GET")
+	subRouter.HandleFunc("/webhooks", s.handlePOSTWebhook).Methods("POST")
+	subRouter.HandleFunc("/webhooks/{id}", s.handleDELETEWebhook).Methods("DELETE")
+	subRouter.HandleFunc("/webhooks/{id}/deliveries", s.handleGETWebhookDeliveries).Methods("GET")
+	subRouter.HandleFunc("/webhooks/{id}/ping", s.handlePOSTWebhookPing).Methods("POST")
 	subRouter.HandleFunc("/admin/sales", s.handleGETAdminSales).Methods("GET")
+	subRouter.HandleFunc("/admin/purchases/{id}/release", s.handlePOSTAdminPurchaseRelease).Methods("POST")
+	subRouter.HandleFunc("/admin/purchases/{id}/refund", s.handlePOSTAdminPurchaseRefund).Methods("POST")
+	subRouter.HandleFunc("/admin/fees", s.handleGETAdminFees).Methods("GET")
+	subRouter.HandleFunc("/admin/fees/sellers/{id}", s.handlePUTAdminSellerFee).Methods("PUT")
+	subRouter.HandleFunc("/admin/fees/sellers/{id}", s.handleDELETEAdminSellerFee).Methods("DELETE")
+	subRouter.HandleFunc("/admin/fees/promotions", s.handlePOSTAdminFeePromotion).Methods("POST")
+	subRouter.HandleFunc("/admin/fees/promotions/{id}", s.handleDELETEAdminFeePromotion).Methods("DELETE")
+	subRouter.HandleFunc("/admin/categories", s.handlePOSTAdminCategory).Methods("POST")
+	subRouter.HandleFunc("/admin/categories/{id}", s.handlePATCHAdminCategory).Methods("PATCH")
+	subRouter.HandleFunc("/admin/categories/{id}", s.handleDELETEAdminCategory).Methods("DELETE")
+	subRouter.HandleFunc("/admin/jobs", s.handleGETAdminJobs).Methods("GET")
+	subRouter.HandleFunc("/admin/jobs/{id}/retry", s.handlePOSTAdminJobRetry).Methods("POST")
+	subRouter.HandleFunc("/admin/emails", s.handleGETAdminEmails).Methods("GET")
+	subRouter.HandleFunc("/admin/emails/{id}/resend", s.handlePOSTAdminEmailResend).Methods("POST")
+	subRouter.HandleFunc("/admin/emails/preview/{name}", s.handleGETAdminEmailPreview).Methods("GET")
+	subRouter.HandleFunc("/admin/cache", s.handleGETAdminCache).Methods("GET")
+	subRouter.HandleFunc("/admin/cache/{id}", s.handleDELETEAdminCacheEntry).Methods("DELETE")
+	subRouter.HandleFunc("/admin/cache/{id}/pin", s.handlePUTAdminCachePin).Methods("PUT")
+	subRouter.HandleFunc("/admin/cache/{id}/pin", s.handleDELETEAdminCachePin).Methods("DELETE")
 	subRouter.HandleFunc("/download/{cid}", s.handleGETDatasetFile).Methods("GET")
 	subRouter.HandleFunc("/users", s.handleGETUsers).Methods("GET")
 	subRouter.HandleFunc("/users/disable", s.handlePOSTDisableUsers).Methods("POST")
@@ -210,6 +370,7 @@ func (s *FileHiveServer) newV1Router() *mux.Router {
 // Options represents the filehive server options.
 type Options struct {
 	JWTKey          []byte
+	DataDir         string
 	Domain          string
 	UseSSL          bool
 	FilecoinAddress string
@@ -217,7 +378,22 @@ type Options struct {
 	SSLKey          string
 	TestMode        bool
 	MailgunKey      string
+	Mailer          mail.Mailer
 	MailDomain      string
+	MaxUploadSize   int64
+	JobPollInterval time.Duration
+	MaxJobRetries   int
+	EscrowAddress   string
+	EscrowToken     string
+	DisputeWindow   time.Duration
+	GracePeriod     time.Duration
+	CacheSize       int64
+	FeePercent      float64
+	MinimumFee      fil.Amount
+	LowBalance      fil.Amount
+	RateProvider    fil.RateProvider
+
+	RateRefreshInterval time.Duration
 }
 
 // Apply sets the provided options in the main options struct.
@@ -243,6 +419,15 @@ func JWTKey(key []byte) Option {
 	}
 }
 
+// DataDir sets the directory used to hold server data such as partially
+// uploaded files. Defaults to the static file dir.
+func DataDir(dataDir string) Option {
+	return func(o *Options) error {
+		o.DataDir = dataDir
+		return nil
+	}
+}
+
 // Domain sets the domain the server is running on.  Defaults to the current domain of the request
 // only (recommended).
 //
@@ -256,6 +441,8 @@ func Domain(domain string) Option {
 	}
 }
 
+// MailgunKey sets the Mailgun API key email is sent with when no Mailer is
+// set.
 func MailgunKey(mailgunKey string) Option {
 	return func(o *Options) error {
 		o.MailgunKey = mailgunKey
@@ -263,6 +450,14 @@ func MailgunKey(mailgunKey string) Option {
 	}
 }
 
+// Mailer sets how the server sends email.
+func Mailer(mailer mail.Mailer) Option {
+	return func(o *Options) error {
+		o.Mailer = mailer
+		return nil
+	}
+}
+
 func MailDomain(mailDomain string) Option {
 	return func(o *Options) error {
 		o.MailDomain = mailDomain
@@ -277,6 +472,158 @@ func FilecoinAddress(filecoinAddress string) Option {
 	}
 }
 
+// MaxUploadSize sets the maximum size in bytes of an uploaded dataset file.
+// Zero, the default, means there is no limit.
+func MaxUploadSize(maxUploadSize int64) Option {
+	return func(o *Options) error {
+		if maxUploadSize < 0 {
+			return errors.New("max upload size cannot be negative")
+		}
+		o.MaxUploadSize = maxUploadSize
+		return nil
+	}
+}
+
+// CacheSize sets the maximum size in bytes of the local cache of retrieved
+// datasets. Zero means there is no limit.
+func CacheSize(cacheSize int64) Option {
+	return func(o *Options) error {
+		if cacheSize < 0 {
+			return errors.New("cache size cannot be negative")
+		}
+		o.CacheSize = cacheSize
+		return nil
+	}
+}
+
+// EscrowAddress puts the server in escrow mode. Buyers pay into this
+// marketplace controlled address and the seller is only paid once the
+// dataset has been delivered.
+func EscrowAddress(escrowAddress string) Option {
+	return func(o *Options) error {
+		if _, err := address.NewFromString(escrowAddress); err != nil {
+			return ErrInvalidAddress
+		}
+		o.EscrowAddress = escrowAddress
+		return nil
+	}
+}
+
+// EscrowToken is the Powergate token used to send funds out of the
+// escrow address.
+func EscrowToken(escrowToken string) Option {
+	return func(o *Options) error {
+		o.EscrowToken = escrowToken
+		return nil
+	}
+}
+
+// DisputeWindow sets how long a buyer has to dispute an escrowed purchase
+// that has not been downloaded before the seller is paid. Defaults to
+// 72 hours.
+func DisputeWindow(window time.Duration) Option {
+	return func(o *Options) error {
+		if window <= 0 {
+			return errors.New("dispute window must be positive")
+		}
+		o.DisputeWindow = window
+		return nil
+	}
+}
+
+// SubscriptionGracePeriod sets how long a subscriber keeps access to a
+// dataset after a renewal charge fails. Defaults to 72 hours.
+func SubscriptionGracePeriod(period time.Duration) Option {
+	return func(o *Options) error {
+		if period <= 0 {
+			return errors.New("subscription grace period must be positive")
+		}
+		o.GracePeriod = period
+		return nil
+	}
+}
+
+// FeePercent sets the percentage of each sale kept by the marketplace.
+// Defaults to five percent.
+func FeePercent(percent float64) Option {
+	return func(o *Options) error {
+		if percent < 0 || percent > 100 {
+			return ErrInvalidFee
+		}
+		o.FeePercent = percent
+		return nil
+	}
+}
+
+// MinimumFee sets the smallest marketplace fee, in FIL, charged on a sale.
+func MinimumFee(fee fil.Amount) Option {
+	return func(o *Options) error {
+		if fee.Sign() < 0 {
+			return ErrInvalidFee
+		}
+		o.MinimumFee = fee
+		return nil
+	}
+}
+
+// LowBalanceThreshold sets the wallet balance, in FIL, below which a user
+// is notified that their balance is low. Zero disables the notification.
+func LowBalanceThreshold(threshold fil.Amount) Option {
+	return func(o *Options) error {
+		if threshold.Sign() < 0 {
+			return fil.ErrInvalidAmount
+		}
+		o.LowBalance = threshold
+		return nil
+	}
+}
+
+// RateProvider sets the source of the exchange rates used to show prices in
+// fiat currencies and to price datasets pegged to a fiat price. Without one
+// prices are only given in FIL.
+func RateProvider(provider fil.RateProvider) Option {
+	return func(o *Options) error {
+		o.RateProvider = provider
+		return nil
+	}
+}
+
+// RateRefreshInterval sets how often the exchange rates are refreshed.
+// Defaults to ten minutes.
+func RateRefreshInterval(interval time.Duration) Option {
+	return func(o *Options) error {
+		if interval <= 0 {
+			return errors.New("rate refresh interval must be positive")
+		}
+		o.RateRefreshInterval = interval
+		return nil
+	}
+}
+
+// JobPollInterval sets how often the status of unfinished storage jobs is
+// checked. Defaults to one minute.
+func JobPollInterval(interval time.Duration) Option {
+	return func(o *Options) error {
+		if interval <= 0 {
+			return errors.New("job poll interval must be positive")
+		}
+		o.JobPollInterval = interval
+		return nil
+	}
+}
+
+// MaxJobRetries sets how many times a failed storage job is retried before
+// it is flagged for an admin. Zero disables retries.
+func MaxJobRetries(maxJobRetries int) Option {
+	return func(o *Options) error {
+		if maxJobRetries < 0 {
+			return errors.New("max job retries cannot be negative")
+		}
+		o.MaxJobRetries = maxJobRetries
+		return nil
+	}
+}
+
 // TestMode option allows exposes an additional API
 // to generate mock coins.
 func TestMode(testMode bool) Option {
@@ -0,0 +1,592 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/repo/models"
+	"gorm.io/gorm"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultGracePeriod        = time.Hour * 72
+	subscriptionCheckInterval = time.Hour
+	maxSubscriptionPeriod     = 366
+)
+
+// Subscription statuses. A subscription is pending until its first period
+// has been paid and failed if that payment could not be made. Active
+// subscriptions are renewed at the end of each period and become past due
+// when a renewal can't be charged, keeping access until the grace period
+// runs out. Canceled subscriptions are not renewed and keep access until
+// the end of the period that was paid for.
+const (
+	subscriptionStatusPending  = "pending"
+	subscriptionStatusFailed   = "failed"
+	subscriptionStatusActive   = "active"
+	subscriptionStatusPastDue  = "past_due"
+	subscriptionStatusCanceled = "canceled"
+	subscriptionStatusExpired  = "expired"
+)
+
+// liveSubscriptionStatuses are the statuses of subscriptions that give
+// access to their dataset until GraceUntil.
+var liveSubscriptionStatuses = []string{subscriptionStatusActive, subscriptionStatusPastDue, subscriptionStatusCanceled}
+
+// validSubscriptionTerms returns whether a dataset may be offered with the
+// given subscription price and period. A zero period means the dataset is
+// not offered by subscription.
+func validSubscriptionTerms(price fil.Amount, period int) bool {
+	if price.Sign() < 0 || period < 0 || period > maxSubscriptionPeriod {
+		return false
+	}
+	return period > 0 || price.IsZero()
+}
+
+func subscriptionPeriod(days int) time.Duration {
+	return time.Hour * 24 * time.Duration(days)
+}
+
+// hasLiveSubscription returns whether the user has a subscription to the
+// dataset that currently gives access to it.
+func hasLiveSubscription(db *gorm.DB, userID, datasetID string) (bool, error) {
+	var count int64
+	err := db.Model(&models.Subscription{}).
+		Where("user_id = ? AND dataset_id = ? AND status IN ? AND grace_until > ?", userID, datasetID, liveSubscriptionStatuses, time.Now()).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// newSubscription saves a pending subscription to the dataset. The price
+// and period are fixed here so later changes to the listing only apply to
+// new subscribers.
+func (s *FileHiveServer) newSubscription(buyer models.User, dataset models.Dataset) (models.Subscription, error) {
+	id, err := makeID()
+	if err != nil {
+		return models.Subscription{}, err
+	}
+	now := time.Now()
+	sub := models.Subscription{
+		ID:         id,
+		UserID:     buyer.ID,
+		DatasetID:  dataset.ID,
+		SellerID:   dataset.UserID,
+		Title:      dataset.Title,
+		Price:      dataset.SubscriptionPrice,
+		Period:     dataset.SubscriptionPeriod,
+		Status:     subscriptionStatusPending,
+		StartsAt:   now,
+		PeriodEnd:  now,
+		GraceUntil: now,
+	}
+	err = s.db.Update(func(db *gorm.DB) error {
+		return db.Create(&sub).Error
+	})
+	return sub, err
+}
+
+// newSubscriptionCharge saves a pending charge for a period of the
+// subscription. Like a purchase, the amounts and addresses are fixed when
+// the charge is created.
+func (s *FileHiveServer) newSubscriptionCharge(sub models.Subscription, seller models.User, period int) (models.SubscriptionCharge, error) {
+	id, err := makeID()
+	if err != nil {
+		return models.SubscriptionCharge{}, err
+	}
+	amt := sub.Price.AttoFIL()
+	charge := models.SubscriptionCharge{
+		ID:             id,
+		SubscriptionID: sub.ID,
+		Period:         period,
+		State:          purchaseStatePending,
+		Amount:         amt.String(),
+		Price:          sub.Price,
+		PaymentAddress: seller.FilecoinAddress,
+		FeeAddress:     s.filecoinAddress,
+		Timestamp:      time.Now(),
+	}
+	fee, err := s.quoteFee(seller.ID, amt, charge.Timestamp)
+	if err != nil {
+		return models.SubscriptionCharge{}, err
+	}
+	charge.FeeAmount = fee.Amount.String()
+
+	err = s.db.Update(func(db *gorm.DB) error {
+		return db.Create(&charge).Error
+	})
+	return charge, err
+}
+
+// chargeAmounts returns the price of a subscription charge and the part of
+// it that goes to the fee address.
+func chargeAmounts(charge models.SubscriptionCharge) (*big.Int, *big.Int, error) {
+	amt, ok := new(big.Int).SetString(charge.Amount, 10)
+	if !ok {
+		return nil, nil, fmt.Errorf("subscription charge %s has an invalid amount", charge.ID)
+	}
+	if charge.FeeAddress == "" {
+		return amt, new(big.Int), nil
+	}
+	feeAmount, ok := new(big.Int).SetString(charge.FeeAmount, 10)
+	if !ok {
+		return nil, nil, fmt.Errorf("subscription charge %s has an invalid fee amount", charge.ID)
+	}
+	return amt, feeAmount, nil
+}
+
+func (s *FileHiveServer) setChargeState(charge *models.SubscriptionCharge, state string, fields map[string]interface{}) error {
+	if fields == nil {
+		fields = make(map[string]interface{})
+	}
+	fields["state"] = state
+	err := s.db.Update(func(db *gorm.DB) error {
+		return db.Model(charge).Updates(fields).Error
+	})
+	if err != nil {
+		return err
+	}
+	charge.State = state
+	return nil
+}
+
+// chargeSubscription charges the buyer for the next period of the
+// subscription and extends it once the charge is paid. An unfinished
+// charge for the period is resumed rather than starting a new one.
+// Subscriptions are paid straight to the seller as there is no single
+// delivery for an escrow to wait on. The returned charge is in the last
+// state that was reached.
+func (s *FileHiveServer) chargeSubscription(sub models.Subscription) (models.Subscription, models.SubscriptionCharge, error) {
+	var (
+		buyer, seller models.User
+		charge        models.SubscriptionCharge
+	)
+	period := sub.PeriodsPaid + 1
+	err := s.db.View(func(db *gorm.DB) error {
+		if err := db.Where("id = ?", sub.UserID).First(&buyer).Error; err != nil {
+			return err
+		}
+		if err := db.Where("id = ?", sub.SellerID).First(&seller).Error; err != nil {
+			return err
+		}
+		return db.Where("subscription_id = ? AND period = ? AND state <> ?", sub.ID, period, purchaseStateFailed).First(&charge).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) && buyer.ID != "" && seller.ID != "" {
+		charge, err = s.newSubscriptionCharge(sub, seller, period)
+	}
+	if err != nil {
+		return sub, charge, err
+	}
+
+	amt, feeAmount, err := chargeAmounts(charge)
+	if err != nil {
+		return sub, charge, err
+	}
+
+	if charge.State == purchaseStatePending {
+		if feeAmount.Sign() > 0 {
+			charge.FeeTxid, err = s.sendPayment(charge.ID, charge.CreatedAt, buyer, charge.FeeAddress, feeAmount, transactionTypeFee)
+			if err != nil {
+				return sub, charge, s.failSubscriptionCharge(&charge, err)
+			}
+		}
+		if err := s.setChargeState(&charge, purchaseStateFeeSent, map[string]interface{}{"fee_txid": charge.FeeTxid}); err != nil {
+			return sub, charge, err
+		}
+	}
+
+	if charge.State == purchaseStateFeeSent {
+		charge.Txid, err = s.sendPayment(charge.ID, charge.CreatedAt, buyer, charge.PaymentAddress, new(big.Int).Sub(amt, feeAmount), transactionTypeSubscription)
+		if err != nil {
+			if feeAmount.Sign() == 0 {
+				return sub, charge, s.failSubscriptionCharge(&charge, err)
+			}
+			return sub, charge, err
+		}
+		if err := s.setChargeState(&charge, purchaseStatePaid, map[string]interface{}{"txid": charge.Txid}); err != nil {
+			return sub, charge, err
+		}
+	}
+
+	sub, err = s.extendSubscription(sub, period)
+	return sub, charge, err
+}
+
+// failSubscriptionCharge marks a charge that failed before the buyer paid
+// anything as failed and returns the cause.
+func (s *FileHiveServer) failSubscriptionCharge(charge *models.SubscriptionCharge, cause error) error {
+	if err := s.setChargeState(charge, purchaseStateFailed, nil); err != nil {
+		log.Errorf("Error marking subscription charge %s failed: %s", charge.ID, err)
+	}
+	return cause
+}
+
+// extendSubscription moves the end of the subscription on by a period
+// after the given period has been paid for.
+func (s *FileHiveServer) extendSubscription(sub models.Subscription, period int) (models.Subscription, error) {
+	sub.PeriodEnd = sub.PeriodEnd.Add(subscriptionPeriod(sub.Period))
+	sub.GraceUntil = sub.PeriodEnd.Add(s.gracePeriod)
+	sub.PeriodsPaid = period
+	sub.FailedAttempts = 0
+	sub.LastError = ""
+	if sub.Status != subscriptionStatusCanceled {
+		sub.Status = subscriptionStatusActive
+	}
+	err := s.db.Update(func(db *gorm.DB) error {
+		return db.Model(&sub).Updates(map[string]interface{}{
+			"period_end":      sub.PeriodEnd,
+			"grace_until":     sub.GraceUntil,
+			"periods_paid":    sub.PeriodsPaid,
+			"failed_attempts": sub.FailedAttempts,
+			"last_error":      sub.LastError,
+			"status":          sub.Status,
+		}).Error
+	})
+	return sub, err
+}
+
+// renewSubscriptions periodically charges subscriptions that have come to
+// the end of their period until the server is shut down.
+func (s *FileHiveServer) renewSubscriptions() {
+	ticker := time.NewTicker(subscriptionCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.renewDueSubscriptions(); err != nil {
+			log.Errorf("Error renewing subscriptions: %s", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-s.shutdown:
+			return
+		}
+	}
+}
+
+// renewDueSubscriptions expires subscriptions that are past their grace
+// period, finishes first charges that were interrupted and renews
+// subscriptions whose period has ended. Expiring comes first so a past due
+// subscription isn't charged again once its grace period has run out.
+func (s *FileHiveServer) renewDueSubscriptions() error {
+	now := time.Now()
+	err := s.db.Update(func(db *gorm.DB) error {
+		return db.Model(&models.Subscription{}).
+			Where("status IN ? AND grace_until <= ?", []string{subscriptionStatusPastDue, subscriptionStatusCanceled}, now).
+			Update("status", subscriptionStatusExpired).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	var pending, due []models.Subscription
+	err = s.db.View(func(db *gorm.DB) error {
+		if err := db.Where("status = ?", subscriptionStatusPending).Find(&pending).Error; err != nil {
+			return err
+		}
+		return db.Where("status IN ? AND period_end <= ?", []string{subscriptionStatusActive, subscriptionStatusPastDue}, now).Find(&due).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range pending {
+		if !s.purchaseLocks.acquire(sub.ID) {
+			continue
+		}
+		if err := s.recoverSubscription(sub); err != nil {
+			log.Errorf("Error recovering subscription %s: %s", sub.ID, err)
+		}
+		s.purchaseLocks.release(sub.ID)
+	}
+
+	for _, sub := range due {
+		if !s.purchaseLocks.acquire(sub.ID) {
+			continue
+		}
+		if err := s.renewSubscription(sub); err != nil {
+			log.Warningf("Error renewing subscription %s: %s", sub.ID, err)
+		}
+		s.purchaseLocks.release(sub.ID)
+	}
+	return nil
+}
+
+// renewSubscription charges the next period of the subscription. If the
+// charge fails the subscription is marked past due and retried on the
+// next pass until its grace period runs out.
+func (s *FileHiveServer) renewSubscription(sub models.Subscription) error {
+	renewed, _, err := s.chargeSubscription(sub)
+	if err == nil {
+		log.Infof("Renewed subscription %s until %s", sub.ID, renewed.PeriodEnd)
+		return nil
+	}
+	updateErr := s.db.Update(func(db *gorm.DB) error {
+		return db.Model(&sub).Updates(map[string]interface{}{
+			"status":          subscriptionStatusPastDue,
+			"failed_attempts": sub.FailedAttempts + 1,
+			"last_error":      err.Error(),
+		}).Error
+	})
+	if updateErr != nil {
+		log.Errorf("Error marking subscription %s past due: %s", sub.ID, updateErr)
+	}
+	return err
+}
+
+// recoverSubscription finishes the first charge of a subscription that was
+// left part way through. If nothing was paid yet the subscription is failed
+// rather than charging a buyer who is no longer waiting on it.
+func (s *FileHiveServer) recoverSubscription(sub models.Subscription) error {
+	var (
+		buyer  models.User
+		charge models.SubscriptionCharge
+	)
+	err := s.db.View(func(db *gorm.DB) error {
+		if err := db.Where("id = ?", sub.UserID).First(&buyer).Error; err != nil {
+			return err
+		}
+		return db.Where("subscription_id = ? AND period = ? AND state <> ?", sub.ID, 1, purchaseStateFailed).First(&charge).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return s.failSubscription(sub, errors.New("first period was never charged"))
+	} else if err != nil {
+		return err
+	}
+
+	if charge.State == purchaseStatePending {
+		amt, feeAmount, err := chargeAmounts(charge)
+		if err != nil {
+			return err
+		}
+		to, sent := charge.PaymentAddress, new(big.Int).Sub(amt, feeAmount)
+		if feeAmount.Sign() > 0 {
+			to, sent = charge.FeeAddress, feeAmount
+		}
+		txid, err := s.findPaymentTransaction(charge.CreatedAt, buyer.FilecoinAddress, to, sent)
+		if err != nil {
+			return err
+		}
+		if txid == "" {
+			log.Warningf("Failing subscription %s which was never paid", sub.ID)
+			if err := s.setChargeState(&charge, purchaseStateFailed, nil); err != nil {
+				return err
+			}
+			return s.failSubscription(sub, errors.New("first period was never paid"))
+		}
+	}
+
+	sub, _, err = s.chargeSubscription(sub)
+	if err != nil {
+		return err
+	}
+	log.Infof("Recovered subscription %s in status %s", sub.ID, sub.Status)
+	return nil
+}
+
+// failSubscription marks a subscription whose first period could not be
+// paid as failed.
+func (s *FileHiveServer) failSubscription(sub models.Subscription, cause error) error {
+	return s.db.Update(func(db *gorm.DB) error {
+		return db.Model(&sub).Updates(map[string]interface{}{
+			"status":     subscriptionStatusFailed,
+			"last_error": cause.Error(),
+		}).Error
+	})
+}
+
+// handlePOSTSubscribe subscribes the user to a dataset and charges the
+// first period straight away.
+func (s *FileHiveServer) handlePOSTSubscribe(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	id := sp[len(sp)-1]
+
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var dataset models.Dataset
+	err = s.db.View(func(db *gorm.DB) error {
+		return db.Where("id = ?", id).First(&dataset).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrDatasetNotFound), http.StatusBadRequest)
+		return
+	}
+
+	if dataset.SubscriptionPeriod <= 0 || dataset.Delisted || dataset.UserID == user.ID {
+		http.Error(w, wrapError(ErrNotSubscribable), http.StatusBadRequest)
+		return
+	}
+
+	var existing int64
+	err = s.db.View(func(db *gorm.DB) error {
+		return db.Model(&models.Subscription{}).
+			Where("user_id = ? AND dataset_id = ? AND (status = ? OR (status IN ? AND grace_until > ?))", user.ID, dataset.ID, subscriptionStatusPending, liveSubscriptionStatuses, time.Now()).
+			Count(&existing).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	if existing > 0 {
+		http.Error(w, wrapError(ErrSubscribed), http.StatusConflict)
+		return
+	}
+
+	balance, err := s.walletBackend.Balance(user.FilecoinAddress, "")
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	if balance.Cmp(dataset.SubscriptionPrice.AttoFIL()) < 0 {
+		http.Error(w, wrapError(ErrInsuffientFunds), http.StatusBadRequest)
+		return
+	}
+
+	sub, err := s.newSubscription(user, dataset)
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	if !s.purchaseLocks.acquire(sub.ID) {
+		http.Error(w, wrapError(ErrPurchaseInProgress), http.StatusConflict)
+		return
+	}
+	defer s.purchaseLocks.release(sub.ID)
+
+	sub, charge, err := s.chargeSubscription(sub)
+	if err != nil {
+		// A charge that sent nothing fails the subscription. Anything else
+		// is left pending for the scheduler to finish.
+		if charge.ID == "" || charge.State == purchaseStateFailed {
+			if err := s.failSubscription(sub, err); err != nil {
+				log.Errorf("Error marking subscription %s failed: %s", sub.ID, err)
+			}
+		}
+		if errors.Is(err, fil.ErrInsuffientFunds) {
+			http.Error(w, wrapError(ErrInsuffientFunds), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	sanitizedJSONResponse(w, sub)
+}
+
+// handleGETSubscriptions returns the user's subscriptions, newest first.
+func (s *FileHiveServer) handleGETSubscriptions(w http.ResponseWriter, r *http.Request) {
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	subs := []models.Subscription{}
+	err = s.db.View(func(db *gorm.DB) error {
+		return db.Where("user_id = ? AND status NOT IN ?", user.ID, []string{subscriptionStatusPending, subscriptionStatusFailed}).
+			Order("starts_at DESC").Find(&subs).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	sanitizedJSONResponse(w, struct {
+		Subscriptions []models.Subscription `json:"subscriptions"`
+	}{
+		Subscriptions: subs,
+	})
+}
+
+// handlePOSTSubscriptionCancel stops a subscription from renewing. The
+// subscriber keeps access until the end of the period they paid for.
+func (s *FileHiveServer) handlePOSTSubscriptionCancel(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	id := sp[len(sp)-2]
+
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var (
+		user models.User
+		sub  models.Subscription
+	)
+	err := s.db.View(func(db *gorm.DB) error {
+		if err := db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error; err != nil {
+			return ErrInvalidCredentials
+		}
+		if err := db.Where("id = ? AND user_id = ?", id, user.ID).First(&sub).Error; err != nil {
+			return ErrSubNotFound
+		}
+		return nil
+	})
+	if errors.Is(err, ErrInvalidCredentials) {
+		http.Error(w, wrapError(err), http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		http.Error(w, wrapError(err), http.StatusNotFound)
+		return
+	}
+
+	if sub.Status != subscriptionStatusActive && sub.Status != subscriptionStatusPastDue {
+		http.Error(w, wrapError(ErrSubNotFound), http.StatusNotFound)
+		return
+	}
+
+	if !s.purchaseLocks.acquire(sub.ID) {
+		http.Error(w, wrapError(ErrPurchaseInProgress), http.StatusConflict)
+		return
+	}
+	defer s.purchaseLocks.release(sub.ID)
+
+	sub.Status = subscriptionStatusCanceled
+	sub.CanceledAt = time.Now()
+	sub.GraceUntil = sub.PeriodEnd
+	err = s.db.Update(func(db *gorm.DB) error {
+		return db.Model(&sub).Updates(map[string]interface{}{
+			"status":      sub.Status,
+			"canceled_at": sub.CanceledAt,
+			"grace_until": sub.GraceUntil,
+		}).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	sanitizedJSONResponse(w, sub)
+}
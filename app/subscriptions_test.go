@@ -0,0 +1,225 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/repo"
+	"github.com/OB1Company/filehive/repo/models"
+	"github.com/filecoin-project/go-address"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Subscriptions(t *testing.T) {
+	db, err := repo.NewDatabase("", repo.Dialect("memory"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filesDir := path.Join(testStaticDir, "files")
+	defer os.RemoveAll(testStaticDir)
+
+	filBackend, err := fil.NewMockFilecoinBackend(filesDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wbe := fil.NewMockWalletBackend()
+	addresses := make([]string, 4)
+	for i := range addresses {
+		addresses[i], err = wbe.NewAddress("")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	feeAddr, buyerAddr, sellerAddr, otherAddr := addresses[0], addresses[1], addresses[2], addresses[3]
+	wbe.GenerateToAddress(buyerAddr, fil.MustParseAmount("5").AttoFIL())
+
+	server := &FileHiveServer{
+		db:              db,
+		walletBackend:   wbe,
+		filecoinBackend: filBackend,
+		filecoinAddress: feeAddr,
+		feePercent:      defaultFeePercent,
+		gracePeriod:     time.Hour,
+		staticFileDir:   testStaticDir,
+		dataDir:         testStaticDir,
+	}
+
+	jobID, cid, _, err := filBackend.Store(strings.NewReader("daily prices"), address.Undef, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Update(func(db *gorm.DB) error {
+		for _, user := range []models.User{
+			{ID: "buyer", Email: "buyer@ob1.io", FilecoinAddress: buyerAddr},
+			{ID: "seller", Email: "seller@ob1.io", FilecoinAddress: sellerAddr},
+			{ID: "other", Email: "other@ob1.io", FilecoinAddress: otherAddr},
+		} {
+			if err := db.Save(&user).Error; err != nil {
+				return err
+			}
+		}
+		for _, dataset := range []models.Dataset{
+			{ID: "feed", UserID: "seller", Title: "Prices", Version: 1, ContentID: cid, JobID: jobID, SubscriptionPrice: fil.MustParseAmount("2"), SubscriptionPeriod: 30},
+			{ID: "oneoff", UserID: "seller", Title: "Census", Version: 1, ContentID: cid, JobID: jobID, Price: fil.MustParseAmount("1")},
+		} {
+			if err := db.Save(&dataset).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(handler http.HandlerFunc, method, target, email string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, target, nil)
+		handler(w, r.WithContext(context.WithValue(r.Context(), "email", email)))
+		return w
+	}
+	loadSubscription := func(id string) models.Subscription {
+		var sub models.Subscription
+		err := db.View(func(db *gorm.DB) error {
+			return db.Where("id = ?", id).First(&sub).Error
+		})
+		if err != nil {
+			t.Fatalf("subscription %s: %s", id, err)
+		}
+		return sub
+	}
+	checkBalance := func(name, addr string, expected string) {
+		balance, err := wbe.Balance(addr, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fil.NewAmount(balance).Cmp(fil.MustParseAmount(expected)) != 0 {
+			t.Errorf("Expected %s balance of %s, got %s", name, expected, fil.NewAmount(balance))
+		}
+	}
+	checkDownload := func(email string, statusCode int) {
+		if w := request(server.handleGETDatasetFile, http.MethodGet, "/api/v1/download/feed", email); w.Code != statusCode {
+			t.Errorf("%s: expected download status code %d, got %d", email, statusCode, w.Code)
+		}
+	}
+
+	// Subscription datasets can't be downloaded without a subscription.
+	checkDownload("buyer@ob1.io", http.StatusUnauthorized)
+
+	if w := request(server.handlePOSTSubscribe, http.MethodPost, "/api/v1/subscribe/oneoff", "buyer@ob1.io"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	w := request(server.handlePOSTSubscribe, http.MethodPost, "/api/v1/subscribe/feed", "buyer@ob1.io")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var sub models.Subscription
+	if err := json.NewDecoder(w.Body).Decode(&sub); err != nil {
+		t.Fatal(err)
+	}
+	if sub.Status != subscriptionStatusActive || sub.PeriodsPaid != 1 || !sub.PeriodEnd.Equal(sub.StartsAt.Add(subscriptionPeriod(30))) {
+		t.Errorf("Unexpected subscription %v", sub)
+	}
+	checkBalance("seller", sellerAddr, "1.9")
+	checkBalance("fee", feeAddr, "0.1")
+	checkDownload("buyer@ob1.io", http.StatusOK)
+	checkDownload("other@ob1.io", http.StatusUnauthorized)
+
+	if w := request(server.handlePOSTSubscribe, http.MethodPost, "/api/v1/subscribe/feed", "buyer@ob1.io"); w.Code != http.StatusConflict {
+		t.Errorf("Expected status code %d, got %d", http.StatusConflict, w.Code)
+	}
+	if w := request(server.handlePOSTSubscribe, http.MethodPost, "/api/v1/subscribe/feed", "other@ob1.io"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for an unfunded subscriber, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	// A subscription at the end of its period is renewed by the scheduler.
+	setSubscription := func(id string, fields map[string]interface{}) {
+		err := db.Update(func(db *gorm.DB) error {
+			return db.Model(&models.Subscription{}).Where("id = ?", id).Updates(fields).Error
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	periodEnd := time.Now().Add(-time.Minute)
+	setSubscription(sub.ID, map[string]interface{}{"period_end": periodEnd})
+	if err := server.renewDueSubscriptions(); err != nil {
+		t.Fatal(err)
+	}
+	renewed := loadSubscription(sub.ID)
+	if renewed.Status != subscriptionStatusActive || renewed.PeriodsPaid != 2 || !renewed.PeriodEnd.Equal(periodEnd.Add(subscriptionPeriod(30))) {
+		t.Errorf("Expected subscription to be renewed, got %v", renewed)
+	}
+	checkBalance("seller", sellerAddr, "3.8")
+	checkBalance("buyer", buyerAddr, "1")
+
+	// The next renewal can't be paid so the subscription is past due but
+	// usable until its grace period runs out.
+	setSubscription(sub.ID, map[string]interface{}{"period_end": periodEnd, "grace_until": periodEnd.Add(time.Hour)})
+	if err := server.renewDueSubscriptions(); err != nil {
+		t.Fatal(err)
+	}
+	pastDue := loadSubscription(sub.ID)
+	if pastDue.Status != subscriptionStatusPastDue || pastDue.FailedAttempts != 1 || pastDue.LastError == "" || pastDue.PeriodsPaid != 2 {
+		t.Errorf("Expected subscription to be past due, got %v", pastDue)
+	}
+	checkDownload("buyer@ob1.io", http.StatusOK)
+
+	// Once the grace period has lapsed the subscription expires without
+	// being charged, even if the buyer could now pay.
+	wbe.GenerateToAddress(buyerAddr, fil.MustParseAmount("2").AttoFIL())
+	setSubscription(sub.ID, map[string]interface{}{"grace_until": time.Now().Add(-time.Second)})
+	if err := server.renewDueSubscriptions(); err != nil {
+		t.Fatal(err)
+	}
+	if expired := loadSubscription(sub.ID); expired.Status != subscriptionStatusExpired || expired.FailedAttempts != 1 || expired.PeriodsPaid != 2 {
+		t.Errorf("Expected subscription to be expired, got %v", expired)
+	}
+	checkBalance("seller", sellerAddr, "3.8")
+	checkBalance("buyer", buyerAddr, "2.9")
+	checkDownload("buyer@ob1.io", http.StatusUnauthorized)
+
+	// A canceled subscription is not renewed and lasts until the end of the
+	// paid period.
+	wbe.GenerateToAddress(buyerAddr, fil.MustParseAmount("2").AttoFIL())
+	w = request(server.handlePOSTSubscribe, http.MethodPost, "/api/v1/subscribe/feed", "buyer@ob1.io")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if err := json.NewDecoder(w.Body).Decode(&sub); err != nil {
+		t.Fatal(err)
+	}
+	if w := request(server.handlePOSTSubscriptionCancel, http.MethodPost, "/api/v1/subscriptions/"+sub.ID+"/cancel", "other@ob1.io"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+	}
+	if w := request(server.handlePOSTSubscriptionCancel, http.MethodPost, "/api/v1/subscriptions/"+sub.ID+"/cancel", "buyer@ob1.io"); w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+	canceled := loadSubscription(sub.ID)
+	if canceled.Status != subscriptionStatusCanceled || !canceled.GraceUntil.Equal(canceled.PeriodEnd) {
+		t.Errorf("Expected subscription to be canceled, got %v", canceled)
+	}
+	checkDownload("buyer@ob1.io", http.StatusOK)
+
+	w = request(server.handleGETSubscriptions, http.MethodGet, "/api/v1/subscriptions", "buyer@ob1.io")
+	var list struct {
+		Subscriptions []models.Subscription `json:"subscriptions"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Subscriptions) != 2 || list.Subscriptions[0].ID != sub.ID {
+		t.Errorf("Unexpected subscriptions %v", list.Subscriptions)
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"encoding/base64"
 	"fmt"
 	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/mail"
 	"github.com/OB1Company/filehive/repo"
+	"github.com/OB1Company/filehive/repo/search"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -33,6 +35,7 @@ type apiTest struct {
 	body             []byte
 	statusCode       int
 	contentType      string
+	headers          map[string]string
 	setup            func(db *repo.Database, wbe fil.WalletBackend) error
 	expectedResponse []byte
 }
@@ -66,6 +69,9 @@ func runAPITests(t *testing.T, tests apiTests) {
 		filecoinBackend: filBackend,
 		walletBackend:   fil.NewMockWalletBackend(),
 		staticFileDir:   testStaticDir,
+		dataDir:         testStaticDir,
+		searchIndex:     search.NewMemoryIndex(),
+		mailer:          mail.NewMemoryMailer(),
 	}
 
 	r := server.newV1Router()
@@ -90,6 +96,9 @@ func runAPITests(t *testing.T, tests apiTests) {
 		if test.contentType != "" {
 			req.Header.Set("Content-Type", test.contentType)
 		}
+		for k, v := range test.headers {
+			req.Header.Set(k, v)
+		}
 		res, err := http.DefaultClient.Do(req)
 		if err != nil {
 			t.Fatal(err)
@@ -0,0 +1,620 @@
+package app
+
+import (
+	"errors"
+	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/repo/models"
+	"gorm.io/gorm"
+	"math"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	walletScanInterval = time.Minute
+
+	// messageDropTimeout is how long a sent message can go unseen by the
+	// chain before its transaction is marked dropped.
+	messageDropTimeout = time.Hour * 24
+
+	// walletSettleTime is how long an unconfirmed transaction may take to
+	// reach an address's balance before a shortfall in the balance is no
+	// longer put down to it.
+	walletSettleTime = time.Hour
+)
+
+// Transaction types recorded in the ledger.
+const (
+	transactionTypeDeposit    = "deposit"
+	transactionTypeWithdrawal = "withdrawal"
+	transactionTypePurchase   = "purchase"
+	transactionTypeFee        = "fee"
+	transactionTypeRelease    = "release"
+	transactionTypeRefund     = "refund"
+
+	transactionTypeSubscription = "subscription"
+)
+
+// Transaction statuses. Transactions without a message CID can't be
+// followed on chain and are left unknown.
+const (
+	transactionStatusSending   = "sending"
+	transactionStatusUnknown   = "unknown"
+	transactionStatusPending   = "pending"
+	transactionStatusConfirmed = "confirmed"
+	transactionStatusFailed    = "failed"
+	transactionStatusDropped   = "dropped"
+)
+
+// Transaction directions relative to the user's address.
+const (
+	transactionDirectionIn  = "in"
+	transactionDirectionOut = "out"
+)
+
+// sendFIL sends amt through the wallet backend and records the
+// transaction in the ledger. The transaction is recorded as sending before
+// the funds are sent so a crash part way through leaves a record that the
+// payment may have gone out. A failure to complete the record is logged
+// rather than returned as the funds have already been sent. Funds sent
+// without a known message CID are recorded with an unknown status and an
+// empty transaction ID is returned.
+func (s *FileHiveServer) sendFIL(from, to string, amt *big.Int, userToken, txType, purchaseID string) (string, error) {
+	s.walletMtx.RLock()
+	defer s.walletMtx.RUnlock()
+
+	id, err := makeID()
+	if err != nil {
+		return "", err
+	}
+	tx := models.Transaction{
+		ID:          id,
+		FromAddress: from,
+		ToAddress:   to,
+		Amount:      amt.String(),
+		Type:        txType,
+		PurchaseID:  purchaseID,
+		Timestamp:   time.Now(),
+		Status:      transactionStatusSending,
+	}
+	err = s.db.Update(func(db *gorm.DB) error {
+		return db.Create(&tx).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	txid, err := s.walletBackend.Send(from, to, amt, userToken)
+	if errors.Is(err, fil.ErrTxidUnknown) {
+		log.Warningf("Sent %s from %s to %s without a message CID: %s", fil.NewAmount(amt), from, to, err)
+		err = nil
+	}
+	if err != nil {
+		err2 := s.db.Update(func(db *gorm.DB) error {
+			return db.Where("id = ?", tx.ID).Delete(&models.Transaction{}).Error
+		})
+		if err2 != nil {
+			log.Errorf("Error removing unsent transaction %s: %s", tx.ID, err2)
+		}
+		return "", err
+	}
+
+	tx.Txid = txid
+	tx.Timestamp = s.transactionTime(from, txid)
+	if err := s.completeTransaction(tx); err != nil {
+		log.Errorf("Error recording transaction %s from %s: %s", txid, from, err)
+	}
+	return txid, nil
+}
+
+// transactionTime returns the time the wallet backend reports for the
+// transaction, or now if the backend does not know about it.
+func (s *FileHiveServer) transactionTime(addr, txid string) time.Time {
+	if txid != "" {
+		txs, err := s.walletBackend.Transactions(addr, -1, 0)
+		if err == nil {
+			for _, tx := range txs {
+				if tx.ID == txid {
+					return tx.Timestamp
+				}
+			}
+		}
+	}
+	return time.Now()
+}
+
+// recordTransaction saves a transaction sent by the marketplace. If the
+// scanner already recorded it as a deposit the row is updated with the
+// details of the send.
+func (s *FileHiveServer) recordTransaction(tx models.Transaction) error {
+	return s.db.Update(func(db *gorm.DB) error {
+		if tx.Txid != "" {
+			var existing models.Transaction
+			err := db.Where("txid = ?", tx.Txid).First(&existing).Error
+			if err == nil {
+				return db.Model(&existing).Updates(map[string]interface{}{
+					"type":        tx.Type,
+					"purchase_id": tx.PurchaseID,
+				}).Error
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+
+		id, err := makeID()
+		if err != nil {
+			return err
+		}
+		tx.ID = id
+		tx.Status = initialTransactionStatus(tx.Txid)
+		return db.Create(&tx).Error
+	})
+}
+
+// completeTransaction records the outcome of a send that was recorded as
+// sending. If the scanner already recorded the message as a deposit that
+// row is updated with the details of the send instead.
+func (s *FileHiveServer) completeTransaction(tx models.Transaction) error {
+	return s.db.Update(func(db *gorm.DB) error {
+		if tx.Txid != "" {
+			var existing models.Transaction
+			err := db.Where("txid = ? AND id <> ?", tx.Txid, tx.ID).First(&existing).Error
+			if err == nil {
+				if err := db.Where("id = ?", tx.ID).Delete(&models.Transaction{}).Error; err != nil {
+					return err
+				}
+				return db.Model(&existing).Updates(map[string]interface{}{
+					"type":        tx.Type,
+					"purchase_id": tx.PurchaseID,
+				}).Error
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+
+		return db.Model(&models.Transaction{}).Where("id = ?", tx.ID).Updates(map[string]interface{}{
+			"txid":      tx.Txid,
+			"timestamp": tx.Timestamp,
+			"status":    initialTransactionStatus(tx.Txid),
+		}).Error
+	})
+}
+
+func initialTransactionStatus(txid string) string {
+	if txid == "" {
+		return transactionStatusUnknown
+	}
+	return transactionStatusPending
+}
+
+// scanWallets periodically looks for incoming funds in every user's
+// wallet until the server shuts down.
+func (s *FileHiveServer) scanWallets() {
+	ticker := time.NewTicker(walletScanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			var users []models.User
+			err := s.db.View(func(db *gorm.DB) error {
+				return db.Where("filecoin_address <> ''").Find(&users).Error
+			})
+			if err != nil {
+				log.Errorf("Error loading wallets to scan: %s", err)
+				continue
+			}
+			for _, user := range users {
+				if err := s.scanWalletAddress(user.FilecoinAddress, user.PowergateToken); err != nil {
+					log.Errorf("Error scanning wallet %s: %s", user.FilecoinAddress, err)
+				}
+			}
+		case <-s.shutdown:
+			return
+		}
+	}
+}
+
+// scanWalletAddress records incoming funds to addr that are not yet in the
+// ledger. Transactions the backend reports are recorded by their ID.
+// Powergate does not report incoming transactions, so a balance above what
+// the ledger accounts for is recorded as a deposit from an unknown sender.
+// Balances only change once messages are on chain, so funds sent are only
+// counted once confirmed while funds received are counted straight away,
+// meaning funds in flight never look like a deposit. A shortfall left
+// once nothing is in flight, such as gas, is taken out of the opening
+// balance. The owner is notified if the balance has fallen below the low
+// balance threshold since the last scan.
+func (s *FileHiveServer) scanWalletAddress(addr, userToken string) error {
+	if addr == "" {
+		return nil
+	}
+
+	s.walletMtx.Lock()
+	defer s.walletMtx.Unlock()
+
+	txs, err := s.walletBackend.Transactions(addr, -1, 0)
+	if err != nil {
+		return err
+	}
+	balance, err := s.walletBackend.Balance(addr, userToken)
+	if err != nil {
+		return err
+	}
+
+	var previous *big.Int
+	err = s.db.Update(func(db *gorm.DB) error {
+		var deposits []models.Transaction
+		for _, tx := range txs {
+			if tx.ID == "" || tx.To != addr || tx.From == addr {
+				continue
+			}
+			var count int64
+			if err := db.Model(&models.Transaction{}).Where("txid = ?", tx.ID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+			deposit, err := createDeposit(db, tx.ID, tx.From, addr, tx.Amount, tx.Timestamp)
+			if err != nil {
+				return err
+			}
+			deposits = append(deposits, deposit)
+		}
+
+		now := time.Now()
+		var ledger []models.Transaction
+		if err := db.Where("(from_address = ? OR to_address = ?) AND from_address <> to_address", addr, addr).Find(&ledger).Error; err != nil {
+			return err
+		}
+		net, inFlight := new(big.Int), false
+		for _, tx := range ledger {
+			amt, ok := new(big.Int).SetString(tx.Amount, 10)
+			if !ok || tx.Status == transactionStatusFailed || tx.Status == transactionStatusDropped {
+				continue
+			}
+			if tx.Status != transactionStatusConfirmed && tx.CreatedAt.After(now.Add(-walletSettleTime)) {
+				inFlight = true
+			}
+			if tx.ToAddress == addr {
+				net.Add(net, amt)
+			} else if tx.Status == transactionStatusConfirmed {
+				net.Sub(net, amt)
+			}
+		}
+
+		var scan models.WalletScan
+		err := db.Where("id = ?", addr).First(&scan).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Funds received before the first scan are part of the
+			// opening balance.
+			opening := new(big.Int).Sub(balance, net)
+			return db.Create(&models.WalletScan{ID: addr, Balance: balance.String(), Opening: opening.String(), ScannedAt: now}).Error
+		} else if err != nil {
+			return err
+		}
+
+		var ok bool
+		if previous, ok = new(big.Int).SetString(scan.Balance, 10); !ok {
+			previous = new(big.Int)
+		}
+		opening, ok := new(big.Int).SetString(scan.Opening, 10)
+		if !ok {
+			opening = new(big.Int).Sub(balance, net)
+		}
+		switch unaccounted := new(big.Int).Sub(balance, new(big.Int).Add(opening, net)); unaccounted.Sign() {
+		case 1:
+			deposit, err := createDeposit(db, "", "", addr, unaccounted, now)
+			if err != nil {
+				return err
+			}
+			deposits = append(deposits, deposit)
+		case -1:
+			if !inFlight {
+				opening.Add(opening, unaccounted)
+			}
+		}
+
+		if err := db.Model(&scan).Updates(map[string]interface{}{
+			"balance":    balance.String(),
+			"opening":    opening.String(),
+			"scanned_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		return queueDepositWebhooks(db, addr, deposits)
+	})
+	if err != nil {
+		return err
+	}
+	s.wakeWebhooks()
+
+	// Warn the user once as their balance falls below the threshold rather
+	// than on every scan while it stays low.
+	threshold := s.lowBalance.AttoFIL()
+	if threshold.Sign() > 0 && previous != nil && previous.Cmp(threshold) >= 0 && balance.Cmp(threshold) < 0 {
+		if err := s.notifyLowBalance(addr, balance); err != nil {
+			log.Errorf("Error notifying low balance of %s: %s", addr, err)
+		}
+	}
+	return nil
+}
+
+func createDeposit(db *gorm.DB, txid, from, to string, amt *big.Int, timestamp time.Time) (models.Transaction, error) {
+	id, err := makeID()
+	if err != nil {
+		return models.Transaction{}, err
+	}
+	tx := models.Transaction{
+		ID:          id,
+		Txid:        txid,
+		FromAddress: from,
+		ToAddress:   to,
+		Amount:      amt.String(),
+		Type:        transactionTypeDeposit,
+		Timestamp:   timestamp,
+		Status:      initialTransactionStatus(txid),
+	}
+	return tx, db.Create(&tx).Error
+}
+
+// queueDepositWebhooks sends the deposits to the webhooks of the user who
+// owns the address.
+func queueDepositWebhooks(db *gorm.DB, addr string, deposits []models.Transaction) error {
+	if len(deposits) == 0 {
+		return nil
+	}
+	var user models.User
+	err := db.Where("filecoin_address = ?", addr).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// The escrow and fee addresses don't belong to a user.
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, deposit := range deposits {
+		if err := queueWebhookEvent(db, user.ID, webhookEventWalletReceived, newTransactionResponse(deposit, addr)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// watchTransactions periodically follows pending transactions on to the
+// chain until the server shuts down.
+func (s *FileHiveServer) watchTransactions() {
+	ticker := time.NewTicker(walletScanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.checkPendingTransactions(); err != nil {
+				log.Errorf("Error checking pending transactions: %s", err)
+			}
+		case <-s.shutdown:
+			return
+		}
+	}
+}
+
+func (s *FileHiveServer) checkPendingTransactions() error {
+	var pending []models.Transaction
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("status = ?", transactionStatusPending).Order("created_at").Find(&pending).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range pending {
+		if err := s.checkTransaction(&pending[i]); errors.Is(err, fil.ErrMessageLookupUnavailable) {
+			return nil
+		} else if err != nil {
+			log.Errorf("Error checking transaction %s: %s", pending[i].Txid, err)
+		}
+	}
+	return nil
+}
+
+// checkTransaction updates a pending transaction with the status of its
+// message. A transaction whose message fails on chain is marked failed.
+func (s *FileHiveServer) checkTransaction(tx *models.Transaction) error {
+	status, err := s.walletBackend.MessageStatus(tx.Txid)
+	if errors.Is(err, fil.ErrMessageNotFound) {
+		if time.Since(tx.CreatedAt) < messageDropTimeout {
+			return nil
+		}
+		log.Warningf("Transaction %s was dropped before reaching the chain", tx.Txid)
+		tx.Status = transactionStatusDropped
+	} else if err != nil {
+		return err
+	} else if !status.Included {
+		return nil
+	} else {
+		tx.Status = transactionStatusConfirmed
+		if status.ExitCode != 0 {
+			tx.Status = transactionStatusFailed
+		}
+		tx.Height = status.Height
+		tx.ExitCode = status.ExitCode
+	}
+
+	return s.db.Update(func(db *gorm.DB) error {
+		return db.Model(tx).Updates(map[string]interface{}{
+			"status":    tx.Status,
+			"height":    tx.Height,
+			"exit_code": tx.ExitCode,
+		}).Error
+	})
+}
+
+func (s *FileHiveServer) handleGETWalletTransactions(w http.ResponseWriter, r *http.Request) {
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var limit, offset int
+	limitStr := r.URL.Query().Get("limit")
+	offsetStr := r.URL.Query().Get("offset")
+
+	if limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil {
+			http.Error(w, wrapError(ErrInvalidOption), http.StatusBadRequest)
+			return
+		}
+	}
+	if limitStr == "" || limit < 0 {
+		// SQLite needs a limit to apply an offset.
+		limit = math.MaxInt32
+	}
+	if offsetStr != "" {
+		offset, err = strconv.Atoi(offsetStr)
+		if err != nil {
+			http.Error(w, wrapError(ErrInvalidOption), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var after, before time.Time
+	if afterStr := r.URL.Query().Get("after"); afterStr != "" {
+		after, err = time.Parse(time.RFC3339, afterStr)
+		if err != nil {
+			http.Error(w, wrapError(ErrInvalidOption), http.StatusBadRequest)
+			return
+		}
+	}
+	if beforeStr := r.URL.Query().Get("before"); beforeStr != "" {
+		before, err = time.Parse(time.RFC3339, beforeStr)
+		if err != nil {
+			http.Error(w, wrapError(ErrInvalidOption), http.StatusBadRequest)
+			return
+		}
+	}
+
+	direction := r.URL.Query().Get("direction")
+	if direction != "" && direction != transactionDirectionIn && direction != transactionDirectionOut {
+		http.Error(w, wrapError(ErrInvalidOption), http.StatusBadRequest)
+		return
+	}
+
+	if user.FilecoinAddress == "" {
+		sanitizedJSONResponse(w, []models.Transaction{})
+		return
+	}
+
+	if err := s.scanWalletAddress(user.FilecoinAddress, user.PowergateToken); err != nil {
+		log.Errorf("Error scanning wallet %s: %s", user.FilecoinAddress, err)
+	}
+
+	var txs []models.Transaction
+	err = s.db.View(func(db *gorm.DB) error {
+		tx := db.Where("from_address = ? OR to_address = ?", user.FilecoinAddress, user.FilecoinAddress)
+		switch direction {
+		case transactionDirectionIn:
+			tx = tx.Where("to_address = ?", user.FilecoinAddress)
+		case transactionDirectionOut:
+			tx = tx.Where("from_address = ?", user.FilecoinAddress)
+		}
+		if !after.IsZero() {
+			tx = tx.Where("timestamp >= ?", after)
+		}
+		if !before.IsZero() {
+			tx = tx.Where("timestamp < ?", before)
+		}
+		return tx.Order("timestamp, created_at").Offset(offset).Limit(limit).Find(&txs).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	ret := make([]transactionResponse, 0, len(txs))
+	for _, tx := range txs {
+		ret = append(ret, newTransactionResponse(tx, user.FilecoinAddress))
+	}
+
+	sanitizedJSONResponse(w, ret)
+}
+
+func (s *FileHiveServer) handleGETWalletTransaction(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	txid := sp[len(sp)-1]
+
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var tx models.Transaction
+	err = s.db.View(func(db *gorm.DB) error {
+		return db.Where("txid = ? AND (from_address = ? OR to_address = ?)", txid, user.FilecoinAddress, user.FilecoinAddress).First(&tx).Error
+	})
+	if txid == "" || user.FilecoinAddress == "" || errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, wrapError(ErrTxNotFound), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	if tx.Status == transactionStatusPending {
+		if err := s.checkTransaction(&tx); err != nil && !errors.Is(err, fil.ErrMessageLookupUnavailable) {
+			log.Errorf("Error checking transaction %s: %s", tx.Txid, err)
+		}
+	}
+
+	sanitizedJSONResponse(w, newTransactionResponse(tx, user.FilecoinAddress))
+}
+
+// transactionResponse is a ledger transaction as seen from one address.
+type transactionResponse struct {
+	models.Transaction
+	Amount    fil.Amount `json:"amount"`
+	Direction string     `json:"direction"`
+}
+
+func newTransactionResponse(tx models.Transaction, addr string) transactionResponse {
+	amt, _ := fil.ParseAttoFIL(tx.Amount)
+	direction := transactionDirectionIn
+	if tx.FromAddress == addr {
+		direction = transactionDirectionOut
+	}
+	return transactionResponse{
+		Transaction: tx,
+		Amount:      amt,
+		Direction:   direction,
+	}
+}
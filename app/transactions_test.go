@@ -0,0 +1,292 @@
+package app
+
+import (
+	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/repo"
+	"github.com/OB1Company/filehive/repo/models"
+	"gorm.io/gorm"
+	"math/big"
+	"testing"
+)
+
+// historylessWallet is a wallet backend that does not report transactions,
+// like Powergate.
+type historylessWallet struct {
+	*fil.MockWalletBackend
+}
+
+func (w historylessWallet) Transactions(addr string, limit, offset int) ([]fil.Transaction, error) {
+	return nil, nil
+}
+
+// unknownTxidWallet is a wallet backend that sends funds without learning
+// the message CID.
+type unknownTxidWallet struct {
+	*fil.MockWalletBackend
+}
+
+func (w unknownTxidWallet) Send(from, to string, amount *big.Int, userToken string) (string, error) {
+	if _, err := w.MockWalletBackend.Send(from, to, amount, userToken); err != nil {
+		return "", err
+	}
+	return "", fil.ErrTxidUnknown
+}
+
+// laggingWallet is a historyless wallet backend whose balances only change
+// once transactions are included in a block, like Powergate.
+type laggingWallet struct {
+	historylessWallet
+}
+
+func (w laggingWallet) Balance(addr string, userToken string) (*big.Int, error) {
+	balance, err := w.MockWalletBackend.Balance(addr, userToken)
+	if err != nil {
+		return nil, err
+	}
+	txs, err := w.MockWalletBackend.Transactions(addr, -1, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, tx := range txs {
+		status, err := w.MessageStatus(tx.ID)
+		if err != nil {
+			return nil, err
+		}
+		if status.Included {
+			continue
+		}
+		if tx.From == addr {
+			balance.Add(balance, tx.Amount)
+		} else if tx.To == addr {
+			balance.Sub(balance, tx.Amount)
+		}
+	}
+	return balance, nil
+}
+
+func Test_TransactionLedger(t *testing.T) {
+	for _, historyless := range []bool{false, true} {
+		db, err := repo.NewDatabase("", repo.Dialect("memory"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mock := fil.NewMockWalletBackend()
+		server := &FileHiveServer{
+			db:            db,
+			walletBackend: mock,
+		}
+		if historyless {
+			server.walletBackend = historylessWallet{mock}
+		}
+
+		alice, err := mock.NewAddress("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		bob, err := mock.NewAddress("")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		loadTransactions := func(addr string) []models.Transaction {
+			var txs []models.Transaction
+			err := db.View(func(db *gorm.DB) error {
+				return db.Where("from_address = ? OR to_address = ?", addr, addr).Order("created_at").Find(&txs).Error
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return txs
+		}
+		scan := func(addr string) {
+			if err := server.scanWalletAddress(addr, ""); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// Funds received before the first scan are the starting balance.
+		mock.GenerateToAddress(alice, fil.MustParseAmount("1").AttoFIL())
+		scan(alice)
+		scan(bob)
+		if txs := loadTransactions(alice); len(txs) != 0 && historyless {
+			t.Errorf("Expected no transactions before the first scan, got %d", len(txs))
+		}
+
+		mock.GenerateToAddress(alice, fil.MustParseAmount("5").AttoFIL())
+		scan(alice)
+		scan(alice)
+		txs := loadTransactions(alice)
+		deposit := txs[len(txs)-1]
+		if deposit.Type != transactionTypeDeposit || deposit.Amount != fil.MustParseAmount("5").AttoFIL().String() || deposit.ToAddress != alice {
+			t.Errorf("historyless %t: expected deposit of 5 FIL to alice, got %s of %s to %s", historyless, deposit.Type, deposit.Amount, deposit.ToAddress)
+		}
+
+		txid, err := server.sendFIL(alice, bob, fil.MustParseAmount("2").AttoFIL(), "", transactionTypeWithdrawal, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		scan(alice)
+		scan(bob)
+
+		// The transfer is recorded once and is not mistaken for a deposit
+		// on either side.
+		txs = loadTransactions(bob)
+		if len(txs) != 1 {
+			t.Fatalf("historyless %t: expected one transaction for bob, got %d", historyless, len(txs))
+		}
+		if txs[0].Txid != txid || txs[0].Type != transactionTypeWithdrawal || txs[0].FromAddress != alice {
+			t.Errorf("historyless %t: expected withdrawal %s from alice, got %s %s from %s", historyless, txid, txs[0].Type, txs[0].Txid, txs[0].FromAddress)
+		}
+		if n := len(loadTransactions(alice)); (historyless && n != 2) || (!historyless && n != 3) {
+			t.Errorf("historyless %t: unexpected number of transactions for alice: %d", historyless, n)
+		}
+
+		// Transactions with a message CID are confirmed once they are
+		// included in a block.
+		if err := server.checkPendingTransactions(); err != nil {
+			t.Fatal(err)
+		}
+		if txs := loadTransactions(bob); txs[0].Status != transactionStatusPending {
+			t.Errorf("historyless %t: expected transaction to be pending, got %s", historyless, txs[0].Status)
+		}
+		mock.GenerateBlock()
+		if err := server.checkPendingTransactions(); err != nil {
+			t.Fatal(err)
+		}
+		for _, tx := range loadTransactions(alice) {
+			expected := transactionStatusConfirmed
+			if tx.Txid == "" {
+				expected = transactionStatusUnknown
+			}
+			if tx.Status != expected {
+				t.Errorf("historyless %t: expected transaction %s to be %s, got %s", historyless, tx.Txid, expected, tx.Status)
+			}
+		}
+	}
+}
+
+func Test_WalletScanBalanceLag(t *testing.T) {
+	db, err := repo.NewDatabase("", repo.Dialect("memory"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock := fil.NewMockWalletBackend()
+	server := &FileHiveServer{
+		db:            db,
+		walletBackend: laggingWallet{historylessWallet{mock}},
+	}
+
+	alice, err := mock.NewAddress("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := mock.NewAddress("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deposits := func(addr string) []models.Transaction {
+		var txs []models.Transaction
+		err := db.View(func(db *gorm.DB) error {
+			return db.Where("to_address = ? AND type = ?", addr, transactionTypeDeposit).Order("created_at").Find(&txs).Error
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return txs
+	}
+	scan := func(addrs ...string) {
+		for _, addr := range addrs {
+			if err := server.scanWalletAddress(addr, ""); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	mock.GenerateToAddress(alice, fil.MustParseAmount("6").AttoFIL())
+	mock.GenerateBlock()
+	scan(alice, bob)
+
+	// A transfer is not mistaken for a deposit by either side while the
+	// balances have yet to change, nor once they have.
+	if _, err := server.sendFIL(alice, bob, fil.MustParseAmount("2").AttoFIL(), "", transactionTypeWithdrawal, ""); err != nil {
+		t.Fatal(err)
+	}
+	scan(alice, bob)
+	mock.GenerateBlock()
+	scan(alice, bob)
+	if err := server.checkPendingTransactions(); err != nil {
+		t.Fatal(err)
+	}
+	scan(alice, bob)
+	if d := deposits(alice); len(d) != 0 {
+		t.Errorf("Expected no deposits to alice, got %d", len(d))
+	}
+	if d := deposits(bob); len(d) != 0 {
+		t.Errorf("Expected no deposits to bob, got %d", len(d))
+	}
+
+	// A deposit is recorded once, however many times it is scanned.
+	mock.GenerateToAddress(alice, fil.MustParseAmount("1").AttoFIL())
+	mock.GenerateBlock()
+	scan(alice, alice, alice)
+	d := deposits(alice)
+	if len(d) != 1 || d[0].Amount != fil.MustParseAmount("1").AttoFIL().String() {
+		t.Fatalf("Expected one deposit of 1 FIL to alice, got %+v", d)
+	}
+}
+
+func Test_SendUnknownTxid(t *testing.T) {
+	db, err := repo.NewDatabase("", repo.Dialect("memory"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock := fil.NewMockWalletBackend()
+	server := &FileHiveServer{
+		db:            db,
+		walletBackend: unknownTxidWallet{mock},
+	}
+
+	alice, err := mock.NewAddress("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := mock.NewAddress("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.GenerateToAddress(alice, fil.MustParseAmount("1").AttoFIL())
+
+	// Funds sent without a message CID are still recorded as sent.
+	txid, err := server.sendFIL(alice, bob, fil.MustParseAmount("1").AttoFIL(), "", transactionTypeWithdrawal, "")
+	if err != nil || txid != "" {
+		t.Fatalf("Expected the send to succeed without a transaction ID, got %s, %v", txid, err)
+	}
+	var txs []models.Transaction
+	err = db.View(func(db *gorm.DB) error {
+		return db.Where("from_address = ?", alice).Find(&txs).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 1 || txs[0].Status != transactionStatusUnknown || txs[0].Type != transactionTypeWithdrawal {
+		t.Errorf("Expected one withdrawal with an unknown status, got %+v", txs)
+	}
+
+	// A send that fails is not recorded.
+	if _, err := server.sendFIL(alice, bob, fil.MustParseAmount("1").AttoFIL(), "", transactionTypeWithdrawal, ""); err != fil.ErrInsuffientFunds {
+		t.Errorf("Expected insufficient funds, got %v", err)
+	}
+	err = db.View(func(db *gorm.DB) error {
+		return db.Where("from_address = ?", alice).Find(&txs).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 1 {
+		t.Errorf("Expected the failed send not to be recorded, got %d transactions", len(txs))
+	}
+}
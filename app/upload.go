@@ -0,0 +1,561 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/OB1Company/filehive/repo/models"
+	"github.com/filecoin-project/go-address"
+	"gorm.io/gorm"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// uploadExpiration is how long an unfinished resumable upload is kept
+// before its session and partial file are removed.
+const uploadExpiration = time.Hour * 24 * 7
+
+// uploadReader wraps the stream of an uploaded dataset file. It counts and
+// hashes the bytes as they pass through so the file never has to be held in
+// memory, and it fails the read once more than max bytes have been seen.
+// A max of zero or less disables the limit.
+type uploadReader struct {
+	r        io.Reader
+	hash     hash.Hash
+	size     int64
+	max      int64
+	exceeded bool
+}
+
+func newUploadReader(r io.Reader, max int64) *uploadReader {
+	return &uploadReader{
+		r:    r,
+		hash: sha256.New(),
+		max:  max,
+	}
+}
+
+// Read implements io.Reader.
+func (u *uploadReader) Read(p []byte) (int, error) {
+	n, err := u.r.Read(p)
+	if n > 0 {
+		u.hash.Write(p[:n])
+		u.size += int64(n)
+	}
+	if u.max > 0 && u.size > u.max {
+		u.exceeded = true
+		return n, ErrFileTooLarge
+	}
+	return n, err
+}
+
+// Size returns the number of bytes read so far.
+func (u *uploadReader) Size() int64 {
+	return u.size
+}
+
+// Checksum returns the hex encoded SHA-256 of the bytes read so far.
+func (u *uploadReader) Checksum() string {
+	return hex.EncodeToString(u.hash.Sum(nil))
+}
+
+// Exceeded returns whether the upload went over the maximum size.
+func (u *uploadReader) Exceeded() bool {
+	return u.exceeded
+}
+
+// spoolUpload copies an uploaded file to a temporary file in the uploads
+// directory, counting and hashing it on the way. Nothing is kept if the
+// file is over the maximum upload size, in which case ErrFileTooLarge is
+// returned. The caller removes the returned file once it is stored.
+func (s *FileHiveServer) spoolUpload(r io.Reader) (*os.File, *uploadReader, error) {
+	dir := path.Join(s.dataDir, "uploads")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, nil, err
+	}
+	f, err := ioutil.TempFile(dir, "dataset-")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	upload := newUploadReader(r, s.maxUploadSize)
+	if _, err = io.Copy(f, upload); err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, nil, err
+	}
+	return f, upload, nil
+}
+
+// idLocks makes sure only one request at a time works on a given resource,
+// such as a resumable upload or a purchase. The zero value is ready to use.
+type idLocks struct {
+	mtx    sync.Mutex
+	active map[string]struct{}
+}
+
+func (l *idLocks) acquire(id string) bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.active == nil {
+		l.active = make(map[string]struct{})
+	}
+	if _, ok := l.active[id]; ok {
+		return false
+	}
+	l.active[id] = struct{}{}
+	return true
+}
+
+func (l *idLocks) release(id string) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	delete(l.active, id)
+}
+
+func (s *FileHiveServer) uploadPath(id string) string {
+	return path.Join(s.dataDir, "uploads", id)
+}
+
+// loadUpload returns the upload session with the given ID provided it
+// belongs to the logged in user.
+func (s *FileHiveServer) loadUpload(r *http.Request, id string) (models.User, models.Upload, error) {
+	var (
+		user   models.User
+		upload models.Upload
+	)
+	email, ok := r.Context().Value("email").(string)
+	if !ok {
+		return user, upload, ErrInvalidCredentials
+	}
+
+	err := s.db.View(func(db *gorm.DB) error {
+		if err := db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error; err != nil {
+			return ErrInvalidCredentials
+		}
+		if err := db.Where("id = ? and user_id = ?", id, user.ID).First(&upload).Error; err != nil {
+			return ErrUploadNotFound
+		}
+		return nil
+	})
+	return user, upload, err
+}
+
+func uploadError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidCredentials):
+		http.Error(w, wrapError(err), http.StatusUnauthorized)
+	case errors.Is(err, ErrUploadNotFound):
+		http.Error(w, wrapError(err), http.StatusNotFound)
+	default:
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+	}
+}
+
+// removeExpiredUploads deletes abandoned upload sessions along with their
+// partially uploaded files.
+func (s *FileHiveServer) removeExpiredUploads() error {
+	var expired []models.Upload
+	err := s.db.Update(func(db *gorm.DB) error {
+		if err := db.Where("expires_at < ?", time.Now()).Find(&expired).Error; err != nil {
+			return err
+		}
+		if len(expired) == 0 {
+			return nil
+		}
+		return db.Where("expires_at < ?", time.Now()).Delete(&models.Upload{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	for _, upload := range expired {
+		os.Remove(s.uploadPath(upload.ID))
+	}
+	return nil
+}
+
+// handlePOSTUpload opens a new resumable upload session. The client declares
+// the total size of the file and, optionally, its SHA-256 which is checked
+// when the upload is finalized.
+func (s *FileHiveServer) handlePOSTUpload(w http.ResponseWriter, r *http.Request) {
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	type data struct {
+		Filename string `json:"filename"`
+		Size     int64  `json:"size"`
+		SHA256   string `json:"sha256"`
+	}
+	var d data
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+		http.Error(w, wrapError(ErrInvalidJSON), http.StatusBadRequest)
+		return
+	}
+	if d.Size <= 0 {
+		http.Error(w, wrapError(ErrInvalidOption), http.StatusBadRequest)
+		return
+	}
+	if s.maxUploadSize > 0 && d.Size > s.maxUploadSize {
+		http.Error(w, wrapError(ErrFileTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if err := s.removeExpiredUploads(); err != nil {
+		log.Errorf("Error removing expired uploads: %s", err)
+	}
+
+	id, err := makeID()
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	if err := os.MkdirAll(path.Join(s.dataDir, "uploads"), os.ModePerm); err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	f, err := os.Create(s.uploadPath(id))
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	f.Close()
+
+	upload := models.Upload{
+		ID:        id,
+		UserID:    user.ID,
+		Filename:  d.Filename,
+		Size:      d.Size,
+		SHA256:    strings.ToLower(d.SHA256),
+		ExpiresAt: time.Now().Add(uploadExpiration),
+	}
+	err = s.db.Update(func(db *gorm.DB) error {
+		return db.Save(&upload).Error
+	})
+	if err != nil {
+		os.Remove(s.uploadPath(id))
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	sanitizedJSONResponse(w, upload)
+}
+
+// handleHEADUpload reports how much of the file the server has received so
+// the client knows where to resume from.
+func (s *FileHiveServer) handleHEADUpload(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	id := sp[len(sp)-1]
+
+	_, upload, err := s.loadUpload(r, id)
+	if err != nil {
+		uploadError(w, err)
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	w.Header().Set("Cache-Control", "no-store")
+}
+
+// handlePATCHUpload appends a chunk to the upload. The Upload-Offset header
+// must match the number of bytes already received. Whatever portion of the
+// chunk arrives is kept, so an interrupted request can be resumed from the
+// new offset.
+func (s *FileHiveServer) handlePATCHUpload(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	id := sp[len(sp)-1]
+
+	_, upload, err := s.loadUpload(r, id)
+	if err != nil {
+		uploadError(w, err)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidOption), http.StatusBadRequest)
+		return
+	}
+
+	if !s.uploadLocks.acquire(upload.ID) {
+		http.Error(w, wrapError(ErrUploadInProgress), http.StatusConflict)
+		return
+	}
+	defer s.uploadLocks.release(upload.ID)
+
+	// Reload now that we hold the lock in case another request moved it on.
+	err = s.db.View(func(db *gorm.DB) error {
+		return db.Where("id = ?", upload.ID).First(&upload).Error
+	})
+	if err != nil {
+		uploadError(w, ErrUploadNotFound)
+		return
+	}
+	if offset != upload.Offset {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		http.Error(w, wrapError(ErrUploadOffset), http.StatusConflict)
+		return
+	}
+
+	f, err := os.OpenFile(s.uploadPath(upload.ID), os.O_WRONLY, os.ModePerm)
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	if err := f.Truncate(upload.Offset); err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	if _, err := f.Seek(upload.Offset, io.SeekStart); err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	remaining := upload.Size - upload.Offset
+	n, copyErr := io.Copy(f, io.LimitReader(r.Body, remaining+1))
+	if n > remaining {
+		// Throw away the extra byte so the file matches the declared size.
+		if err := f.Truncate(upload.Size); err != nil {
+			http.Error(w, wrapError(err), http.StatusInternalServerError)
+			return
+		}
+		n = remaining
+		copyErr = ErrFileTooLarge
+	}
+	if err := f.Sync(); err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	upload.Offset += n
+	upload.ExpiresAt = time.Now().Add(uploadExpiration)
+	err = s.db.Update(func(db *gorm.DB) error {
+		return db.Save(&upload).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	if errors.Is(copyErr, ErrFileTooLarge) {
+		http.Error(w, wrapError(ErrFileTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if copyErr != nil {
+		http.Error(w, wrapError(copyErr), http.StatusBadRequest)
+		return
+	}
+
+	sanitizedJSONResponse(w, upload)
+}
+
+// handleDELETEUpload abandons an upload and removes the partial file.
+func (s *FileHiveServer) handleDELETEUpload(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	id := sp[len(sp)-1]
+
+	_, upload, err := s.loadUpload(r, id)
+	if err != nil {
+		uploadError(w, err)
+		return
+	}
+
+	if !s.uploadLocks.acquire(upload.ID) {
+		http.Error(w, wrapError(ErrUploadInProgress), http.StatusConflict)
+		return
+	}
+	defer s.uploadLocks.release(upload.ID)
+
+	err = s.db.Update(func(db *gorm.DB) error {
+		return db.Where("id = ?", upload.ID).Delete(&models.Upload{}).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	os.Remove(s.uploadPath(upload.ID))
+}
+
+// handlePOSTUploadFinalize checks the assembled file against its declared
+// size and checksum, stores it with the Filecoin backend and creates the
+// dataset from the metadata in the request body, just as handlePOSTDataset
+// does for single request uploads. If the metadata names an existing
+// dataset the file becomes its next version, as with
+// handlePOSTDatasetVersion.
+func (s *FileHiveServer) handlePOSTUploadFinalize(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	uploadID := sp[len(sp)-2]
+
+	user, upload, err := s.loadUpload(r, uploadID)
+	if err != nil {
+		uploadError(w, err)
+		return
+	}
+
+	var d datasetMetadata
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+		http.Error(w, wrapError(ErrInvalidJSON), http.StatusBadRequest)
+		return
+	}
+	if d.Filename == "" {
+		d.Filename = upload.Filename
+	}
+
+	addr, err := address.NewFromString(user.FilecoinAddress)
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	if !s.uploadLocks.acquire(upload.ID) {
+		http.Error(w, wrapError(ErrUploadInProgress), http.StatusConflict)
+		return
+	}
+	defer s.uploadLocks.release(upload.ID)
+
+	if upload.Offset != upload.Size {
+		http.Error(w, wrapError(ErrUploadIncomplete), http.StatusConflict)
+		return
+	}
+
+	f, err := os.Open(s.uploadPath(upload.ID))
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	checksummer := newUploadReader(f, 0)
+	if _, err := io.Copy(ioutil.Discard, checksummer); err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	if checksummer.Size() != upload.Size {
+		http.Error(w, wrapError(ErrUploadIncomplete), http.StatusConflict)
+		return
+	}
+	checksum := checksummer.Checksum()
+	if upload.SHA256 != "" && upload.SHA256 != checksum {
+		http.Error(w, wrapError(ErrChecksumMismatch), http.StatusBadRequest)
+		return
+	}
+
+	var dataset models.Dataset
+	if d.DatasetID != "" {
+		err := s.db.View(func(db *gorm.DB) error {
+			return db.Where("id = ?", d.DatasetID).First(&dataset).Error
+		})
+		if err != nil || dataset.UserID != user.ID {
+			http.Error(w, wrapError(ErrDatasetNotFound), http.StatusNotFound)
+			return
+		}
+	} else {
+		id, err := makeID()
+		if err != nil {
+			http.Error(w, wrapError(err), http.StatusInternalServerError)
+			return
+		}
+
+		dataset, err = s.newDataset(user, id, d)
+		if err != nil {
+			http.Error(w, wrapError(err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	jobID, cid, _, err := s.filecoinBackend.Store(f, addr, user.PowergateToken)
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	err = s.db.Update(func(db *gorm.DB) error {
+		if d.DatasetID != "" {
+			var err error
+			dataset, err = addDatasetVersion(db, dataset.ID, models.DatasetVersion{
+				Changelog:       d.Changelog,
+				ContentID:       cid,
+				JobID:           jobID,
+				FileSize:        upload.Size,
+				SHA256:          checksum,
+				DatasetFilename: d.Filename,
+				FileType:        d.FileType,
+			})
+			if err != nil {
+				return err
+			}
+		} else {
+			dataset.FileSize = upload.Size
+			dataset.ContentID = cid
+			dataset.SHA256 = checksum
+			dataset.JobID = jobID
+			if err := db.Save(&dataset).Error; err != nil {
+				return err
+			}
+			if err := saveDatasetTags(db, dataset.ID, dataset.Tags); err != nil {
+				return err
+			}
+			if err := saveDatasetVersion(db, dataset, d.Changelog); err != nil {
+				return err
+			}
+			if err := saveStorageJob(db, newStorageJob(dataset)); err != nil {
+				return err
+			}
+			if err := queueWebhookEvent(db, user.ID, webhookEventDatasetCreated, dataset); err != nil {
+				return err
+			}
+		}
+		return db.Where("id = ?", upload.ID).Delete(&models.Upload{}).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	f.Close()
+	os.Remove(s.uploadPath(upload.ID))
+	s.updateSearchIndex(dataset.ID)
+	s.wakeWebhooks()
+
+	sanitizedJSONResponse(w, struct {
+		DatasetID string `json:"datasetID"`
+	}{
+		DatasetID: dataset.ID,
+	})
+}
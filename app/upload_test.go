@@ -0,0 +1,166 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/repo"
+	"github.com/OB1Company/filehive/repo/models"
+	"github.com/OB1Company/filehive/repo/search"
+	"gorm.io/gorm"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func Test_UploadReader(t *testing.T) {
+	data := []byte("some dataset content")
+	sum := sha256.Sum256(data)
+
+	upload := newUploadReader(bytes.NewReader(data), int64(len(data)))
+	if _, err := ioutil.ReadAll(upload); err != nil {
+		t.Fatal(err)
+	}
+	if upload.Exceeded() || upload.Size() != int64(len(data)) || upload.Checksum() != hex.EncodeToString(sum[:]) {
+		t.Errorf("Unexpected upload size %d checksum %s", upload.Size(), upload.Checksum())
+	}
+
+	upload = newUploadReader(bytes.NewReader(data), int64(len(data))-1)
+	if _, err := ioutil.ReadAll(upload); err != ErrFileTooLarge || !upload.Exceeded() {
+		t.Errorf("Expected ErrFileTooLarge, got %v", err)
+	}
+
+	upload = newUploadReader(bytes.NewReader(data), 0)
+	if _, err := ioutil.ReadAll(upload); err != nil || upload.Exceeded() {
+		t.Errorf("Expected no limit, got %v", err)
+	}
+}
+
+func Test_DatasetUploadLimit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filehive_upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(path.Join(dir, "images"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := repo.NewDatabase("", repo.Dialect("memory"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filesDir := path.Join(dir, "files")
+	filBackend, err := fil.NewMockFilecoinBackend(filesDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallet := fil.NewMockWalletBackend()
+	addr, err := wallet.NewAddress("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &FileHiveServer{
+		db:              db,
+		filecoinBackend: filBackend,
+		walletBackend:   wallet,
+		staticFileDir:   dir,
+		dataDir:         dir,
+		maxUploadSize:   16,
+		searchIndex:     search.NewMemoryIndex(),
+	}
+	err = db.Update(func(db *gorm.DB) error {
+		return db.Save(&models.User{ID: "brian", Email: "brian@ob1.io", FilecoinAddress: addr}).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(content []byte) *httptest.ResponseRecorder {
+		body := new(bytes.Buffer)
+		mw := multipart.NewWriter(body)
+		fw, err := mw.CreateFormFile("file", "weather.csv")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(content)
+		mw.WriteField("metadata", `{"title": "Weather", "shortDescription": "Rain", "fileType": "csv", "filename": "weather.csv", "price": "1", "image": "`+jpgTestImage+`"}`)
+		mw.Close()
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/dataset", body)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		server.handlePOSTDataset(w, r.WithContext(context.WithValue(r.Context(), "email", "brian@ob1.io")))
+		return w
+	}
+	stored := func() int {
+		infos, err := ioutil.ReadDir(filesDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(infos)
+	}
+
+	// A file over the limit is rejected before any of it is stored.
+	w := post([]byte(strings.Repeat("a", 17)))
+	if w.Code != http.StatusRequestEntityTooLarge || w.Body.String() != string(errorReturn(ErrFileTooLarge)) {
+		t.Fatalf("Expected the file to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+	if n := stored(); n != 0 {
+		t.Errorf("Expected nothing to be stored, got %d files", n)
+	}
+
+	// A file within the limit is stored with its checksum and the spooled
+	// copy is removed.
+	content := []byte(strings.Repeat("a", 16))
+	w = post(content)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the dataset to be created, got %d: %s", w.Code, w.Body.String())
+	}
+	if n := stored(); n != 1 {
+		t.Errorf("Expected the file to be stored, got %d files", n)
+	}
+	var dataset models.Dataset
+	err = db.View(func(db *gorm.DB) error {
+		return db.Where("user_id = ?", "brian").First(&dataset).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(content)
+	if dataset.FileSize != 16 || dataset.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("Unexpected size %d and checksum %s", dataset.FileSize, dataset.SHA256)
+	}
+	if infos, err := ioutil.ReadDir(path.Join(dir, "uploads")); err != nil || len(infos) != 0 {
+		t.Errorf("Expected the spooled files to be removed, got %d: %v", len(infos), err)
+	}
+
+	// The same goes for new versions of the dataset.
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	fw, err := mw.CreateFormFile("file", "weather.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte(strings.Repeat("b", 17)))
+	mw.WriteField("metadata", `{"changelog": "More rain"}`)
+	mw.Close()
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/dataset/"+dataset.ID+"/versions", body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	server.handlePOSTDatasetVersion(w, r.WithContext(context.WithValue(r.Context(), "email", "brian@ob1.io")))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected the version to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+	if n := stored(); n != 1 {
+		t.Errorf("Expected the version not to be stored, got %d files", n)
+	}
+}
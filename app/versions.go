@@ -0,0 +1,400 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/OB1Company/filehive/repo/models"
+	"github.com/filecoin-project/go-address"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// datasetVersionMetadata describes the content of a new dataset version.
+// Empty fields keep the values of the previous version.
+type datasetVersionMetadata struct {
+	Changelog string `json:"changelog"`
+	Filename  string `json:"filename"`
+	FileType  string `json:"fileType"`
+}
+
+// versionChange is a field whose value differs between two versions.
+type versionChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// saveDatasetVersion records the content the dataset currently points at as
+// its current version, along with a copy of its listing metadata.
+func saveDatasetVersion(db *gorm.DB, dataset models.Dataset, changelog string) error {
+	id, err := makeID()
+	if err != nil {
+		return err
+	}
+	return db.Create(&models.DatasetVersion{
+		ID:               id,
+		DatasetID:        dataset.ID,
+		Version:          dataset.Version,
+		Changelog:        changelog,
+		ContentID:        dataset.ContentID,
+		JobID:            dataset.JobID,
+		FileSize:         dataset.FileSize,
+		SHA256:           dataset.SHA256,
+		DatasetFilename:  dataset.DatasetFilename,
+		FileType:         dataset.FileType,
+		Title:            dataset.Title,
+		ShortDescription: dataset.ShortDescription,
+		FullDescription:  dataset.FullDescription,
+		Price:            dataset.Price,
+	}).Error
+}
+
+// updateVersionListing copies the dataset's listing metadata to its current
+// version as the listing is edited, so each version holds the listing as it
+// last stood while it was current.
+func updateVersionListing(db *gorm.DB, dataset models.Dataset) error {
+	return db.Model(&models.DatasetVersion{}).Where("dataset_id = ? AND version = ?", dataset.ID, dataset.Version).Updates(map[string]interface{}{
+		"file_type":         dataset.FileType,
+		"title":             dataset.Title,
+		"short_description": dataset.ShortDescription,
+		"full_description":  dataset.FullDescription,
+		"price_atto":        dataset.Price,
+	}).Error
+}
+
+// addDatasetVersion makes newly stored content the latest version of the
+// dataset and starts tracking its storage job. The dataset is reloaded so
+// that concurrent uploads are given different version numbers.
+func addDatasetVersion(db *gorm.DB, datasetID string, content models.DatasetVersion) (models.Dataset, error) {
+	var dataset models.Dataset
+	if err := db.Where("id = ?", datasetID).First(&dataset).Error; err != nil {
+		return dataset, err
+	}
+	dataset.Version++
+	dataset.ContentID = content.ContentID
+	dataset.JobID = content.JobID
+	dataset.FileSize = content.FileSize
+	dataset.SHA256 = content.SHA256
+	if content.DatasetFilename != "" {
+		dataset.DatasetFilename = content.DatasetFilename
+	}
+	if content.FileType != "" {
+		dataset.FileType = content.FileType
+	}
+	if err := db.Save(&dataset).Error; err != nil {
+		return dataset, err
+	}
+	if err := saveDatasetVersion(db, dataset, content.Changelog); err != nil {
+		return dataset, err
+	}
+	return dataset, saveStorageJob(db, newStorageJob(dataset))
+}
+
+// backfillDatasetVersions records the content of datasets created before
+// versions were tracked as their first version. Purchases made before then
+// cover that version.
+func (s *FileHiveServer) backfillDatasetVersions() error {
+	return s.db.Update(func(db *gorm.DB) error {
+		var datasets []models.Dataset
+		err := db.Where("content_id <> ? AND id NOT IN (?)", "", db.Model(&models.DatasetVersion{}).Select("dataset_id")).
+			Find(&datasets).Error
+		if err != nil {
+			return err
+		}
+		for _, dataset := range datasets {
+			if dataset.Version == 0 {
+				dataset.Version = 1
+				if err := db.Model(&dataset).Update("version", dataset.Version).Error; err != nil {
+					return err
+				}
+			}
+			if err := saveDatasetVersion(db, dataset, ""); err != nil {
+				return err
+			}
+		}
+		return db.Model(&models.Purchase{}).Where("version = ?", 0).Update("version", 1).Error
+	})
+}
+
+// downloadVersion returns the version of the dataset to send the user. If
+// no version is requested the user gets the latest version they are
+// entitled to. Users who are not entitled to the dataset get
+// ErrNotEntitled.
+func downloadVersion(db *gorm.DB, user models.User, dataset models.Dataset, requested int) (models.DatasetVersion, error) {
+	if requested > dataset.Version {
+		return models.DatasetVersion{}, ErrVersionNotFound
+	}
+	ent, err := datasetEntitlement(db, user, dataset)
+	if err != nil {
+		return models.DatasetVersion{}, err
+	}
+	if requested == 0 {
+		requested = ent.Version
+	} else if requested > ent.Version {
+		return models.DatasetVersion{}, ErrVersionNotCovered
+	}
+	if requested == dataset.Version {
+		return models.DatasetVersion{
+			DatasetID:       dataset.ID,
+			Version:         dataset.Version,
+			ContentID:       dataset.ContentID,
+			JobID:           dataset.JobID,
+			FileSize:        dataset.FileSize,
+			SHA256:          dataset.SHA256,
+			DatasetFilename: dataset.DatasetFilename,
+			FileType:        dataset.FileType,
+		}, nil
+	}
+	var version models.DatasetVersion
+	if err := db.Where("dataset_id = ? AND version = ?", dataset.ID, requested).First(&version).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return version, ErrVersionNotFound
+		}
+		return version, err
+	}
+	return version, nil
+}
+
+// diffVersions returns the fields that changed between two versions.
+func diffVersions(from, to models.DatasetVersion) []versionChange {
+	fields := []versionChange{
+		{"title", from.Title, to.Title},
+		{"shortDescription", from.ShortDescription, to.ShortDescription},
+		{"fullDescription", from.FullDescription, to.FullDescription},
+		{"price", from.Price.String(), to.Price.String()},
+		{"fileType", from.FileType, to.FileType},
+		{"datasetFilename", from.DatasetFilename, to.DatasetFilename},
+		{"fileSize", from.FileSize, to.FileSize},
+		{"sha256", from.SHA256, to.SHA256},
+	}
+	changes := []versionChange{}
+	for _, field := range fields {
+		if field.From != field.To {
+			changes = append(changes, field)
+		}
+	}
+	return changes
+}
+
+// handlePOSTDatasetVersion uploads new content for an existing dataset as
+// its next version. The request is a multipart form with the file and the
+// version metadata, as for handlePOSTDataset.
+func (s *FileHiveServer) handlePOSTDatasetVersion(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	id := sp[len(sp)-2]
+
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var dataset models.Dataset
+	err = s.db.View(func(db *gorm.DB) error {
+		return db.Where("id = ?", id).First(&dataset).Error
+	})
+	if err != nil || dataset.UserID != user.ID {
+		http.Error(w, wrapError(ErrDatasetNotFound), http.StatusNotFound)
+		return
+	}
+
+	addr, err := address.NewFromString(user.FilecoinAddress)
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	var (
+		containsFile, containsMetadata bool
+		content                        models.DatasetVersion
+	)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if part.FormName() == "file" {
+			f, upload, err := s.spoolUpload(part)
+			if errors.Is(err, ErrFileTooLarge) {
+				http.Error(w, wrapError(ErrFileTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			} else if err != nil {
+				http.Error(w, wrapError(err), http.StatusInternalServerError)
+				return
+			}
+
+			content.JobID, content.ContentID, _, err = s.filecoinBackend.Store(f, addr, user.PowergateToken)
+			f.Close()
+			os.Remove(f.Name())
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			content.FileSize = upload.Size()
+			content.SHA256 = upload.Checksum()
+
+			containsFile = true
+		}
+
+		if part.FormName() == "metadata" {
+			var d datasetVersionMetadata
+			if err := json.NewDecoder(part).Decode(&d); err != nil {
+				http.Error(w, wrapError(ErrInvalidJSON), http.StatusBadRequest)
+				return
+			}
+			content.Changelog = d.Changelog
+			content.DatasetFilename = d.Filename
+			content.FileType = d.FileType
+			containsMetadata = true
+		}
+	}
+
+	if !containsFile || !containsMetadata {
+		http.Error(w, wrapError(ErrMissingForm), http.StatusInternalServerError)
+		return
+	}
+	err = s.db.Update(func(db *gorm.DB) error {
+		dataset, err = addDatasetVersion(db, dataset.ID, content)
+		return err
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	log.Infof("Published version %d of dataset %s", dataset.Version, dataset.ID)
+
+	sanitizedJSONResponse(w, struct {
+		DatasetID string `json:"datasetID"`
+		Version   int    `json:"version"`
+	}{
+		DatasetID: dataset.ID,
+		Version:   dataset.Version,
+	})
+}
+
+// handleGETDatasetVersions returns the version history of a dataset, newest
+// first. The content and storage job IDs are left out as the content could
+// be fetched with them without paying for it.
+func (s *FileHiveServer) handleGETDatasetVersions(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	id := sp[len(sp)-2]
+
+	versions := []models.DatasetVersion{}
+	err := s.db.View(func(db *gorm.DB) error {
+		if err := db.Where("id = ?", id).First(&models.Dataset{}).Error; err != nil {
+			return err
+		}
+		return db.Where("dataset_id = ?", id).Order("version DESC").Find(&versions).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, wrapError(ErrDatasetNotFound), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	for i := range versions {
+		versions[i].ContentID = ""
+		versions[i].JobID = ""
+	}
+
+	sanitizedJSONResponse(w, struct {
+		Versions []models.DatasetVersion `json:"versions"`
+	}{
+		Versions: versions,
+	})
+}
+
+// handleGETDatasetVersionDiff compares the metadata of two versions of a
+// dataset. The versions are given by the from and to query parameters and
+// default to the latest version and the one before it.
+func (s *FileHiveServer) handleGETDatasetVersionDiff(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	id := sp[len(sp)-3]
+
+	var dataset models.Dataset
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("id = ?", id).First(&dataset).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrDatasetNotFound), http.StatusNotFound)
+		return
+	}
+
+	to := dataset.Version
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
+		to, err = strconv.Atoi(toStr)
+		if err != nil {
+			http.Error(w, wrapError(ErrInvalidOption), http.StatusBadRequest)
+			return
+		}
+	}
+	from := to - 1
+	if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+		from, err = strconv.Atoi(fromStr)
+		if err != nil {
+			http.Error(w, wrapError(ErrInvalidOption), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var versions []models.DatasetVersion
+	err = s.db.View(func(db *gorm.DB) error {
+		return db.Where("dataset_id = ? AND version IN ?", dataset.ID, []int{from, to}).Find(&versions).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	var fromVersion, toVersion *models.DatasetVersion
+	for i := range versions {
+		if versions[i].Version == from {
+			fromVersion = &versions[i]
+		}
+		if versions[i].Version == to {
+			toVersion = &versions[i]
+		}
+	}
+	if fromVersion == nil || toVersion == nil {
+		http.Error(w, wrapError(ErrVersionNotFound), http.StatusNotFound)
+		return
+	}
+
+	sanitizedJSONResponse(w, struct {
+		From    int             `json:"from"`
+		To      int             `json:"to"`
+		Changes []versionChange `json:"changes"`
+	}{
+		From:    from,
+		To:      to,
+		Changes: diffVersions(*fromVersion, *toVersion),
+	})
+}
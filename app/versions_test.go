@@ -0,0 +1,159 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/repo"
+	"github.com/OB1Company/filehive/repo/models"
+	"github.com/OB1Company/filehive/repo/search"
+	"github.com/filecoin-project/go-address"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_DatasetVersions(t *testing.T) {
+	db, err := repo.NewDatabase("", repo.Dialect("memory"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filesDir := path.Join(testStaticDir, "files")
+	defer os.RemoveAll(testStaticDir)
+
+	filBackend, err := fil.NewMockFilecoinBackend(filesDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &FileHiveServer{
+		db:              db,
+		filecoinBackend: filBackend,
+		walletBackend:   fil.NewMockWalletBackend(),
+		staticFileDir:   testStaticDir,
+		dataDir:         testStaticDir,
+		searchIndex:     search.NewMemoryIndex(),
+	}
+
+	store := func(content string) models.DatasetVersion {
+		jobID, cid, _, err := filBackend.Store(strings.NewReader(content), address.Undef, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return models.DatasetVersion{ContentID: cid, JobID: jobID, FileSize: int64(len(content)), SHA256: content}
+	}
+	v1, v2 := store("january"), store("february")
+
+	err = db.Update(func(db *gorm.DB) error {
+		for _, user := range []models.User{
+			{ID: "seller", Email: "seller@ob1.io"},
+			{ID: "buyer", Email: "buyer@ob1.io"},
+			{ID: "other", Email: "other@ob1.io"},
+		} {
+			if err := db.Save(&user).Error; err != nil {
+				return err
+			}
+		}
+		dataset := models.Dataset{ID: "dataset1", UserID: "seller", Title: "Rainfall", Price: fil.MustParseAmount("1"), Version: 1, ContentID: v1.ContentID, JobID: v1.JobID, FileSize: v1.FileSize, SHA256: v1.SHA256, DatasetFilename: "rainfall.csv"}
+		if err := db.Save(&dataset).Error; err != nil {
+			return err
+		}
+		if err := saveDatasetVersion(db, dataset, "First release"); err != nil {
+			return err
+		}
+		if err := db.Save(&models.Purchase{ID: "purchase1", UserID: "buyer", DatasetID: "dataset1", Version: 1, State: purchaseStateNotified, Status: purchaseStatusComplete}).Error; err != nil {
+			return err
+		}
+		if err := db.Model(&dataset).Update("title", "Monthly rainfall").Error; err != nil {
+			return err
+		}
+		v2.Changelog = "Added February"
+		_, err := addDatasetVersion(db, dataset.ID, v2)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	server.handleGETDatasetVersions(w, httptest.NewRequest(http.MethodGet, "/api/v1/dataset/dataset1/versions", nil))
+	var history struct {
+		Versions []models.DatasetVersion `json:"versions"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&history); err != nil {
+		t.Fatal(err)
+	}
+	if len(history.Versions) != 2 || history.Versions[0].Version != 2 || history.Versions[0].Changelog != "Added February" || history.Versions[1].SHA256 != v1.SHA256 {
+		t.Errorf("Unexpected version history %v", history.Versions)
+	}
+	for _, version := range history.Versions {
+		if version.ContentID != "" || version.JobID != "" {
+			t.Errorf("Expected version %d to be listed without its content and job IDs", version.Version)
+		}
+	}
+
+	// Edits to the listing are kept with the current version.
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPatch, "/api/v1/dataset", strings.NewReader(`{"id": "dataset1", "shortDescription": "Rain by month", "price": "2"}`))
+	server.handlePATCHDataset(w, r.WithContext(context.WithValue(r.Context(), "email", "seller@ob1.io")))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the dataset to be updated, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	server.handleGETDatasetVersionDiff(w, httptest.NewRequest(http.MethodGet, "/api/v1/dataset/dataset1/versions/diff", nil))
+	var diff struct {
+		From    int             `json:"from"`
+		To      int             `json:"to"`
+		Changes []versionChange `json:"changes"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&diff); err != nil {
+		t.Fatal(err)
+	}
+	var fields []string
+	for _, change := range diff.Changes {
+		fields = append(fields, change.Field)
+	}
+	if diff.From != 1 || diff.To != 2 || !reflect.DeepEqual(fields, []string{"title", "shortDescription", "price", "fileSize", "sha256"}) {
+		t.Errorf("Unexpected diff %v", diff)
+	}
+
+	w = httptest.NewRecorder()
+	server.handleGETDatasetVersionDiff(w, httptest.NewRequest(http.MethodGet, "/api/v1/dataset/dataset1/versions/diff?from=1&to=3", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	tests := []struct {
+		email      string
+		query      string
+		statusCode int
+		content    string
+	}{
+		{email: "seller@ob1.io", query: "", statusCode: http.StatusOK, content: "february"},
+		{email: "seller@ob1.io", query: "?version=1", statusCode: http.StatusOK, content: "january"},
+		{email: "buyer@ob1.io", query: "", statusCode: http.StatusOK, content: "january"},
+		{email: "buyer@ob1.io", query: "?version=2", statusCode: http.StatusUnauthorized},
+		{email: "other@ob1.io", query: "?version=1", statusCode: http.StatusUnauthorized},
+		{email: "seller@ob1.io", query: "?version=3", statusCode: http.StatusNotFound},
+		{email: "seller@ob1.io", query: "?version=x", statusCode: http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/download/dataset1"+test.query, nil)
+		server.handleGETDatasetFile(w, r.WithContext(context.WithValue(r.Context(), "email", test.email)))
+		if w.Code != test.statusCode {
+			t.Errorf("%s%s: expected status code %d, got %d", test.email, test.query, test.statusCode, w.Code)
+			continue
+		}
+		if test.statusCode == http.StatusOK && w.Body.String() != test.content {
+			t.Errorf("%s%s: expected %s, got %s", test.email, test.query, test.content, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,642 @@
+package app
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/OB1Company/filehive/repo/models"
+	"gorm.io/gorm"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Events a webhook can subscribe to.
+const (
+	webhookEventPurchaseCreated    = "purchase.created"
+	webhookEventDatasetCreated     = "dataset.created"
+	webhookEventDatasetDealUpdated = "dataset.deal_updated"
+	webhookEventWalletReceived     = "wallet.received"
+
+	// webhookEventPing is only sent by the ping endpoint to test a webhook
+	// and can't be subscribed to.
+	webhookEventPing = "ping"
+)
+
+var webhookEvents = []string{
+	webhookEventPurchaseCreated,
+	webhookEventDatasetCreated,
+	webhookEventDatasetDealUpdated,
+	webhookEventWalletReceived,
+}
+
+// Webhook delivery statuses as persisted in the WebhookDelivery model.
+const (
+	webhookStatusPending   = "pending"
+	webhookStatusDelivered = "delivered"
+	webhookStatusFailed    = "failed"
+)
+
+const (
+	defaultWebhookPollInterval = time.Second * 30
+
+	// maxWebhookAttempts is how many times a delivery is tried before it is
+	// marked failed.
+	maxWebhookAttempts = 8
+
+	// webhookRetryDelay is how long to wait after the first failed attempt.
+	// The delay doubles after each attempt up to maxWebhookRetryDelay.
+	webhookRetryDelay    = time.Minute
+	maxWebhookRetryDelay = time.Hour * 4
+
+	// webhookBatchSize is the most deliveries made in one pass.
+	webhookBatchSize = 100
+
+	webhookTimeout  = time.Second * 10
+	webhookPageSize = 50
+)
+
+// webhookClient posts the deliveries. Redirects are not followed so a
+// webhook has to be registered with the URL that handles it. Connections
+// are only made to public addresses, and not through a proxy, so webhooks
+// can't be used to reach services on the marketplace's own network.
+var webhookClient = &http.Client{
+	Timeout: webhookTimeout,
+	Transport: &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: webhookTimeout, Control: publicAddressOnly}).DialContext,
+		TLSHandshakeTimeout: webhookTimeout,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// nonPublicNetworks are the private and shared address ranges webhooks may
+// not connect to.
+var nonPublicNetworks = func() []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range []string{"10.0.0.0/8", "100.64.0.0/10", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"} {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}()
+
+// publicAddressOnly refuses connections to loopback, private, link-local,
+// multicast and unspecified addresses. It is run on the address being
+// dialed, after the host name is resolved, so a name that is rebound to
+// such an address after the webhook is registered is refused too.
+func publicAddressOnly(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return ErrWebhookAddressNotAllowed
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return ErrWebhookAddressNotAllowed
+		}
+	}
+	return nil
+}
+
+// webhookPayload is the body posted to a webhook. The data depends on the
+// event.
+type webhookPayload struct {
+	ID      string      `json:"id"`
+	Event   string      `json:"event"`
+	Created time.Time   `json:"created"`
+	Data    interface{} `json:"data"`
+}
+
+// webhookResponse is a webhook as returned by the API. The secret is only
+// set when the webhook is created.
+type webhookResponse struct {
+	models.Webhook
+	Events []string `json:"events"`
+	Secret string   `json:"secret,omitempty"`
+}
+
+func newWebhookResponse(hook models.Webhook) webhookResponse {
+	return webhookResponse{
+		Webhook: hook,
+		Events:  webhookSubscriptions(hook),
+	}
+}
+
+// webhookSubscriptions returns the events the webhook subscribes to.
+func webhookSubscriptions(hook models.Webhook) []string {
+	if hook.Events == "" {
+		return []string{}
+	}
+	return strings.Split(hook.Events, ",")
+}
+
+// queueWebhookEvent adds a delivery of the event to each of the user's
+// webhooks that subscribe to it. It is called in the same transaction as
+// the change that caused the event, after which the caller should wake the
+// webhook worker.
+func queueWebhookEvent(db *gorm.DB, userID, event string, data interface{}) error {
+	var hooks []models.Webhook
+	if err := db.Where("user_id = ?", userID).Find(&hooks).Error; err != nil {
+		return err
+	}
+	for _, hook := range hooks {
+		subscribed := false
+		for _, e := range webhookSubscriptions(hook) {
+			if e == event {
+				subscribed = true
+			}
+		}
+		if !subscribed {
+			continue
+		}
+		if _, err := createWebhookDelivery(db, hook.ID, event, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createWebhookDelivery saves a pending delivery of the event to the
+// webhook.
+func createWebhookDelivery(db *gorm.DB, webhookID, event string, data interface{}) (models.WebhookDelivery, error) {
+	id, err := makeID()
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	now := time.Now()
+	payload, err := json.Marshal(webhookPayload{
+		ID:      id,
+		Event:   event,
+		Created: now,
+		Data:    data,
+	})
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	delivery := models.WebhookDelivery{
+		ID:          id,
+		WebhookID:   webhookID,
+		Event:       event,
+		Payload:     string(payload),
+		Status:      webhookStatusPending,
+		QueuedAt:    now,
+		NextAttempt: now,
+	}
+	return delivery, db.Create(&delivery).Error
+}
+
+// wakeWebhooks has the webhook worker look for deliveries to make without
+// waiting for its next poll.
+func (s *FileHiveServer) wakeWebhooks() {
+	select {
+	case s.webhookWake <- struct{}{}:
+	default:
+	}
+}
+
+// runWebhooks makes the queued deliveries until the server is shut down.
+// It wakes whenever an event is queued and otherwise polls for deliveries
+// that are due to be retried.
+func (s *FileHiveServer) runWebhooks() {
+	ticker := time.NewTicker(defaultWebhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.deliverWebhooks(); err != nil {
+			log.Errorf("Error delivering webhooks: %s", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-s.webhookWake:
+		case <-s.shutdown:
+			return
+		}
+	}
+}
+
+// deliverWebhooks makes a single pass over the deliveries that are due.
+func (s *FileHiveServer) deliverWebhooks() error {
+	var deliveries []models.WebhookDelivery
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("status = ? AND next_attempt <= ?", webhookStatusPending, time.Now()).
+			Order("queued_at").Limit(webhookBatchSize).Find(&deliveries).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		if _, err := s.deliverWebhook(delivery); err != nil {
+			log.Errorf("Error updating webhook delivery %s: %s", delivery.ID, err)
+		}
+	}
+	return nil
+}
+
+// deliverWebhook posts the delivery to its webhook and records the outcome.
+// A failed delivery is retried with exponential backoff until it runs out
+// of attempts. Pings are only tried once.
+func (s *FileHiveServer) deliverWebhook(delivery models.WebhookDelivery) (models.WebhookDelivery, error) {
+	var hook models.Webhook
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("id = ?", delivery.WebhookID).First(&hook).Error
+	})
+	var postErr error
+	final := delivery.Event == webhookEventPing
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// The webhook was deleted after the event was queued.
+		postErr = ErrWebhookNotFound
+		final = true
+	} else if err != nil {
+		return delivery, err
+	} else {
+		delivery.ResponseCode, postErr = postWebhook(hook, delivery)
+	}
+
+	now := time.Now()
+	delivery.Attempts++
+	switch {
+	case postErr == nil:
+		delivery.Status = webhookStatusDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	case delivery.Attempts >= maxWebhookAttempts || final:
+		log.Warningf("Webhook delivery %s to %s failed after %d attempts: %s", delivery.ID, delivery.WebhookID, delivery.Attempts, postErr)
+		delivery.Status = webhookStatusFailed
+		delivery.LastError = postErr.Error()
+	default:
+		log.Debugf("Error delivering webhook %s to %s: %s", delivery.ID, delivery.WebhookID, postErr)
+		delivery.LastError = postErr.Error()
+		delivery.NextAttempt = now.Add(retryBackoff(delivery.Attempts, webhookRetryDelay, maxWebhookRetryDelay))
+	}
+
+	err = s.db.Update(func(db *gorm.DB) error {
+		return db.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(map[string]interface{}{
+			"status":        delivery.Status,
+			"attempts":      delivery.Attempts,
+			"response_code": delivery.ResponseCode,
+			"last_error":    delivery.LastError,
+			"next_attempt":  delivery.NextAttempt,
+			"delivered_at":  delivery.DeliveredAt,
+		}).Error
+	})
+	return delivery, err
+}
+
+// postWebhook posts the payload of a delivery to the webhook and returns
+// the response status. Any status other than 2xx is an error.
+func postWebhook(hook models.Webhook, delivery models.WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Filehive-Webhooks/1.0")
+	req.Header.Set("X-Filehive-Event", delivery.Event)
+	req.Header.Set("X-Filehive-Delivery", delivery.ID)
+	req.Header.Set("X-Filehive-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Filehive-Signature", "sha256="+signWebhook(hook.Secret, timestamp, []byte(delivery.Payload)))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// signWebhook returns the HMAC-SHA256 of the timestamp and payload, joined
+// by a period, keyed with the webhook's secret. Receivers recompute it to
+// check the payload came from us and use the timestamp to reject replays.
+func signWebhook(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// validWebhookURL reports whether the URL is an absolute http or https URL.
+func validWebhookURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.User == nil
+}
+
+// normalizeWebhookEvents checks the events can be subscribed to and
+// removes duplicates.
+func normalizeWebhookEvents(events []string) ([]string, error) {
+	if len(events) == 0 {
+		return nil, ErrInvalidWebhookEvent
+	}
+	seen := make(map[string]bool)
+	normalized := make([]string, 0, len(events))
+	for _, event := range events {
+		valid := false
+		for _, e := range webhookEvents {
+			if e == event {
+				valid = true
+			}
+		}
+		if !valid {
+			return nil, ErrInvalidWebhookEvent
+		}
+		if !seen[event] {
+			seen[event] = true
+			normalized = append(normalized, event)
+		}
+	}
+	return normalized, nil
+}
+
+// loadWebhook returns the user's webhook with the given ID.
+func (s *FileHiveServer) loadWebhook(r *http.Request, id string) (models.User, models.Webhook, error) {
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		return models.User{}, models.Webhook{}, ErrInvalidCredentials
+	}
+
+	var (
+		user models.User
+		hook models.Webhook
+	)
+	err := s.db.View(func(db *gorm.DB) error {
+		if err := db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error; err != nil {
+			return ErrInvalidCredentials
+		}
+		if err := db.Where("id = ? AND user_id = ?", id, user.ID).First(&hook).Error; err != nil {
+			return ErrWebhookNotFound
+		}
+		return nil
+	})
+	return user, hook, err
+}
+
+// webhookError writes the error returned by loadWebhook.
+func webhookError(w http.ResponseWriter, err error) {
+	switch err {
+	case ErrInvalidCredentials:
+		http.Error(w, wrapError(err), http.StatusUnauthorized)
+	case ErrWebhookNotFound:
+		http.Error(w, wrapError(err), http.StatusNotFound)
+	default:
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+	}
+}
+
+// handleGETWebhooks lists the user's webhooks.
+func (s *FileHiveServer) handleGETWebhooks(w http.ResponseWriter, r *http.Request) {
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var hooks []models.Webhook
+	err = s.db.View(func(db *gorm.DB) error {
+		return db.Where("user_id = ?", user.ID).Order("created").Find(&hooks).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]webhookResponse, 0, len(hooks))
+	for _, hook := range hooks {
+		resp = append(resp, newWebhookResponse(hook))
+	}
+	sanitizedJSONResponse(w, struct {
+		Webhooks []webhookResponse `json:"webhooks"`
+	}{
+		Webhooks: resp,
+	})
+}
+
+// handlePOSTWebhook registers a webhook for the user. The response holds
+// the secret the payloads are signed with. It isn't returned again.
+func (s *FileHiveServer) handlePOSTWebhook(w http.ResponseWriter, r *http.Request) {
+	emailIface := r.Context().Value("email")
+
+	email, ok := emailIface.(string)
+	if !ok {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	err := s.db.View(func(db *gorm.DB) error {
+		return db.Where("LOWER(email) = ?", strings.ToLower(email)).First(&user).Error
+
+	})
+	if err != nil {
+		http.Error(w, wrapError(ErrInvalidCredentials), http.StatusUnauthorized)
+		return
+	}
+
+	type data struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+	var d data
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+		http.Error(w, wrapError(ErrInvalidJSON), http.StatusBadRequest)
+		return
+	}
+
+	if !validWebhookURL(d.URL) {
+		http.Error(w, wrapError(ErrInvalidWebhookURL), http.StatusBadRequest)
+		return
+	}
+	events, err := normalizeWebhookEvents(d.Events)
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusBadRequest)
+		return
+	}
+
+	id, err := makeID()
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	hook := models.Webhook{
+		ID:      id,
+		UserID:  user.ID,
+		URL:     d.URL,
+		Events:  strings.Join(events, ","),
+		Secret:  hex.EncodeToString(secret),
+		Created: time.Now(),
+	}
+	err = s.db.Update(func(db *gorm.DB) error {
+		return db.Create(&hook).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	resp := newWebhookResponse(hook)
+	resp.Secret = hook.Secret
+	sanitizedJSONResponse(w, resp)
+}
+
+// handleDELETEWebhook removes one of the user's webhooks along with its
+// delivery log.
+func (s *FileHiveServer) handleDELETEWebhook(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	id := sp[len(sp)-1]
+
+	_, hook, err := s.loadWebhook(r, id)
+	if err != nil {
+		webhookError(w, err)
+		return
+	}
+
+	err = s.db.Update(func(db *gorm.DB) error {
+		if err := db.Where("webhook_id = ?", hook.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return db.Where("id = ?", hook.ID).Delete(&models.Webhook{}).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// handleGETWebhookDeliveries returns the delivery log of one of the user's
+// webhooks, newest first, optionally filtered by status.
+func (s *FileHiveServer) handleGETWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	id := sp[len(sp)-2]
+
+	_, hook, err := s.loadWebhook(r, id)
+	if err != nil {
+		webhookError(w, err)
+		return
+	}
+
+	var page int
+	pageStr := r.URL.Query().Get("page")
+	if pageStr != "" {
+		page, err = strconv.Atoi(pageStr)
+		if err != nil || page < 0 {
+			http.Error(w, wrapError(ErrInvalidOption), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var (
+		deliveries []models.WebhookDelivery
+		count      int64
+	)
+	err = s.db.View(func(db *gorm.DB) error {
+		query := db.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", hook.ID)
+		if status := r.URL.Query().Get("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+		if err := query.Count(&count).Error; err != nil {
+			return err
+		}
+		return query.Order("queued_at DESC").Offset(page * webhookPageSize).Limit(webhookPageSize).Find(&deliveries).Error
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	sanitizedJSONResponse(w, struct {
+		Pages      int                      `json:"pages"`
+		Page       int                      `json:"page"`
+		Deliveries []models.WebhookDelivery `json:"deliveries"`
+	}{
+		Pages:      (int(count) / webhookPageSize) + 1,
+		Page:       page,
+		Deliveries: deliveries,
+	})
+}
+
+// handlePOSTWebhookPing sends a ping event to one of the user's webhooks
+// straight away and returns the delivery so the endpoint can be tested.
+func (s *FileHiveServer) handlePOSTWebhookPing(w http.ResponseWriter, r *http.Request) {
+	sp := strings.Split(r.URL.Path, "/")
+	id := sp[len(sp)-2]
+
+	_, hook, err := s.loadWebhook(r, id)
+	if err != nil {
+		webhookError(w, err)
+		return
+	}
+
+	var delivery models.WebhookDelivery
+	err = s.db.Update(func(db *gorm.DB) error {
+		delivery, err = createWebhookDelivery(db, hook.ID, webhookEventPing, struct {
+			WebhookID string   `json:"webhookID"`
+			Events    []string `json:"events"`
+		}{
+			WebhookID: hook.ID,
+			Events:    webhookSubscriptions(hook),
+		})
+		return err
+	})
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	delivery, err = s.deliverWebhook(delivery)
+	if err != nil {
+		http.Error(w, wrapError(err), http.StatusInternalServerError)
+		return
+	}
+
+	sanitizedJSONResponse(w, delivery)
+}
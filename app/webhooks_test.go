@@ -0,0 +1,278 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/repo"
+	"github.com/OB1Company/filehive/repo/models"
+	userPb "github.com/textileio/powergate/api/gen/powergate/user/v1"
+	"gorm.io/gorm"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_Webhooks(t *testing.T) {
+	db, err := repo.NewDatabase("", repo.Dialect("memory"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wallet := fil.NewMockWalletBackend()
+	server := &FileHiveServer{
+		db:            db,
+		walletBackend: wallet,
+		webhookWake:   make(chan struct{}, 1),
+	}
+
+	addr, err := wallet.NewAddress("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(db *gorm.DB) error {
+		if err := db.Save(&models.User{ID: "brian", Email: "brian@ob1.io", FilecoinAddress: addr}).Error; err != nil {
+			return err
+		}
+		if err := db.Save(&models.User{ID: "amanda", Email: "amanda@ob1.io"}).Error; err != nil {
+			return err
+		}
+		return db.Save(&models.StorageJob{ID: "job1", DatasetID: "weather", UserID: "brian", Status: jobStatusQueued}).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The receiver fails its first request so the delivery is retried.
+	type received struct {
+		header  http.Header
+		payload []byte
+	}
+	var (
+		mtx      sync.Mutex
+		requests []received
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mtx.Lock()
+		defer mtx.Unlock()
+		requests = append(requests, received{r.Header, body})
+		if len(requests) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer receiver.Close()
+
+	// The receiver is on loopback, which the real client refuses.
+	client := webhookClient
+	webhookClient = &http.Client{Timeout: webhookTimeout}
+	defer func() { webhookClient = client }()
+
+	request := func(method, target, email string, body []byte, handler http.HandlerFunc) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, target, bytes.NewReader(body))
+		handler(w, r.WithContext(context.WithValue(r.Context(), "email", email)))
+		return w
+	}
+
+	for _, body := range []string{
+		`{"url": "ftp://example.com", "events": ["wallet.received"]}`,
+		`{"url": "/hooks", "events": ["wallet.received"]}`,
+	} {
+		w := request(http.MethodPost, "/api/v1/webhooks", "brian@ob1.io", []byte(body), server.handlePOSTWebhook)
+		if w.Code != http.StatusBadRequest || w.Body.String() != string(errorReturn(ErrInvalidWebhookURL)) {
+			t.Errorf("Expected %s to be rejected, got %d: %s", body, w.Code, w.Body.String())
+		}
+	}
+	for _, body := range []string{
+		`{"url": "` + receiver.URL + `", "events": []}`,
+		`{"url": "` + receiver.URL + `", "events": ["ping"]}`,
+	} {
+		w := request(http.MethodPost, "/api/v1/webhooks", "brian@ob1.io", []byte(body), server.handlePOSTWebhook)
+		if w.Code != http.StatusBadRequest || w.Body.String() != string(errorReturn(ErrInvalidWebhookEvent)) {
+			t.Errorf("Expected %s to be rejected, got %d: %s", body, w.Code, w.Body.String())
+		}
+	}
+
+	w := request(http.MethodPost, "/api/v1/webhooks", "brian@ob1.io", []byte(`{"url": "`+receiver.URL+`", "events": ["wallet.received", "dataset.deal_updated", "wallet.received"]}`), server.handlePOSTWebhook)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the webhook to be created, got %d: %s", w.Code, w.Body.String())
+	}
+	var hook webhookResponse
+	if err := json.NewDecoder(w.Body).Decode(&hook); err != nil {
+		t.Fatal(err)
+	}
+	if hook.Secret == "" || len(hook.Events) != 2 {
+		t.Fatalf("Expected a secret and two events, got %+v", hook)
+	}
+
+	w = request(http.MethodGet, "/api/v1/webhooks", "brian@ob1.io", nil, server.handleGETWebhooks)
+	var list struct {
+		Webhooks []webhookResponse `json:"webhooks"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Webhooks) != 1 || list.Webhooks[0].ID != hook.ID || list.Webhooks[0].Secret != "" {
+		t.Errorf("Expected the webhook to be listed without its secret, got %+v", list.Webhooks)
+	}
+
+	// Funds received after the first scan are sent to the webhook, while
+	// events it doesn't subscribe to are not.
+	if err := server.scanWalletAddress(addr, ""); err != nil {
+		t.Fatal(err)
+	}
+	wallet.GenerateToAddress(addr, fil.MustParseAmount("2").AttoFIL())
+	if err := server.scanWalletAddress(addr, ""); err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(db *gorm.DB) error {
+		return queueWebhookEvent(db, "brian", webhookEventPurchaseCreated, nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries := func() []models.WebhookDelivery {
+		var deliveries []models.WebhookDelivery
+		err := db.View(func(db *gorm.DB) error {
+			return db.Order("queued_at").Find(&deliveries).Error
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return deliveries
+	}
+	if d := deliveries(); len(d) != 1 || d[0].Event != webhookEventWalletReceived {
+		t.Fatalf("Expected one wallet.received delivery, got %+v", d)
+	}
+
+	// A failed delivery is retried later.
+	if err := server.deliverWebhooks(); err != nil {
+		t.Fatal(err)
+	}
+	d := deliveries()[0]
+	if d.Status != webhookStatusPending || d.Attempts != 1 || d.ResponseCode != http.StatusInternalServerError || !d.NextAttempt.After(time.Now()) {
+		t.Fatalf("Expected the delivery to be retried, got %+v", d)
+	}
+	err = db.Update(func(db *gorm.DB) error {
+		return db.Model(&models.WebhookDelivery{}).Where("id = ?", d.ID).Update("next_attempt", time.Now()).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.deliverWebhooks(); err != nil {
+		t.Fatal(err)
+	}
+	if d := deliveries()[0]; d.Status != webhookStatusDelivered || d.Attempts != 2 || d.DeliveredAt == nil {
+		t.Fatalf("Expected the delivery to succeed, got %+v", d)
+	}
+
+	// Each request is signed with the secret.
+	if len(requests) != 2 {
+		t.Fatalf("Expected two requests, got %d", len(requests))
+	}
+	req := requests[1]
+	timestamp, err := strconv.ParseInt(req.header.Get("X-Filehive-Timestamp"), 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.header.Get("X-Filehive-Signature") != "sha256="+signWebhook(hook.Secret, timestamp, req.payload) {
+		t.Error("Expected a valid signature")
+	}
+	if req.header.Get("X-Filehive-Event") != webhookEventWalletReceived || req.header.Get("X-Filehive-Delivery") != d.ID {
+		t.Errorf("Unexpected headers %v", req.header)
+	}
+	var payload struct {
+		ID    string              `json:"id"`
+		Event string              `json:"event"`
+		Data  transactionResponse `json:"data"`
+	}
+	if err := json.Unmarshal(req.payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.ID != d.ID || payload.Data.Amount.String() != "2" || payload.Data.Direction != transactionDirectionIn {
+		t.Errorf("Unexpected payload %s", req.payload)
+	}
+
+	// Deal updates are only sent when the job or its deals change.
+	job := models.StorageJob{ID: "job1", DatasetID: "weather", UserID: "brian", Status: jobStatusQueued}
+	status := &userPb.StorageJob{
+		Status:   userPb.JobStatus_JOB_STATUS_EXECUTING,
+		DealInfo: []*userPb.DealInfo{{ProposalCid: "deal1", StateName: "StorageDealPublishing"}},
+	}
+	for i := 0; i < 2; i++ {
+		if job, err = server.updateStorageJob(job, status); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := deliveries(); len(d) != 2 || d[1].Event != webhookEventDatasetDealUpdated {
+		t.Fatalf("Expected one dataset.deal_updated delivery, got %+v", d)
+	}
+
+	// A ping is delivered straight away.
+	w = request(http.MethodPost, "/api/v1/webhooks/"+hook.ID+"/ping", "amanda@ob1.io", nil, server.handlePOSTWebhookPing)
+	if w.Code != http.StatusNotFound || w.Body.String() != string(errorReturn(ErrWebhookNotFound)) {
+		t.Errorf("Expected another user's webhook not to be found, got %d: %s", w.Code, w.Body.String())
+	}
+	w = request(http.MethodPost, "/api/v1/webhooks/"+hook.ID+"/ping", "brian@ob1.io", nil, server.handlePOSTWebhookPing)
+	var ping models.WebhookDelivery
+	if err := json.NewDecoder(w.Body).Decode(&ping); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusOK || ping.Event != webhookEventPing || ping.Status != webhookStatusDelivered || ping.ResponseCode != http.StatusOK {
+		t.Errorf("Expected the ping to be delivered, got %d: %+v", w.Code, ping)
+	}
+
+	w = request(http.MethodGet, "/api/v1/webhooks/"+hook.ID+"/deliveries?status=delivered", "brian@ob1.io", nil, server.handleGETWebhookDeliveries)
+	var deliveryLog struct {
+		Deliveries []models.WebhookDelivery `json:"deliveries"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&deliveryLog); err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveryLog.Deliveries) != 2 || deliveryLog.Deliveries[0].ID != ping.ID {
+		t.Errorf("Expected the delivered events in the log, got %+v", deliveryLog.Deliveries)
+	}
+
+	// Deleting the webhook removes its delivery log.
+	w = request(http.MethodDelete, "/api/v1/webhooks/"+hook.ID, "brian@ob1.io", nil, server.handleDELETEWebhook)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the webhook to be deleted, got %d: %s", w.Code, w.Body.String())
+	}
+	w = request(http.MethodGet, "/api/v1/webhooks/"+hook.ID+"/deliveries", "brian@ob1.io", nil, server.handleGETWebhookDeliveries)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected the webhook to be gone, got %d: %s", w.Code, w.Body.String())
+	}
+	if d := deliveries(); len(d) != 0 {
+		t.Errorf("Expected the deliveries to be deleted, got %d", len(d))
+	}
+}
+
+func Test_WebhookPrivateAddresses(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected the loopback receiver not to be reached")
+	}))
+	defer receiver.Close()
+
+	if _, err := webhookClient.Post(receiver.URL, "application/json", bytes.NewReader([]byte("{}"))); !errors.Is(err, ErrWebhookAddressNotAllowed) {
+		t.Errorf("Expected the loopback address to be refused, got %v", err)
+	}
+
+	for _, address := range []string{"127.0.0.1:80", "[::1]:80", "10.1.2.3:443", "172.16.0.1:80", "192.168.1.1:80", "169.254.169.254:80", "0.0.0.0:80", "[fe80::1]:80", "[fd00::1]:80"} {
+		if err := publicAddressOnly("tcp", address, nil); err != ErrWebhookAddressNotAllowed {
+			t.Errorf("Expected %s to be refused, got %v", address, err)
+		}
+	}
+	for _, address := range []string{"93.184.216.34:443", "[2606:2800:220:1:248:1893:25c8:1946]:443"} {
+		if err := publicAddressOnly("tcp", address, nil); err != nil {
+			t.Errorf("Expected %s to be allowed, got %v", address, err)
+		}
+	}
+}
@@ -1,109 +0,0 @@
-<!DOCTYPE html>
-<html lang="en" xmlns:v="urn:schemas-microsoft-com:vml" xmlns:o="urn:schemas-microsoft-com:office:office">
-<head>
-  <meta charset="utf-8">
-  <meta name="x-apple-disable-message-reformatting">
-  <meta http-equiv="x-ua-compatible" content="ie=edge">
-  <meta name="viewport" content="width=device-width, initial-scale=1">
-  <meta name="format-detection" content="telephone=no, date=no, address=no, email=no">
-
-  <!--[if mso]>
-    <xml><o:OfficeDocumentSettings><o:PixelsPerInch>96</o:PixelsPerInch></o:OfficeDocumentSettings></xml>
-    <style>
-      td,th,div,p,a,h1,h2,h3,h4,h5,h6 {font-family: "Segoe UI", sans-serif; mso-line-height-rule: exactly;}
-    </style>
-  <![endif]-->
-  <title>Welcome to the Filehive, %recipient_name%!</title>
-  <style>
-    .hover-bg-brand-600:hover {
-      background-color: #D99512 !important;
-    }
-    .hover-text-brand-700:hover {
-      color: #D99512 !important;
-    }
-    .hover-underline:hover {
-      text-decoration: underline !important;
-    }
-    @media (max-width: 640px) {
-      .sm-block {
-        display: block !important;
-      }
-      .sm-h-16 {
-        height: 16px !important;
-      }
-      .sm-text-14 {
-        font-size: 14px !important;
-      }
-      .sm-mt-16 {
-        margin-top: 16px !important;
-      }
-      .sm-py-16 {
-        padding-top: 16px !important;
-        padding-bottom: 16px !important;
-      }
-      .sm-px-16 {
-        padding-left: 16px !important;
-        padding-right: 16px !important;
-      }
-      .sm-py-24 {
-        padding-top: 24px !important;
-        padding-bottom: 24px !important;
-      }
-      .sm-w-full {
-        width: 100% !important;
-      }
-    }
-  </style>
-</head>
-<body lang="en" style="margin: 0; padding: 0; width: 100%; word-break: break-word; -webkit-font-smoothing: antialiased" bgcolor="#ffffff">
-<div style="display: none">Nice to have you on board, %recipient_name%!&#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &zwnj;
-  &#160;&#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &#847; &zwnj;
-  &#160;&#847; &#847; &#847; &#847; &#847; </div>
-<div role="article" aria-roledescription="email" aria-label="Password Reset Instructions for Filehive" lang="en">
-  <table style="font-family: -apple-system, 'Segoe UI', sans-serif; width: 100%" cellpadding="0" cellspacing="0" role="presentation">
-    <tr>
-      <td align="center" bgcolor="#ffffff">
-        <table class="sm-w-full" style="width: 640px" cellpadding="0" cellspacing="0" role="presentation">
-          <tr>
-            <td class="sm-px-16 sm-py-24" style="padding: 48px 40px; text-align: left" bgcolor="#ffffff">
-              <div style="margin-bottom: 24px">
-                <a href="https://%domain_name%" style="color: #0047c3; text-decoration: none">
-                  <img src="https://filehive.app/filehive-logo.png" alt="Filehive" width="119" style="border: 0; line-height: 100%; max-width: 100%; vertical-align: middle">
-                </a>
-              </div>
-              <p style="font-size: 21px; line-height: 28px; margin-bottom:10px; color: #4a5566">Hello %recipient_name%,</p>
-              <p style="font-size: 21px; line-height: 28px; margin: 0; color: #4a5566">Click the button below to reset your password for your Filehive account.</p>
-              <div class="sm-h-16" style="line-height: 16px">&nbsp;</div>
-              <table class="sm-w-full" cellpadding="0" cellspacing="0" role="presentation">
-                <tr>
-                  <td align="center" class="hover-bg-brand-600" style="mso-padding-alt: 20px 32px; border-radius: 4px; color: #ffffff; box-shadow: 0 1px 3px 0 rgba(0, 0, 0, 0.1), 0 1px 2px 0 rgba(0, 0, 0, 0.06)" bgcolor="#F3A815">
-                    <a href="https://%domain_name%/change_password?email=%email%&code=%code%" class="sm-text-14 sm-py-16" style="display: inline-block; font-weight: 700; font-size: 16px; line-height: 16px; padding: 20px 32px; color: #ffffff; text-decoration: none">Reset Password</a>
-                  </td>
-                </tr>
-              </table>
-              <div class="sm-h-16" style="line-height: 16px">&nbsp;</div>
-              <p style="font-size: 16px; line-height: 22px; margin-bottom: 10px; color: #8492a6">Is the button not working for you? Copy the url below into your browser.<br/><br/><a href="https://%domain_name%/change_password?email=%email%&code=%code%">https://%domain_name%/change_password?email=%email%&code=%code%</a></p>
-              <p style="font-size: 16px; line-height: 22px; margin: 0; color: #8492a6">Thank you,<br/>The Filehive Team</p>
-              <div style="text-align: left">
-                <table style="width: 100%" cellpadding="0" cellspacing="0" role="presentation">
-                  <tr>
-                    <td style="padding-bottom: 16px; padding-top: 64px">
-                      <div style="background-color: #e1e1ea; height: 1px; line-height: 1px">&nbsp;</div>
-                    </td>
-                  </tr>
-                </table>
-                <p style="font-size: 12px; line-height: 16px; margin-top: 0; margin-bottom: 16px; color: #8492a6">
-                  This email was sent to you as a registered member of <a href="https://%domain_name%" class="hover-text-brand-700 hover-underline" style="color: #F3A815; text-decoration: none; display: inline-block">%domain_name%</a>. To update your emails preferences <a href="https://%domain_name%" class="hover-text-brand-700 hover-underline" style="color: #F3A815; text-decoration: none; display: inline-block">click here</a>.
-                  <span class="sm-block sm-mt-16">Use of the service and website is subject to our <a href="https://%domain_name%" class="hover-text-brand-700 hover-underline" style="color: #F3A815; text-decoration: none; display: inline-block">Terms of Use</a>.</span>
-                </p>
-                <p style="font-size: 12px; line-height: 16px; margin: 0; color: #8492a6">&copy; 2021 Filehive. All rights reserved.</p>
-              </div>
-            </td>
-          </tr>
-        </table>
-      </td>
-    </tr>
-  </table>
-</div>
-</body>
-</html>
\ No newline at end of file
@@ -1,140 +0,0 @@
-<!DOCTYPE html>
-<html lang="en" xmlns:v="urn:schemas-microsoft-com:vml" xmlns:o="urn:schemas-microsoft-com:office:office">
-<head>
-  <meta charset="utf-8">
-  <meta name="x-apple-disable-message-reformatting">
-  <meta http-equiv="x-ua-compatible" content="ie=edge">
-  <meta name="viewport" content="width=device-width, initial-scale=1">
-  <meta name="format-detection" content="telephone=no, date=no, address=no, email=no">
-
-  <!--[if mso]>
-    <xml><o:OfficeDocumentSettings><o:PixelsPerInch>96</o:PixelsPerInch></o:OfficeDocumentSettings></xml>
-    <style>
-      td,th,div,p,a,h1,h2,h3,h4,h5,h6 {font-family: "Segoe UI", sans-serif; mso-line-height-rule: exactly;}
-    </style>
-  <![endif]-->
-  <style>
-    .hover-bg-brand-600:hover {
-      background-color: #F3A815 !important;
-    }
-    .hover-underline:hover {
-      text-decoration: underline !important;
-    }
-    @media (max-width: 640px) {
-      .sm-block {
-        display: block !important;
-      }
-      .sm-inline-block {
-        display: inline-block !important;
-      }
-      .sm-px-0 {
-        padding-left: 0 !important;
-        padding-right: 0 !important;
-      }
-      .sm-py-16 {
-        padding-top: 16px !important;
-        padding-bottom: 16px !important;
-      }
-      .sm-px-16 {
-        padding-left: 16px !important;
-        padding-right: 16px !important;
-      }
-      .sm-py-24 {
-        padding-top: 24px !important;
-        padding-bottom: 24px !important;
-      }
-      .sm-w-auto {
-        width: auto !important;
-      }
-      .sm-w-full {
-        width: 100% !important;
-      }
-    }
-  </style>
-</head>
-<body lang="en" style="margin: 0; padding: 0; width: 100%; word-break: break-word; -webkit-font-smoothing: antialiased" bgcolor="#ffffff">
-<div role="article" aria-roledescription="email" aria-label="" lang="en">
-  <table style="font-family: -apple-system, 'Segoe UI', sans-serif; width: 100%" cellpadding="0" cellspacing="0" role="presentation">
-    <tr>
-      <td align="center" bgcolor="#ffffff">
-        <table class="sm-w-full" style="width: 640px" cellpadding="0" cellspacing="0" role="presentation">
-          <tr>
-            <td align="left" class="sm-px-16 sm-py-24" style="padding: 48px 40px" bgcolor="#ffffff">
-              <div style="margin-bottom: 24px">
-                <a href="https://%domain_name%" style="color: #F3A815; text-decoration: none">
-                  <img src="https://filehive.app/filehive-logo.png" alt="Filehive" width="119" style="border: 0; line-height: 100%; max-width: 100%; vertical-align: middle">
-                </a>
-              </div>
-              <p style="font-size: 21px; line-height: 28px; margin: 0; color: #4a5566">Hi %recipient_name% 💵</p>
-              <p style="font-size: 21px; line-height: 28px; margin: 10px 0 0 0; color: #4a5566">
-                %customer% placed a new order <a href="https://%domain_name%/dashboard/sales" class="hover-underline" style="text-decoration: none; color: #F3A815">%order_id%</a> with your store on %timestamp%.
-              </p>
-
-              <div style="line-height: 32px">&nbsp;</div>
-
-              <div style="line-height: 32px">&nbsp;</div>
-              <p style="font-size: 16px; line-height: 22px; margin-top: 0; margin-bottom: 16px; color: #8492a6">Order summary:</p>
-              <table style="width: 100%" cellpadding="0" cellspacing="0" role="presentation">
-                <tr>
-                  <td style="vertical-align: top; width: 72px" valign="top">
-                    <img src="%image%" alt="%dataset_name%" width="48" style="border: 0; line-height: 100%; max-width: 100%; vertical-align: middle">
-                  </td>
-                  <td class="sm-w-auto" style="text-align: left; vertical-align: top; width: 488px" valign="top">
-                    <p style="font-weight: 700; font-size: 16px; margin-top: 0; margin-bottom: 8px; color: #4a5566">%dataset_name%</p>
-                    <p style="font-size: 16px; line-height: 22px; margin-top: 0; margin-bottom: 8px; color: #4a5566">%dataset_shortdescription%</p>
-                  </td>
-                  <td style="text-align: right; vertical-align: top; width: 88px" valign="top">
-                    <p style="font-weight: 700; font-size: 16px; line-height: 22px; margin: 0 0 8px; color: #4a5566">%dataset_price%</p>
-
-                  </td>
-                </tr>
-              </table>
-              <div style="line-height: 24px">&nbsp;</div>
-              <table style="width: 100%" cellpadding="0" cellspacing="0" role="presentation">
-                <tr>
-                  <td style="padding-top: 12px; padding-bottom: 24px">
-                    <div style="background-color: #e1e1ea; height: 2px; line-height: 2px">&nbsp;</div>
-                  </td>
-                </tr>
-              </table>
-              <table style="width: 100%" cellpadding="0" cellspacing="0" role="presentation">
-                <tr>
-                  <td align="right">
-                    <table class="sm-w-full" cellpadding="0" cellspacing="0" role="presentation">
-                      <tr>
-                        <td align="center" class="hover-bg-brand-600" style="mso-padding-alt: 16px 32px; border-radius: 4px; color: #ffffff; box-shadow: 0 1px 3px 0 rgba(0, 0, 0, 0.1), 0 1px 2px 0 rgba(0, 0, 0, 0.06)" bgcolor="#F3A815">
-                          <a href="https://%domain_name%/dashboard/sales" class="sm-block sm-py-16" style="text-decoration: none; display: inline-block; font-weight: 700; font-size: 14px; line-height: 16px; padding: 16px 32px; color: #ffffff">View order</a>
-                        </td>
-                      </tr>
-                    </table>
-                  </td>
-                </tr>
-              </table>
-
-
-              <div style="text-align: left">
-                <table style="width: 100%" cellpadding="0" cellspacing="0" role="presentation">
-                  <tr>
-                    <td style="padding-bottom: 16px; padding-top: 64px">
-                      <div style="background-color: #e1e1ea; height: 1px; line-height: 1px">&nbsp;</div>
-                    </td>
-                  </tr>
-                </table>
-                <p style="font-size: 12px; line-height: 16px; margin: 0; color: #8492a6">If you have any questions, reply to this email or contact us at <a href="mailto:support@%domain_name%" class="hover-underline" style="text-decoration: none; color: #F3A815">support@%domain_name%</a></p>
-                <table style="width: 100%" cellpadding="0" cellspacing="0" role="presentation">
-                  <tr>
-                    <td>
-                      <p style="font-size: 12px; line-height: 16px; margin: 16px 0 0; color: #8492a6">&copy; 2021 Filehive. All rights reserved.</p>
-                    </td>
-                  </tr>
-                </table>
-              </div>
-            </td>
-          </tr>
-        </table>
-      </td>
-    </tr>
-  </table>
-</div>
-</body>
-</html>
\ No newline at end of file
@@ -0,0 +1,425 @@
+// Package emails renders the emails the server sends to users.
+//
+// Every email has an HTML template, executed inside a shared layout, and a
+// plain-text template that also defines the subject. Both live under
+// templates/<locale>/ and are compiled into the binary with go-bindata, so
+// rendering doesn't depend on the working directory. Regenerate bindata.go
+// with `make email-templates` after editing them.
+package emails
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"path"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// DefaultLocale is used when a user has no language preference and their
+// country doesn't map to one of the translated locales.
+const DefaultLocale = "en"
+
+// Names of the emails that can be rendered.
+const (
+	NameWelcome         = "welcome"
+	NamePasswordReset   = "password-reset"
+	NameSale            = "sale"
+	NameJobCompleted    = "job-completed"
+	NameLowBalance      = "low-balance"
+	NameDatasetDelisted = "dataset-delisted"
+)
+
+var (
+	// ErrUnknownEmail means no email by the given name exists.
+	ErrUnknownEmail = errors.New("unknown email")
+
+	// ErrWrongData means the data passed to Render is not the type the
+	// email's templates expect.
+	ErrWrongData = errors.New("wrong data for email")
+)
+
+// outlookStyles is the conditional comment block for Outlook that goes in
+// the layout's head. html/template strips comments so it is added through a
+// template function.
+const outlookStyles = `<!--[if mso]>
+  <xml><o:OfficeDocumentSettings><o:PixelsPerInch>96</o:PixelsPerInch></o:OfficeDocumentSettings></xml>
+  <style>
+    td,th,div,p,a,h1,h2,h3,h4,h5,h6 {font-family: "Segoe UI", sans-serif; mso-line-height-rule: exactly;}
+  </style>
+  <![endif]-->`
+
+// Site is the data shared by every email.
+type Site struct {
+	// Domain is the domain the site is served from, used to build links.
+	Domain string
+}
+
+// WelcomeData is the data for the email sent after signing up.
+type WelcomeData struct {
+	Site
+	Name  string
+	Email string
+	Code  string
+}
+
+// PasswordResetData is the data for the email sent when a user asks to
+// reset their password.
+type PasswordResetData struct {
+	Site
+	Name  string
+	Email string
+	Code  string
+}
+
+// SaleData is the data for the email sent to a seller when one of their
+// datasets is bought.
+type SaleData struct {
+	Site
+	SellerName         string
+	BuyerName          string
+	BuyerEmail         string
+	DatasetTitle       string
+	DatasetDescription string
+	Price              string
+	OrderID            string
+	Timestamp          time.Time
+
+	// Image is an optional JPEG thumbnail of the dataset.
+	Image []byte
+}
+
+// ImageURL returns the thumbnail as a data URL for embedding in the email.
+func (d SaleData) ImageURL() htmltemplate.URL {
+	if len(d.Image) == 0 {
+		return ""
+	}
+	return htmltemplate.URL("data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(d.Image))
+}
+
+// JobCompletedData is the data for the email sent when a dataset's storage
+// job succeeds.
+type JobCompletedData struct {
+	Site
+	Name         string
+	DatasetID    string
+	DatasetTitle string
+}
+
+// LowBalanceData is the data for the email sent when a user's wallet
+// balance falls below the low balance threshold.
+type LowBalanceData struct {
+	Site
+	Name      string
+	Address   string
+	Balance   string
+	Threshold string
+}
+
+// DatasetDelistedData is the data for the email sent when an admin delists
+// a user's dataset.
+type DatasetDelistedData struct {
+	Site
+	Name         string
+	DatasetID    string
+	DatasetTitle string
+}
+
+// Email is a rendered email.
+type Email struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// layoutData is what the layout template is executed with. The email's own
+// templates are executed with Data.
+type layoutData struct {
+	Locale  string
+	Subject string
+	Data    interface{}
+}
+
+// emailTemplates holds the templates for one email in one locale.
+type emailTemplates struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+var (
+	// sampleData returns the data each email is rendered with for
+	// previews. It also records which type of data each email expects.
+	sampleData = map[string]func(site Site) interface{}{
+		NameWelcome: func(site Site) interface{} {
+			return WelcomeData{
+				Site:  site,
+				Name:  "Satoshi",
+				Email: "satoshi@example.com",
+				Code:  "123456",
+			}
+		},
+		NamePasswordReset: func(site Site) interface{} {
+			return PasswordResetData{
+				Site:  site,
+				Name:  "Satoshi",
+				Email: "satoshi@example.com",
+				Code:  "123456",
+			}
+		},
+		NameSale: func(site Site) interface{} {
+			return SaleData{
+				Site:               site,
+				SellerName:         "Satoshi",
+				BuyerName:          "Hal",
+				BuyerEmail:         "hal@example.com",
+				DatasetTitle:       "Global weather 2020",
+				DatasetDescription: "Hourly readings from 10,000 weather stations",
+				Price:              "1.5 FIL",
+				OrderID:            "2xRbWzF5QmNQ1Xb3",
+				Timestamp:          time.Date(2021, 3, 14, 15, 9, 26, 0, time.UTC),
+			}
+		},
+		NameJobCompleted: func(site Site) interface{} {
+			return JobCompletedData{
+				Site:         site,
+				Name:         "Satoshi",
+				DatasetID:    "2xRbWzF5QmNQ1Xb3",
+				DatasetTitle: "Global weather 2020",
+			}
+		},
+		NameLowBalance: func(site Site) interface{} {
+			return LowBalanceData{
+				Site:      site,
+				Name:      "Satoshi",
+				Address:   "f1om6safovokyxkddpbbvg5avhsjsmsn2pletqvqa",
+				Balance:   "0.005 FIL",
+				Threshold: "0.01 FIL",
+			}
+		},
+		NameDatasetDelisted: func(site Site) interface{} {
+			return DatasetDelistedData{
+				Site:         site,
+				Name:         "Satoshi",
+				DatasetID:    "2xRbWzF5QmNQ1Xb3",
+				DatasetTitle: "Global weather 2020",
+			}
+		},
+	}
+
+	// templates maps locale and then email name to the parsed templates.
+	templates = mustParseTemplates()
+)
+
+func mustParseTemplates() map[string]map[string]emailTemplates {
+	t, err := parseTemplates()
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// parseTemplates parses the embedded templates for every locale. Each
+// locale directory must have a common.html with the shared footers.
+func parseTemplates() (map[string]map[string]emailTemplates, error) {
+	funcs := htmltemplate.FuncMap{
+		"outlookStyles": func() htmltemplate.HTML { return outlookStyles },
+	}
+	layout, err := htmltemplate.New("layout.html").Funcs(funcs).Parse(string(MustAsset("layout.html")))
+	if err != nil {
+		return nil, err
+	}
+
+	all := make(map[string]map[string]emailTemplates)
+	for _, locale := range localeDirs() {
+		common, err := layout.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := common.Parse(string(MustAsset(path.Join(locale, "common.html")))); err != nil {
+			return nil, fmt.Errorf("%s/common.html: %w", locale, err)
+		}
+
+		all[locale] = make(map[string]emailTemplates)
+		for name := range sampleData {
+			htmlAsset, err := Asset(path.Join(locale, name+".html"))
+			if err != nil {
+				continue
+			}
+			textAsset, err := Asset(path.Join(locale, name+".txt"))
+			if err != nil {
+				return nil, fmt.Errorf("%s/%s.txt is missing", locale, name)
+			}
+
+			html, err := common.Clone()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := html.Parse(string(htmlAsset)); err != nil {
+				return nil, fmt.Errorf("%s/%s.html: %w", locale, name, err)
+			}
+			text, err := texttemplate.New(name).Parse(string(textAsset))
+			if err != nil {
+				return nil, fmt.Errorf("%s/%s.txt: %w", locale, name, err)
+			}
+			if text.Lookup("subject") == nil {
+				return nil, fmt.Errorf("%s/%s.txt does not define a subject", locale, name)
+			}
+			all[locale][name] = emailTemplates{html: html, text: text}
+		}
+	}
+	if len(all[DefaultLocale]) != len(sampleData) {
+		return nil, fmt.Errorf("every email needs a %s template", DefaultLocale)
+	}
+	return all, nil
+}
+
+// localeDirs returns the locale directories among the embedded assets.
+func localeDirs() []string {
+	var locales []string
+	for name := range _bintree.Children {
+		if _, err := AssetDir(name); err == nil {
+			locales = append(locales, name)
+		}
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Names returns the names of the emails that can be rendered.
+func Names() []string {
+	names := make([]string, 0, len(sampleData))
+	for name := range sampleData {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Locales returns the locales the emails are translated into.
+func Locales() []string {
+	locales := make([]string, 0, len(templates))
+	for locale := range templates {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// spanishCountries are the ISO 3166-1 alpha-2 codes of the countries where
+// Spanish is the main language.
+var spanishCountries = map[string]bool{
+	"AR": true, "BO": true, "CL": true, "CO": true, "CR": true, "CU": true,
+	"DO": true, "EC": true, "ES": true, "GQ": true, "GT": true, "HN": true,
+	"MX": true, "NI": true, "PA": true, "PE": true, "PR": true, "PY": true,
+	"SV": true, "UY": true, "VE": true,
+}
+
+// Locale picks the locale to email a user in. An explicit language
+// preference wins. Otherwise the locale is guessed from the user's country
+// code, falling back to DefaultLocale.
+func Locale(language, country string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if i := strings.IndexAny(language, "-_"); i > 0 {
+		language = language[:i]
+	}
+	if _, ok := templates[language]; ok {
+		return language
+	}
+
+	if spanishCountries[strings.ToUpper(strings.TrimSpace(country))] {
+		return "es"
+	}
+	return DefaultLocale
+}
+
+// Welcome renders the email sent after signing up.
+func Welcome(locale string, data WelcomeData) (Email, error) {
+	return Render(NameWelcome, locale, data)
+}
+
+// PasswordReset renders the email with the password reset link.
+func PasswordReset(locale string, data PasswordResetData) (Email, error) {
+	return Render(NamePasswordReset, locale, data)
+}
+
+// Sale renders the email sent to a seller about a purchase.
+func Sale(locale string, data SaleData) (Email, error) {
+	return Render(NameSale, locale, data)
+}
+
+// JobCompleted renders the email sent when a dataset has been stored.
+func JobCompleted(locale string, data JobCompletedData) (Email, error) {
+	return Render(NameJobCompleted, locale, data)
+}
+
+// LowBalance renders the email warning that a wallet is running low.
+func LowBalance(locale string, data LowBalanceData) (Email, error) {
+	return Render(NameLowBalance, locale, data)
+}
+
+// DatasetDelisted renders the email sent when an admin delists a dataset.
+func DatasetDelisted(locale string, data DatasetDelistedData) (Email, error) {
+	return Render(NameDatasetDelisted, locale, data)
+}
+
+// Render renders the named email in the locale, falling back to
+// DefaultLocale if it hasn't been translated. The data must be the type the
+// email expects, such as WelcomeData for the welcome email.
+func Render(name, locale string, data interface{}) (Email, error) {
+	sample, ok := sampleData[name]
+	if !ok {
+		return Email{}, ErrUnknownEmail
+	}
+	if fmt.Sprintf("%T", sample(Site{})) != fmt.Sprintf("%T", data) {
+		return Email{}, ErrWrongData
+	}
+
+	t, ok := templates[locale][name]
+	if !ok {
+		locale = DefaultLocale
+		t = templates[locale][name]
+	}
+
+	subject := new(bytes.Buffer)
+	if err := t.text.ExecuteTemplate(subject, "subject", data); err != nil {
+		return Email{}, err
+	}
+	email := Email{Subject: strings.TrimSpace(subject.String())}
+
+	text := new(bytes.Buffer)
+	if err := t.text.Execute(text, data); err != nil {
+		return Email{}, err
+	}
+	email.Text = strings.TrimSpace(text.String()) + "\n"
+
+	html := new(bytes.Buffer)
+	err := t.html.ExecuteTemplate(html, "layout", layoutData{
+		Locale:  locale,
+		Subject: email.Subject,
+		Data:    data,
+	})
+	if err != nil {
+		return Email{}, err
+	}
+	email.HTML = html.String()
+
+	return email, nil
+}
+
+// Preview renders the named email with sample data so the templates can be
+// checked without triggering a real email. Links point at the domain, or at
+// filehive.app if it is empty.
+func Preview(name, locale, domain string) (Email, error) {
+	sample, ok := sampleData[name]
+	if !ok {
+		return Email{}, ErrUnknownEmail
+	}
+	if domain == "" {
+		domain = "filehive.app"
+	}
+	return Render(name, locale, sample(Site{Domain: domain}))
+}
@@ -0,0 +1,114 @@
+package emails
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderAll(t *testing.T) {
+	for _, locale := range Locales() {
+		for _, name := range Names() {
+			email, err := Preview(name, locale, "")
+			if err != nil {
+				t.Errorf("%s/%s: %s", locale, name, err)
+				continue
+			}
+			if email.Subject == "" || email.Text == "" {
+				t.Errorf("%s/%s: expected a subject and plain text", locale, name)
+			}
+			if !strings.Contains(email.HTML, `<html lang="`+locale+`"`) || !strings.Contains(email.HTML, "<title>"+template.HTMLEscapeString(email.Subject)+"</title>") {
+				t.Errorf("%s/%s: expected the layout to be rendered", locale, name)
+			}
+		}
+	}
+	if len(Locales()) < 2 {
+		t.Errorf("Expected translated locales, got %v", Locales())
+	}
+}
+
+func TestRenderEscapes(t *testing.T) {
+	email, err := Sale("en", SaleData{
+		Site:               Site{Domain: "filehive.io"},
+		SellerName:         "Brian",
+		BuyerName:          `<script>alert("hi")</script>`,
+		DatasetTitle:       `Weather" onload="alert(1)`,
+		DatasetDescription: "Temperatures & rainfall",
+		Price:              "1 FIL",
+		OrderID:            "abc",
+		Timestamp:          time.Now(),
+		Image:              []byte{0xff, 0xd8},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(email.HTML, "<script>") || !strings.Contains(email.HTML, "&lt;script&gt;") {
+		t.Error("Expected the buyer name to be escaped")
+	}
+	if strings.Contains(email.HTML, `" onload="`) {
+		t.Error("Expected the dataset title to be escaped in attributes")
+	}
+	if !strings.Contains(email.HTML, `src="data:image/jpeg;base64,/9g="`) {
+		t.Error("Expected the thumbnail to be embedded")
+	}
+	if !strings.Contains(email.Text, `<script>alert("hi")</script>`) {
+		t.Error("Expected the plain text not to be HTML escaped")
+	}
+
+	email, err = Welcome("en", WelcomeData{
+		Site:  Site{Domain: "filehive.io"},
+		Name:  "Brian",
+		Email: "brian+test@ob1.io",
+		Code:  "123",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	link := "https://filehive.io/confirm_email?email=brian%2btest%40ob1.io&code=123"
+	if !strings.Contains(strings.ToLower(email.HTML), link) {
+		t.Errorf("Expected the link %s in the HTML", link)
+	}
+	if !strings.Contains(email.Text, "email=brian%2Btest%40ob1.io&code=123") {
+		t.Errorf("Expected the link in the plain text, got %s", email.Text)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	if _, err := Render("invoice", "en", WelcomeData{}); err != ErrUnknownEmail {
+		t.Errorf("Expected ErrUnknownEmail, got %v", err)
+	}
+	if _, err := Render(NameSale, "en", WelcomeData{}); err != ErrWrongData {
+		t.Errorf("Expected ErrWrongData, got %v", err)
+	}
+
+	// An untranslated locale falls back to the default.
+	email, err := Welcome("fr", WelcomeData{Name: "Brian"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(email.Text, "Hi Brian") {
+		t.Errorf("Expected the English email, got %s", email.Text)
+	}
+}
+
+func TestLocale(t *testing.T) {
+	for _, test := range []struct {
+		language string
+		country  string
+		locale   string
+	}{
+		{"", "", "en"},
+		{"", "US", "en"},
+		{"", "MX", "es"},
+		{"", "es", "es"},
+		{"en", "MX", "en"},
+		{"es-AR", "", "es"},
+		{"fr", "ES", "es"},
+		{"fr", "FR", "en"},
+	} {
+		if locale := Locale(test.language, test.country); locale != test.locale {
+			t.Errorf("Locale(%q, %q): expected %s, got %s", test.language, test.country, test.locale, locale)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package fil
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// attoFILDecimals is the number of decimal places in an amount of FIL.
+const attoFILDecimals = 18
+
+// amountColumnWidth is the number of digits amounts are zero padded to in
+// the database so that columns of amounts sort and compare correctly as
+// strings. It is enough for any amount up to 10^21 FIL.
+const amountColumnWidth = 40
+
+// ErrInvalidAmount is returned when parsing an amount that is not a decimal
+// number of FIL with at most 18 decimal places.
+var ErrInvalidAmount = errors.New("invalid FIL amount")
+
+var attoFILPerFIL = new(big.Int).Exp(big.NewInt(10), big.NewInt(attoFILDecimals), nil)
+
+// Amount is an exact amount of Filecoin held in attoFIL. The zero value is
+// zero FIL. Amounts are never modified in place, arithmetic returns a new
+// amount.
+//
+// Amounts are marshalled to JSON as a string of FIL, such as "1.5", and are
+// stored in the database as a zero padded string of attoFIL.
+type Amount struct {
+	atto *big.Int
+}
+
+// NewAmount returns an amount of attoFIL.
+func NewAmount(attoFIL *big.Int) Amount {
+	if attoFIL == nil || attoFIL.Sign() == 0 {
+		return Amount{}
+	}
+	return Amount{atto: new(big.Int).Set(attoFIL)}
+}
+
+// ParseAmount parses a decimal amount of FIL such as "1.5". It is exact,
+// any number of FIL with up to 18 decimal places is accepted.
+func ParseAmount(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	if whole == "" && frac == "" || len(frac) > attoFILDecimals || !isDigits(whole) || !isDigits(frac) {
+		return Amount{}, ErrInvalidAmount
+	}
+
+	atto, ok := new(big.Int).SetString("0"+whole+frac+strings.Repeat("0", attoFILDecimals-len(frac)), 10)
+	if !ok {
+		return Amount{}, ErrInvalidAmount
+	}
+	if neg {
+		atto.Neg(atto)
+	}
+	return NewAmount(atto), nil
+}
+
+// ParseAttoFIL parses a decimal integer amount of attoFIL.
+func ParseAttoFIL(s string) (Amount, error) {
+	s = strings.TrimSpace(s)
+	if !isDigits(strings.TrimPrefix(s, "-")) || s == "" || s == "-" {
+		return Amount{}, ErrInvalidAmount
+	}
+	atto, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return Amount{}, ErrInvalidAmount
+	}
+	return NewAmount(atto), nil
+}
+
+// MustParseAmount is like ParseAmount but panics if the amount is invalid.
+// It is meant for amounts written out in code.
+func MustParseAmount(s string) Amount {
+	a, err := ParseAmount(s)
+	if err != nil {
+		panic(fmt.Sprintf("fil: %s: %q", err, s))
+	}
+	return a
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// AttoFIL returns the amount in attoFIL.
+func (a Amount) AttoFIL() *big.Int {
+	if a.atto == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(a.atto)
+}
+
+// String returns the amount as a decimal number of FIL without trailing
+// zeros, such as "1.5".
+func (a Amount) String() string {
+	if a.atto == nil {
+		return "0"
+	}
+	whole, frac := new(big.Int).QuoRem(new(big.Int).Abs(a.atto), attoFILPerFIL, new(big.Int))
+
+	s := whole.String()
+	if frac.Sign() != 0 {
+		fs := frac.String()
+		s += "." + strings.TrimRight(strings.Repeat("0", attoFILDecimals-len(fs))+fs, "0")
+	}
+	if a.atto.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// Add returns a + b.
+func (a Amount) Add(b Amount) Amount {
+	return NewAmount(new(big.Int).Add(a.AttoFIL(), b.AttoFIL()))
+}
+
+// Sub returns a - b.
+func (a Amount) Sub(b Amount) Amount {
+	return NewAmount(new(big.Int).Sub(a.AttoFIL(), b.AttoFIL()))
+}
+
+// Cmp compares a and b and returns -1, 0 or +1 like big.Int.Cmp.
+func (a Amount) Cmp(b Amount) int {
+	return a.AttoFIL().Cmp(b.AttoFIL())
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of the amount.
+func (a Amount) Sign() int {
+	if a.atto == nil {
+		return 0
+	}
+	return a.atto.Sign()
+}
+
+// IsZero returns whether the amount is zero.
+func (a Amount) IsZero() bool {
+	return a.Sign() == 0
+}
+
+// MarshalJSON marshals the amount as a string of FIL.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON accepts a string of FIL. Plain JSON numbers are accepted too
+// and are parsed exactly from their decimal digits.
+func (a *Amount) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) {
+		*a = Amount{}
+		return nil
+	}
+	s := string(b)
+	if len(b) > 0 && b[0] == '"' {
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+	} else if strings.ContainsAny(s, "eE") {
+		// Numbers in exponent notation, which JavaScript uses for small
+		// amounts, are rounded to the nearest attoFIL.
+		f, ok := new(big.Float).SetPrec(256).SetString(s)
+		if !ok {
+			return ErrInvalidAmount
+		}
+		s = f.Text('f', attoFILDecimals)
+	}
+	amt, err := ParseAmount(s)
+	if err != nil {
+		return err
+	}
+	*a = amt
+	return nil
+}
+
+// Value implements driver.Valuer. Amounts are stored as attoFIL zero padded
+// to a fixed width.
+func (a Amount) Value() (driver.Value, error) {
+	atto := a.AttoFIL()
+	s := new(big.Int).Abs(atto).String()
+	if len(s) < amountColumnWidth {
+		s = strings.Repeat("0", amountColumnWidth-len(s)) + s
+	}
+	if atto.Sign() < 0 {
+		s = "-" + s
+	}
+	return s, nil
+}
+
+// Scan implements sql.Scanner.
+func (a *Amount) Scan(src interface{}) error {
+	var (
+		amt Amount
+		err error
+	)
+	switch v := src.(type) {
+	case nil:
+	case string:
+		amt, err = ParseAttoFIL(v)
+	case []byte:
+		amt, err = ParseAttoFIL(string(v))
+	default:
+		err = fmt.Errorf("cannot scan %T into amount", src)
+	}
+	if err != nil {
+		return err
+	}
+	*a = amt
+	return nil
+}
+
+// GormDataType stores amounts in a string column.
+func (Amount) GormDataType() string {
+	return "string"
+}
@@ -0,0 +1,166 @@
+package fil
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		Value    string
+		Expected string
+		Valid    bool
+	}{
+		{
+			Value:    "1.234",
+			Expected: "1234000000000000000",
+			Valid:    true,
+		},
+		{
+			Value:    "1.234000000123",
+			Expected: "1234000000123000000",
+			Valid:    true,
+		},
+		{
+			Value:    "0.000000000000000001",
+			Expected: "1",
+			Valid:    true,
+		},
+		{
+			Value:    "123456789012345678901234567890.5",
+			Expected: "123456789012345678901234567890500000000000000000",
+			Valid:    true,
+		},
+		{
+			Value:    ".5",
+			Expected: "500000000000000000",
+			Valid:    true,
+		},
+		{
+			Value:    "-2",
+			Expected: "-2000000000000000000",
+			Valid:    true,
+		},
+		{
+			Value: "0.0000000000000000001",
+		},
+		{
+			Value: "1e18",
+		},
+		{
+			Value: "1.2.3",
+		},
+		{
+			Value: "",
+		},
+	}
+
+	for i, test := range tests {
+		amt, err := ParseAmount(test.Value)
+		if (err == nil) != test.Valid {
+			t.Errorf("Test %d: expected valid %t, got error %v", i, test.Valid, err)
+			continue
+		}
+		if test.Valid && amt.AttoFIL().String() != test.Expected {
+			t.Errorf("Test %d: got %s, want %s", i, amt.AttoFIL(), test.Expected)
+		}
+	}
+}
+
+func TestAmountString(t *testing.T) {
+	tests := []struct {
+		Value    string
+		Expected string
+	}{
+		{
+			Value:    "0",
+			Expected: "0",
+		},
+		{
+			Value:    "1234000000000000111",
+			Expected: "1.234000000000000111",
+		},
+		{
+			Value:    "1500000000000000000",
+			Expected: "1.5",
+		},
+		{
+			Value:    "-50000000000000000",
+			Expected: "-0.05",
+		},
+		{
+			Value:    "7000000000000000000",
+			Expected: "7",
+		},
+	}
+
+	for i, test := range tests {
+		amt, err := ParseAttoFIL(test.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if amt.String() != test.Expected {
+			t.Errorf("Test %d: got %s, want %s", i, amt, test.Expected)
+		}
+	}
+}
+
+func TestAmountJSON(t *testing.T) {
+	var v struct {
+		A Amount `json:"a"`
+		B Amount `json:"b"`
+		C Amount `json:"c"`
+		D Amount `json:"d"`
+	}
+	if err := json.Unmarshal([]byte(`{"a": "0.1", "b": 0.3, "c": 1e-7, "d": null}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.A.Add(v.B).Cmp(MustParseAmount("0.4")) != 0 {
+		t.Errorf("Expected 0.1 + 0.3 to be exactly 0.4, got %s", v.A.Add(v.B))
+	}
+	if v.C.AttoFIL().Cmp(big.NewInt(100000000000)) != 0 {
+		t.Errorf("Expected 1e-7 FIL, got %s attoFIL", v.C.AttoFIL())
+	}
+	if !v.D.IsZero() {
+		t.Errorf("Expected null to be zero, got %s", v.D)
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"a":"0.1","b":"0.3","c":"0.0000001","d":"0"}` {
+		t.Errorf("Unexpected JSON %s", b)
+	}
+
+	if err := json.Unmarshal([]byte(`{"a": "ten"}`), &v); err == nil {
+		t.Error("Expected invalid amount to fail")
+	}
+}
+
+func TestAmountValue(t *testing.T) {
+	small, large := MustParseAmount("9.5"), MustParseAmount("10")
+	sv, err := small.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lv, err := large.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sv.(string) >= lv.(string) {
+		t.Errorf("Expected stored amounts to sort as strings, got %s and %s", sv, lv)
+	}
+
+	var amt Amount
+	if err := amt.Scan([]byte(lv.(string))); err != nil {
+		t.Fatal(err)
+	}
+	if amt.Cmp(large) != 0 {
+		t.Errorf("Expected %s, got %s", large, amt)
+	}
+	if err := amt.Scan(nil); err != nil || !amt.IsZero() {
+		t.Errorf("Expected NULL to scan as zero, got %s %v", amt, err)
+	}
+}
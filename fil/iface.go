@@ -18,7 +18,18 @@ func init() {
 // if the address does not have enough funds.
 var ErrInsuffientFunds = errors.New("insufficient funds")
 
-const attoFilPerFilecoin = 1000000000000000000
+// ErrMessageNotFound is returned by WalletBackend.MessageStatus if the
+// message is neither on chain nor waiting to be included.
+var ErrMessageNotFound = errors.New("message not found")
+
+// ErrMessageLookupUnavailable is returned by WalletBackend.MessageStatus if
+// the backend has no way to look up messages.
+var ErrMessageLookupUnavailable = errors.New("message lookup is not available")
+
+// ErrTxidUnknown is returned by WalletBackend.Send if the funds were sent
+// but the CID of the message could not be determined. The send must be
+// treated as made.
+var ErrTxidUnknown = errors.New("message cid is unknown")
 
 // FilecoinBackend is an interface to a Filecoin backend that interacts with the
 // Filecoin network and handles storage deals and retrieval.
@@ -29,6 +40,10 @@ type FilecoinBackend interface {
 
 	JobStatus(cid string, userToken string) (*userPb.StorageJob, error)
 
+	// Retry re-applies the storage config for content that is already
+	// staged, starting a new storage job. The new jobID is returned.
+	Retry(contentID string, userToken string) (jobID string, err error)
+
 	Get(cid string, userToken string) (io.Reader, error)
 
 	CreateUser() (id string, token string, error error)
@@ -41,7 +56,8 @@ type WalletBackend interface {
 	NewAddress(userToken string) (string, error)
 
 	// Send filecoin from one address to another. Returns the cid of the
-	// transaction.
+	// transaction, or ErrTxidUnknown if it was sent but the cid can't be
+	// determined.
 	Send(from, to string, amount *big.Int, userToken string) (string, error)
 
 	// Balance returns the balance for an address.
@@ -49,6 +65,18 @@ type WalletBackend interface {
 
 	// Transactions returns the list of transactions for an address.
 	Transactions(addr string, limit, offset int) ([]Transaction, error)
+
+	// MessageStatus returns the status of a message sent by Send.
+	MessageStatus(txid string) (*MessageStatus, error)
+}
+
+// MessageStatus describes a message on its way on to the chain. Height
+// and ExitCode are only set once the message is included.
+type MessageStatus struct {
+	Cid      string
+	Included bool
+	Height   int64
+	ExitCode int64
 }
 
 // Transaction represents a Filecoin transaction.
@@ -66,39 +94,13 @@ func (t *Transaction) MarshalJSON() ([]byte, error) {
 		ID        string    `json:"transactionID"`
 		From      string    `json:"from"`
 		To        string    `json:"to"`
-		Amount    float64   `json:"amount"`
+		Amount    Amount    `json:"amount"`
 		Timestamp time.Time `json:"timestamp"`
 	}{
 		ID:        t.ID,
 		From:      t.From,
 		To:        t.To,
-		Amount:    AttoFILToFIL(t.Amount),
+		Amount:    NewAmount(t.Amount),
 		Timestamp: t.Timestamp,
 	})
 }
-
-// FILtoAttoFIL converts a float containing an amount of Filecoin to
-// a big.Int representation in the attoFil base unit.
-func FILtoAttoFIL(fil float64) *big.Int {
-	bigAtto := big.NewFloat(fil)
-	bigAtto = bigAtto.Mul(bigAtto, big.NewFloat(attoFilPerFilecoin))
-	ret, acc := bigAtto.Int(nil)
-
-	if acc == big.Above {
-		ret.Add(ret, big.NewInt(-1))
-	}
-	if acc == big.Below {
-		ret.Add(ret, big.NewInt(1))
-	}
-
-	return ret
-}
-
-// AttoFILtoFIL converts a big.Int containing the attoFIL base unit to
-// a float of the amount of Filecoin.
-func AttoFILToFIL(attoFIL *big.Int) float64 {
-	bigAtto := new(big.Float).SetInt(attoFIL)
-	bigAtto = bigAtto.Quo(bigAtto, new(big.Float).SetInt(big.NewInt(attoFilPerFilecoin)))
-	ret, _ := bigAtto.Float64()
-	return ret
-}
@@ -0,0 +1,144 @@
+package fil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+// LotusClient makes calls to the JSON-RPC API of a Lotus node. Powergate
+// does not expose the messages it sends, so the node is used to find the
+// CID of a sent message and follow it on to the chain.
+type LotusClient struct {
+	endpoint string
+	token    string
+	client   *http.Client
+}
+
+// NewLotusClient returns a client for the Lotus API at endpoint, for
+// example http://127.0.0.1:1234/rpc/v0. The token is only needed if the
+// node requires authorization for read calls.
+func NewLotusClient(endpoint, token string) *LotusClient {
+	return &LotusClient{
+		endpoint: endpoint,
+		token:    token,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type lotusCid struct {
+	Root string `json:"/"`
+}
+
+type lotusMessage struct {
+	From  string
+	To    string
+	Nonce uint64
+	Value string
+}
+
+type lotusSignedMessage struct {
+	Message lotusMessage
+	CID     lotusCid
+}
+
+type lotusMsgLookup struct {
+	Message lotusCid
+	Receipt struct {
+		ExitCode int64
+	}
+	Height int64
+}
+
+func (c *LotusClient) call(method string, result interface{}, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "Filecoin." + method,
+		"params":  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("lotus %s: %s", method, err)
+	}
+	if rpcResp.Error != nil {
+		return fmt.Errorf("lotus %s: %s", method, rpcResp.Error.Message)
+	}
+	return json.Unmarshal(rpcResp.Result, result)
+}
+
+// PendingMessageCIDs returns the CIDs of the messages in the message pool
+// that send amount from one address to another.
+func (c *LotusClient) PendingMessageCIDs(from, to string, amount *big.Int) ([]string, error) {
+	var pending []lotusSignedMessage
+	if err := c.call("MpoolPending", &pending, nil); err != nil {
+		return nil, err
+	}
+
+	var cids []string
+	for _, msg := range pending {
+		if msg.Message.From == from && msg.Message.To == to && msg.Message.Value == amount.String() {
+			cids = append(cids, msg.CID.Root)
+		}
+	}
+	return cids, nil
+}
+
+// MessageStatus looks for the message on chain. A message that is neither
+// on chain nor in the message pool returns ErrMessageNotFound.
+func (c *LotusClient) MessageStatus(txid string) (*MessageStatus, error) {
+	var lookup *lotusMsgLookup
+	if err := c.call("StateSearchMsg", &lookup, lotusCid{Root: txid}); err != nil {
+		return nil, err
+	}
+	if lookup != nil {
+		return &MessageStatus{
+			Cid:      lookup.Message.Root,
+			Included: true,
+			Height:   lookup.Height,
+			ExitCode: lookup.Receipt.ExitCode,
+		}, nil
+	}
+
+	var pending []lotusSignedMessage
+	if err := c.call("MpoolPending", &pending, nil); err != nil {
+		return nil, err
+	}
+	for _, msg := range pending {
+		if msg.CID.Root == txid {
+			return &MessageStatus{Cid: txid}, nil
+		}
+	}
+	return nil, ErrMessageNotFound
+}
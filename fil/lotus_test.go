@@ -0,0 +1,72 @@
+package fil
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLotusClient(t *testing.T) {
+	const pending = `[
+		{"Message": {"From": "f1from", "To": "f1to", "Nonce": 1, "Value": "1000"}, "CID": {"/": "bafy2bzaceold"}},
+		{"Message": {"From": "f1from", "To": "f1to", "Nonce": 2, "Value": "1000"}, "CID": {"/": "bafy2bzacenew"}},
+		{"Message": {"From": "f1from", "To": "f1other", "Nonce": 3, "Value": "1000"}, "CID": {"/": "bafy2bzaceother"}}
+	]`
+	const lookup = `{"Message": {"/": "bafy2bzaceold"}, "Receipt": {"ExitCode": 0}, "Height": 42}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Expected authorization header, got %s", r.Header.Get("Authorization"))
+		}
+		var req struct {
+			Method string
+			Params []json.RawMessage
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		result := "null"
+		switch req.Method {
+		case "Filecoin.MpoolPending":
+			result = pending
+		case "Filecoin.StateSearchMsg":
+			if string(req.Params[0]) == `{"/":"bafy2bzaceold"}` {
+				result = lookup
+			}
+		}
+		w.Write([]byte(`{"jsonrpc": "2.0", "id": 1, "result": ` + result + `}`))
+	}))
+	defer server.Close()
+
+	client := NewLotusClient(server.URL, "token")
+
+	cids, err := client.PendingMessageCIDs("f1from", "f1to", big.NewInt(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cids) != 2 || cids[0] != "bafy2bzaceold" || cids[1] != "bafy2bzacenew" {
+		t.Errorf("Expected the matching messages, got %v", cids)
+	}
+
+	status, err := client.MessageStatus("bafy2bzaceold")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Included || status.Height != 42 {
+		t.Errorf("Expected message to be included at height 42, got %v", status)
+	}
+
+	status, err = client.MessageStatus("bafy2bzacenew")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Included {
+		t.Error("Expected pending message not to be included")
+	}
+
+	if _, err := client.MessageStatus("bafy2bzacemissing"); err != ErrMessageNotFound {
+		t.Errorf("Expected ErrMessageNotFound, got %v", err)
+	}
+}
@@ -2,6 +2,8 @@ package fil
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"errors"
 	addr "github.com/filecoin-project/go-address"
 	"github.com/gcash/bchd/bchec"
 	"github.com/ipfs/go-cid"
@@ -9,6 +11,7 @@ import (
 	pow "github.com/textileio/powergate/api/client"
 	userPb "github.com/textileio/powergate/api/gen/powergate/user/v1"
 	"io"
+	"io/ioutil"
 	"math/big"
 	"os"
 	"path"
@@ -19,7 +22,7 @@ import (
 // MockFilecoinBackend is a mock backend for a Filecoin service
 type MockFilecoinBackend struct {
 	dataDir    string
-	jobs       map[string]string
+	jobs       map[string]userPb.JobStatus
 	adminToken string
 
 	mtx sync.RWMutex
@@ -30,48 +33,90 @@ func NewMockFilecoinBackend(dataDir string, adminToken string) (*MockFilecoinBac
 	if err := os.MkdirAll(dataDir, os.ModePerm); err != nil {
 		return nil, err
 	}
-	return &MockFilecoinBackend{dataDir: dataDir, jobs: make(map[string]string), mtx: sync.RWMutex{}, adminToken: ""}, nil
+	return &MockFilecoinBackend{dataDir: dataDir, jobs: make(map[string]userPb.JobStatus), mtx: sync.RWMutex{}, adminToken: ""}, nil
 }
 
 // Store will put a file to Filecoin and pay for it out of the provided
-// address. A jobID is return or an error.
+// address. A jobID is return or an error. The content ID is the raw CID
+// of the data so the content can be verified against it.
 func (f *MockFilecoinBackend) Store(data io.Reader, addr addr.Address, userToken string) (jobID, contentID string, size int64, err error) {
-
-	contentID, err = randCid()
-	if err != nil {
-		return
-	}
 	jobID, err = randCid()
 	if err != nil {
 		return
 	}
 
-	outfile, err := os.Create(path.Join(f.dataDir, contentID))
+	outfile, err := ioutil.TempFile(f.dataDir, "store")
 	if err != nil {
 		return
 	}
+	defer os.Remove(outfile.Name())
 	defer outfile.Close()
 
-	size, err = io.Copy(outfile, data)
+	h := sha256.New()
+	size, err = io.Copy(io.MultiWriter(outfile, h), data)
 	if err != nil {
 		return
 	}
+	mh, err := multihash.Encode(h.Sum(nil), multihash.SHA2_256)
+	if err != nil {
+		return
+	}
+	contentID = cid.NewCidV1(cid.Raw, mh).String()
+
+	if err = outfile.Close(); err != nil {
+		return
+	}
+	if err = os.Rename(outfile.Name(), path.Join(f.dataDir, contentID)); err != nil {
+		return
+	}
 
 	f.mtx.Lock()
 	defer f.mtx.Unlock()
-	f.jobs[jobID] = "finalized" // TODO: use correct status
+	f.jobs[jobID] = userPb.JobStatus_JOB_STATUS_SUCCESS
 
 	// TODO: check address balance?
 
-	return contentID, jobID, size, nil
+	return jobID, contentID, size, nil
 }
 
+// JobStatus returns the status of a job created by Store or Retry.
 func (f *MockFilecoinBackend) JobStatus(cid string, userToken string) (*userPb.StorageJob, error) {
-	return nil, nil
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+
+	status, ok := f.jobs[cid]
+	if !ok {
+		return nil, errors.New("job not found")
+	}
+	return &userPb.StorageJob{Id: cid, Status: status}, nil
 }
 
+// SetJobStatus sets the status that will be returned by JobStatus
+// for the given job.
+func (f *MockFilecoinBackend) SetJobStatus(jobID string, status userPb.JobStatus) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.jobs[jobID] = status
+}
+
+// Retry returns a new random jobID for the content.
+func (f *MockFilecoinBackend) Retry(contentID string, userToken string) (string, error) {
+	jobID, err := randCid()
+	if err != nil {
+		return "", err
+	}
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.jobs[jobID] = userPb.JobStatus_JOB_STATUS_SUCCESS
+
+	return jobID, nil
+}
+
+// Get returns the content stored under the cid.
 func (f *MockFilecoinBackend) Get(cid string, userToken string) (io.Reader, error) {
-	return nil, nil
+	return os.Open(path.Join(f.dataDir, cid))
 }
 
 func (f *MockFilecoinBackend) CreateUser() (string, string, error) {
@@ -82,6 +127,8 @@ func (f *MockFilecoinBackend) CreateUser() (string, string, error) {
 // for making mock transactions and generating mock blocks.
 type MockWalletBackend struct {
 	transactions map[string][]Transaction
+	included     map[string]int64
+	height       int64
 	nextAddr     *addr.Address
 	nextTxid     string
 	nextTime     *time.Time
@@ -93,6 +140,7 @@ type MockWalletBackend struct {
 func NewMockWalletBackend() *MockWalletBackend {
 	return &MockWalletBackend{
 		transactions: make(map[string][]Transaction),
+		included:     make(map[string]int64),
 		mtx:          sync.RWMutex{},
 	}
 }
@@ -240,6 +288,40 @@ func (w *MockWalletBackend) balance(addr string, userToken string) (*big.Int, er
 	return new(big.Int).Sub(incoming, outgoing), nil
 }
 
+// GenerateBlock includes every pending transaction in a new mock block.
+func (w *MockWalletBackend) GenerateBlock() {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	w.height++
+	for _, txs := range w.transactions {
+		for _, tx := range txs {
+			if _, ok := w.included[tx.ID]; !ok {
+				w.included[tx.ID] = w.height
+			}
+		}
+	}
+}
+
+// MessageStatus returns the status of a mock transaction. Transactions
+// are pending until a block is generated.
+func (w *MockWalletBackend) MessageStatus(txid string) (*MessageStatus, error) {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	if height, ok := w.included[txid]; ok {
+		return &MessageStatus{Cid: txid, Included: true, Height: height}, nil
+	}
+	for _, txs := range w.transactions {
+		for _, tx := range txs {
+			if tx.ID == txid {
+				return &MessageStatus{Cid: txid}, nil
+			}
+		}
+	}
+	return nil, ErrMessageNotFound
+}
+
 // Transactions returns the list of transactions for an address.
 func (w *MockWalletBackend) Transactions(addr string, limit, offset int) ([]Transaction, error) {
 	w.mtx.RLock()
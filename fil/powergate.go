@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/rand"
 	"errors"
+	"fmt"
 	addr "github.com/filecoin-project/go-address"
 	"github.com/ipfs/go-cid"
 	"github.com/multiformats/go-multihash"
@@ -91,6 +92,18 @@ func (f *PowergateBackend) JobStatus(cid string, userToken string) (*userPb.Stor
 	return summary.StorageJob, nil
 }
 
+// Retry re-applies the storage config for content that is already
+// staged, starting a new storage job. The new jobID is returned.
+func (f *PowergateBackend) Retry(contentID string, userToken string) (string, error) {
+	ctx := context.WithValue(context.Background(), pow.AuthKey, userToken)
+
+	configResponse, err := f.powClient.StorageConfig.Apply(ctx, contentID, pow.WithOverride(true))
+	if err != nil {
+		return "", err
+	}
+	return configResponse.JobId, nil
+}
+
 func (f *PowergateBackend) Get(cid string, userToken string) (io.Reader, error) {
 	ctx := context.WithValue(context.Background(), pow.AuthKey, userToken)
 
@@ -130,6 +143,7 @@ type PowergateWalletBackend struct {
 	nextTxid     string
 	nextTime     *time.Time
 	powClient    *pow.Client
+	lotus        *LotusClient
 	mtx          sync.RWMutex
 }
 
@@ -237,6 +251,18 @@ func (w *PowergateWalletBackend) Send(from, to string, amount *big.Int, userToke
 		return "", ErrInsuffientFunds
 	}
 
+	// Powergate doesn't report the message it sends, so note the matching
+	// messages already in the Lotus message pool to tell the new one apart
+	// once it is sent. Nothing is sent if that isn't possible.
+	lotus := w.lotusClient()
+	if lotus == nil {
+		return "", ErrMessageLookupUnavailable
+	}
+	before, err := lotus.PendingMessageCIDs(from, to, amount)
+	if err != nil {
+		return "", err
+	}
+
 	ctx := context.WithValue(context.Background(), pow.AuthKey, userToken)
 
 	resp, err := w.powClient.Wallet.SendFil(ctx, from, to, amount)
@@ -246,40 +272,52 @@ func (w *PowergateWalletBackend) Send(from, to string, amount *big.Int, userToke
 
 	log.Debug(resp.String())
 
-	//var txid cid.Cid
-	//if w.nextTxid != nil {
-	//	txid = *w.nextTxid
-	//	w.nextTxid = nil
-	//} else {
-	//	txid, err = randCid()
-	//	if err != nil {
-	//		return cid.Cid{}, err
-	//	}
-	//}
-	//
-	//ts := time.Now()
-	//if w.nextTime != nil {
-	//	ts = *w.nextTime
-	//}
-	//
-	//tx := Transaction{
-	//	ID:        txid,
-	//	To:        to,
-	//	From:      from,
-	//	Timestamp: ts,
-	//	Amount:    amount,
-	//}
-	//
-	//w.transactions[to] = append(w.transactions[to], tx)
-	//if to != from {
-	//	w.transactions[from] = append(w.transactions[from], tx)
-	//}
-
-	//TODO: Update when powergate supports
-	//cidString := resp.String()
-	//cid := cidString[3 : len(cidString)-1]
-
-	return "", nil
+	after, err := lotus.PendingMessageCIDs(from, to, amount)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrTxidUnknown, err)
+	}
+	known := make(map[string]bool)
+	for _, id := range before {
+		known[id] = true
+	}
+	var sent []string
+	for _, id := range after {
+		if !known[id] {
+			sent = append(sent, id)
+		}
+	}
+	// Another send of the same amount between the same addresses at the
+	// same time makes it impossible to tell which message is ours.
+	if len(sent) != 1 {
+		return "", ErrTxidUnknown
+	}
+	return sent[0], nil
+}
+
+// SetLotusClient sets the Lotus node used to find and track the messages
+// sent by Send. Send refuses to send funds without one.
+func (w *PowergateWalletBackend) SetLotusClient(client *LotusClient) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	w.lotus = client
+}
+
+func (w *PowergateWalletBackend) lotusClient() *LotusClient {
+	w.mtx.RLock()
+	defer w.mtx.RUnlock()
+
+	return w.lotus
+}
+
+// MessageStatus returns the status of a message sent by Send. A Lotus
+// client must be set to look up messages.
+func (w *PowergateWalletBackend) MessageStatus(txid string) (*MessageStatus, error) {
+	lotus := w.lotusClient()
+	if lotus == nil {
+		return nil, ErrMessageLookupUnavailable
+	}
+	return lotus.MessageStatus(txid)
 }
 
 // Balance returns the balance for an address.
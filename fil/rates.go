@@ -0,0 +1,191 @@
+package fil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrInvalidRate is returned when an exchange rate is not a positive
+// decimal number or its currency is not a three letter code.
+var ErrInvalidRate = errors.New("invalid exchange rate")
+
+// RateProvider is an interface to a source of exchange rates for FIL.
+type RateProvider interface {
+	// Rates returns the price of one FIL keyed by ISO 4217 currency code,
+	// such as USD.
+	Rates() (map[string]*big.Rat, error)
+}
+
+// ParseRate parses a decimal exchange rate such as "5.25".
+func ParseRate(s string) (*big.Rat, error) {
+	rate, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok || rate.Sign() <= 0 || strings.Contains(s, "/") {
+		return nil, ErrInvalidRate
+	}
+	return rate, nil
+}
+
+// ValidCurrency returns whether code looks like an ISO 4217 currency code.
+func ValidCurrency(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func parseRates(rates map[string]string) (map[string]*big.Rat, error) {
+	parsed := make(map[string]*big.Rat, len(rates))
+	for currency, s := range rates {
+		currency = strings.ToUpper(currency)
+		if !ValidCurrency(currency) {
+			return nil, fmt.Errorf("%w: currency %q", ErrInvalidRate, currency)
+		}
+		rate, err := ParseRate(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s %q", ErrInvalidRate, currency, s)
+		}
+		parsed[currency] = rate
+	}
+	return parsed, nil
+}
+
+// Fiat returns the value of the amount at the given price of one FIL.
+func (a Amount) Fiat(rate *big.Rat) *big.Rat {
+	v := new(big.Rat).SetFrac(a.AttoFIL(), attoFILPerFIL)
+	return v.Mul(v, rate)
+}
+
+// AmountFromFiat returns the amount of FIL worth fiat at the given price of
+// one FIL, rounded to the nearest attoFIL.
+func AmountFromFiat(fiat, rate *big.Rat) Amount {
+	atto := new(big.Rat).Quo(fiat, rate)
+	atto.Mul(atto, new(big.Rat).SetInt(attoFILPerFIL))
+
+	// Round half away from zero.
+	q, m := new(big.Int).QuoRem(atto.Num(), atto.Denom(), new(big.Int))
+	if m.Abs(m).Lsh(m, 1).Cmp(atto.Denom()) >= 0 {
+		if atto.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return NewAmount(q)
+}
+
+// StaticRateProvider returns a fixed set of exchange rates. It is meant for
+// offline use and testing.
+type StaticRateProvider struct {
+	rates map[string]*big.Rat
+}
+
+// NewStaticRateProvider returns a provider of the rates, which are keyed by
+// currency code and given as decimal strings.
+func NewStaticRateProvider(rates map[string]string) (*StaticRateProvider, error) {
+	parsed, err := parseRates(rates)
+	if err != nil {
+		return nil, err
+	}
+	return &StaticRateProvider{rates: parsed}, nil
+}
+
+// Rates returns the static rates.
+func (p *StaticRateProvider) Rates() (map[string]*big.Rat, error) {
+	rates := make(map[string]*big.Rat, len(p.rates))
+	for currency, rate := range p.rates {
+		rates[currency] = new(big.Rat).Set(rate)
+	}
+	return rates, nil
+}
+
+// FileRateProvider reads exchange rates from a JSON file mapping currency
+// codes to the price of one FIL, for example {"USD": "5.25"}. The file is
+// read again each time the rates are refreshed so it can be updated by an
+// external job.
+type FileRateProvider struct {
+	path string
+}
+
+// NewFileRateProvider returns a provider that reads the rates at path.
+func NewFileRateProvider(path string) *FileRateProvider {
+	return &FileRateProvider{path: path}
+}
+
+// Rates reads the rates from the file.
+func (p *FileRateProvider) Rates() (map[string]*big.Rat, error) {
+	b, err := ioutil.ReadFile(p.path)
+	if err != nil {
+		return nil, err
+	}
+	// Rates may be written as numbers or strings.
+	var rates map[string]json.Number
+	if err := json.Unmarshal(b, &rates); err != nil {
+		return nil, err
+	}
+	strs := make(map[string]string, len(rates))
+	for currency, rate := range rates {
+		strs[currency] = rate.String()
+	}
+	return parseRates(strs)
+}
+
+const coinGeckoPriceURL = "https://api.coingecko.com/api/v3/simple/price"
+
+// CoinGeckoRateProvider fetches exchange rates from the CoinGecko API.
+type CoinGeckoRateProvider struct {
+	currencies []string
+	endpoint   string
+	client     *http.Client
+}
+
+// NewCoinGeckoRateProvider returns a provider of the price of FIL in each of
+// the currencies.
+func NewCoinGeckoRateProvider(currencies []string) *CoinGeckoRateProvider {
+	return &CoinGeckoRateProvider{
+		currencies: currencies,
+		endpoint:   coinGeckoPriceURL,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Rates fetches the current rates.
+func (p *CoinGeckoRateProvider) Rates() (map[string]*big.Rat, error) {
+	query := url.Values{}
+	query.Set("ids", "filecoin")
+	query.Set("vs_currencies", strings.ToLower(strings.Join(p.currencies, ",")))
+
+	resp, err := p.client.Get(p.endpoint + "?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("coingecko returned status %d", resp.StatusCode)
+	}
+
+	var prices struct {
+		Filecoin map[string]json.Number `json:"filecoin"`
+	}
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+	if err := dec.Decode(&prices); err != nil {
+		return nil, err
+	}
+	strs := make(map[string]string, len(prices.Filecoin))
+	for currency, rate := range prices.Filecoin {
+		strs[currency] = rate.String()
+	}
+	return parseRates(strs)
+}
@@ -0,0 +1,76 @@
+package fil
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestStaticRateProvider(t *testing.T) {
+	p, err := NewStaticRateProvider(map[string]string{"usd": "5.25", "EUR": "4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rates, err := p.Rates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rates["USD"].Cmp(big.NewRat(21, 4)) != 0 || rates["EUR"].Cmp(big.NewRat(4, 1)) != 0 {
+		t.Errorf("Unexpected rates %v", rates)
+	}
+
+	for _, bad := range []map[string]string{
+		{"USD": "0"},
+		{"USD": "-1"},
+		{"USD": "1/3"},
+		{"DOLLARS": "1"},
+	} {
+		if _, err := NewStaticRateProvider(bad); err == nil {
+			t.Errorf("Expected %v to be invalid", bad)
+		}
+	}
+}
+
+func TestFileRateProvider(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "filehive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	filename := path.Join(tmpDir, "rates.json")
+	p := NewFileRateProvider(filename)
+	if _, err := p.Rates(); err == nil {
+		t.Error("Expected a missing file to fail")
+	}
+
+	if err := ioutil.WriteFile(filename, []byte(`{"USD": 5.25, "GBP": "3.5"}`), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	rates, err := p.Rates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rates["USD"].Cmp(big.NewRat(21, 4)) != 0 || rates["GBP"].Cmp(big.NewRat(7, 2)) != 0 {
+		t.Errorf("Unexpected rates %v", rates)
+	}
+}
+
+func TestAmountFiat(t *testing.T) {
+	rate := big.NewRat(3, 1)
+	if v := MustParseAmount("1.5").Fiat(rate); v.FloatString(2) != "4.50" {
+		t.Errorf("Expected 4.50, got %s", v.FloatString(2))
+	}
+
+	// 10 at 3 per FIL is a third of a FIL, rounded to the nearest attoFIL.
+	amt := AmountFromFiat(big.NewRat(10, 1), rate)
+	if amt.String() != "3.333333333333333333" {
+		t.Errorf("Expected 3.333333333333333333, got %s", amt)
+	}
+	amt = AmountFromFiat(big.NewRat(20, 1), rate)
+	if amt.String() != "6.666666666666666667" {
+		t.Errorf("Expected 6.666666666666666667, got %s", amt)
+	}
+}
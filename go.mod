@@ -10,6 +10,9 @@ require (
 	github.com/gorilla/csrf v1.7.0
 	github.com/gorilla/mux v1.8.0
 	github.com/ipfs/go-cid v0.0.7
+	github.com/ipfs/go-ipfs-chunker v0.0.5
+	github.com/ipfs/go-ipld-format v0.2.0
+	github.com/ipfs/go-unixfs v0.2.4
 	github.com/jessevdk/go-flags v1.4.0
 	github.com/mailgun/mailgun-go/v4 v4.3.3
 	github.com/microcosm-cc/bluemonday v1.0.4
@@ -0,0 +1,52 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+)
+
+// FileMailer writes each message to a file in a maildir instead of sending
+// it. It lets a server run without a mail provider, with the emails left
+// for an admin or a local mail client to read.
+type FileMailer struct {
+	dir string
+}
+
+// NewFileMailer returns a mailer that delivers to the maildir at dir,
+// creating it if needed.
+func NewFileMailer(dir string) (*FileMailer, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(path.Join(dir, sub), os.ModePerm); err != nil {
+			return nil, err
+		}
+	}
+	return &FileMailer{dir: dir}, nil
+}
+
+// Send writes the message to the new directory of the maildir. It is
+// written to tmp first so readers never see a partial message.
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	b, err := format(msg, now)
+	if err != nil {
+		return err
+	}
+
+	unique := make([]byte, 8)
+	if _, err := rand.Read(unique); err != nil {
+		return err
+	}
+	filename := fmt.Sprintf("%d.%s.filehive.eml", now.UnixNano(), hex.EncodeToString(unique))
+
+	tmp := path.Join(m.dir, "tmp", filename)
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path.Join(m.dir, "new", filename))
+}
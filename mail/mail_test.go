@@ -0,0 +1,99 @@
+package mail
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestFileMailer(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "filehive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	m, err := NewFileMailer(path.Join(tmpDir, "mail"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := Message{
+		From:    "administrator@filehive.io",
+		To:      "brian@ob1.io",
+		Subject: "Welcome to Filehive! 🐝",
+		HTML:    `<p style="color: red">Hello</p>`,
+		Text:    "Hello",
+	}
+	if err := m.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ioutil.ReadDir(path.Join(tmpDir, "mail", "new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("Expected one message, got %d", len(files))
+	}
+	f, err := os.Open(path.Join(tmpDir, "mail", "new", files[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	parsed, err := mail.ReadMessage(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Header.Get("To") != msg.To || parsed.Header.Get("From") != msg.From {
+		t.Errorf("Unexpected recipients %s and %s", parsed.Header.Get("To"), parsed.Header.Get("From"))
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != msg.Subject {
+		t.Errorf("Expected subject %q, got %q %v", msg.Subject, subject, err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Expected multipart/alternative, got %q %v", mediaType, err)
+	}
+	var bodies []string
+	mr := multipart.NewReader(parsed.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bodies = append(bodies, string(body))
+	}
+	if len(bodies) != 2 || bodies[0] != msg.Text || bodies[1] != msg.HTML {
+		t.Errorf("Unexpected parts %q", bodies)
+	}
+}
+
+func TestMemoryMailer(t *testing.T) {
+	m := NewMemoryMailer()
+	if err := m.Send(context.Background(), Message{To: "brian@ob1.io"}); err != nil {
+		t.Fatal(err)
+	}
+	m.SetError(os.ErrClosed)
+	if err := m.Send(context.Background(), Message{To: "amanda@ob1.io"}); err != os.ErrClosed {
+		t.Errorf("Expected the set error, got %v", err)
+	}
+	if messages := m.Messages(); len(messages) != 1 || messages[0].To != "brian@ob1.io" {
+		t.Errorf("Unexpected messages %v", messages)
+	}
+}
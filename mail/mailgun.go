@@ -0,0 +1,29 @@
+package mail
+
+import (
+	"context"
+	"github.com/mailgun/mailgun-go/v4"
+	"github.com/op/go-logging"
+)
+
+var log = logging.MustGetLogger("MAIL")
+
+// MailgunMailer sends email with the Mailgun API.
+type MailgunMailer struct {
+	mg mailgun.Mailgun
+}
+
+// NewMailgunMailer returns a mailer sending from the domain with the API key.
+func NewMailgunMailer(domain, apiKey string) *MailgunMailer {
+	return &MailgunMailer{mg: mailgun.NewMailgun(domain, apiKey)}
+}
+
+// Send sends the message through Mailgun.
+func (m *MailgunMailer) Send(ctx context.Context, msg Message) error {
+	message := m.mg.NewMessage(msg.From, msg.Subject, msg.Text, msg.To)
+	message.SetHtml(msg.HTML)
+
+	resp, id, err := m.mg.Send(ctx, message)
+	log.Debugf("Mailgun Response: %v, %v", resp, id)
+	return err
+}
@@ -0,0 +1,47 @@
+package mail
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryMailer keeps the messages it is asked to send in memory. It is meant
+// for tests.
+type MemoryMailer struct {
+	mtx      sync.Mutex
+	messages []Message
+	err      error
+}
+
+// NewMemoryMailer returns a new MemoryMailer.
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+// Send records the message, or returns the error set with SetError.
+func (m *MemoryMailer) Send(ctx context.Context, msg Message) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if m.err != nil {
+		return m.err
+	}
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// SetError makes Send fail with err. A nil error makes it succeed again.
+func (m *MemoryMailer) SetError(err error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.err = err
+}
+
+// Messages returns the messages sent so far.
+func (m *MemoryMailer) Messages() []Message {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	return append([]Message(nil), m.messages...)
+}
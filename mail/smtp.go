@@ -0,0 +1,50 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTPMailer sends email through an SMTP server.
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+}
+
+// NewSMTPMailer returns a mailer that sends through the server at addr, a
+// host:port. The server is logged in to with PLAIN authentication if a
+// username is given.
+func NewSMTPMailer(addr, username, password string) (*SMTPMailer, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	m := &SMTPMailer{addr: addr}
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m, nil
+}
+
+// Send sends the message to the SMTP server.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	b, err := format(msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// net/smtp doesn't take a context so the send is abandoned rather
+	// than cancelled when the context is done.
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- smtp.SendMail(m.addr, m.auth, msg.From, []string{msg.To}, b)
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -2,8 +2,11 @@ package main
 
 import (
 	"crypto/rand"
+	"errors"
+	"fmt"
 	"github.com/OB1Company/filehive/app"
 	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/mail"
 	"github.com/OB1Company/filehive/repo"
 	"github.com/jessevdk/go-flags"
 	"github.com/op/go-logging"
@@ -12,6 +15,7 @@ import (
 	"os"
 	"os/signal"
 	"path"
+	"strings"
 )
 
 var log = logging.MustGetLogger("MAIN")
@@ -48,6 +52,7 @@ func main() {
 	if err != nil {
 		log.Fatalf("Powergate server is not available: %v", err)
 	}
+	wbe.SetLotusClient(fil.NewLotusClient(config.LotusAPI, config.LotusToken))
 
 	if err := os.MkdirAll(path.Join(config.DataDir, "files"), os.ModePerm); err != nil {
 		log.Fatal(err)
@@ -62,9 +67,40 @@ func main() {
 		log.Fatal(err)
 	}
 
+	minimumFee, err := fil.ParseAmount(config.MinimumFee)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	lowBalance, err := fil.ParseAmount(config.LowBalance)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rateProvider, err := newRateProvider(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mailer, err := newMailer(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	serverOpts := []app.Option{
 		app.JWTKey(key),
+		app.DataDir(config.DataDir),
 		app.Domain(config.Domain),
+		app.JobPollInterval(config.JobPollInterval),
+		app.MaxJobRetries(config.MaxJobRetries),
+		app.FeePercent(config.FeePercent),
+		app.MinimumFee(minimumFee),
+		app.LowBalanceThreshold(lowBalance),
+		app.CacheSize(config.CacheSize),
+		app.RateRefreshInterval(config.RateRefreshInterval),
+	}
+	if rateProvider != nil {
+		serverOpts = append(serverOpts, app.RateProvider(rateProvider))
 	}
 	if config.UseSSL {
 		serverOpts = append(serverOpts, []app.Option{
@@ -78,6 +114,10 @@ func main() {
 		serverOpts = append(serverOpts, app.MailgunKey(config.MailgunKey))
 	}
 
+	if mailer != nil {
+		serverOpts = append(serverOpts, app.Mailer(mailer))
+	}
+
 	if config.MailDomain != "" {
 		serverOpts = append(serverOpts, app.MailDomain(config.MailDomain))
 	}
@@ -86,6 +126,22 @@ func main() {
 		serverOpts = append(serverOpts, app.FilecoinAddress(config.FilecoinAddress))
 	}
 
+	if config.MaxUploadSize > 0 {
+		serverOpts = append(serverOpts, app.MaxUploadSize(config.MaxUploadSize))
+	}
+
+	if config.SubscriptionGracePeriod > 0 {
+		serverOpts = append(serverOpts, app.SubscriptionGracePeriod(config.SubscriptionGracePeriod))
+	}
+
+	if config.EscrowAddress != "" {
+		serverOpts = append(serverOpts, []app.Option{
+			app.EscrowAddress(config.EscrowAddress),
+			app.EscrowToken(config.EscrowToken),
+			app.DisputeWindow(config.DisputeWindow),
+		}...)
+	}
+
 	server, err := app.NewServer(listener, db, config.StaticFileDir, wbe, fbe, serverOpts...)
 	if err != nil {
 		log.Fatal(err)
@@ -127,3 +183,53 @@ func loadJWTKey(dataDir string) ([]byte, error) {
 	}
 	return key, nil
 }
+
+// newRateProvider returns the exchange rate provider selected in the config
+// or nil if fiat prices are disabled.
+func newRateProvider(config *repo.Config) (fil.RateProvider, error) {
+	switch strings.ToLower(config.RateSource) {
+	case "":
+		return nil, nil
+	case "coingecko":
+		return fil.NewCoinGeckoRateProvider(strings.Split(config.FiatCurrencies, ",")), nil
+	case "file":
+		if config.RateFile == "" {
+			return nil, errors.New("ratefile must be set to use the file rate source")
+		}
+		return fil.NewFileRateProvider(config.RateFile), nil
+	case "static":
+		rates := make(map[string]string, len(config.FiatRates))
+		for _, rate := range config.FiatRates {
+			sp := strings.SplitN(rate, "=", 2)
+			if len(sp) != 2 {
+				return nil, fmt.Errorf("invalid fiat rate %q", rate)
+			}
+			rates[strings.TrimSpace(sp[0])] = sp[1]
+		}
+		return fil.NewStaticRateProvider(rates)
+	}
+	return nil, fmt.Errorf("unknown rate source %q", config.RateSource)
+}
+
+// newMailer returns the mailer selected in the config or nil to leave the
+// choice to the server.
+func newMailer(config *repo.Config) (mail.Mailer, error) {
+	switch strings.ToLower(config.Mailer) {
+	case "":
+		return nil, nil
+	case "mailgun":
+		if config.MailgunKey == "" {
+			return nil, errors.New("mailgunkey must be set to use the mailgun mailer")
+		}
+		return mail.NewMailgunMailer(config.MailDomain, config.MailgunKey), nil
+	case "smtp":
+		return mail.NewSMTPMailer(config.SMTPHost, config.SMTPUser, config.SMTPPass)
+	case "file":
+		dir := config.MailDir
+		if dir == "" {
+			dir = path.Join(config.DataDir, "mail")
+		}
+		return mail.NewFileMailer(dir)
+	}
+	return nil, fmt.Errorf("unknown mailer %q", config.Mailer)
+}
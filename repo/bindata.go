@@ -77,7 +77,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var _sampleFilehiveConf = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8c\x92\xdf\x6a\xdc\x3a\x10\x87\xef\xfd\x14\xf3\x00\x66\xb3\x49\x38\xa7\x21\xc6\x17\x81\xb6\x10\x4a\x49\x20\xa5\xf4\x76\x6c\x8d\xed\x21\xb2\xc6\xd1\xc8\xeb\x9a\xd2\x3e\x7b\x91\xfc\xa7\x9b\x96\x42\xd9\x1b\x6b\x67\xbe\x4f\x3f\x8d\x54\xc0\xa7\x8e\xc0\xb0\xa7\x3a\x88\x9f\x21\x08\x68\x10\x4f\x60\x30\x20\xe8\x58\x77\x80\x0a\xa1\x23\x68\xd8\x52\xc7\xa7\xa5\x52\xa1\xd2\x21\x5b\x61\x6a\x70\xb4\x01\x58\xe1\xc7\xc5\x61\x6f\x13\x07\x8f\x0f\x4f\xf7\x5f\xe0\xe1\x89\xf4\x90\x65\x05\xbc\xa5\x6a\x6c\xc1\x4a\xdb\xb2\x6b\xc1\xd2\x89\x6c\x74\x7c\x46\xcb\x66\x59\x2a\xa0\x27\xf8\x66\x62\x63\x0e\xec\x1a\xc9\xc1\x49\xe0\x9a\x72\x98\xd0\x3b\x76\x6d\x0e\xe4\xbd\xf8\x1c\x6a\xcf\x81\x6b\xb4\xdf\xb3\x22\x3a\x13\x5f\x46\x24\xcb\xfe\x7a\x28\x2b\x6d\x3a\x87\x2e\x8c\x61\x5f\x9e\x45\xbe\xb0\xd2\x6a\xa4\xef\x5e\xb3\xa3\x52\x52\xe0\x89\x40\x03\x06\xae\x17\xc9\x3e\x1e\xee\xb1\x25\x8d\x3d\xe8\x0c\x28\xf9\x13\xf9\x38\xb3\x1e\x1a\x2f\x7d\x3c\xe3\x82\x45\xea\xf7\x3d\xa7\x69\xda\x03\x4b\x8f\xec\xd2\xb0\x57\xc7\xc4\xd6\x82\x1f\x1d\x88\xcb\x8a\xb5\x5e\xd2\x57\xec\x07\x4b\x87\x5a\xfa\x8d\x64\x17\xc8\x37\x58\xd3\xed\x20\x3e\x40\x23\x69\x7b\x98\xa8\xda\xd3\x08\x54\xec\x0c\x04\x89\x71\x2c\x6b\x20\x57\x1e\x0f\xe9\x77\x7b\x73\xbc\x39\x2e\x2a\xd6\x78\x8d\x91\x35\x15\x84\x79\xa0\x03\xdc\x07\xa8\xd1\x01\x71\xe8\xc8\x43\x45\xa0\x2f\x96\x03\x5d\xe7\xd0\xcf\xfa\x62\x73\x10\x0f\x83\x68\x68\x3d\xa9\x46\xb9\xa9\x0c\xa3\xa5\x3a\x94\xa9\x61\xcb\xd8\x89\x86\x4d\x1e\xbf\x5f\x47\x4d\xad\xb0\x2e\x76\xdd\x9a\x7e\xb1\x46\xa8\xbc\xbc\x7a\x93\x42\x5f\xde\x5e\x5f\x1f\xff\xdf\xdc\xa3\x92\x77\xd8\xd3\x9f\xba\x5f\x2a\x53\x2d\x9a\xd8\x5b\x6e\xc0\x26\x18\x50\x75\x12\x6f\xfe\x45\x10\x7b\xcb\x0d\xd8\x04\x68\xfa\x78\x75\xf2\x4c\x2e\x39\x1e\x65\x22\xdf\x62\xa0\xac\x80\x61\xfb\x4e\xe5\x72\x43\xde\xb3\xa5\x5a\xd8\x01\x1a\x93\x36\x88\xdc\x80\xb3\x8c\x21\xbe\xcf\x66\x2d\xaf\xd5\x84\xed\xd6\x34\xc1\x74\x80\x33\xfd\xd9\x70\xfe\x3b\x1e\xaf\x22\xf0\x11\xd9\xb6\xa3\x83\xbb\xc7\x7b\xf8\x40\x73\x56\x40\xbf\xfc\xf3\x4c\x73\x32\xbe\x8b\xeb\xf5\x65\xad\xd5\xf5\x99\xfd\x0c\x00\x00\xff\xff\xe6\x8a\x11\xed\x1a\x04\x00\x00")
+var _sampleFilehiveConf = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x8d\x57\x4d\x6f\xdc\x36\x10\xbd\xfb\x57\x10\xbe\xf4\xb2\x95\x77\xd7\x76\x6c\x38\xd0\x21\xc8\x07\xea\xd6\x89\x0d\x3b\x69\x8a\xde\x28\x89\x5a\x31\x96\x44\x85\xa4\x2c\x6f\x82\xf4\xb7\xf7\xcd\x50\xd4\x6a\xed\x14\x28\x7c\xf0\xae\x34\xf3\xf8\x66\xe6\xcd\x0c\xf7\xa5\xf8\x58\x29\x51\x68\xab\x72\x6f\xec\x56\x78\x23\x1c\x3e\xe0\x91\xf4\x52\xb8\x3e\xaf\x84\x74\xc2\xc3\xa6\xd4\xb5\xaa\xf4\x43\x78\x93\x49\xa7\x92\x83\x97\xc1\x59\x95\xb2\xaf\xbd\xd0\x4e\xfc\x73\x94\x4c\x66\xa6\x15\x37\xd7\x77\x97\x7f\x89\xeb\x3b\xe5\x92\x03\x18\xbf\x51\x59\xbf\x11\xb5\xd9\x6c\x74\x8b\xff\xea\x41\xd5\x84\xf1\xa7\xac\x75\x11\xbe\x3a\x21\x71\xf4\xf7\x82\x0c\x17\x42\xb7\xa5\x59\x88\xd6\x78\x9d\xab\x85\x18\xa4\x6d\xe1\xb7\x10\xca\x5a\x63\x17\x22\xb7\x1a\x2f\x64\xfd\x03\x10\xc0\x64\xff\x94\x5c\x0e\x22\xaf\xe7\x41\xc1\x8e\xe3\x70\xc1\x07\x16\xe9\x8c\xf2\x11\x1e\x39\xf2\x7e\xb5\xef\xdb\x3b\xc5\x10\x12\x51\x39\x2f\x71\x6a\x00\x99\xd2\xa3\x1b\xb9\xc1\x57\xd8\xc8\xb6\x10\x4e\xd9\x07\x65\x29\x67\x8d\x28\xad\x69\x28\xc6\xe0\x46\x5e\x4f\xcf\x1c\x86\x61\x22\x6c\x1a\xa9\x5b\x4e\xf6\x88\x31\xe8\xba\x16\xb6\x6f\x91\x4c\xd8\x84\xf7\xa9\x7a\x94\x4d\x57\xab\x24\x37\x4d\xf4\xd4\xad\x57\xb6\x94\xb9\xba\xe8\x8c\xf5\xa2\x34\x7c\xbc\x18\x54\x36\xb1\x31\x22\xd3\x20\xe7\x0d\xd1\xa9\xb5\xf3\xaa\x4d\x97\x09\xff\x5d\x9c\x2f\xcf\x97\x01\x0a\x35\xd4\xa1\xdc\x45\x26\xfc\xb6\x53\x89\xb8\xf4\x22\x97\xad\x50\x1a\x4f\xad\xc8\xc0\xed\x6b\xad\xbd\x3a\x5e\x88\x66\x8b\x8f\x0b\x81\xc3\x3a\xe3\xfc\xc6\x2a\xe7\x08\xbc\xc8\x0a\x2d\x6b\xa4\x2f\x65\x83\xc8\xb1\x82\x4d\x04\xa7\xcf\xfb\x54\xd9\x54\x8c\x5f\x26\xb8\x91\x7d\x40\x25\xa7\x74\xb5\x3e\x63\xd2\xab\x8b\xe3\xe3\xe5\x8b\x88\x8d\x0a\xd9\x56\x36\xea\x39\xdc\x0e\xaa\xc8\x02\x0c\xd9\xa6\xd1\x21\x02\x74\xd2\xb9\xc1\xd8\xe2\xff\x00\x90\x6d\x1a\x1d\x22\x80\x2c\x1a\x2a\x9d\xb9\x57\x2d\x63\xdc\x98\x41\xd9\x8d\xf4\x0a\xef\xbb\xf8\x99\x5f\xa7\xd1\xe5\x1d\x24\x90\x1b\x78\xc9\xa2\xe0\x03\xc8\xaf\x93\x5b\xd3\x7b\xd2\x67\x39\xbe\x1e\xdf\xb2\xdb\x84\xca\x19\xe4\x00\x66\xf0\xb3\xe4\x9c\x2e\x97\x6b\x72\x78\x2f\x75\xbd\x81\x7e\x5e\xdd\x5c\x8a\x3f\xd4\x16\x4f\x9a\xf0\xe4\x5e\x6d\x19\xf1\x2d\x7d\x1f\x95\x35\xbe\x1d\x65\x46\x6f\x7f\x33\x03\x2b\x5f\x41\x39\x8a\xde\x25\xe2\xba\x45\x6b\x97\x11\x66\x21\x5c\xe3\x3b\xca\x13\xd1\x4d\xd0\xe2\x3c\x0b\xb8\x17\x46\x13\xa1\xcb\xbd\x63\x49\x03\x4e\x79\x6e\x15\x43\x9a\x1a\x74\xe8\x2f\x42\x40\x9b\x57\x1a\x4d\x35\xa0\xb9\xd1\x51\x4a\xe2\x33\x1f\xcc\xcd\x15\x09\x6a\x0b\xc5\x43\xc1\xb2\x20\x2a\xc4\x8e\x06\x8a\xf6\xc9\x68\x80\x02\x13\xd8\x5c\x79\x41\x6d\xb0\xa6\xe2\xde\xbd\xff\x78\x13\x1b\x03\x4a\x28\x44\xb6\x0d\x5d\x47\xb1\x04\x00\x6e\x5a\x7c\x7d\x22\xba\xd3\xf3\x33\x42\xfd\x14\xf5\x46\x41\x3c\xd3\xce\x0c\x3e\x11\x57\x8a\xc6\x86\x9f\x8b\xb4\x6f\x29\xfe\x31\xaf\x40\x1b\xd0\x5a\x28\xfa\x34\x19\x75\x1b\x4f\x67\xad\x8e\x9f\x59\x76\xb1\xa6\x94\x82\x38\x94\x47\xc6\x53\xce\xc6\x74\x25\xbb\x6c\xed\x0d\x1c\x7a\xc6\x31\xdc\x5e\x51\x3e\xa4\xb8\x32\xbe\x77\x18\xb3\x85\x12\xbf\xdf\x5d\x7f\xf8\xf5\xf6\xe6\x35\xe9\x25\x11\xb7\xea\x6b\x8f\x39\x58\x24\x33\xdd\x15\x46\x91\xad\x17\x56\x71\x46\x41\x82\xce\x81\x40\x79\x04\x6a\xcf\x41\xa1\xc2\x86\xf9\x31\x2a\x0a\xce\x59\xe6\x1a\xd3\x0c\xa2\xc9\x48\x99\xcb\x4d\x5b\x6a\xdb\xd0\x83\x2d\x4f\x7e\x1e\x73\x79\x05\xf9\x2d\xd8\xa0\x85\x47\x4f\x70\xf4\x12\xc0\x7e\xca\x55\x28\x76\x4d\xd4\x65\xa7\xd3\xca\xfb\xee\xe2\xe8\x68\x57\xa8\xd5\xfa\xf8\xe4\xc8\x76\xf9\xd1\x03\x4f\xb6\x57\x3d\xdc\xac\xfe\x86\x29\x6c\xe6\x6d\x4a\x1c\x43\xfc\x88\x38\x02\xee\xda\xf4\xbd\x7c\xd4\x4d\xdf\x08\xa7\xbf\xd1\x90\x85\x4e\x28\xc3\x94\xb5\x56\xf4\x5d\x6d\x64\x81\xb0\x68\x1f\x52\x41\x43\x07\x84\x7a\x87\x12\xd3\x09\x08\xa1\xd6\x4d\xd4\xe6\x63\xf0\x22\xbc\x74\xbd\x3a\x39\x3b\x39\x3f\x7e\x71\x12\x86\x2f\xf5\x9a\x29\x31\x96\x29\x4f\x79\xa5\xf2\xfb\xa0\x49\x6c\x8e\x9e\xcf\xec\x91\xac\x56\xbb\x0a\x47\x4e\xa3\x83\xd6\x1a\x12\x2f\xbe\x98\x8c\x67\x2f\xfe\x77\xa6\xae\x79\x1f\x3c\xc8\x3a\x5d\xf1\x8a\xf8\xd0\x37\x19\x04\x42\xea\xd7\x28\x15\x6a\x5e\x92\x64\x8a\xb9\x3b\x55\xc9\x2a\x6f\x35\xb5\x83\x2a\x69\x5b\x6a\x9e\xd7\x65\x2d\x37\x1b\x15\xd4\x2d\xdb\x30\xe8\xc6\x60\xe0\x16\x5c\x5c\x7a\x1c\xd2\x65\xef\x95\xef\x6a\x2c\x22\xaa\xad\xb7\xa0\x32\x67\x1b\x07\x1d\x02\xac\x4c\x8d\xce\xe9\x2d\x8a\xed\x68\xfc\x6e\x1b\x54\xd7\x51\x8e\x95\xcb\xad\x19\xe8\x84\xcf\x15\x92\x81\x3c\x62\xc8\x28\x00\xd9\x20\x83\x4e\xe2\xb6\x20\x91\xa8\x50\xbd\xac\xdf\x2a\xfb\x0b\x68\x6a\x8b\xfd\x82\x8d\x9c\xe3\x84\xb2\xa7\x99\x36\xb4\x94\x6b\x0c\x27\x60\x99\x36\x0f\x5d\x58\x68\xd7\xf5\x10\xf2\x00\x25\x22\xe3\x38\x9e\x1b\x98\x54\x3e\x2f\x1d\x28\x82\xd4\x74\xb0\x6e\x1a\x6c\x6e\x34\x40\xbd\x25\x66\x81\xe3\x7c\x32\xd3\x9c\xd9\x75\xc9\xbe\xc0\x06\x09\x10\xcf\x21\x53\x7b\xd3\xa3\x00\x10\x13\xb2\x83\xdc\x49\x8f\xe4\x50\x1b\x98\xcb\x10\x22\x33\x05\xab\xc8\x5f\xc6\x4c\xa9\x59\x1e\xc7\xc2\x85\x1b\x04\x31\xa7\x02\x52\xc2\x78\x73\x05\xcf\x10\x78\x7a\xb6\xae\x78\xa3\x28\x9b\x23\xf3\x24\x02\xc8\x83\xe7\xad\xc3\xf2\x16\xf7\xaa\xf3\x71\x2c\x36\xbb\xb2\x26\xe2\x03\x5a\x52\x71\x4f\x7b\x49\x41\xf6\x2d\x6e\x42\x3f\xd9\x57\xe3\x98\xa7\x83\x61\xdf\x85\x63\xd2\x53\x96\x09\x64\x4c\x5d\x35\xc3\x0d\x98\xad\x78\x77\x79\x45\xfd\x6f\x49\x70\x68\xd4\x89\x0f\x0b\x2e\xb8\xc1\x32\xe5\x86\xf9\x1c\xd2\x9a\xc9\x5a\x52\x71\x47\xef\x4c\xd5\xc8\x5c\xd8\x22\x92\x67\x2e\x31\xa1\x4b\x64\x49\xd2\xf6\x95\xe4\xa1\x85\xf9\x39\x39\x86\xdb\xe0\x90\x88\xbf\x95\xe5\x04\xcb\x8c\x6e\x77\x61\x7e\x91\x5f\xce\x63\x23\x0c\x9c\x61\x74\xa3\xcb\xd3\xea\x49\xa1\x5c\x9f\xa1\x24\x9a\x7a\xed\x1e\x31\x43\xaf\xac\x46\xde\x5d\xd3\x98\x08\xd2\x95\x68\xb6\x56\x41\x19\x63\xb8\xdc\x91\x2c\x84\x11\xa4\xa3\x23\x37\x16\xb9\x41\xee\xb4\x29\x62\xc1\xfe\x73\x24\x11\xdd\xda\xe4\x84\x88\xac\x71\x39\xc7\x23\x63\x63\x3f\x50\x17\xe3\x42\x3a\x35\x24\x1d\xc7\x31\x37\x4a\xb6\x6e\x6f\x54\x31\x06\x8f\xa9\xd5\xf2\xec\xf8\xec\x64\x75\xbe\x3e\x09\x59\xc7\xc6\xe6\x75\xbd\x41\x2c\xea\x11\xec\x5b\xb0\xb7\x92\x58\xc4\x29\xef\x2a\xe4\xa4\xb3\xb8\xb6\x73\x47\x97\x68\x1c\x91\xf7\x16\x11\xe7\x9a\xc6\x0f\xdf\x43\xe9\xe6\x42\x26\x3b\x96\x9d\xe2\x41\xc3\xd9\x62\x1f\x7e\x3f\x5d\x39\x88\xf1\x06\x63\x11\xbf\x09\x78\xed\xa1\xbb\xc2\xb5\x9a\xf8\x3e\xe9\x5d\xd3\xd6\xdb\xa7\x34\xa0\x0e\xb2\x64\xaa\x06\x1d\xa3\xd2\x09\x91\x02\x7b\xbd\x23\x48\x8b\x4a\x79\x08\x88\xb3\x45\x89\x9d\x2c\xd9\x5d\x04\x7f\xd6\x36\x78\xee\x42\x4b\x3f\xdd\xbd\x59\xbc\xfd\x74\x4b\x78\xb4\x4b\x47\xa2\xe5\x4f\x13\x45\xab\x6c\xb7\xc4\x67\xb8\x8b\xf8\xc3\x02\x28\xdf\x0f\x01\x79\x78\x21\x0e\x4f\x93\xf5\xe9\xe1\x0f\xbe\x8e\xf3\xa4\xc6\x60\x93\x1b\xfa\xb9\x40\x6d\x82\x9b\xc6\x16\xcf\x4a\x34\x5e\x15\x63\x24\xd4\xbd\xc5\xcf\x47\x27\x5f\x1c\x96\x2c\xff\xc6\x29\xf5\x23\x58\xec\x31\x7b\x42\x6c\xfc\xb1\x33\x0f\x19\xfa\xdb\xd2\x8f\x00\xac\x7f\x85\xc7\x45\xcc\x01\xd9\x50\xf4\x29\xf1\x7c\xb6\xce\x46\x6a\x61\xf4\xed\xa5\x22\xb2\x1d\x2d\x76\x8b\x6b\xd9\x1c\xfc\x0b\x98\xbd\x3c\x5c\x9c\x0e\x00\x00")
 
 func sampleFilehiveConfBytes() ([]byte, error) {
 	return bindataRead(
@@ -92,7 +92,7 @@ func sampleFilehiveConf() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "sample-filehive.conf", size: 1050, mode: os.FileMode(420), modTime: time.Unix(1612645556, 0)}
+	info := bindataFileInfo{name: "sample-filehive.conf", size: 3740, mode: os.FileMode(420), modTime: time.Unix(1792232230, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -13,6 +13,7 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 const (
@@ -66,6 +67,36 @@ type Config struct {
 	PowergateHost   string `long:"powergate" description:"Hostname for the Powergate instance"`
 	MailgunKey      string `long:"mailgunkey" description:"API key for Mailgun"`
 	MailDomain      string `long:"maildomain" description:"Domain to send email"`
+	Mailer          string `long:"mailer" description:"How to send email [mailgun, smtp, file]. Defaults to mailgun if a mailgunkey is set and file otherwise."`
+	SMTPHost        string `long:"smtphost" description:"The host:port of the SMTP server used by the smtp mailer"`
+	SMTPUser        string `long:"smtpuser" description:"Username for the SMTP server. Leave unset to send without logging in."`
+	SMTPPass        string `long:"smtppass" description:"Password for the SMTP server"`
+	MailDir         string `long:"maildir" description:"Maildir the file mailer writes email to. Defaults to dataDir/mail"`
+	MaxUploadSize   int64  `long:"maxuploadsize" description:"Maximum size in bytes of an uploaded dataset file. Zero means no limit."`
+	LotusAPI        string `long:"lotusapi" description:"URL of a Lotus node JSON-RPC API used to find and confirm wallet transactions. Required as Powergate does not report the messages it sends."`
+	LotusToken      string `long:"lotustoken" description:"Authorization token for the Lotus API"`
+
+	JobPollInterval time.Duration `long:"jobpollinterval" description:"How often to check the status of unfinished Filecoin storage jobs." default:"1m"`
+	MaxJobRetries   int           `long:"maxjobretries" description:"Number of times a failed storage job is retried before it is flagged for an admin." default:"3"`
+
+	EscrowAddress string        `long:"escrowaddress" description:"Marketplace controlled Filecoin address to hold purchase payments in escrow. Leave unset to pay sellers immediately."`
+	EscrowToken   string        `long:"escrowtoken" description:"The Powergate token for the wallet holding the escrow address"`
+	DisputeWindow time.Duration `long:"disputewindow" description:"How long a buyer has to dispute an escrowed purchase before the seller is paid." default:"72h"`
+
+	FeePercent float64 `long:"feepercent" description:"Percentage of each sale kept by the marketplace." default:"5"`
+	MinimumFee string  `long:"minimumfee" description:"Minimum marketplace fee in FIL charged on each sale." default:"0"`
+
+	LowBalance string `long:"lowbalance" description:"Wallet balance in FIL below which a user is notified that their balance is low. Zero disables the notification." default:"0.01"`
+
+	SubscriptionGracePeriod time.Duration `long:"subscriptiongraceperiod" description:"How long a subscriber keeps access after a renewal charge fails." default:"72h"`
+
+	CacheSize int64 `long:"cachesize" description:"Maximum size in bytes of the local cache of retrieved datasets. Zero means no limit." default:"10737418240"`
+
+	RateSource          string        `long:"ratesource" description:"Where to get exchange rates for fiat prices [coingecko, file, static]. Leave unset to only show prices in FIL."`
+	RateFile            string        `long:"ratefile" description:"Path to a JSON file of exchange rates, such as {\"USD\": \"5.25\"}, used with the file rate source."`
+	FiatRates           []string      `long:"fiatrate" description:"A fixed exchange rate, such as USD=5.25, used with the static rate source. May be repeated."`
+	FiatCurrencies      string        `long:"fiatcurrencies" description:"Comma separated currencies to fetch exchange rates for from the coingecko rate source." default:"USD,EUR"`
+	RateRefreshInterval time.Duration `long:"raterefreshinterval" description:"How often to refresh the exchange rates." default:"10m"`
 }
 
 // LoadConfig initializes and parses the config using a config file and command
@@ -139,6 +170,10 @@ func LoadConfig() (*Config, error) {
 		return nil, errors.New("invalid log level")
 	}
 
+	if cfg.LotusAPI == "" {
+		return nil, errors.New("lotusapi must be set")
+	}
+
 	// Warn about missing config file only after all other configuration is
 	// done.  This prevents the warning on help messages and invalid
 	// options.  Note this should go directly before the return.
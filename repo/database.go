@@ -3,6 +3,7 @@ package repo
 import (
 	"errors"
 	"fmt"
+	"github.com/OB1Company/filehive/fil"
 	"github.com/OB1Company/filehive/repo/models"
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
@@ -12,6 +13,7 @@ import (
 	glog "log"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"sync"
 )
@@ -73,13 +75,73 @@ func NewDatabase(dataDir string, opts ...Option) (*Database, error) {
 		return nil, err
 	}
 
-	if err := db.AutoMigrate(&models.User{}, &models.Dataset{}, &models.Purchase{}, &models.Click{}); err != nil {
+	if err := db.AutoMigrate(&models.User{}, &models.Dataset{}, &models.DatasetVersion{}, &models.Purchase{}, &models.Subscription{}, &models.SubscriptionCharge{}, &models.DownloadLink{}, &models.CachePin{}, &models.Click{}, &models.Upload{}, &models.StorageJob{}, &models.StorageJobEvent{}, &models.StorageDeal{}, &models.FeeOverride{}, &models.FeePromotion{}, &models.Transaction{}, &models.WalletScan{}, &models.Category{}, &models.DatasetTag{}, &models.OutboxEmail{}, &models.Notification{}, &models.NotificationPreference{}, &models.Webhook{}, &models.WebhookDelivery{}); err != nil {
+		return nil, err
+	}
+
+	if err := migrateAmounts(db); err != nil {
 		return nil, err
 	}
 
 	return &Database{db: db}, nil
 }
 
+// legacyAmounts are the columns that held FIL amounts as floats before
+// amounts were stored exactly, along with the columns that replaced them.
+var legacyAmounts = []struct {
+	model  interface{}
+	legacy string
+	column string
+}{
+	{&models.Dataset{}, "price", "price_atto"},
+	{&models.Dataset{}, "minimum_price", "minimum_price_atto"},
+	{&models.Dataset{}, "subscription_price", "subscription_price_atto"},
+	{&models.DatasetVersion{}, "price", "price_atto"},
+	{&models.Purchase{}, "price", "price_atto"},
+	{&models.Purchase{}, "fee_minimum", "fee_minimum_atto"},
+	{&models.Subscription{}, "price", "price_atto"},
+	{&models.SubscriptionCharge{}, "price", "price_atto"},
+	{&models.FeeOverride{}, "minimum_fee", "minimum_fee_atto"},
+}
+
+// migrateAmounts copies the amounts in legacy float columns into the
+// columns that replaced them. Rows that have already been copied have
+// the new column set and are skipped.
+func migrateAmounts(db *gorm.DB) error {
+	for _, m := range legacyAmounts {
+		columns, err := db.Migrator().ColumnTypes(m.model)
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, column := range columns {
+			found = found || column.Name() == m.legacy
+		}
+		if !found {
+			continue
+		}
+
+		var rows []struct {
+			ID     string
+			Legacy float64
+		}
+		err = db.Model(m.model).Unscoped().Select("id, " + m.legacy + " AS legacy").Where(m.column + " IS NULL AND " + m.legacy + " IS NOT NULL").Scan(&rows).Error
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			amt, err := fil.ParseAmount(strconv.FormatFloat(row.Legacy, 'f', -1, 64))
+			if err != nil {
+				return fmt.Errorf("migrating %s of %s: %s", m.legacy, row.ID, err)
+			}
+			if err := db.Model(m.model).Unscoped().Where("id = ?", row.ID).Update(m.column, amt).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // View is used for read access to the db. Reads are made
 // inside and open transaction.
 func (d *Database) View(fn func(db *gorm.DB) error) error {
@@ -0,0 +1,52 @@
+package repo
+
+import (
+	"github.com/OB1Company/filehive/fil"
+	"github.com/OB1Company/filehive/repo/models"
+	"gorm.io/gorm"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestMigrateAmounts(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "filehive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	db, err := NewDatabase(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Add a dataset the way it was stored before amounts were exact.
+	err = db.Update(func(db *gorm.DB) error {
+		if err := db.Exec("ALTER TABLE datasets ADD COLUMN price real").Error; err != nil {
+			return err
+		}
+		if err := db.Create(&models.Dataset{ID: "legacy", Price: fil.MustParseAmount("5")}).Error; err != nil {
+			return err
+		}
+		return db.Exec("UPDATE datasets SET price = ?, price_atto = NULL WHERE id = ?", 1.1, "legacy").Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = NewDatabase(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dataset models.Dataset
+	err = db.View(func(db *gorm.DB) error {
+		return db.Where("id = ?", "legacy").First(&dataset).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dataset.Price.Cmp(fil.MustParseAmount("1.1")) != 0 {
+		t.Errorf("Expected legacy price of 1.1, got %s", dataset.Price)
+	}
+}
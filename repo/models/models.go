@@ -1,6 +1,7 @@
 package models
 
 import (
+	"github.com/OB1Company/filehive/fil"
 	"gorm.io/gorm"
 	"time"
 )
@@ -14,6 +15,7 @@ type User struct {
 	Salt            []byte    `json:"-"`
 	HashedPassword  []byte    `json:"-"`
 	Country         string    `json:"country"`
+	Language        string    `json:"language"`
 	AvatarFilename  string    `json:"avatar"`
 	FilecoinAddress string    `json:"filecoinAddress"`
 	PowergateToken  string    `json:"powergateToken"`
@@ -29,40 +31,363 @@ type User struct {
 // Dataset holds metadata about a dataaset.
 type Dataset struct {
 	gorm.Model       `json:"-"`
-	ID               string    `json:"id" gorm:"primary_key"`
-	CreatedAt        time.Time `gorm:"index" json:"createdAt"`
-	UserID           string    `json:"userID"`
-	JobID            string    `json:"jobID"`
-	ContentID        string    `json:"contentID"`
-	Username         string    `json:"username"`
-	Title            string    `gorm:"index:idx_search" json:"title"`
-	ShortDescription string    `gorm:"index:idx_search" json:"shortDescription"`
-	FullDescription  string    `gorm:"index:idx_search" json:"fullDescription"`
-	ImageFilename    string    `json:"imageFilename"`
-	DatasetFilename  string    `json:"datasetFilename"`
-	FileType         string    `json:"fileType"`
-	FileSize         int64     `json:"fileSize"`
-	Price            float64   `json:"price"`
-	Views            int64     `json:"totalViews"`
-	Purchases        int64     `json:"totalPurchases"`
-	Delisted         bool      `gorm:"default:false;non null" json:"delisted"`
+	ID               string     `json:"id" gorm:"primary_key"`
+	CreatedAt        time.Time  `gorm:"index" json:"createdAt"`
+	UserID           string     `json:"userID"`
+	JobID            string     `json:"jobID"`
+	ContentID        string     `json:"contentID"`
+	Username         string     `json:"username"`
+	Title            string     `gorm:"index:idx_search" json:"title"`
+	ShortDescription string     `gorm:"index:idx_search" json:"shortDescription"`
+	FullDescription  string     `gorm:"index:idx_search" json:"fullDescription"`
+	ImageFilename    string     `json:"imageFilename"`
+	DatasetFilename  string     `json:"datasetFilename"`
+	FileType         string     `json:"fileType"`
+	FileSize         int64      `json:"fileSize"`
+	SHA256           string     `json:"sha256"`
+	Price            fil.Amount `gorm:"column:price_atto" json:"price"`
+	PricingMode      string     `json:"pricingMode"`
+	MinimumPrice     fil.Amount `gorm:"column:minimum_price_atto" json:"minimumPrice"`
+	Views            int64      `json:"totalViews"`
+	Purchases        int64      `json:"totalPurchases"`
+	Delisted         bool       `gorm:"default:false;non null" json:"delisted"`
+	CategoryID       string     `gorm:"index" json:"categoryID"`
+	Tags             []string   `gorm:"-" json:"tags"`
+	Version          int        `json:"version"`
+
+	// A dataset with a subscription period can also be subscribed to for
+	// SubscriptionPrice per period. The period is in days.
+	SubscriptionPrice  fil.Amount `gorm:"column:subscription_price_atto" json:"subscriptionPrice"`
+	SubscriptionPeriod int        `json:"subscriptionPeriod"`
+
+	// A dataset with a FiatCurrency has its price pegged to FiatPrice in
+	// that currency. Price is kept at the converted amount as exchange
+	// rates change and is converted again when the dataset is bought.
+	FiatCurrency string `gorm:"index" json:"fiatCurrency"`
+	FiatPrice    string `json:"fiatPrice"`
+}
+
+// DatasetVersion is one upload of a dataset's content. The listing metadata
+// at the time the version was published is kept alongside it so versions
+// can be compared. The dataset points at the content of its latest version.
+type DatasetVersion struct {
+	gorm.Model       `json:"-"`
+	ID               string     `json:"id" gorm:"primary_key"`
+	DatasetID        string     `gorm:"uniqueIndex:idx_dataset_version" json:"datasetID"`
+	Version          int        `gorm:"uniqueIndex:idx_dataset_version" json:"version"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	Changelog        string     `json:"changelog"`
+	ContentID        string     `json:"contentID"`
+	JobID            string     `json:"jobID"`
+	FileSize         int64      `json:"fileSize"`
+	SHA256           string     `json:"sha256"`
+	DatasetFilename  string     `json:"datasetFilename"`
+	FileType         string     `json:"fileType"`
+	Title            string     `json:"title"`
+	ShortDescription string     `json:"shortDescription"`
+	FullDescription  string     `json:"fullDescription"`
+	Price            fil.Amount `gorm:"column:price_atto" json:"price"`
+}
+
+// Category is a node in the category tree that datasets are filed under.
+// Categories are managed by admins.
+type Category struct {
+	gorm.Model `json:"-"`
+	ID         string `json:"id" gorm:"primary_key"`
+	ParentID   string `gorm:"index" json:"parentID"`
+	Name       string `json:"name"`
+	Slug       string `gorm:"uniqueIndex" json:"slug"`
+}
+
+// DatasetTag is a tag a seller has given a dataset.
+type DatasetTag struct {
+	DatasetID string `gorm:"primary_key" json:"datasetID"`
+	Tag       string `gorm:"primary_key;index" json:"tag"`
 }
 
 // Purchase holds information about a user purchase.
 type Purchase struct {
 	gorm.Model       `json:"-"`
-	ID               string    `json:"id" gorm:"primary_key"`
-	UserID           string    `json:"userID"`
-	SellerID         string    `json:"sellerID"`
-	DatasetID        string    `json:"datasetID"`
-	Timestamp        time.Time `json:"timestamp"`
-	Title            string    `json:"title"`
-	ShortDescription string    `json:"shortDescription"`
-	ImageFilename    string    `json:"imageFilename"`
-	FileType         string    `json:"fileType"`
-	Username         string    `json:"username"`
-	Price            float64   `json:"price"`
-	Cid              string    `json:"cid"`
+	ID               string     `json:"id" gorm:"primary_key"`
+	UserID           string     `gorm:"uniqueIndex:idx_purchase_idempotency" json:"userID"`
+	IdempotencyKey   *string    `gorm:"uniqueIndex:idx_purchase_idempotency" json:"-"`
+	SellerID         string     `json:"sellerID"`
+	DatasetID        string     `json:"datasetID"`
+	Timestamp        time.Time  `json:"timestamp"`
+	Title            string     `json:"title"`
+	ShortDescription string     `json:"shortDescription"`
+	ImageFilename    string     `json:"imageFilename"`
+	FileType         string     `json:"fileType"`
+	Username         string     `json:"username"`
+	Price            fil.Amount `gorm:"column:price_atto" json:"price"`
+	PricingMode      string     `json:"pricingMode"`
+	FiatCurrency     string     `json:"fiatCurrency"`
+	FiatPrice        string     `json:"fiatPrice"`
+	Cid              string     `json:"cid"`
+	Version          int        `json:"version"`
+	State            string     `gorm:"index;default:notified" json:"state"`
+	Amount           string     `json:"-"`
+	FeeAmount        string     `json:"feeAmount"`
+	FeePercent       float64    `json:"feePercent"`
+	FeeMinimum       fil.Amount `gorm:"column:fee_minimum_atto" json:"feeMinimum"`
+	FeePolicy        string     `json:"feePolicy"`
+	PaymentAddress   string     `json:"-"`
+	FeeAddress       string     `json:"-"`
+	FeeTxid          string     `json:"feeTxid"`
+	Status           string     `gorm:"index" json:"status"`
+	Txid             string     `json:"txid"`
+	ReleaseAfter     time.Time  `json:"releaseAfter"`
+	ReleaseTxid      string     `json:"releaseTxid"`
+	RefundTxid       string     `json:"refundTxid"`
+	DisputeReason    string     `json:"disputeReason"`
+	SettlingFrom     string     `json:"-"`
+}
+
+// Subscription gives a user access to every version of a dataset while it
+// is paid up. The price and period are fixed when the user subscribes and
+// the subscription is renewed at the end of each period. A subscription
+// that can't be renewed stays usable until GraceUntil.
+type Subscription struct {
+	gorm.Model     `json:"-"`
+	ID             string     `json:"id" gorm:"primary_key"`
+	UserID         string     `gorm:"index" json:"userID"`
+	DatasetID      string     `gorm:"index" json:"datasetID"`
+	SellerID       string     `gorm:"index" json:"sellerID"`
+	Title          string     `json:"title"`
+	Price          fil.Amount `gorm:"column:price_atto" json:"price"`
+	Period         int        `json:"period"`
+	Status         string     `gorm:"index" json:"status"`
+	StartsAt       time.Time  `json:"startsAt"`
+	PeriodEnd      time.Time  `gorm:"index" json:"periodEnd"`
+	GraceUntil     time.Time  `json:"graceUntil"`
+	CanceledAt     time.Time  `json:"canceledAt"`
+	PeriodsPaid    int        `json:"periodsPaid"`
+	FailedAttempts int        `json:"failedAttempts"`
+	LastError      string     `json:"lastError"`
+}
+
+// SubscriptionCharge is the payment for one period of a subscription. It
+// moves through the same states as a purchase so an interrupted charge can
+// be resumed without paying twice.
+type SubscriptionCharge struct {
+	gorm.Model     `json:"-"`
+	ID             string     `json:"id" gorm:"primary_key"`
+	SubscriptionID string     `gorm:"index" json:"subscriptionID"`
+	Period         int        `json:"period"`
+	State          string     `gorm:"index" json:"state"`
+	Amount         string     `json:"-"`
+	Price          fil.Amount `gorm:"column:price_atto" json:"price"`
+	FeeAmount      string     `json:"feeAmount"`
+	PaymentAddress string     `json:"-"`
+	FeeAddress     string     `json:"-"`
+	Txid           string     `json:"txid"`
+	FeeTxid        string     `json:"feeTxid"`
+	Timestamp      time.Time  `json:"timestamp"`
+}
+
+// DownloadLink is a signed URL that lets a buyer download a purchased
+// dataset without a session. It can be used MaxUses times until ExpiresAt.
+type DownloadLink struct {
+	gorm.Model `json:"-"`
+	ID         string    `json:"id" gorm:"primary_key"`
+	UserID     string    `gorm:"index" json:"userID"`
+	PurchaseID string    `gorm:"index" json:"purchaseID"`
+	DatasetID  string    `json:"datasetID"`
+	MaxUses    int       `json:"maxUses"`
+	Uses       int       `json:"uses"`
+	ExpiresAt  time.Time `gorm:"index" json:"expiresAt"`
+}
+
+// CachePin keeps content in the local retrieval cache no matter how long
+// ago it was used. The ID is the content ID.
+type CachePin struct {
+	gorm.Model `json:"-"`
+	ID         string `json:"id" gorm:"primary_key"`
+}
+
+// Upload tracks a resumable dataset upload. The partial file lives under the
+// uploads directory in the data dir until the upload is finalized.
+type Upload struct {
+	gorm.Model `json:"-"`
+	ID         string    `json:"id" gorm:"primary_key"`
+	UserID     string    `gorm:"index" json:"userID"`
+	Filename   string    `json:"filename"`
+	Size       int64     `json:"size"`
+	Offset     int64     `json:"offset"`
+	SHA256     string    `json:"sha256"`
+	ExpiresAt  time.Time `gorm:"index" json:"expiresAt"`
+}
+
+// StorageJob tracks a Powergate storage job for a dataset. The ID is the
+// Powergate job ID. A failed job that is retried keeps its row and points
+// to the job that replaced it through RetryJobID.
+type StorageJob struct {
+	gorm.Model `json:"-"`
+	ID         string    `json:"id" gorm:"primary_key"`
+	DatasetID  string    `gorm:"index" json:"datasetID"`
+	UserID     string    `gorm:"index" json:"userID"`
+	ContentID  string    `json:"contentID"`
+	Status     string    `gorm:"index" json:"status"`
+	ErrorCause string    `json:"errorCause"`
+	Attempt    int       `json:"attempt"`
+	RetryJobID string    `json:"retryJobID"`
+	Flagged    bool      `gorm:"default:false;not null" json:"flagged"`
+	CheckedAt  time.Time `json:"checkedAt"`
+}
+
+// StorageJobEvent records a status transition of a storage job.
+type StorageJobEvent struct {
+	gorm.Model `json:"-"`
+	JobID      string    `gorm:"index" json:"jobID"`
+	Status     string    `json:"status"`
+	ErrorCause string    `json:"errorCause"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// StorageDeal holds the latest known state of a storage deal made by
+// a storage job. The ID is the deal proposal CID.
+type StorageDeal struct {
+	gorm.Model      `json:"-"`
+	ID              string `json:"proposalCid" gorm:"primary_key"`
+	JobID           string `gorm:"index" json:"jobID"`
+	Miner           string `json:"miner"`
+	StateName       string `json:"stateName"`
+	PieceCid        string `json:"pieceCid"`
+	Size            uint64 `json:"size"`
+	PricePerEpoch   uint64 `json:"pricePerEpoch"`
+	StartEpoch      uint64 `json:"startEpoch"`
+	Duration        uint64 `json:"duration"`
+	DealID          uint64 `json:"dealID"`
+	ActivationEpoch int64  `json:"activationEpoch"`
+	Message         string `json:"message"`
+}
+
+// FeeOverride replaces the default marketplace fee for one seller. The ID
+// is the seller's user ID.
+type FeeOverride struct {
+	gorm.Model `json:"-"`
+	ID         string     `json:"userID" gorm:"primary_key"`
+	FeePercent float64    `json:"feePercent"`
+	MinimumFee fil.Amount `gorm:"column:minimum_fee_atto" json:"minimumFee"`
+}
+
+// FeePromotion waives the marketplace fee on sales made between StartsAt
+// and EndsAt. A promotion without a UserID applies to every seller.
+type FeePromotion struct {
+	gorm.Model `json:"-"`
+	ID         string    `json:"id" gorm:"primary_key"`
+	Name       string    `json:"name"`
+	UserID     string    `gorm:"index" json:"userID"`
+	StartsAt   time.Time `gorm:"index" json:"startsAt"`
+	EndsAt     time.Time `gorm:"index" json:"endsAt"`
+}
+
+// Transaction records a transfer of FIL into or out of a user's wallet.
+// PurchaseID is the purchase or subscription charge the transfer paid for.
+// Txid is the message CID reported by the wallet backend and is empty if
+// the backend did not report one. Height and ExitCode are set once the
+// message is included on chain.
+type Transaction struct {
+	gorm.Model  `json:"-"`
+	ID          string    `json:"-" gorm:"primary_key"`
+	Txid        string    `gorm:"index" json:"transactionID"`
+	FromAddress string    `gorm:"index" json:"from"`
+	ToAddress   string    `gorm:"index" json:"to"`
+	Amount      string    `json:"-"`
+	Type        string    `json:"type"`
+	PurchaseID  string    `gorm:"index" json:"purchaseID"`
+	Timestamp   time.Time `gorm:"index" json:"timestamp"`
+	Status      string    `gorm:"index" json:"status"`
+	Height      int64     `json:"height"`
+	ExitCode    int64     `json:"exitCode"`
+}
+
+// OutboxEmail is an email queued to be sent by the outbox worker. Failed
+// sends are retried at NextAttempt until the email runs out of attempts and
+// is marked failed. The bodies of a sent email are cleared as it may hold a
+// password reset code.
+type OutboxEmail struct {
+	gorm.Model  `json:"-"`
+	ID          string     `gorm:"primary_key" json:"id"`
+	Sender      string     `json:"sender"`
+	Recipient   string     `gorm:"index" json:"recipient"`
+	Subject     string     `json:"subject"`
+	HTML        string     `json:"-"`
+	Text        string     `json:"-"`
+	Status      string     `gorm:"index" json:"status"`
+	Attempts    int        `json:"attempts"`
+	LastError   string     `json:"lastError"`
+	QueuedAt    time.Time  `gorm:"index" json:"queuedAt"`
+	NextAttempt time.Time  `gorm:"index" json:"nextAttempt"`
+	SentAt      *time.Time `json:"sentAt"`
+}
+
+// Notification is an event shown in a user's notification feed. The
+// dataset, purchase and job IDs are set for the events they apply to.
+type Notification struct {
+	gorm.Model `json:"-"`
+	ID         string    `gorm:"primary_key" json:"id"`
+	UserID     string    `gorm:"index" json:"-"`
+	Type       string    `gorm:"index" json:"type"`
+	Message    string    `json:"message"`
+	DatasetID  string    `json:"datasetID,omitempty"`
+	PurchaseID string    `gorm:"index" json:"purchaseID,omitempty"`
+	JobID      string    `json:"jobID,omitempty"`
+	Read       bool      `gorm:"index;default:false;not null" json:"read"`
+	Timestamp  time.Time `gorm:"index" json:"timestamp"`
+}
+
+// NotificationPreference records whether a user wants a type of
+// notification emailed to them as well as added to their feed. Types
+// without a preference use the default for the type.
+type NotificationPreference struct {
+	UserID string `gorm:"primary_key"`
+	Type   string `gorm:"primary_key"`
+	Email  bool   `gorm:"not null"`
+}
+
+// Webhook is an endpoint registered by a user to be sent the events it
+// subscribes to. Events is a comma separated list of event names. The
+// secret signs each payload and is only returned when the webhook is
+// created.
+type Webhook struct {
+	gorm.Model `json:"-"`
+	ID         string    `gorm:"primary_key" json:"id"`
+	UserID     string    `gorm:"index" json:"-"`
+	URL        string    `json:"url"`
+	Events     string    `json:"-"`
+	Secret     string    `json:"-"`
+	Created    time.Time `json:"created"`
+}
+
+// WebhookDelivery is an event queued to be posted to a webhook. Failed
+// deliveries are retried at NextAttempt until they run out of attempts and
+// are marked failed. The deliveries make up the webhook's delivery log.
+type WebhookDelivery struct {
+	gorm.Model   `json:"-"`
+	ID           string     `gorm:"primary_key" json:"id"`
+	WebhookID    string     `gorm:"index" json:"webhookID"`
+	Event        string     `json:"event"`
+	Payload      string     `json:"-"`
+	Status       string     `gorm:"index" json:"status"`
+	Attempts     int        `json:"attempts"`
+	ResponseCode int        `json:"responseCode"`
+	LastError    string     `json:"lastError"`
+	QueuedAt     time.Time  `gorm:"index" json:"queuedAt"`
+	NextAttempt  time.Time  `gorm:"index" json:"nextAttempt"`
+	DeliveredAt  *time.Time `json:"deliveredAt"`
+}
+
+// WalletScan holds the balance of an address when it was last scanned
+// for incoming funds. The ID is the address. Opening is the part of the
+// balance the ledger does not account for, such as funds received before
+// the first scan.
+type WalletScan struct {
+	gorm.Model `json:"-"`
+	ID         string `gorm:"primary_key"`
+	Balance    string
+	Opening    string
+	ScannedAt  time.Time
 }
 
 // Click represents a view on a dataset.
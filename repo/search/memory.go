@@ -0,0 +1,224 @@
+package search
+
+import (
+	"github.com/OB1Company/filehive/repo/models"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// memoryDocument is a dataset's words, by field.
+type memoryDocument struct {
+	title, shortDescription, fullDescription string
+	categoryID                               string
+	fields                                   [3][]string
+}
+
+var fieldWeights = [3]float64{titleWeight, shortDescriptionWeight, fullDescriptionWeight}
+
+// MemoryIndex is an inverted index held in memory. It is used when the
+// database has no full text support and needs to be rebuilt on start up.
+type MemoryIndex struct {
+	documents map[string]*memoryDocument
+	postings  map[string]map[string]struct{}
+	mtx       sync.RWMutex
+}
+
+// NewMemoryIndex returns an empty in-memory index.
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		documents: make(map[string]*memoryDocument),
+		postings:  make(map[string]map[string]struct{}),
+	}
+}
+
+// Index adds the dataset to the index or updates its entry.
+func (m *MemoryIndex) Index(dataset models.Dataset) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.index(dataset)
+	return nil
+}
+
+// Remove removes the dataset from the index.
+func (m *MemoryIndex) Remove(datasetID string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.remove(datasetID)
+	return nil
+}
+
+// Rebuild replaces the contents of the index with the datasets.
+func (m *MemoryIndex) Rebuild(datasets []models.Dataset) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.documents = make(map[string]*memoryDocument)
+	m.postings = make(map[string]map[string]struct{})
+	for _, dataset := range datasets {
+		m.index(dataset)
+	}
+	return nil
+}
+
+// Search returns a page of the datasets matching the query. Datasets are
+// ranked by the BM25 saturation of each clause's frequency in a field,
+// weighted by field and by how rare the clause is.
+func (m *MemoryIndex) Search(query string, filter Filter, limit, offset int) ([]Result, int64, error) {
+	clauses := parseQuery(query)
+	if len(clauses) == 0 {
+		return nil, 0, ErrEmptyQuery
+	}
+
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	var categories map[string]bool
+	if len(filter.Categories) > 0 {
+		categories = make(map[string]bool)
+		for _, id := range filter.Categories {
+			categories[id] = true
+		}
+	}
+
+	var (
+		scores = make(map[string]float64)
+		n      = float64(len(m.documents))
+	)
+	for i, c := range clauses {
+		var (
+			matches = make(map[string]float64)
+			df      float64
+		)
+		for id := range m.candidates(c) {
+			doc := m.documents[id]
+			score := 0.0
+			for f, w := range doc.fields {
+				if tf := float64(c.occurrences(w)); tf > 0 {
+					score += fieldWeights[f] * tf / (tf + 1.2)
+				}
+			}
+			if score == 0 {
+				continue
+			}
+			// Rarity is measured across all datasets, not just the
+			// filtered ones, so that filtering does not change scores.
+			df++
+			if categories == nil || categories[doc.categoryID] {
+				matches[id] = score
+			}
+		}
+
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		combined := make(map[string]float64)
+		for id, score := range matches {
+			if previous, ok := scores[id]; ok || i == 0 {
+				combined[id] = previous + score*idf
+			}
+		}
+		scores = combined
+		if len(scores) == 0 {
+			break
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for id, score := range scores {
+		results = append(results, Result{DatasetID: id, Score: score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].DatasetID < results[j].DatasetID
+	})
+
+	total := int64(len(results))
+	if offset > len(results) {
+		offset = len(results)
+	}
+	results = results[offset:]
+	if limit >= 0 && limit < len(results) {
+		results = results[:limit]
+	}
+	for i := range results {
+		doc := m.documents[results[i].DatasetID]
+		results[i].Snippet = snippet(clauses, doc.title, doc.shortDescription, doc.fullDescription)
+	}
+	return results, total, nil
+}
+
+// candidates returns the datasets containing every word in the clause.
+// Phrases are checked by the caller.
+func (m *MemoryIndex) candidates(c clause) map[string]struct{} {
+	var found map[string]struct{}
+	for i, word := range c.words {
+		ids := m.postings[word]
+		if c.prefix && i == len(c.words)-1 {
+			ids = make(map[string]struct{})
+			for term, docs := range m.postings {
+				if strings.HasPrefix(term, word) {
+					for id := range docs {
+						ids[id] = struct{}{}
+					}
+				}
+			}
+		}
+		if found == nil {
+			found = ids
+			continue
+		}
+		intersection := make(map[string]struct{})
+		for id := range ids {
+			if _, ok := found[id]; ok {
+				intersection[id] = struct{}{}
+			}
+		}
+		found = intersection
+	}
+	return found
+}
+
+func (m *MemoryIndex) index(dataset models.Dataset) {
+	m.remove(dataset.ID)
+
+	doc := &memoryDocument{
+		title:            dataset.Title,
+		shortDescription: dataset.ShortDescription,
+		fullDescription:  dataset.FullDescription,
+		categoryID:       dataset.CategoryID,
+		fields: [3][]string{
+			words(dataset.Title),
+			words(dataset.ShortDescription),
+			words(dataset.FullDescription),
+		},
+	}
+	m.documents[dataset.ID] = doc
+	for _, field := range doc.fields {
+		for _, word := range field {
+			if m.postings[word] == nil {
+				m.postings[word] = make(map[string]struct{})
+			}
+			m.postings[word][dataset.ID] = struct{}{}
+		}
+	}
+}
+
+func (m *MemoryIndex) remove(datasetID string) {
+	doc, ok := m.documents[datasetID]
+	if !ok {
+		return
+	}
+	for _, field := range doc.fields {
+		for _, word := range field {
+			delete(m.postings[word], datasetID)
+			if len(m.postings[word]) == 0 {
+				delete(m.postings, word)
+			}
+		}
+	}
+	delete(m.documents, datasetID)
+}
@@ -0,0 +1,114 @@
+package search
+
+import (
+	"fmt"
+	"github.com/OB1Company/filehive/repo"
+	"github.com/OB1Company/filehive/repo/models"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// mysqlIndex is an InnoDB table with FULLTEXT indexes. Each field has its
+// own index so that matches can be weighted by field. InnoDB ignores words
+// shorter than innodb_ft_min_token_size and its stop words.
+type mysqlIndex struct {
+	db *repo.Database
+}
+
+func newMySQLIndex(db *repo.Database) (*mysqlIndex, error) {
+	err := db.Update(func(db *gorm.DB) error {
+		return db.Exec(`CREATE TABLE IF NOT EXISTS dataset_search (
+			dataset_id VARCHAR(191) NOT NULL PRIMARY KEY,
+			category_id VARCHAR(191) NOT NULL,
+			title TEXT NOT NULL,
+			short_description TEXT NOT NULL,
+			full_description TEXT NOT NULL,
+			KEY idx_dataset_search_category (category_id),
+			FULLTEXT KEY ft_dataset_search (title, short_description, full_description),
+			FULLTEXT KEY ft_dataset_search_title (title),
+			FULLTEXT KEY ft_dataset_search_short (short_description),
+			FULLTEXT KEY ft_dataset_search_full (full_description)
+		) ENGINE=InnoDB`).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &mysqlIndex{db: db}, nil
+}
+
+func (m *mysqlIndex) Index(dataset models.Dataset) error {
+	return m.db.Update(func(db *gorm.DB) error {
+		return mysqlInsert(db, dataset)
+	})
+}
+
+func (m *mysqlIndex) Remove(datasetID string) error {
+	return m.db.Update(func(db *gorm.DB) error {
+		return db.Exec("DELETE FROM dataset_search WHERE dataset_id = ?", datasetID).Error
+	})
+}
+
+func (m *mysqlIndex) Rebuild(datasets []models.Dataset) error {
+	return m.db.Update(func(db *gorm.DB) error {
+		if err := db.Exec("DELETE FROM dataset_search").Error; err != nil {
+			return err
+		}
+		for _, dataset := range datasets {
+			if err := mysqlInsert(db, dataset); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (m *mysqlIndex) Search(query string, filter Filter, limit, offset int) ([]Result, int64, error) {
+	clauses := parseQuery(query)
+	if len(clauses) == 0 {
+		return nil, 0, ErrEmptyQuery
+	}
+	against := mysqlQuery(clauses)
+	cond, args := filter.condition()
+
+	var (
+		rows  []indexedDataset
+		total int64
+	)
+	err := m.db.View(func(db *gorm.DB) error {
+		if err := db.Raw("SELECT count(*) FROM dataset_search WHERE MATCH (title, short_description, full_description) AGAINST (? IN BOOLEAN MODE)"+cond, append([]interface{}{against}, args...)...).Scan(&total).Error; err != nil {
+			return err
+		}
+		rank := fmt.Sprintf("MATCH (title) AGAINST (? IN BOOLEAN MODE) * %d + MATCH (short_description) AGAINST (? IN BOOLEAN MODE) * %d + MATCH (full_description) AGAINST (? IN BOOLEAN MODE) * %d", titleWeight, shortDescriptionWeight, fullDescriptionWeight)
+		return db.Raw("SELECT dataset_id, title, short_description, full_description, "+rank+" AS score FROM dataset_search WHERE MATCH (title, short_description, full_description) AGAINST (? IN BOOLEAN MODE)"+cond+" ORDER BY score DESC, dataset_id LIMIT ? OFFSET ?", append(append([]interface{}{against, against, against, against}, args...), limit, offset)...).Scan(&rows).Error
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return newResults(rows, clauses), total, nil
+}
+
+func mysqlInsert(db *gorm.DB, dataset models.Dataset) error {
+	return db.Exec("REPLACE INTO dataset_search (dataset_id, category_id, title, short_description, full_description) VALUES (?, ?, ?, ?, ?)", dataset.ID, dataset.CategoryID, dataset.Title, dataset.ShortDescription, dataset.FullDescription).Error
+}
+
+// mysqlQuery returns the clauses as a boolean mode query with every clause
+// required. MySQL does not support a prefix at the end of a phrase, so the
+// last word of such a phrase is required separately.
+func mysqlQuery(clauses []clause) string {
+	var parts []string
+	for _, c := range clauses {
+		w := c.words
+		if c.prefix {
+			parts = append(parts, "+"+w[len(w)-1]+"*")
+			w = w[:len(w)-1]
+		}
+		switch len(w) {
+		case 0:
+		case 1:
+			parts = append(parts, "+"+w[0])
+		default:
+			parts = append(parts, `+"`+strings.Join(w, " ")+`"`)
+		}
+	}
+	return strings.Join(parts, " ")
+}
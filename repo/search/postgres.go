@@ -0,0 +1,106 @@
+package search
+
+import (
+	"fmt"
+	"github.com/OB1Company/filehive/repo"
+	"github.com/OB1Company/filehive/repo/models"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// postgresIndex stores a weighted tsvector of each dataset with a GIN
+// index. The simple configuration is used so that words are matched the
+// same way as the other indexes, without stemming or stop words.
+type postgresIndex struct {
+	db *repo.Database
+}
+
+func newPostgresIndex(db *repo.Database) (*postgresIndex, error) {
+	err := db.Update(func(db *gorm.DB) error {
+		if err := db.Exec("CREATE TABLE IF NOT EXISTS dataset_search (dataset_id text PRIMARY KEY, category_id text NOT NULL, title text NOT NULL, short_description text NOT NULL, full_description text NOT NULL, document tsvector NOT NULL)").Error; err != nil {
+			return err
+		}
+		if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_dataset_search_category ON dataset_search (category_id)").Error; err != nil {
+			return err
+		}
+		return db.Exec("CREATE INDEX IF NOT EXISTS idx_dataset_search_document ON dataset_search USING GIN (document)").Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &postgresIndex{db: db}, nil
+}
+
+func (p *postgresIndex) Index(dataset models.Dataset) error {
+	return p.db.Update(func(db *gorm.DB) error {
+		return postgresInsert(db, dataset)
+	})
+}
+
+func (p *postgresIndex) Remove(datasetID string) error {
+	return p.db.Update(func(db *gorm.DB) error {
+		return db.Exec("DELETE FROM dataset_search WHERE dataset_id = ?", datasetID).Error
+	})
+}
+
+func (p *postgresIndex) Rebuild(datasets []models.Dataset) error {
+	return p.db.Update(func(db *gorm.DB) error {
+		if err := db.Exec("DELETE FROM dataset_search").Error; err != nil {
+			return err
+		}
+		for _, dataset := range datasets {
+			if err := postgresInsert(db, dataset); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (p *postgresIndex) Search(query string, filter Filter, limit, offset int) ([]Result, int64, error) {
+	clauses := parseQuery(query)
+	if len(clauses) == 0 {
+		return nil, 0, ErrEmptyQuery
+	}
+	tsquery := postgresQuery(clauses)
+	cond, args := filter.condition()
+
+	var (
+		rows  []indexedDataset
+		total int64
+	)
+	err := p.db.View(func(db *gorm.DB) error {
+		if err := db.Raw("SELECT count(*) FROM dataset_search WHERE document @@ to_tsquery('simple', ?)"+cond, append([]interface{}{tsquery}, args...)...).Scan(&total).Error; err != nil {
+			return err
+		}
+		// The weights are given in the order D, C, B, A.
+		rank := fmt.Sprintf("ts_rank('{0, %g, %g, 1}', document, to_tsquery('simple', ?))", float64(fullDescriptionWeight)/titleWeight, float64(shortDescriptionWeight)/titleWeight)
+		return db.Raw("SELECT dataset_id, title, short_description, full_description, "+rank+" AS score FROM dataset_search WHERE document @@ to_tsquery('simple', ?)"+cond+" ORDER BY score DESC, dataset_id LIMIT ? OFFSET ?", append(append([]interface{}{tsquery, tsquery}, args...), limit, offset)...).Scan(&rows).Error
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return newResults(rows, clauses), total, nil
+}
+
+func postgresInsert(db *gorm.DB, dataset models.Dataset) error {
+	return db.Exec(`INSERT INTO dataset_search (dataset_id, category_id, title, short_description, full_description, document)
+		VALUES (?, ?, ?, ?, ?, setweight(to_tsvector('simple', ?), 'A') || setweight(to_tsvector('simple', ?), 'B') || setweight(to_tsvector('simple', ?), 'C'))
+		ON CONFLICT (dataset_id) DO UPDATE SET category_id = EXCLUDED.category_id, title = EXCLUDED.title, short_description = EXCLUDED.short_description, full_description = EXCLUDED.full_description, document = EXCLUDED.document`,
+		dataset.ID, dataset.CategoryID, dataset.Title, dataset.ShortDescription, dataset.FullDescription,
+		dataset.Title, dataset.ShortDescription, dataset.FullDescription).Error
+}
+
+// postgresQuery returns the clauses as a tsquery. Phrases use the followed
+// by operator and prefixes the :* label.
+func postgresQuery(clauses []clause) string {
+	parts := make([]string, len(clauses))
+	for i, c := range clauses {
+		w := append([]string{}, c.words...)
+		if c.prefix {
+			w[len(w)-1] += ":*"
+		}
+		parts[i] = "(" + strings.Join(w, " <-> ") + ")"
+	}
+	return strings.Join(parts, " & ")
+}
@@ -0,0 +1,308 @@
+// Package search maintains a full text index of the datasets listed on the
+// marketplace. The index is backed by the database's own full text support
+// where it is available, otherwise by an in-process inverted index.
+package search
+
+import (
+	"errors"
+	"github.com/OB1Company/filehive/repo"
+	"github.com/OB1Company/filehive/repo/models"
+	"github.com/op/go-logging"
+	"gorm.io/gorm"
+	"html"
+	"strings"
+	"unicode"
+)
+
+var log = logging.MustGetLogger("SEARCH")
+
+// ErrEmptyQuery is returned when a query contains nothing to search for.
+var ErrEmptyQuery = errors.New("empty search query")
+
+// The fields are weighted so that a match in the title counts for more
+// than one in the descriptions.
+const (
+	titleWeight            = 10
+	shortDescriptionWeight = 2
+	fullDescriptionWeight  = 1
+)
+
+// snippetLength is the number of words in a snippet.
+const snippetLength = 16
+
+// Filter restricts a search to some of the datasets.
+type Filter struct {
+	// Categories limits the search to datasets in any of the categories.
+	Categories []string
+}
+
+// condition returns the SQL condition, and its arguments, that applies the
+// filter to a database backed index.
+func (f Filter) condition() (string, []interface{}) {
+	if len(f.Categories) == 0 {
+		return "", nil
+	}
+	return " AND category_id IN ?", []interface{}{f.Categories}
+}
+
+// Result is a dataset that matched a query.
+type Result struct {
+	DatasetID string
+	Score     float64
+	Snippet   string
+}
+
+// Index is a full text index of datasets.
+//
+// Queries match datasets containing every word in the query. Words may be
+// grouped into a phrase with double quotes, and a word ending in * matches
+// any word starting with it.
+type Index interface {
+	// Index adds the dataset to the index or updates its entry.
+	Index(dataset models.Dataset) error
+
+	// Remove removes the dataset from the index.
+	Remove(datasetID string) error
+
+	// Rebuild replaces the contents of the index with the datasets.
+	Rebuild(datasets []models.Dataset) error
+
+	// Search returns a page of the datasets matching the query and
+	// filter, most relevant first, along with the total number of matches.
+	Search(query string, filter Filter, limit, offset int) ([]Result, int64, error)
+}
+
+// NewIndex returns an index using the full text support of the database:
+// FTS5 for SQLite, tsvector for Postgres and FULLTEXT for MySQL. If the
+// database does not support it an in-memory index is returned.
+func NewIndex(db *repo.Database) Index {
+	var dialect string
+	db.View(func(db *gorm.DB) error {
+		dialect = db.Dialector.Name()
+		return nil
+	})
+
+	var (
+		index Index
+		err   error
+	)
+	switch dialect {
+	case "sqlite":
+		index, err = newSQLiteIndex(db)
+	case "postgres":
+		index, err = newPostgresIndex(db)
+	case "mysql":
+		index, err = newMySQLIndex(db)
+	default:
+		err = errors.New("unsupported dialect")
+	}
+	if err != nil {
+		// The in-memory index is lost on restart and doesn't scale, so this
+		// is an error rather than a quiet fallback.
+		if dialect == "sqlite" {
+			log.Errorf("Full text search unavailable for sqlite database, using in-memory index. Build with -tags sqlite_fts5 to enable it: %s", err)
+		} else {
+			log.Errorf("Full text search unavailable for %s database, using in-memory index: %s", dialect, err)
+		}
+		return NewMemoryIndex()
+	}
+	return index
+}
+
+// token is a word in a text along with its position in the text.
+type token struct {
+	word       string
+	start, end int
+}
+
+// tokenize splits text into lower case words of letters and digits.
+func tokenize(text string) []token {
+	var (
+		tokens []token
+		start  = -1
+	)
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, token{strings.ToLower(text[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{strings.ToLower(text[start:]), start, len(text)})
+	}
+	return tokens
+}
+
+func words(text string) []string {
+	tokens := tokenize(text)
+	w := make([]string, len(tokens))
+	for i, t := range tokens {
+		w[i] = t.word
+	}
+	return w
+}
+
+// clause is a part of a query that a dataset must match. A clause of more
+// than one word is a phrase. If prefix is set the last word matches any
+// word starting with it.
+type clause struct {
+	words  []string
+	prefix bool
+}
+
+// parseQuery splits a query into clauses.
+func parseQuery(query string) []clause {
+	var clauses []clause
+	for len(query) > 0 {
+		query = strings.TrimLeftFunc(query, unicode.IsSpace)
+		if query == "" {
+			break
+		}
+
+		var part string
+		if query[0] == '"' {
+			end := strings.IndexByte(query[1:], '"')
+			if end < 0 {
+				part, query = query[1:], ""
+			} else {
+				part, query = query[1:end+1], query[end+2:]
+			}
+			if w := words(part); len(w) > 0 {
+				clauses = append(clauses, clause{words: w})
+			}
+			continue
+		}
+
+		end := strings.IndexFunc(query, unicode.IsSpace)
+		if end < 0 {
+			end = len(query)
+		}
+		part, query = query[:end], query[end:]
+		if w := words(part); len(w) > 0 {
+			clauses = append(clauses, clause{
+				words:  w,
+				prefix: strings.HasSuffix(part, "*"),
+			})
+		}
+	}
+	return clauses
+}
+
+// matches returns whether the word matches the i'th word of the clause.
+func (c clause) matches(i int, word string) bool {
+	if c.prefix && i == len(c.words)-1 {
+		return strings.HasPrefix(word, c.words[i])
+	}
+	return word == c.words[i]
+}
+
+// occurrences returns the number of times the clause appears in the words.
+func (c clause) occurrences(w []string) int {
+	n := 0
+	for i := 0; i+len(c.words) <= len(w); i++ {
+		found := true
+		for j := range c.words {
+			if !c.matches(j, w[i+j]) {
+				found = false
+				break
+			}
+		}
+		if found {
+			n++
+		}
+	}
+	return n
+}
+
+// snippet returns an HTML excerpt from the dataset with the words matching
+// the query wrapped in <mark> tags. The excerpt is taken from the first
+// field with a match, preferring the descriptions, and falls back to the
+// start of the short description.
+func snippet(clauses []clause, title, shortDescription, fullDescription string) string {
+	for _, text := range []string{shortDescription, fullDescription, title} {
+		tokens := tokenize(text)
+		for i, t := range tokens {
+			if matchesAny(clauses, t.word) {
+				return highlight(text, tokens, i, clauses)
+			}
+		}
+	}
+	return highlight(shortDescription, tokenize(shortDescription), 0, clauses)
+}
+
+func matchesAny(clauses []clause, word string) bool {
+	for _, c := range clauses {
+		for i := range c.words {
+			if c.matches(i, word) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// highlight returns the words of text around the first match, escaped
+// for HTML, with the matching words marked.
+func highlight(text string, tokens []token, first int, clauses []clause) string {
+	if len(tokens) == 0 {
+		return ""
+	}
+	start := first - snippetLength/4
+	if start < 0 {
+		start = 0
+	}
+	end := start + snippetLength
+	if end > len(tokens) {
+		end = len(tokens)
+	}
+
+	var (
+		b   strings.Builder
+		pos = tokens[start].start
+	)
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for _, t := range tokens[start:end] {
+		b.WriteString(html.EscapeString(text[pos:t.start]))
+		if matchesAny(clauses, t.word) {
+			b.WriteString("<mark>" + html.EscapeString(text[t.start:t.end]) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(text[t.start:t.end]))
+		}
+		pos = t.end
+	}
+	if end < len(tokens) {
+		b.WriteString("…")
+	} else {
+		b.WriteString(html.EscapeString(text[pos:]))
+	}
+	return b.String()
+}
+
+// indexedDataset is a row of a database backed index.
+type indexedDataset struct {
+	DatasetID        string
+	Title            string
+	ShortDescription string
+	FullDescription  string
+	Score            float64
+}
+
+func newResults(rows []indexedDataset, clauses []clause) []Result {
+	results := make([]Result, len(rows))
+	for i, row := range rows {
+		results[i] = Result{
+			DatasetID: row.DatasetID,
+			Score:     row.Score,
+			Snippet:   snippet(clauses, row.Title, row.ShortDescription, row.FullDescription),
+		}
+	}
+	return results
+}
@@ -0,0 +1,141 @@
+package search
+
+import (
+	"github.com/OB1Company/filehive/repo"
+	"github.com/OB1Company/filehive/repo/models"
+	"reflect"
+	"testing"
+)
+
+func Test_ParseQuery(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected []clause
+	}{
+		{
+			query:    "  Snowden  leaks ",
+			expected: []clause{{words: []string{"snowden"}}, {words: []string{"leaks"}}},
+		},
+		{
+			query:    `"climate data" temp*`,
+			expected: []clause{{words: []string{"climate", "data"}}, {words: []string{"temp"}, prefix: true}},
+		},
+		{
+			query:    `covid-19 "open phrase`,
+			expected: []clause{{words: []string{"covid", "19"}}, {words: []string{"open", "phrase"}}},
+		},
+		{
+			query:    `"" * !!`,
+			expected: nil,
+		},
+	}
+
+	for _, test := range tests {
+		if clauses := parseQuery(test.query); !reflect.DeepEqual(clauses, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.query, test.expected, clauses)
+		}
+	}
+}
+
+func Test_Snippet(t *testing.T) {
+	s := snippet(parseQuery("leak*"), "Leaks", "Files & <documents> that leaked", "")
+	expected := "Files &amp; &lt;documents&gt; that <mark>leaked</mark>"
+	if s != expected {
+		t.Errorf("Expected snippet %s, got %s", expected, s)
+	}
+}
+
+func testIndex(t *testing.T, name string, index Index) {
+	datasets := []models.Dataset{
+		{ID: "1", CategoryID: "weather", Title: "Global temperature records", ShortDescription: "Climate data from weather stations", FullDescription: "Daily temperatures since 1880."},
+		{ID: "2", CategoryID: "weather", Title: "Ocean climate", ShortDescription: "Sea surface temperatures", FullDescription: "Data on the ocean climate."},
+		{ID: "3", CategoryID: "politics", Title: "Election results", ShortDescription: "Results by county", FullDescription: "Includes climate data for polling days."},
+	}
+	if err := index.Rebuild(datasets); err != nil {
+		t.Fatal(err)
+	}
+
+	search := func(query string, limit, offset int) ([]string, int64) {
+		results, total, err := index.Search(query, Filter{}, limit, offset)
+		if err != nil {
+			t.Fatalf("%s: %s: %s", name, query, err)
+		}
+		ids := []string{}
+		for _, r := range results {
+			ids = append(ids, r.DatasetID)
+		}
+		return ids, total
+	}
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{query: "climate", expected: []string{"2", "1", "3"}},
+		{query: `"climate data"`, expected: []string{"1", "3"}},
+		{query: `"data climate"`, expected: []string{}},
+		{query: "temp*", expected: []string{"1", "2"}},
+		{query: "ocean climate", expected: []string{"2"}},
+		{query: "missing", expected: []string{}},
+	}
+	for _, test := range tests {
+		ids, total := search(test.query, 10, 0)
+		if !reflect.DeepEqual(ids, test.expected) || total != int64(len(test.expected)) {
+			t.Errorf("%s: %s: expected %v, got %v (%d total)", name, test.query, test.expected, ids, total)
+		}
+	}
+
+	if ids, total := search("climate", 1, 1); !reflect.DeepEqual(ids, []string{"1"}) || total != 3 {
+		t.Errorf("%s: expected the second page to contain 1, got %v (%d total)", name, ids, total)
+	}
+
+	results, total, err := index.Search("climate", Filter{Categories: []string{"politics", "sports"}}, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].DatasetID != "3" || total != 1 {
+		t.Errorf("%s: expected the category filter to match 3, got %v (%d total)", name, results, total)
+	}
+
+	if _, _, err := index.Search(" * ", Filter{}, 10, 0); err != ErrEmptyQuery {
+		t.Errorf("%s: expected ErrEmptyQuery, got %v", name, err)
+	}
+
+	datasets[2].ShortDescription = "Ocean county results"
+	if err := index.Index(datasets[2]); err != nil {
+		t.Fatal(err)
+	}
+	if ids, _ := search("ocean", 10, 0); !reflect.DeepEqual(ids, []string{"2", "3"}) {
+		t.Errorf("%s: expected the edited dataset to match, got %v", name, ids)
+	}
+	if err := index.Remove("2"); err != nil {
+		t.Fatal(err)
+	}
+	if ids, _ := search("ocean", 10, 0); !reflect.DeepEqual(ids, []string{"3"}) {
+		t.Errorf("%s: expected the removed dataset not to match, got %v", name, ids)
+	}
+
+	results, _, err = index.Search("weather", Filter{}, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Snippet != "Climate data from <mark>weather</mark> stations" {
+		t.Errorf("%s: unexpected snippet %v", name, results)
+	}
+}
+
+func Test_MemoryIndex(t *testing.T) {
+	testIndex(t, "memory", NewMemoryIndex())
+}
+
+func Test_SQLiteIndex(t *testing.T) {
+	db, err := repo.NewDatabase("", repo.Dialect("memory"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := NewIndex(db)
+	if _, ok := index.(*sqliteIndex); !ok {
+		t.Skip("sqlite driver built without FTS5")
+	}
+	testIndex(t, "sqlite", index)
+}
@@ -0,0 +1,98 @@
+package search
+
+import (
+	"fmt"
+	"github.com/OB1Company/filehive/repo"
+	"github.com/OB1Company/filehive/repo/models"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// sqliteIndex is an FTS5 virtual table. FTS5 is only compiled into the
+// sqlite driver when it is built with the sqlite_fts5 tag.
+type sqliteIndex struct {
+	db *repo.Database
+}
+
+func newSQLiteIndex(db *repo.Database) (*sqliteIndex, error) {
+	err := db.Update(func(db *gorm.DB) error {
+		return db.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS dataset_search USING fts5(dataset_id UNINDEXED, category_id UNINDEXED, title, short_description, full_description)").Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteIndex{db: db}, nil
+}
+
+func (s *sqliteIndex) Index(dataset models.Dataset) error {
+	return s.db.Update(func(db *gorm.DB) error {
+		return sqliteInsert(db, dataset)
+	})
+}
+
+func (s *sqliteIndex) Remove(datasetID string) error {
+	return s.db.Update(func(db *gorm.DB) error {
+		return db.Exec("DELETE FROM dataset_search WHERE dataset_id = ?", datasetID).Error
+	})
+}
+
+func (s *sqliteIndex) Rebuild(datasets []models.Dataset) error {
+	return s.db.Update(func(db *gorm.DB) error {
+		if err := db.Exec("DELETE FROM dataset_search").Error; err != nil {
+			return err
+		}
+		for _, dataset := range datasets {
+			if err := sqliteInsert(db, dataset); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *sqliteIndex) Search(query string, filter Filter, limit, offset int) ([]Result, int64, error) {
+	clauses := parseQuery(query)
+	if len(clauses) == 0 {
+		return nil, 0, ErrEmptyQuery
+	}
+	match := fts5Query(clauses)
+	cond, args := filter.condition()
+	args = append([]interface{}{match}, args...)
+
+	var (
+		rows  []indexedDataset
+		total int64
+	)
+	err := s.db.View(func(db *gorm.DB) error {
+		if err := db.Raw("SELECT count(*) FROM dataset_search WHERE dataset_search MATCH ?"+cond, args...).Scan(&total).Error; err != nil {
+			return err
+		}
+		// bm25 is lower for better matches.
+		rank := fmt.Sprintf("-bm25(dataset_search, 0, 0, %d, %d, %d)", titleWeight, shortDescriptionWeight, fullDescriptionWeight)
+		return db.Raw("SELECT dataset_id, title, short_description, full_description, "+rank+" AS score FROM dataset_search WHERE dataset_search MATCH ?"+cond+" ORDER BY score DESC, dataset_id LIMIT ? OFFSET ?", append(args, limit, offset)...).Scan(&rows).Error
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return newResults(rows, clauses), total, nil
+}
+
+func sqliteInsert(db *gorm.DB, dataset models.Dataset) error {
+	if err := db.Exec("DELETE FROM dataset_search WHERE dataset_id = ?", dataset.ID).Error; err != nil {
+		return err
+	}
+	return db.Exec("INSERT INTO dataset_search (dataset_id, category_id, title, short_description, full_description) VALUES (?, ?, ?, ?, ?)", dataset.ID, dataset.CategoryID, dataset.Title, dataset.ShortDescription, dataset.FullDescription).Error
+}
+
+// fts5Query returns the clauses as an FTS5 query. Every clause is quoted
+// as a phrase, which is safe as the words only contain letters and digits.
+func fts5Query(clauses []clause) string {
+	parts := make([]string, len(clauses))
+	for i, c := range clauses {
+		parts[i] = `"` + strings.Join(c.words, " ") + `"`
+		if c.prefix {
+			parts[i] += "*"
+		}
+	}
+	return strings.Join(parts, " AND ")
+}
@@ -84,7 +84,7 @@ function Create() {
         fullDescription: fullDescription,
         image: fileString,
         fileType: fileType,
-        price: Number(price),
+        price: String(price).trim(),
         filename: datasetFilename
       };
 
@@ -109,7 +109,7 @@ export default function Settings() {
         title: title,
         shortDescription: shortDescription,
         fullDescription: fullDescription,
-        price: Number(price),
+        price: String(price).trim(),
       };
 
       if(fileString !== "") {
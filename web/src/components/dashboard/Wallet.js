@@ -138,7 +138,7 @@ export default function Wallet() {
             return false;
         }
 
-        const data = { amount: parseFloat(amount), address: recipient };
+        const data = { amount: amount.trim(), address: recipient };
 
         const sendCoins = async () => {
 